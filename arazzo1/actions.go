@@ -1,7 +1,7 @@
 package arazzo1
 
 import (
-	"encoding/json"
+	"reflect"
 )
 
 // SuccessActionType represents the type of success action to take.
@@ -53,19 +53,31 @@ var successActionKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (s *SuccessAction) UnmarshalJSON(data []byte) error {
-	var alias successActionAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*s = SuccessAction(alias)
+	return decodeJSON(data, s.decodeJSON)
+}
 
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-	s.Extensions = extractExtensions(raw, successActionKnownFields)
+func (s *SuccessAction) decodeJSON(d *decoder) error {
+	*s = SuccessAction{}
+	return d.object(reflect.TypeFor[SuccessAction](), successActionKnownFields, &s.Extensions, func(key string) error {
+		return s.decodeField(d, key)
+	})
+}
 
-	return nil
+// decodeField decodes the value of the known field key.
+func (s *SuccessAction) decodeField(d *decoder, key string) error {
+	switch key {
+	case "name":
+		return decodeString(d, &s.Name)
+	case "type":
+		return decodeString(d, &s.Type)
+	case "workflowId":
+		return decodeString(d, &s.WorkflowId)
+	case "stepId":
+		return decodeString(d, &s.StepId)
+	case "criteria":
+		return decodeSlice(d, &s.Criteria)
+	}
+	return d.skip()
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -136,19 +148,35 @@ var failureActionKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (f *FailureAction) UnmarshalJSON(data []byte) error {
-	var alias failureActionAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*f = FailureAction(alias)
+	return decodeJSON(data, f.decodeJSON)
+}
 
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-	f.Extensions = extractExtensions(raw, failureActionKnownFields)
+func (f *FailureAction) decodeJSON(d *decoder) error {
+	*f = FailureAction{}
+	return d.object(reflect.TypeFor[FailureAction](), failureActionKnownFields, &f.Extensions, func(key string) error {
+		return f.decodeField(d, key)
+	})
+}
 
-	return nil
+// decodeField decodes the value of the known field key.
+func (f *FailureAction) decodeField(d *decoder, key string) error {
+	switch key {
+	case "name":
+		return decodeString(d, &f.Name)
+	case "type":
+		return decodeString(d, &f.Type)
+	case "workflowId":
+		return decodeString(d, &f.WorkflowId)
+	case "stepId":
+		return decodeString(d, &f.StepId)
+	case "retryAfter":
+		return decodeFloat(d, &f.RetryAfter)
+	case "retryLimit":
+		return decodeInt(d, &f.RetryLimit)
+	case "criteria":
+		return decodeSlice(d, &f.Criteria)
+	}
+	return d.skip()
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -1,8 +1,8 @@
 package arazzo1
 
 import (
-	"encoding/json"
 	"fmt"
+	"reflect"
 )
 
 // Arazzo represents the root object of an Arazzo 1.0.x document.
@@ -39,21 +39,31 @@ var arazzoKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (a *Arazzo) UnmarshalJSON(data []byte) error {
-	var alias arazzoAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
+	if err := decodeJSON(data, a.decodeJSON); err != nil {
 		return fmt.Errorf("unmarshaling arazzo: %w", err)
 	}
-	*a = Arazzo(alias)
-
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return fmt.Errorf("unmarshaling arazzo extensions: %w", err)
-	}
-	a.Extensions = extractExtensions(raw, arazzoKnownFields)
-
 	return nil
 }
 
+func (a *Arazzo) decodeJSON(d *decoder) error {
+	*a = Arazzo{}
+	return d.object(reflect.TypeFor[Arazzo](), arazzoKnownFields, &a.Extensions, func(key string) error {
+		switch key {
+		case "arazzo":
+			return decodeString(d, &a.Arazzo)
+		case "info":
+			return decodePtr(d, &a.Info)
+		case "sourceDescriptions":
+			return decodeSlice(d, &a.SourceDescriptions)
+		case "workflows":
+			return decodeSlice(d, &a.Workflows)
+		case "components":
+			return decodePtr(d, &a.Components)
+		}
+		return d.skip()
+	})
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (a Arazzo) MarshalJSON() ([]byte, error) {
 	alias := arazzoAlias(a)
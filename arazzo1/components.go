@@ -1,8 +1,11 @@
 package arazzo1
 
 import (
-	"encoding/json"
-	"strings"
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 )
 
 // Components holds a set of reusable objects for different aspects of the Arazzo Specification.
@@ -34,65 +37,111 @@ var componentsKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (c *Components) UnmarshalJSON(data []byte) error {
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-
-	// Unmarshal each known field
-	if inputsData, ok := raw["inputs"]; ok {
-		if err := json.Unmarshal(inputsData, &c.Inputs); err != nil {
-			return err
-		}
-	}
-
-	if parametersData, ok := raw["parameters"]; ok {
-		if err := json.Unmarshal(parametersData, &c.Parameters); err != nil {
-			return err
-		}
-	}
-
-	if successActionsData, ok := raw["successActions"]; ok {
-		if err := json.Unmarshal(successActionsData, &c.SuccessActions); err != nil {
-			return err
-		}
-	}
+	return decodeJSON(data, c.decodeJSON)
+}
 
-	if failureActionsData, ok := raw["failureActions"]; ok {
-		if err := json.Unmarshal(failureActionsData, &c.FailureActions); err != nil {
-			return err
+func (c *Components) decodeJSON(d *decoder) error {
+	*c = Components{}
+	return d.object(reflect.TypeFor[Components](), componentsKnownFields, &c.Extensions, func(key string) error {
+		switch key {
+		case "inputs":
+			return decodeValueMap(d, &c.Inputs)
+		case "parameters":
+			return decodeMap(d, &c.Parameters)
+		case "successActions":
+			return decodeMap(d, &c.SuccessActions)
+		case "failureActions":
+			return decodeMap(d, &c.FailureActions)
 		}
-	}
-
-	// Extract extensions
-	c.Extensions = extractExtensions(raw, componentsKnownFields)
+		return d.skip()
+	})
+}
 
-	return nil
+// componentsAlias orders the fields of Components as the specification does.
+type componentsAlias struct {
+	Inputs         map[string]any            `json:"inputs,omitempty"`
+	Parameters     map[string]*Parameter     `json:"parameters,omitempty"`
+	SuccessActions map[string]*SuccessAction `json:"successActions,omitempty"`
+	FailureActions map[string]*FailureAction `json:"failureActions,omitempty"`
 }
 
 // MarshalJSON implements the json.Marshaler interface.
 func (c Components) MarshalJSON() ([]byte, error) {
-	result := make(map[string]any)
-
-	if len(c.Inputs) > 0 {
-		result["inputs"] = c.Inputs
-	}
-	if len(c.Parameters) > 0 {
-		result["parameters"] = c.Parameters
-	}
-	if len(c.SuccessActions) > 0 {
-		result["successActions"] = c.SuccessActions
-	}
-	if len(c.FailureActions) > 0 {
-		result["failureActions"] = c.FailureActions
+	alias := componentsAlias{
+		Inputs:         c.Inputs,
+		Parameters:     c.Parameters,
+		SuccessActions: c.SuccessActions,
+		FailureActions: c.FailureActions,
 	}
+	return marshalWithExtensions(&alias, c.Extensions)
+}
 
-	// Add extensions
-	for key, value := range c.Extensions {
-		if strings.HasPrefix(key, "x-") {
-			result[key] = value
+// DecodeHCL decodes the body of a components block, reporting problems as
+// hcl.Diagnostics with the source ranges of body. Parameters and actions are
+// labeled blocks, e.g. parameter "page" { ... }.
+func (c *Components) DecodeHCL(body *hclsyntax.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
+			continue
+		}
+		if attr.Name != "inputs" {
+			diags = append(diags, decodeExtension(attr, val, &c.Extensions)...)
+			continue
 		}
+		if !val.IsNull() && !val.Type().IsObjectType() && !val.Type().IsMapType() {
+			diags = append(diags, typeMismatch(attr, "object")...)
+			continue
+		}
+		inputs, _ := ctyToGo(val).(map[string]any)
+		c.Inputs = inputs
 	}
 
-	return json.Marshal(result)
+	for _, block := range body.Blocks {
+		if block.Type == "inputs" {
+			inputs, d := hclBlockToMap(block)
+			diags = append(diags, d...)
+			c.Inputs = inputs
+			continue
+		}
+		if block.Type != "parameter" && block.Type != "successAction" && block.Type != "failureAction" {
+			diags = append(diags, unsupportedBlock(block))
+			continue
+		}
+		if len(block.Labels) != 1 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing component name",
+				Detail:   fmt.Sprintf("A %s block in components needs one label, its name.", block.Type),
+				Subject:  block.DefRange().Ptr(),
+			})
+			continue
+		}
+		name := block.Labels[0]
+		switch block.Type {
+		case "parameter":
+			param := &Parameter{Name: name}
+			diags = append(diags, parseParameterBody(block.Body, param)...)
+			if c.Parameters == nil {
+				c.Parameters = make(map[string]*Parameter)
+			}
+			c.Parameters[name] = param
+		case "successAction":
+			action := &SuccessAction{Name: name}
+			diags = append(diags, parseSuccessActionBlock(block, action)...)
+			if c.SuccessActions == nil {
+				c.SuccessActions = make(map[string]*SuccessAction)
+			}
+			c.SuccessActions[name] = action
+		case "failureAction":
+			action := &FailureAction{Name: name}
+			diags = append(diags, parseFailureActionBlock(block, action)...)
+			if c.FailureActions == nil {
+				c.FailureActions = make(map[string]*FailureAction)
+			}
+			c.FailureActions[name] = action
+		}
+	}
+	return diags
 }
@@ -0,0 +1,247 @@
+package arazzo1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConditionNode is a node of a parsed simple criterion condition.
+// It is one of *ConditionBinary, *ConditionNot, *ConditionLiteral or *ConditionExpression.
+type ConditionNode interface {
+	conditionNode()
+}
+
+// ConditionBinary is a logical (&&, ||) or comparison (==, !=, <, <=, >, >=) operation.
+type ConditionBinary struct {
+	Op    string
+	Left  ConditionNode
+	Right ConditionNode
+}
+
+// ConditionNot negates its operand.
+type ConditionNot struct {
+	Operand ConditionNode
+}
+
+// ConditionLiteral is a number (float64), string, bool or null (nil) literal.
+type ConditionLiteral struct {
+	Value any
+}
+
+// ConditionExpression is a runtime expression operand. Index and property
+// de-references written after the expression, such as $response.body.items[0],
+// remain part of the expression segments.
+type ConditionExpression struct {
+	Expression *Expression
+}
+
+func (*ConditionBinary) conditionNode()     {}
+func (*ConditionNot) conditionNode()        {}
+func (*ConditionLiteral) conditionNode()    {}
+func (*ConditionExpression) conditionNode() {}
+
+// ParseCondition parses the condition of a simple criterion, as defined by the
+// Arazzo specification: literals, runtime expressions, comparison operators
+// (==, !=, <, <=, >, >=), logical operators (&&, ||, !) and grouping with ().
+func ParseCondition(s string) (ConditionNode, error) {
+	p := &conditionParser{src: s}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in condition %q", p.tokens[p.pos].text, s)
+	}
+	return node, nil
+}
+
+type conditionToken struct {
+	kind string // "op", "expr", "number", "string", "ident"
+	text string
+}
+
+type conditionParser struct {
+	src    string
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) tokenize() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '$':
+			n := ScanExpression(s[i:])
+			p.tokens = append(p.tokens, conditionToken{"expr", s[i : i+n]})
+			i += n
+		case c == '\'' || c == '"':
+			j := i + 1
+			var b strings.Builder
+			for j < len(s) && s[j] != c {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			if j >= len(s) {
+				return fmt.Errorf("unterminated string in condition %q", s)
+			}
+			p.tokens = append(p.tokens, conditionToken{"string", b.String()})
+			i = j + 1
+		case strings.HasPrefix(s[i:], "&&"), strings.HasPrefix(s[i:], "||"),
+			strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="),
+			strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			p.tokens = append(p.tokens, conditionToken{"op", s[i : i+2]})
+			i += 2
+		case c == '<' || c == '>' || c == '!' || c == '(' || c == ')':
+			p.tokens = append(p.tokens, conditionToken{"op", string(c)})
+			i++
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || s[j] == 'e' || s[j] == 'E' || s[j] == '+' || s[j] == '-' || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			p.tokens = append(p.tokens, conditionToken{"number", s[i:j]})
+			i = j
+		case isIdentByte(c):
+			j := i + 1
+			for j < len(s) && isIdentByte(s[j]) {
+				j++
+			}
+			p.tokens = append(p.tokens, conditionToken{"ident", s[i:j]})
+			i = j
+		default:
+			return fmt.Errorf("unexpected character %q in condition %q", c, s)
+		}
+	}
+	return nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (p *conditionParser) peek(texts ...string) bool {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "op" {
+		return false
+	}
+	for _, t := range texts {
+		if p.tokens[p.pos].text == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *conditionParser) parseOr() (ConditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &ConditionBinary{Op: "||", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (ConditionNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &ConditionBinary{Op: "&&", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseComparison() (ConditionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek("==", "!=", "<", "<=", ">", ">=") {
+		op := p.tokens[p.pos].text
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &ConditionBinary{Op: op, Left: left, Right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (ConditionNode, error) {
+	if p.peek("!") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &ConditionNot{Operand: operand}, nil
+	}
+	if p.peek("(") {
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing ')' in condition %q", p.src)
+		}
+		p.pos++
+		return node, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of condition %q", p.src)
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case "expr":
+		expr, err := ParseExpression(tok.text)
+		if err != nil {
+			return nil, err
+		}
+		return &ConditionExpression{Expression: expr}, nil
+	case "string":
+		return &ConditionLiteral{Value: tok.text}, nil
+	case "number":
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in condition %q", tok.text, p.src)
+		}
+		return &ConditionLiteral{Value: f}, nil
+	case "ident":
+		switch tok.text {
+		case "true":
+			return &ConditionLiteral{Value: true}, nil
+		case "false":
+			return &ConditionLiteral{Value: false}, nil
+		case "null":
+			return &ConditionLiteral{Value: nil}, nil
+		}
+		// Bare words are treated as string literals, e.g. $response.body#/status == available.
+		return &ConditionLiteral{Value: tok.text}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q in condition %q", tok.text, p.src)
+}
@@ -1,7 +1,8 @@
 package arazzo1
 
 import (
-	"encoding/json"
+	"maps"
+	"reflect"
 )
 
 // CriterionType represents the type of condition to be applied.
@@ -59,35 +60,38 @@ var criterionKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (c *Criterion) UnmarshalJSON(data []byte) error {
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-
-	// Check if this has both "type" and "version" (criterion-expression-type-object)
-	_, hasType := raw["type"]
-	_, hasVersion := raw["version"]
+	return decodeJSON(data, c.decodeJSON)
+}
 
-	if hasType && hasVersion {
-		// This is a criterion with expression type
-		c.ExpressionType = &CriterionExpressionType{}
-		if err := json.Unmarshal(data, c.ExpressionType); err != nil {
-			return err
+func (c *Criterion) decodeJSON(d *decoder) error {
+	*c = Criterion{}
+	var version *string
+	hasType := false
+	err := d.object(reflect.TypeFor[Criterion](), criterionKnownFields, &c.Extensions, func(key string) error {
+		switch key {
+		case "context":
+			return decodeString(d, &c.Context)
+		case "condition":
+			return decodeString(d, &c.Condition)
+		case "type":
+			hasType = true
+			return decodeString(d, &c.Type)
+		case "version":
+			version = new(string)
+			return decodeString(d, version)
+		}
+		return d.skip()
+	})
+
+	// Both "type" and "version" make a criterion-expression-type-object.
+	if hasType && version != nil {
+		c.ExpressionType = &CriterionExpressionType{
+			Type:       c.Type,
+			Version:    *version,
+			Extensions: maps.Clone(c.Extensions),
 		}
 	}
-
-	// Unmarshal the common fields
-	var alias criterionAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	c.Context = alias.Context
-	c.Condition = alias.Condition
-	c.Type = alias.Type
-
-	c.Extensions = extractExtensions(raw, criterionKnownFields)
-
-	return nil
+	return err
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -142,19 +146,20 @@ var criterionExpressionTypeKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (c *CriterionExpressionType) UnmarshalJSON(data []byte) error {
-	var alias criterionExpressionTypeAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*c = CriterionExpressionType(alias)
-
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-	c.Extensions = extractExtensions(raw, criterionExpressionTypeKnownFields)
+	return decodeJSON(data, c.decodeJSON)
+}
 
-	return nil
+func (c *CriterionExpressionType) decodeJSON(d *decoder) error {
+	*c = CriterionExpressionType{}
+	return d.object(reflect.TypeFor[CriterionExpressionType](), criterionExpressionTypeKnownFields, &c.Extensions, func(key string) error {
+		switch key {
+		case "type":
+			return decodeString(d, &c.Type)
+		case "version":
+			return decodeString(d, &c.Version)
+		}
+		return d.skip()
+	})
 }
 
 // MarshalJSON implements the json.Marshaler interface.
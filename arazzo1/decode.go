@@ -0,0 +1,631 @@
+package arazzo1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// decoder reads a JSON document in a single pass. Each type routes the
+// members of its object as they are read: known fields are decoded in place,
+// x-* fields are collected as extensions and the rest are skipped. Nested
+// objects are decoded from the same decoder instead of being re-scanned from
+// a sub-slice, so every byte of a document is tokenized once.
+//
+// As with encoding/json, a value of the wrong type does not stop decoding:
+// it is skipped, leaving its field unchanged, and the first such mismatch
+// is returned as a *json.UnmarshalTypeError once the rest of the document
+// has been decoded. Syntax errors stop decoding at once.
+type decoder struct {
+	data []byte
+	off  int
+	// typeErr is the first value that did not match its type.
+	typeErr *json.UnmarshalTypeError
+}
+
+// jsonDecoder is implemented by the types that decode from a decoder.
+type jsonDecoder[T any] interface {
+	*T
+	decodeJSON(d *decoder) error
+}
+
+// decodeJSON decodes data, which must hold exactly one value, with decode.
+func decodeJSON(data []byte, decode func(d *decoder) error) error {
+	d := &decoder{data: data}
+	if err := decode(d); err != nil {
+		return err
+	}
+	if d.skipSpace(); d.off < len(d.data) {
+		return d.syntaxError("after top-level value")
+	}
+	if d.typeErr != nil {
+		return d.typeErr
+	}
+	return nil
+}
+
+func (d *decoder) skipSpace() {
+	for d.off < len(d.data) {
+		switch d.data[d.off] {
+		case ' ', '\t', '\n', '\r':
+			d.off++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the first byte of the next value, or 0 at the end of input.
+func (d *decoder) peek() byte {
+	d.skipSpace()
+	if d.off < len(d.data) {
+		return d.data[d.off]
+	}
+	return 0
+}
+
+func (d *decoder) syntaxError(context string) error {
+	if d.off >= len(d.data) {
+		return fmt.Errorf("unexpected end of JSON input")
+	}
+	c := strconv.Quote(string(d.data[d.off]))
+	if d.data[d.off] == '\'' {
+		c = `\'`
+	} else {
+		c = c[1 : len(c)-1]
+	}
+	return fmt.Errorf("invalid character '%s' %s at offset %d", c, context, d.off)
+}
+
+// mismatch records that the value at off, described by value, cannot be
+// decoded into t, unless an earlier value did not match.
+func (d *decoder) mismatch(value string, t reflect.Type, off int) {
+	if d.typeErr == nil {
+		d.typeErr = &json.UnmarshalTypeError{Value: value, Type: t, Offset: int64(off)}
+	}
+}
+
+// typeError records that the next value cannot be decoded into t, and
+// skips it.
+func (d *decoder) typeError(t reflect.Type) error {
+	value := "number"
+	switch d.peek() {
+	case '{':
+		value = "object"
+	case '[':
+		value = "array"
+	case '"':
+		value = "string"
+	case 't', 'f':
+		value = "bool"
+	}
+	d.mismatch(value, t, d.off)
+	return d.skip()
+}
+
+func (d *decoder) literal(lit string) error {
+	if len(d.data)-d.off < len(lit) || string(d.data[d.off:d.off+len(lit)]) != lit {
+		for i := 0; i < len(lit) && d.off < len(d.data) && d.data[d.off] == lit[i]; i++ {
+			d.off++
+		}
+		return d.syntaxError("in literal " + lit)
+	}
+	d.off += len(lit)
+	return nil
+}
+
+// null consumes a null and reports whether the next value was one.
+func (d *decoder) null() (bool, error) {
+	if d.peek() != 'n' {
+		return false, nil
+	}
+	return true, d.literal("null")
+}
+
+// members reads an object, calling member for each key with the decoder
+// positioned at its value, which member must consume. The key may alias the
+// input and is only valid during the call. A null is read as no members.
+func (d *decoder) members(t reflect.Type, member func(key []byte) error) error {
+	switch d.peek() {
+	case '{':
+		d.off++
+	case 'n':
+		return d.literal("null")
+	case '[', '"', 't', 'f', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return d.typeError(t)
+	default:
+		return d.syntaxError("looking for beginning of value")
+	}
+	if d.peek() == '}' {
+		d.off++
+		return nil
+	}
+	for {
+		if d.peek() != '"' {
+			return d.syntaxError("looking for beginning of object key string")
+		}
+		key, err := d.stringBytes()
+		if err != nil {
+			return err
+		}
+		if d.peek() != ':' {
+			return d.syntaxError("after object key")
+		}
+		d.off++
+		if err := member(key); err != nil {
+			return err
+		}
+		switch d.peek() {
+		case ',':
+			d.off++
+		case '}':
+			d.off++
+			return nil
+		default:
+			return d.syntaxError("after object key:value pair")
+		}
+	}
+}
+
+// elements reads an array, calling element with the decoder positioned at
+// each value, which element must consume. A null is read as no elements.
+func (d *decoder) elements(t reflect.Type, element func() error) error {
+	switch d.peek() {
+	case '[':
+		d.off++
+	case 'n':
+		return d.literal("null")
+	case '{', '"', 't', 'f', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return d.typeError(t)
+	default:
+		return d.syntaxError("looking for beginning of value")
+	}
+	if d.peek() == ']' {
+		d.off++
+		return nil
+	}
+	for {
+		if err := element(); err != nil {
+			return err
+		}
+		switch d.peek() {
+		case ',':
+			d.off++
+		case ']':
+			d.off++
+			return nil
+		default:
+			return d.syntaxError("after array element")
+		}
+	}
+}
+
+// object reads the members of an arazzo1 object. Keys are matched against
+// known, case-insensitively as encoding/json does, and field decodes the
+// value of a known key given in its canonical spelling. Other x-* members
+// are collected into ext and the rest are skipped.
+func (d *decoder) object(t reflect.Type, known []string, ext *map[string]any, field func(key string) error) error {
+	return d.members(t, func(key []byte) error {
+		name := ""
+		for _, k := range known {
+			if string(key) == k {
+				name = k
+				break
+			}
+		}
+		if name == "" {
+			for _, k := range known {
+				if bytes.EqualFold(key, []byte(k)) {
+					name = k
+					break
+				}
+			}
+		}
+		if name != "" {
+			// A mismatch is reported at the innermost field it was found in.
+			first := d.typeErr == nil
+			err := field(name)
+			if e := d.typeErr; first && e != nil && e.Field == "" {
+				e.Struct, e.Field = t.Name(), name
+			}
+			return err
+		}
+		if !bytes.HasPrefix(key, []byte("x-")) {
+			return d.skip()
+		}
+		v, err := d.value()
+		if err != nil {
+			return err
+		}
+		if *ext == nil {
+			*ext = make(map[string]any)
+		}
+		(*ext)[string(key)] = v
+		return nil
+	})
+}
+
+// stringBytes reads a string and returns its unquoted bytes. Unless the
+// string needs unescaping, they alias the input.
+func (d *decoder) stringBytes() ([]byte, error) {
+	start := d.off + 1
+	for i := start; i < len(d.data); i++ {
+		switch c := d.data[i]; {
+		case c == '"':
+			d.off = i + 1
+			return d.data[start:i], nil
+		case c == '\\' || c < ' ' || c >= utf8.RuneSelf:
+			return d.unquote(start, i)
+		}
+	}
+	d.off = len(d.data)
+	return nil, d.syntaxError("in string literal")
+}
+
+// unquote finishes reading a string that needs unescaping or UTF-8 checks,
+// replacing invalid UTF-8 and lone surrogates the way encoding/json does.
+func (d *decoder) unquote(start, i int) ([]byte, error) {
+	buf := make([]byte, i-start, i-start+16)
+	copy(buf, d.data[start:i])
+	for i < len(d.data) {
+		c := d.data[i]
+		switch {
+		case c == '"':
+			d.off = i + 1
+			return buf, nil
+		case c < ' ':
+			d.off = i
+			return nil, d.syntaxError("in string literal")
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRune(d.data[i:])
+			buf = utf8.AppendRune(buf, r)
+			i += size
+			continue
+		case c != '\\':
+			buf = append(buf, c)
+			i++
+			continue
+		}
+		if i+1 >= len(d.data) {
+			d.off = len(d.data)
+			return nil, d.syntaxError("in string literal")
+		}
+		i += 2
+		switch e := d.data[i-1]; e {
+		case '"', '\\', '/':
+			buf = append(buf, e)
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case 'u':
+			r, ok := hex4(d.data[i:])
+			if !ok {
+				d.off = i
+				return nil, d.syntaxError("in \\u hexadecimal character escape")
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				r = utf8.RuneError
+				if len(d.data)-i >= 6 && d.data[i] == '\\' && d.data[i+1] == 'u' {
+					if r2, ok := hex4(d.data[i+2:]); ok {
+						if dec := utf16.DecodeRune(rune(r), r2); dec != utf8.RuneError {
+							r = dec
+							i += 6
+						}
+					}
+				}
+			}
+			buf = utf8.AppendRune(buf, r)
+		default:
+			d.off = i - 1
+			return nil, d.syntaxError("in string escape code")
+		}
+	}
+	d.off = len(d.data)
+	return nil, d.syntaxError("in string literal")
+}
+
+func hex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r*16 + rune(c)
+	}
+	return r, true
+}
+
+// number reads a number and returns its text.
+func (d *decoder) number() (string, error) {
+	start := d.off
+	digits := func() bool {
+		n := d.off
+		for d.off < len(d.data) && '0' <= d.data[d.off] && d.data[d.off] <= '9' {
+			d.off++
+		}
+		return d.off > n
+	}
+	if d.off < len(d.data) && d.data[d.off] == '-' {
+		d.off++
+	}
+	switch {
+	case d.off < len(d.data) && d.data[d.off] == '0':
+		d.off++
+	case !digits():
+		return "", d.syntaxError("in numeric literal")
+	}
+	if d.off < len(d.data) && d.data[d.off] == '.' {
+		d.off++
+		if !digits() {
+			return "", d.syntaxError("after decimal point in numeric literal")
+		}
+	}
+	if d.off < len(d.data) && (d.data[d.off] == 'e' || d.data[d.off] == 'E') {
+		d.off++
+		if d.off < len(d.data) && (d.data[d.off] == '+' || d.data[d.off] == '-') {
+			d.off++
+		}
+		if !digits() {
+			return "", d.syntaxError("in exponent of numeric literal")
+		}
+	}
+	return string(d.data[start:d.off]), nil
+}
+
+// value reads any value into the types encoding/json uses for an any.
+func (d *decoder) value() (any, error) {
+	switch d.peek() {
+	case '{':
+		m := make(map[string]any)
+		err := d.members(nil, func(key []byte) error {
+			v, err := d.value()
+			m[string(key)] = v
+			return err
+		})
+		return m, err
+	case '[':
+		s := make([]any, 0)
+		err := d.elements(nil, func() error {
+			v, err := d.value()
+			s = append(s, v)
+			return err
+		})
+		return s, err
+	case '"':
+		b, err := d.stringBytes()
+		return string(b), err
+	case 't':
+		return true, d.literal("true")
+	case 'f':
+		return false, d.literal("false")
+	case 'n':
+		return nil, d.literal("null")
+	}
+	off := d.off
+	s, err := d.number()
+	if err != nil {
+		if d.off == off {
+			return nil, d.syntaxError("looking for beginning of value")
+		}
+		return nil, err
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		d.mismatch("number "+s, reflect.TypeFor[float64](), off)
+		return nil, nil
+	}
+	return f, nil
+}
+
+// skip reads and discards a value.
+func (d *decoder) skip() error {
+	switch d.peek() {
+	case '{':
+		return d.members(nil, func([]byte) error { return d.skip() })
+	case '[':
+		return d.elements(nil, d.skip)
+	case '"':
+		_, err := d.stringBytes()
+		return err
+	case 't':
+		return d.literal("true")
+	case 'f':
+		return d.literal("false")
+	case 'n':
+		return d.literal("null")
+	}
+	off := d.off
+	_, err := d.number()
+	if err != nil && d.off == off {
+		return d.syntaxError("looking for beginning of value")
+	}
+	return err
+}
+
+// decodeString reads a string into p; a null leaves p unchanged.
+func decodeString[T ~string](d *decoder, p *T) error {
+	switch d.peek() {
+	case '"':
+		b, err := d.stringBytes()
+		*p = T(b)
+		return err
+	case 'n':
+		return d.literal("null")
+	}
+	return d.typeError(reflect.TypeFor[T]())
+}
+
+// decodeFloat reads a number into p; a null sets p to nil.
+func decodeFloat(d *decoder, p **float64) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	s, err := decodeNumber(d, reflect.TypeFor[float64]())
+	if s == "" || err != nil {
+		return err
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		d.mismatch("number "+s, reflect.TypeFor[float64](), d.off)
+		return nil
+	}
+	*p = &f
+	return nil
+}
+
+// decodeInt reads an integer into p; a null sets p to nil.
+func decodeInt(d *decoder, p **int) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	s, err := decodeNumber(d, reflect.TypeFor[int]())
+	if s == "" || err != nil {
+		return err
+	}
+	n, err := strconv.ParseInt(s, 10, strconv.IntSize)
+	if err != nil {
+		d.mismatch("number "+s, reflect.TypeFor[int](), d.off)
+		return nil
+	}
+	i := int(n)
+	*p = &i
+	return nil
+}
+
+// decodeNumber reads a number for t; it returns "" when the value is not
+// a number.
+func decodeNumber(d *decoder, t reflect.Type) (string, error) {
+	if c := d.peek(); c != '-' && (c < '0' || c > '9') {
+		return "", d.typeError(t)
+	}
+	return d.number()
+}
+
+// decodeStrings reads an array of strings into p.
+func decodeStrings(d *decoder, p *[]string) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	s := make([]string, 0)
+	err := d.elements(reflect.TypeFor[[]string](), func() error {
+		var v string
+		err := decodeString(d, &v)
+		s = append(s, v)
+		return err
+	})
+	*p = s
+	return err
+}
+
+// decodeStringMap reads an object of strings into p.
+func decodeStringMap(d *decoder, p *map[string]string) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	m := make(map[string]string)
+	*p = m
+	return d.members(reflect.TypeFor[map[string]string](), func(key []byte) error {
+		var v string
+		err := decodeString(d, &v)
+		m[string(key)] = v
+		return err
+	})
+}
+
+// decodeValues reads an array of arbitrary values into p.
+func decodeValues(d *decoder, p *[]any) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	if d.peek() != '[' {
+		return d.typeError(reflect.TypeFor[[]any]())
+	}
+	v, err := d.value()
+	*p, _ = v.([]any)
+	return err
+}
+
+// decodeValueMap reads an object of arbitrary values into p.
+func decodeValueMap(d *decoder, p *map[string]any) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	if d.peek() != '{' {
+		return d.typeError(reflect.TypeFor[map[string]any]())
+	}
+	v, err := d.value()
+	*p, _ = v.(map[string]any)
+	return err
+}
+
+// decodePtr reads an object into a new T; a null sets p to nil.
+func decodePtr[T any, P jsonDecoder[T]](d *decoder, p **T) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	v := new(T)
+	*p = v
+	return P(v).decodeJSON(d)
+}
+
+// decodeSlice reads an array of objects; null elements stay nil.
+func decodeSlice[T any, P jsonDecoder[T]](d *decoder, p *[]*T) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	s := make([]*T, 0)
+	err := d.elements(reflect.TypeFor[[]*T](), func() error {
+		var v *T
+		err := decodePtr[T, P](d, &v)
+		s = append(s, v)
+		return err
+	})
+	*p = s
+	return err
+}
+
+// decodeMap reads an object of objects; null members stay nil.
+func decodeMap[T any, P jsonDecoder[T]](d *decoder, p *map[string]*T) error {
+	if ok, err := d.null(); ok || err != nil {
+		*p = nil
+		return err
+	}
+	m := make(map[string]*T)
+	*p = m
+	return d.members(reflect.TypeFor[map[string]*T](), func(key []byte) error {
+		var v *T
+		err := decodePtr[T, P](d, &v)
+		m[string(key)] = v
+		return err
+	})
+}
@@ -0,0 +1,223 @@
+package arazzo1
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeValueMatchesEncodingJSON(t *testing.T) {
+	for _, input := range []string{
+		`null`,
+		`true`,
+		`-0.5e+3`,
+		`12345678901234567890`,
+		`"plain"`,
+		`"tab\there \"quoted\" \\ \/ \b\f\n\r"`,
+		`"café 😀 lone \ud800 end"`,
+		"\"caf\xc3\xa9 bad \xff byte\"",
+		`[]`,
+		`{}`,
+		`[1, "two", [3], {"four": 4}, null, false]`,
+		`{"a": {"b": [{"c": "d"}]}, "a": "last wins", "x-key": 1}`,
+	} {
+		var want any
+		if err := json.Unmarshal([]byte(input), &want); err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		var got any
+		err := decodeJSON([]byte(input), func(d *decoder) error {
+			v, err := d.value()
+			got = v
+			return err
+		})
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v, want %#v", input, got, want)
+		}
+	}
+}
+
+func TestUnmarshalJSONSyntaxErrors(t *testing.T) {
+	for _, input := range []string{
+		``,
+		`{`,
+		`{"arazzo"}`,
+		`{"arazzo": "1.0.0",}`,
+		`{"arazzo": "1.0.0" "info": {}}`,
+		`{"x-ext": tru}`,
+		`{"x-ext": [1 2]}`,
+		`{"unknown": {"a": 01}}`,
+		`{"unknown": "\q"}`,
+		`{"unknown": "\u12"}`,
+		"{\"unknown\": \"a\nb\"}",
+		`{"workflows": [{"steps": [{"stepId": "s",]}]}`,
+		`{"arazzo": "1.0.0"} {}`,
+		`{"arazzo": -}`,
+		`{"arazzo": 1.}`,
+		`{"arazzo": 1e}`,
+	} {
+		if json.Valid([]byte(input)) {
+			t.Fatalf("%q is valid JSON", input)
+		}
+		var doc Arazzo
+		if err := doc.UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestUnmarshalJSONTypeErrors(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		field string
+		value string
+	}{
+		{`{"arazzo": 1}`, "Arazzo.arazzo", "number"},
+		{`{"info": []}`, "Arazzo.info", "array"},
+		{`{"workflows": [{"steps": {}}]}`, "Workflow.steps", "object"},
+		{`{"workflows": [{"dependsOn": ["a", 1]}]}`, "Workflow.dependsOn", "number"},
+		{`{"workflows": [{"steps": [{"outputs": {"a": true}}]}]}`, "Step.outputs", "bool"},
+		{`{"workflows": [{"steps": [{"onFailure": [{"retryLimit": 1.5}]}]}]}`, "FailureAction.retryLimit", "number 1.5"},
+		{`{"workflows": [{"steps": [{"onFailure": [{"retryAfter": "1"}]}]}]}`, "FailureAction.retryAfter", "string"},
+		{`{"components": {"inputs": []}}`, "Components.inputs", "array"},
+	} {
+		var doc Arazzo
+		err := json.Unmarshal([]byte(tt.input), &doc)
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			t.Errorf("%s: expected *json.UnmarshalTypeError, got %v", tt.input, err)
+			continue
+		}
+		if got := typeErr.Struct + "." + typeErr.Field; got != tt.field || typeErr.Value != tt.value {
+			t.Errorf("%s: got %s (%s), want %s (%s)", tt.input, got, typeErr.Value, tt.field, tt.value)
+		}
+	}
+}
+
+func TestUnmarshalJSONTypeErrorContinues(t *testing.T) {
+	input := `{
+		"arazzo": "1.0.0",
+		"info": {"title": 1, "version": "1"},
+		"workflows": [{
+			"workflowId": "w",
+			"dependsOn": ["a", 2, "c"],
+			"steps": [{"stepId": "s", "onFailure": [{"retryLimit": "x"}]}, {"stepId": "t"}]
+		}],
+		"x-ext": true
+	}`
+	var doc Arazzo
+	err := json.Unmarshal([]byte(input), &doc)
+	// The first mismatch is returned, as encoding/json does.
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Struct != "Info" || typeErr.Field != "title" {
+		t.Fatalf("expected a type error for Info.title, got %v", err)
+	}
+	// The rest of the document is decoded.
+	if doc.Arazzo != "1.0.0" || doc.Info.Title != "" || doc.Info.Version != "1" || doc.Extensions["x-ext"] != true {
+		t.Errorf("document = %+v, info = %+v", doc, doc.Info)
+	}
+	if len(doc.Workflows) != 1 {
+		t.Fatalf("%d workflows, want 1", len(doc.Workflows))
+	}
+	w := doc.Workflows[0]
+	if w.WorkflowId != "w" || !reflect.DeepEqual(w.DependsOn, []string{"a", "", "c"}) {
+		t.Errorf("workflow = %+v", w)
+	}
+	if len(w.Steps) != 2 || w.Steps[0].StepId != "s" || w.Steps[1].StepId != "t" || w.Steps[0].OnFailure[0].FailureAction.RetryLimit != nil {
+		t.Errorf("steps = %+v", w.Steps)
+	}
+}
+
+func TestUnmarshalJSONFieldMatching(t *testing.T) {
+	input := `{
+		"Arazzo": "1.0.0",
+		"INFO": {"title": "T", "version": "1"},
+		"ignored": {"deeply": [{"nested": null}]},
+		"x-Ext": "kept",
+		"workflows": [{
+			"workflowId": "w",
+			"steps": [{"stepid": "s", "x-step": 1, "parameters": null}],
+			"successActions": [null]
+		}]
+	}`
+	var doc Arazzo
+	if err := json.Unmarshal([]byte(input), &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if doc.Arazzo != "1.0.0" || doc.Info == nil || doc.Info.Title != "T" {
+		t.Errorf("case-insensitive keys not matched: %+v", doc)
+	}
+	if len(doc.Extensions) != 1 || doc.Extensions["x-Ext"] != "kept" {
+		t.Errorf("extensions = %v", doc.Extensions)
+	}
+	step := doc.Workflows[0].Steps[0]
+	if step.StepId != "s" || step.Parameters != nil || step.Extensions["x-step"] != float64(1) {
+		t.Errorf("step = %+v", step)
+	}
+	if actions := doc.Workflows[0].SuccessActions; len(actions) != 1 || actions[0] != nil {
+		t.Errorf("success actions = %v", actions)
+	}
+}
+
+func TestUnmarshalJSONReplacesPreviousValue(t *testing.T) {
+	s := Step{StepId: "old", OperationId: "op", Extensions: map[string]any{"x-old": true}}
+	if err := json.Unmarshal([]byte(`{"stepId": "new"}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.StepId != "new" || s.OperationId != "" || s.Extensions != nil {
+		t.Errorf("step = %+v", s)
+	}
+}
+
+func TestUnmarshalJSONReusableDetection(t *testing.T) {
+	var step Step
+	input := `{
+		"stepId": "s",
+		"onSuccess": [
+			{"name": "done", "type": "end", "x-a": 1},
+			{"value": "v", "reference": "$components.successActions.done"}
+		],
+		"onFailure": [{"reference": "", "x-ignored": 1}]
+	}`
+	if err := json.Unmarshal([]byte(input), &step); err != nil {
+		t.Fatal(err)
+	}
+	if a := step.OnSuccess[0]; a.SuccessAction == nil || a.SuccessAction.Name != "done" || a.SuccessAction.Extensions["x-a"] != float64(1) {
+		t.Errorf("onSuccess[0] = %+v", a)
+	}
+	if r := step.OnSuccess[1].Reusable; r == nil || r.Reference != "$components.successActions.done" || r.Value != "v" {
+		t.Errorf("onSuccess[1] = %+v", step.OnSuccess[1])
+	}
+	if f := step.OnFailure[0]; f.Reusable == nil || f.FailureAction != nil {
+		t.Errorf("onFailure[0] = %+v", f)
+	}
+}
+
+func TestUnmarshalJSONLargeDocument(t *testing.T) {
+	doc := generateLargeDocument(3, 4)
+	doc.Extensions = map[string]any{"x-root": map[string]any{"a": []any{"b", 1.5}}}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Arazzo
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	again, err := json.Marshal(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("round trip changed the document:\n%s\n%s", data, again)
+	}
+	if !strings.Contains(string(data), `"x-root"`) {
+		t.Errorf("extension missing from %s", data)
+	}
+}
@@ -0,0 +1,195 @@
+package arazzo1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Runtime expression roots defined by the Arazzo specification.
+const (
+	ExpressionURL                = "url"
+	ExpressionMethod             = "method"
+	ExpressionStatusCode         = "statusCode"
+	ExpressionRequest            = "request"
+	ExpressionResponse           = "response"
+	ExpressionInputs             = "inputs"
+	ExpressionOutputs            = "outputs"
+	ExpressionSteps              = "steps"
+	ExpressionWorkflows          = "workflows"
+	ExpressionSourceDescriptions = "sourceDescriptions"
+	ExpressionComponents         = "components"
+)
+
+var expressionRoots = map[string]bool{
+	ExpressionURL:                true,
+	ExpressionMethod:             true,
+	ExpressionStatusCode:         true,
+	ExpressionRequest:            true,
+	ExpressionResponse:           true,
+	ExpressionInputs:             true,
+	ExpressionOutputs:            true,
+	ExpressionSteps:              true,
+	ExpressionWorkflows:          true,
+	ExpressionSourceDescriptions: true,
+	ExpressionComponents:         true,
+}
+
+// Expression is a parsed Arazzo runtime expression such as
+// $steps.login.outputs.token or $response.body#/data/0/id.
+type Expression struct {
+	// Raw is the original expression text, including the leading '$'.
+	Raw string
+
+	// Root is the expression source without the '$', e.g. "steps" or "response".
+	Root string
+
+	// Segments are the dot-separated names following the root,
+	// e.g. ["login", "outputs", "token"] for $steps.login.outputs.token.
+	Segments []string
+
+	// Pointer is the JSON Pointer following '#', without the '#' itself.
+	Pointer string
+}
+
+// ParseExpression parses a single runtime expression.
+// The whole string must be the expression; use ParseTemplate for strings
+// that embed expressions in braces.
+func ParseExpression(s string) (*Expression, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("runtime expression must start with '$': %q", s)
+	}
+	expr := &Expression{Raw: s}
+	body := s[1:]
+	if idx := strings.Index(body, "#"); idx != -1 {
+		expr.Pointer = body[idx+1:]
+		body = body[:idx]
+		if expr.Pointer != "" && !strings.HasPrefix(expr.Pointer, "/") {
+			return nil, fmt.Errorf("json pointer must start with '/': %q", s)
+		}
+	}
+	parts := strings.Split(body, ".")
+	expr.Root = parts[0]
+	if !expressionRoots[expr.Root] {
+		return nil, fmt.Errorf("unknown runtime expression root %q", "$"+expr.Root)
+	}
+	expr.Segments = parts[1:]
+	for _, seg := range expr.Segments {
+		if seg == "" {
+			return nil, fmt.Errorf("empty segment in runtime expression %q", s)
+		}
+	}
+
+	switch expr.Root {
+	case ExpressionURL, ExpressionMethod, ExpressionStatusCode:
+		if len(expr.Segments) > 0 || expr.Pointer != "" {
+			return nil, fmt.Errorf("%q takes no further segments", "$"+expr.Root)
+		}
+	case ExpressionRequest, ExpressionResponse:
+		if len(expr.Segments) == 0 {
+			return nil, fmt.Errorf("%q requires a source (header, query, path or body)", "$"+expr.Root)
+		}
+		switch expr.Segments[0] {
+		case "body":
+		case "header", "query", "path":
+			if len(expr.Segments) < 2 {
+				return nil, fmt.Errorf("%q requires a name", s)
+			}
+			// Header names may legitimately contain dots.
+			expr.Segments = []string{expr.Segments[0], strings.Join(expr.Segments[1:], ".")}
+		default:
+			return nil, fmt.Errorf("unknown source %q in %q", expr.Segments[0], s)
+		}
+	default:
+		if len(expr.Segments) == 0 {
+			return nil, fmt.Errorf("%q requires a name", "$"+expr.Root)
+		}
+	}
+	return expr, nil
+}
+
+// String returns the canonical text of the expression.
+func (e *Expression) String() string {
+	var b strings.Builder
+	b.WriteString("$")
+	b.WriteString(e.Root)
+	for _, seg := range e.Segments {
+		b.WriteString(".")
+		b.WriteString(seg)
+	}
+	if e.Pointer != "" {
+		b.WriteString("#")
+		b.WriteString(e.Pointer)
+	}
+	return b.String()
+}
+
+// IsRuntimeExpression reports whether s as a whole is a valid runtime expression.
+func IsRuntimeExpression(s string) bool {
+	_, err := ParseExpression(s)
+	return err == nil
+}
+
+// TemplatePart is a fragment of a string that may embed runtime expressions.
+// Exactly one of Literal or Expression is meaningful.
+type TemplatePart struct {
+	Literal    string
+	Expression *Expression
+}
+
+// ParseTemplate splits a string into literal text and embedded runtime
+// expressions written as {$expression}. A string that is itself a bare
+// runtime expression yields a single expression part.
+func ParseTemplate(s string) ([]TemplatePart, error) {
+	if strings.HasPrefix(s, "$") {
+		if expr, err := ParseExpression(s); err == nil {
+			return []TemplatePart{{Expression: expr}}, nil
+		}
+	}
+
+	var parts []TemplatePart
+	rest := s
+	for {
+		start := strings.Index(rest, "{$")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated embedded expression in %q", s)
+		}
+		expr, err := ParseExpression(rest[start+1 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		if start > 0 {
+			parts = append(parts, TemplatePart{Literal: rest[:start]})
+		}
+		parts = append(parts, TemplatePart{Expression: expr})
+		rest = rest[start+end+1:]
+	}
+	if rest != "" || len(parts) == 0 {
+		parts = append(parts, TemplatePart{Literal: rest})
+	}
+	return parts, nil
+}
+
+// ScanExpression returns the length of the runtime expression starting at
+// s[0], as it appears inside a criterion condition. It returns 0 when s
+// does not start with '$'.
+func ScanExpression(s string) int {
+	if !strings.HasPrefix(s, "$") {
+		return 0
+	}
+	i := 1
+	for i < len(s) {
+		c := s[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' ||
+			c == '=' || c == '!' || c == '<' || c == '>' ||
+			c == '&' || c == '|' || c == '(' || c == ')' ||
+			c == ',' || c == '}' || c == '\'' || c == '"' {
+			break
+		}
+		i++
+	}
+	return i
+}
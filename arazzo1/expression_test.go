@@ -0,0 +1,179 @@
+package arazzo1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		root     string
+		segments []string
+		pointer  string
+		wantErr  bool
+	}{
+		{input: "$statusCode", root: "statusCode"},
+		{input: "$url", root: "url"},
+		{input: "$inputs.username", root: "inputs", segments: []string{"username"}},
+		{input: "$steps.login.outputs.token", root: "steps", segments: []string{"login", "outputs", "token"}},
+		{input: "$response.body", root: "response", segments: []string{"body"}},
+		{input: "$response.body#/data/0/id", root: "response", segments: []string{"body"}, pointer: "/data/0/id"},
+		{input: "$response.header.X-Rate-Limit", root: "response", segments: []string{"header", "X-Rate-Limit"}},
+		{input: "$request.header.x.y", root: "request", segments: []string{"header", "x.y"}},
+		{input: "$components.parameters.page", root: "components", segments: []string{"parameters", "page"}},
+		{input: "$sourceDescriptions.petstore.url", root: "sourceDescriptions", segments: []string{"petstore", "url"}},
+		{input: "inputs.username", wantErr: true},
+		{input: "$unknown.x", wantErr: true},
+		{input: "$statusCode.x", wantErr: true},
+		{input: "$response.cookie.x", wantErr: true},
+		{input: "$response.header", wantErr: true},
+		{input: "$inputs", wantErr: true},
+		{input: "$steps..outputs", wantErr: true},
+		{input: "$response.body#id", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := ParseExpression(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseExpression(%q) failed: %v", tt.input, err)
+			}
+			if expr.Root != tt.root {
+				t.Errorf("root: expected %q, got %q", tt.root, expr.Root)
+			}
+			if len(expr.Segments) != len(tt.segments) || (len(tt.segments) > 0 && !reflect.DeepEqual(expr.Segments, tt.segments)) {
+				t.Errorf("segments: expected %v, got %v", tt.segments, expr.Segments)
+			}
+			if expr.Pointer != tt.pointer {
+				t.Errorf("pointer: expected %q, got %q", tt.pointer, expr.Pointer)
+			}
+			if expr.String() != tt.input {
+				t.Errorf("String(): expected %q, got %q", tt.input, expr.String())
+			}
+		})
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	parts, err := ParseTemplate("Bearer {$steps.login.outputs.token}")
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+	if parts[0].Literal != "Bearer " || parts[0].Expression != nil {
+		t.Errorf("unexpected literal part: %+v", parts[0])
+	}
+	if parts[1].Expression == nil || parts[1].Expression.Raw != "$steps.login.outputs.token" {
+		t.Errorf("unexpected expression part: %+v", parts[1])
+	}
+
+	parts, err = ParseTemplate("$inputs.id")
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+	if len(parts) != 1 || parts[0].Expression == nil {
+		t.Errorf("bare expression should yield a single expression part, got %+v", parts)
+	}
+
+	parts, err = ParseTemplate("plain text")
+	if err != nil {
+		t.Fatalf("ParseTemplate failed: %v", err)
+	}
+	if len(parts) != 1 || parts[0].Literal != "plain text" {
+		t.Errorf("plain text should yield a single literal part, got %+v", parts)
+	}
+
+	if _, err := ParseTemplate("broken {$inputs.id"); err == nil {
+		t.Error("expected error for unterminated expression")
+	}
+}
+
+func TestScanExpression(t *testing.T) {
+	cond := "$response.body#/status == 'available' && $statusCode == 200"
+	if n := ScanExpression(cond); cond[:n] != "$response.body#/status" {
+		t.Errorf("unexpected scan result %q", cond[:n])
+	}
+	if n := ScanExpression("200"); n != 0 {
+		t.Errorf("expected 0 for non-expression, got %d", n)
+	}
+}
+
+func TestStepParameterObjects(t *testing.T) {
+	step := &Step{
+		Parameters: []any{
+			&Parameter{Name: "id", In: ParameterInPath, Value: "$inputs.id"},
+			map[string]any{"name": "limit", "in": "query", "value": 10},
+			map[string]any{"reference": "$components.parameters.page", "value": 2},
+		},
+	}
+	params, err := step.ParameterObjects()
+	if err != nil {
+		t.Fatalf("ParameterObjects failed: %v", err)
+	}
+	if len(params) != 3 {
+		t.Fatalf("expected 3 parameters, got %d", len(params))
+	}
+	if params[0].Parameter == nil || params[0].Parameter.Name != "id" {
+		t.Errorf("unexpected first parameter: %+v", params[0])
+	}
+	if params[1].Parameter == nil || params[1].Parameter.In != ParameterInQuery {
+		t.Errorf("unexpected second parameter: %+v", params[1])
+	}
+	if params[2].Reusable == nil || params[2].Reusable.Reference != "$components.parameters.page" {
+		t.Errorf("unexpected third parameter: %+v", params[2])
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	node, err := ParseCondition("$statusCode == 200 && ($response.body#/status == 'available' || !$inputs.strict)")
+	if err != nil {
+		t.Fatalf("ParseCondition failed: %v", err)
+	}
+	and, ok := node.(*ConditionBinary)
+	if !ok || and.Op != "&&" {
+		t.Fatalf("expected && at the root, got %#v", node)
+	}
+	cmp, ok := and.Left.(*ConditionBinary)
+	if !ok || cmp.Op != "==" {
+		t.Fatalf("expected == on the left, got %#v", and.Left)
+	}
+	if expr, ok := cmp.Left.(*ConditionExpression); !ok || expr.Expression.Root != ExpressionStatusCode {
+		t.Errorf("unexpected left operand %#v", cmp.Left)
+	}
+	if lit, ok := cmp.Right.(*ConditionLiteral); !ok || lit.Value != 200.0 {
+		t.Errorf("unexpected right operand %#v", cmp.Right)
+	}
+	or, ok := and.Right.(*ConditionBinary)
+	if !ok || or.Op != "||" {
+		t.Fatalf("expected || on the right, got %#v", and.Right)
+	}
+	if lit := or.Left.(*ConditionBinary).Right.(*ConditionLiteral); lit.Value != "available" {
+		t.Errorf("unexpected string literal %#v", lit)
+	}
+	if _, ok := or.Right.(*ConditionNot); !ok {
+		t.Errorf("expected negation, got %#v", or.Right)
+	}
+
+	node, err = ParseCondition("$response.body#/next != null")
+	if err != nil {
+		t.Fatalf("ParseCondition failed: %v", err)
+	}
+	if lit := node.(*ConditionBinary).Right.(*ConditionLiteral); lit.Value != nil {
+		t.Errorf("expected null literal, got %#v", lit.Value)
+	}
+
+	for _, bad := range []string{"", "$statusCode ==", "($statusCode == 200", "$statusCode == 'open", "$unknown == 1", "1 2"} {
+		if _, err := ParseCondition(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
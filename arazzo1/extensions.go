@@ -3,58 +3,57 @@
 package arazzo1
 
 import (
+	"bytes"
 	"encoding/json"
+	"slices"
+	"sort"
 	"strings"
 )
 
-// extractExtensions extracts x-* extension fields from a raw JSON map.
-// It filters out known fields and returns only extension fields.
-func extractExtensions(raw map[string]json.RawMessage, knownFields []string) map[string]any {
-	known := make(map[string]bool)
-	for _, f := range knownFields {
-		known[f] = true
-	}
-
-	extensions := make(map[string]any)
-	for key, value := range raw {
-		if strings.HasPrefix(key, "x-") && !known[key] {
-			var v any
-			if err := json.Unmarshal(value, &v); err == nil {
-				extensions[key] = v
-			}
-		}
-	}
-
-	if len(extensions) == 0 {
-		return nil
-	}
-	return extensions
-}
-
 // marshalWithExtensions marshals an object along with its x-* extensions.
+// The fields keep the order of v, which follows the specification, and the
+// extensions follow them sorted by name, so the output is deterministic.
+// HTML characters are not escaped, so canonical output can be written
+// without escaping; json.Marshal escapes them again for its callers.
 func marshalWithExtensions(v any, extensions map[string]any) ([]byte, error) {
-	data, err := json.Marshal(v)
-	if err != nil {
-		return nil, err
+	data, err := marshalNoEscape(v)
+	if err != nil || len(extensions) == 0 {
+		return data, err
 	}
 
-	if len(extensions) == 0 {
-		return data, nil
-	}
-
-	// Merge extensions into JSON object
-	var m map[string]json.RawMessage
-	if err := json.Unmarshal(data, &m); err != nil {
-		return nil, err
+	keys := make([]string, 0, len(extensions))
+	for key := range extensions {
+		if strings.HasPrefix(key, "x-") {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
 
-	for key, value := range extensions {
-		extData, err := json.Marshal(value)
+	buf := bytes.NewBuffer(slices.Clip(data[:len(data)-1]))
+	for _, key := range keys {
+		value, err := marshalNoEscape(extensions[key])
 		if err != nil {
 			return nil, err
 		}
-		m[key] = extData
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := marshalNoEscape(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
 	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
 
-	return json.Marshal(m)
+// marshalNoEscape is json.Marshal without HTML escaping.
+func marshalNoEscape(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
 }
@@ -1,7 +1,11 @@
 package arazzo1
 
 import (
+	"errors"
+	"reflect"
 	"testing"
+
+	"github.com/hashicorp/hcl/v2"
 )
 
 func TestUnmarshalHCLBasicWorkflow(t *testing.T) {
@@ -658,3 +662,140 @@ step "test" {
 		t.Errorf("Expected stringVal 'hello', got %v", w.Steps[0].Outputs["stringVal"])
 	}
 }
+
+func TestUnmarshalHCLDiagnostics(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		hcl     string
+		summary string
+		line    int
+	}{
+		{"unknown step attribute", "step \"s\" {\n  operationID = \"op\"\n}\n", "Unsupported argument", 2},
+		{"unknown step block", "step \"s\" {\n  successCriteria {\n  }\n}\n", "Unsupported block type", 2},
+		{"unknown criterion attribute", "step \"s\" {\n  successCriterion {\n    condition = \"$statusCode == 200\"\n    kind = \"simple\"\n  }\n}\n", "Unsupported argument", 4},
+		{"unknown action block", "step \"s\" {\n  onSuccess \"done\" {\n    type = \"end\"\n    criteria {\n    }\n  }\n}\n", "Unsupported block type", 4},
+		{"unknown workflow attribute", "summary = \"s\"\nsteps = []\n", "Unsupported argument", 2},
+		{"string type", "step \"s\" {\n  operationId = 5\n}\n", "Incorrect attribute value type", 2},
+		{"list type", "dependsOn = \"other\"\n", "Incorrect attribute value type", 1},
+		{"number type", "failureAction \"retry\" {\n  type = \"retry\"\n  retryLimit = \"three\"\n}\n", "Incorrect attribute value type", 3},
+		{"inputs evaluation", "inputs {\n  type = object\n}\n", "Variables not allowed", 2},
+		{"conflicting version", "step \"s\" {\n  successCriterion {\n    condition = \"$\"\n    type = \"jsonpath\"\n    version = \"v1\"\n    expressionType {\n      type = \"jsonpath\"\n    }\n  }\n}\n", "Conflicting criterion version", 5},
+	} {
+		w := &Workflow{}
+		err := w.UnmarshalHCL([]byte(tt.hcl), "wf")
+		var diags hcl.Diagnostics
+		if !errors.As(err, &diags) || len(diags) == 0 {
+			t.Errorf("%s: expected hcl.Diagnostics, got %v", tt.name, err)
+			continue
+		}
+		if diags[0].Summary != tt.summary {
+			t.Errorf("%s: summary = %q, want %q", tt.name, diags[0].Summary, tt.summary)
+		}
+		if diags[0].Subject == nil || diags[0].Subject.Start.Line != tt.line {
+			t.Errorf("%s: subject = %v, want line %d", tt.name, diags[0].Subject, tt.line)
+		}
+	}
+}
+
+func TestUnmarshalHCLExtensions(t *testing.T) {
+	hclData := `
+x-owner = "payments"
+step "s" {
+  operationId = "op"
+  x-retries   = 2
+}
+`
+	w := &Workflow{}
+	if err := w.UnmarshalHCL([]byte(hclData), "wf"); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v", err)
+	}
+	if w.Extensions["x-owner"] != "payments" {
+		t.Errorf("workflow extensions = %v", w.Extensions)
+	}
+	if w.Steps[0].Extensions["x-retries"] != int64(2) {
+		t.Errorf("step extensions = %v", w.Steps[0].Extensions)
+	}
+}
+
+func TestUnmarshalHCLWithWorkflowReusables(t *testing.T) {
+	// The layout MarshalHCL writes for workflow-level actions and parameters.
+	hclData := `
+successAction {
+  reusable {
+    reference = "$components.successActions.done"
+  }
+}
+failureAction {
+  failureAction "retry" {
+    type       = "retry"
+    retryLimit = 3
+  }
+}
+parameter {
+  parameter "page" {
+    in    = "query"
+    value = 1
+  }
+}
+parameter {
+  parameter "filter" {
+    in = "query"
+    value {
+      status = "available"
+    }
+  }
+}
+parameter {
+  reusable {
+    reference = "$components.parameters.limit"
+    value     = 10
+  }
+}
+`
+	w := &Workflow{}
+	if err := w.UnmarshalHCL([]byte(hclData), "wf"); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v", err)
+	}
+	if len(w.SuccessActions) != 1 || w.SuccessActions[0].Reusable == nil ||
+		w.SuccessActions[0].Reusable.Reference != "$components.successActions.done" {
+		t.Errorf("success actions = %+v", w.SuccessActions)
+	}
+	if len(w.FailureActions) != 1 || w.FailureActions[0].FailureAction == nil ||
+		w.FailureActions[0].FailureAction.Name != "retry" || *w.FailureActions[0].FailureAction.RetryLimit != 3 {
+		t.Errorf("failure actions = %+v", w.FailureActions)
+	}
+	if len(w.Parameters) != 3 {
+		t.Fatalf("parameters = %+v", w.Parameters)
+	}
+	if p := w.Parameters[0].Parameter; p == nil || p.Name != "page" || p.In != ParameterInQuery {
+		t.Errorf("parameter 0 = %+v", p)
+	}
+	if p := w.Parameters[1].Parameter; p == nil || !reflect.DeepEqual(p.Value, map[string]any{"status": "available"}) {
+		t.Errorf("parameter 1 = %+v", p)
+	}
+	if r := w.Parameters[2].Reusable; r == nil || r.Reference != "$components.parameters.limit" || r.Value != int64(10) {
+		t.Errorf("parameter 2 = %+v", r)
+	}
+}
+
+func TestHCLBlockToMapLabels(t *testing.T) {
+	hclData := `
+inputs {
+  type = "object"
+  properties "id" {
+    type = "string"
+  }
+  properties "name" {
+    type = "string"
+  }
+}
+`
+	w := &Workflow{}
+	if err := w.UnmarshalHCL([]byte(hclData), "wf"); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v", err)
+	}
+	props, _ := w.Inputs.(map[string]any)["properties"].(map[string]any)
+	if len(props) != 2 || props["id"] == nil || props["name"] == nil {
+		t.Errorf("inputs = %v", w.Inputs)
+	}
+}
@@ -1,7 +1,7 @@
 package arazzo1
 
 import (
-	"encoding/json"
+	"reflect"
 )
 
 // Info provides metadata about the Arazzo description.
@@ -35,19 +35,24 @@ var infoKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (i *Info) UnmarshalJSON(data []byte) error {
-	var alias infoAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*i = Info(alias)
-
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-	i.Extensions = extractExtensions(raw, infoKnownFields)
-
-	return nil
+	return decodeJSON(data, i.decodeJSON)
+}
+
+func (i *Info) decodeJSON(d *decoder) error {
+	*i = Info{}
+	return d.object(reflect.TypeFor[Info](), infoKnownFields, &i.Extensions, func(key string) error {
+		switch key {
+		case "title":
+			return decodeString(d, &i.Title)
+		case "summary":
+			return decodeString(d, &i.Summary)
+		case "description":
+			return decodeString(d, &i.Description)
+		case "version":
+			return decodeString(d, &i.Version)
+		}
+		return d.skip()
+	})
 }
 
 // MarshalJSON implements the json.Marshaler interface.
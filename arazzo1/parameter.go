@@ -1,8 +1,8 @@
 package arazzo1
 
 import (
-	"encoding/json"
 	"fmt"
+	"reflect"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -52,19 +52,29 @@ var parameterKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (p *Parameter) UnmarshalJSON(data []byte) error {
-	var alias parameterAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*p = Parameter(alias)
+	return decodeJSON(data, p.decodeJSON)
+}
+
+func (p *Parameter) decodeJSON(d *decoder) error {
+	*p = Parameter{}
+	return d.object(reflect.TypeFor[Parameter](), parameterKnownFields, &p.Extensions, func(key string) error {
+		return p.decodeField(d, key)
+	})
+}
 
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
+// decodeField decodes the value of the known field key.
+func (p *Parameter) decodeField(d *decoder, key string) error {
+	switch key {
+	case "name":
+		return decodeString(d, &p.Name)
+	case "in":
+		return decodeString(d, &p.In)
+	case "value":
+		v, err := d.value()
+		p.Value = v
 		return err
 	}
-	p.Extensions = extractExtensions(raw, parameterKnownFields)
-
-	return nil
+	return d.skip()
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -76,11 +86,12 @@ func (p Parameter) MarshalJSON() ([]byte, error) {
 // UnmarshalHCL implements the dethcl.Unmarshaler interface.
 // This custom unmarshaler handles the Value field which is typed as `any`
 // and needs special handling to parse HCL values (especially numbers) into Go values.
+// Problems are returned as hcl.Diagnostics, with ranges relative to data.
 func (p *Parameter) UnmarshalHCL(data []byte, labels ...string) error {
 	// Parse HCL
 	file, diags := hclsyntax.ParseConfig(data, "", hcl.Pos{Line: 1, Column: 1})
 	if diags.HasErrors() {
-		return fmt.Errorf("parsing HCL: %w", diags)
+		return diags
 	}
 
 	body, ok := file.Body.(*hclsyntax.Body)
@@ -93,22 +104,8 @@ func (p *Parameter) UnmarshalHCL(data []byte, labels ...string) error {
 		p.Name = labels[0]
 	}
 
-	// Process attributes
-	for name, attr := range body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			return fmt.Errorf("attribute %q: %w", name, diags)
-		}
-
-		switch name {
-		case "name":
-			p.Name = val.AsString()
-		case "in":
-			p.In = ParameterIn(val.AsString())
-		case "value":
-			p.Value = ctyToGo(val)
-		}
+	if diags := parseParameterBody(body, p); diags.HasErrors() {
+		return diags
 	}
-
 	return nil
 }
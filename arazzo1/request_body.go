@@ -1,7 +1,7 @@
 package arazzo1
 
 import (
-	"encoding/json"
+	"reflect"
 )
 
 // RequestBody represents the request body to pass to an operation
@@ -30,19 +30,24 @@ var requestBodyKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (r *RequestBody) UnmarshalJSON(data []byte) error {
-	var alias requestBodyAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*r = RequestBody(alias)
-
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-	r.Extensions = extractExtensions(raw, requestBodyKnownFields)
-
-	return nil
+	return decodeJSON(data, r.decodeJSON)
+}
+
+func (r *RequestBody) decodeJSON(d *decoder) error {
+	*r = RequestBody{}
+	return d.object(reflect.TypeFor[RequestBody](), requestBodyKnownFields, &r.Extensions, func(key string) error {
+		switch key {
+		case "contentType":
+			return decodeString(d, &r.ContentType)
+		case "payload":
+			v, err := d.value()
+			r.Payload = v
+			return err
+		case "replacements":
+			return decodeSlice(d, &r.Replacements)
+		}
+		return d.skip()
+	})
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -74,19 +79,20 @@ var payloadReplacementKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (p *PayloadReplacement) UnmarshalJSON(data []byte) error {
-	var alias payloadReplacementAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*p = PayloadReplacement(alias)
-
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-	p.Extensions = extractExtensions(raw, payloadReplacementKnownFields)
-
-	return nil
+	return decodeJSON(data, p.decodeJSON)
+}
+
+func (p *PayloadReplacement) decodeJSON(d *decoder) error {
+	*p = PayloadReplacement{}
+	return d.object(reflect.TypeFor[PayloadReplacement](), payloadReplacementKnownFields, &p.Extensions, func(key string) error {
+		switch key {
+		case "target":
+			return decodeString(d, &p.Target)
+		case "value":
+			return decodeString(d, &p.Value)
+		}
+		return d.skip()
+	})
 }
 
 // MarshalJSON implements the json.Marshaler interface.
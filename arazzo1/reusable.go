@@ -1,5 +1,10 @@
 package arazzo1
 
+import (
+	"fmt"
+	"strings"
+)
+
 // ReusableObject is a simple object to allow referencing of objects
 // contained within the Components Object.
 type ReusableObject struct {
@@ -10,3 +15,110 @@ type ReusableObject struct {
 	// Can be string, boolean, object, array, number, or null.
 	Value any `json:"value,omitempty" yaml:"value,omitempty" hcl:"value,optional"`
 }
+
+// ResolveParameter returns the concrete Parameter for p. A reusable reference
+// of the form $components.parameters.<name> is looked up in the document's
+// components, and the reusable Value, when set, overrides the component's value.
+func (a *Arazzo) ResolveParameter(p *ParameterOrReusable) (*Parameter, error) {
+	if p == nil {
+		return nil, fmt.Errorf("nil parameter")
+	}
+	if p.Reusable == nil {
+		return p.Parameter, nil
+	}
+	const prefix = "$components.parameters."
+	if !strings.HasPrefix(p.Reusable.Reference, prefix) {
+		return nil, fmt.Errorf("parameter reference %q must start with %s", p.Reusable.Reference, prefix)
+	}
+	name := strings.TrimPrefix(p.Reusable.Reference, prefix)
+	if a.Components == nil || a.Components.Parameters[name] == nil {
+		return nil, fmt.Errorf("parameter component %q not found", name)
+	}
+	param := *a.Components.Parameters[name]
+	if p.Reusable.Value != nil {
+		param.Value = p.Reusable.Value
+	}
+	return &param, nil
+}
+
+// ResolveSuccessAction returns the concrete SuccessAction for s, looking up
+// $components.successActions.<name> references.
+func (a *Arazzo) ResolveSuccessAction(s *SuccessActionOrReusable) (*SuccessAction, error) {
+	if s == nil {
+		return nil, fmt.Errorf("nil success action")
+	}
+	if s.Reusable == nil {
+		return s.SuccessAction, nil
+	}
+	const prefix = "$components.successActions."
+	if !strings.HasPrefix(s.Reusable.Reference, prefix) {
+		return nil, fmt.Errorf("success action reference %q must start with %s", s.Reusable.Reference, prefix)
+	}
+	name := strings.TrimPrefix(s.Reusable.Reference, prefix)
+	if a.Components == nil || a.Components.SuccessActions[name] == nil {
+		return nil, fmt.Errorf("success action component %q not found", name)
+	}
+	return a.Components.SuccessActions[name], nil
+}
+
+// ResolveFailureAction returns the concrete FailureAction for f, looking up
+// $components.failureActions.<name> references.
+func (a *Arazzo) ResolveFailureAction(f *FailureActionOrReusable) (*FailureAction, error) {
+	if f == nil {
+		return nil, fmt.Errorf("nil failure action")
+	}
+	if f.Reusable == nil {
+		return f.FailureAction, nil
+	}
+	const prefix = "$components.failureActions."
+	if !strings.HasPrefix(f.Reusable.Reference, prefix) {
+		return nil, fmt.Errorf("failure action reference %q must start with %s", f.Reusable.Reference, prefix)
+	}
+	name := strings.TrimPrefix(f.Reusable.Reference, prefix)
+	if a.Components == nil || a.Components.FailureActions[name] == nil {
+		return nil, fmt.Errorf("failure action component %q not found", name)
+	}
+	return a.Components.FailureActions[name], nil
+}
+
+// StepParameters returns the effective parameters of a step of wf:
+// workflow-level parameters first, overridden by step parameters with the
+// same name and location. Reusable references are resolved against the
+// document's components.
+func (a *Arazzo) StepParameters(wf *Workflow, step *Step) ([]*Parameter, error) {
+	var result []*Parameter
+	index := make(map[string]int)
+	add := func(p *Parameter) {
+		key := string(p.In) + ":" + p.Name
+		if i, ok := index[key]; ok {
+			result[i] = p
+			return
+		}
+		index[key] = len(result)
+		result = append(result, p)
+	}
+
+	for _, p := range wf.Parameters {
+		param, err := a.ResolveParameter(p)
+		if err != nil {
+			return nil, err
+		}
+		if param != nil {
+			add(param)
+		}
+	}
+	params, err := step.ParameterObjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range params {
+		param, err := a.ResolveParameter(p)
+		if err != nil {
+			return nil, err
+		}
+		if param != nil {
+			add(param)
+		}
+	}
+	return result, nil
+}
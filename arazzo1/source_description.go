@@ -1,7 +1,7 @@
 package arazzo1
 
 import (
-	"encoding/json"
+	"reflect"
 )
 
 // SourceDescriptionType represents the type of source description.
@@ -43,19 +43,22 @@ var sourceDescriptionKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (s *SourceDescription) UnmarshalJSON(data []byte) error {
-	var alias sourceDescriptionAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*s = SourceDescription(alias)
-
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-	s.Extensions = extractExtensions(raw, sourceDescriptionKnownFields)
+	return decodeJSON(data, s.decodeJSON)
+}
 
-	return nil
+func (s *SourceDescription) decodeJSON(d *decoder) error {
+	*s = SourceDescription{}
+	return d.object(reflect.TypeFor[SourceDescription](), sourceDescriptionKnownFields, &s.Extensions, func(key string) error {
+		switch key {
+		case "name":
+			return decodeString(d, &s.Name)
+		case "url":
+			return decodeString(d, &s.URL)
+		case "type":
+			return decodeString(d, &s.Type)
+		}
+		return d.skip()
+	})
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -2,6 +2,8 @@ package arazzo1
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
 )
 
 // Step describes a single workflow step which MAY be a call to an API operation
@@ -70,19 +72,38 @@ var stepKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (s *Step) UnmarshalJSON(data []byte) error {
-	var alias stepAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*s = Step(alias)
-
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-	s.Extensions = extractExtensions(raw, stepKnownFields)
+	return decodeJSON(data, s.decodeJSON)
+}
 
-	return nil
+func (s *Step) decodeJSON(d *decoder) error {
+	*s = Step{}
+	return d.object(reflect.TypeFor[Step](), stepKnownFields, &s.Extensions, func(key string) error {
+		switch key {
+		case "stepId":
+			return decodeString(d, &s.StepId)
+		case "description":
+			return decodeString(d, &s.Description)
+		case "operationId":
+			return decodeString(d, &s.OperationId)
+		case "operationPath":
+			return decodeString(d, &s.OperationPath)
+		case "workflowId":
+			return decodeString(d, &s.WorkflowId)
+		case "parameters":
+			return decodeValues(d, &s.Parameters)
+		case "requestBody":
+			return decodePtr(d, &s.RequestBody)
+		case "successCriteria":
+			return decodeSlice(d, &s.SuccessCriteria)
+		case "onSuccess":
+			return decodeSlice(d, &s.OnSuccess)
+		case "onFailure":
+			return decodeSlice(d, &s.OnFailure)
+		case "outputs":
+			return decodeStringMap(d, &s.Outputs)
+		}
+		return d.skip()
+	})
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -100,3 +121,36 @@ func (s *Step) IsOperationStep() bool {
 func (s *Step) IsWorkflowStep() bool {
 	return s.WorkflowId != ""
 }
+
+// ParameterObjects returns the step parameters as typed values.
+// Step.Parameters is loosely typed because it holds whatever the source
+// document contained; entries may be *Parameter, *ParameterOrReusable,
+// *ReusableObject or generic maps decoded from JSON, YAML or HCL.
+func (s *Step) ParameterObjects() ([]*ParameterOrReusable, error) {
+	var result []*ParameterOrReusable
+	for i, p := range s.Parameters {
+		switch v := p.(type) {
+		case nil:
+			continue
+		case *Parameter:
+			result = append(result, &ParameterOrReusable{Parameter: v})
+		case Parameter:
+			result = append(result, &ParameterOrReusable{Parameter: &v})
+		case *ReusableObject:
+			result = append(result, &ParameterOrReusable{Reusable: v})
+		case *ParameterOrReusable:
+			result = append(result, v)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("parameters[%d]: %w", i, err)
+			}
+			item := &ParameterOrReusable{}
+			if err := json.Unmarshal(data, item); err != nil {
+				return nil, fmt.Errorf("parameters[%d]: %w", i, err)
+			}
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
@@ -69,21 +72,43 @@ var workflowKnownFields = []string{
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (w *Workflow) UnmarshalJSON(data []byte) error {
-	var alias workflowAlias
-	if err := json.Unmarshal(data, &alias); err != nil {
+	if err := decodeJSON(data, w.decodeJSON); err != nil {
 		return fmt.Errorf("unmarshaling workflow: %w", err)
 	}
-	*w = Workflow(alias)
-
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return fmt.Errorf("unmarshaling workflow extensions: %w", err)
-	}
-	w.Extensions = extractExtensions(raw, workflowKnownFields)
-
 	return nil
 }
 
+func (w *Workflow) decodeJSON(d *decoder) error {
+	*w = Workflow{}
+	return d.object(reflect.TypeFor[Workflow](), workflowKnownFields, &w.Extensions, func(key string) error {
+		switch key {
+		case "workflowId":
+			return decodeString(d, &w.WorkflowId)
+		case "summary":
+			return decodeString(d, &w.Summary)
+		case "description":
+			return decodeString(d, &w.Description)
+		case "inputs":
+			v, err := d.value()
+			w.Inputs = v
+			return err
+		case "dependsOn":
+			return decodeStrings(d, &w.DependsOn)
+		case "steps":
+			return decodeSlice(d, &w.Steps)
+		case "successActions":
+			return decodeSlice(d, &w.SuccessActions)
+		case "failureActions":
+			return decodeSlice(d, &w.FailureActions)
+		case "outputs":
+			return decodeStringMap(d, &w.Outputs)
+		case "parameters":
+			return decodeSlice(d, &w.Parameters)
+		}
+		return d.skip()
+	})
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (w Workflow) MarshalJSON() ([]byte, error) {
 	alias := workflowAlias(w)
@@ -93,11 +118,11 @@ func (w Workflow) MarshalJSON() ([]byte, error) {
 // UnmarshalHCL implements the dethcl.Unmarshaler interface.
 // This custom unmarshaler handles the Inputs field which is typed as `any`
 // and needs special handling to parse HCL blocks into map[string]any.
+// Problems are returned as hcl.Diagnostics, with ranges relative to data.
 func (w *Workflow) UnmarshalHCL(data []byte, labels ...string) error {
-	// Parse HCL
 	file, diags := hclsyntax.ParseConfig(data, "", hcl.Pos{Line: 1, Column: 1})
 	if diags.HasErrors() {
-		return fmt.Errorf("parsing HCL: %w", diags)
+		return diags
 	}
 
 	body, ok := file.Body.(*hclsyntax.Body)
@@ -105,110 +130,177 @@ func (w *Workflow) UnmarshalHCL(data []byte, labels ...string) error {
 		return fmt.Errorf("unexpected HCL body type: %T", file.Body)
 	}
 
+	if diags := w.DecodeHCL(body, labels...); diags.HasErrors() {
+		return diags
+	}
+	return nil
+}
+
+// DecodeHCL decodes the body of a workflow block. Problems are reported as
+// hcl.Diagnostics with the source ranges of body, including attributes and
+// blocks that a workflow, step, criterion or action does not have. Attributes
+// named x-* are read as extensions.
+func (w *Workflow) DecodeHCL(body *hclsyntax.Body, labels ...string) hcl.Diagnostics {
 	// Set label (workflowId) if provided
 	if len(labels) > 0 {
 		w.WorkflowId = labels[0]
 	}
 
-	// Accumulate errors for attributes
-	var errs []string
-
-	// Process attributes
-	for name, attr := range body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-
-		switch name {
+		switch attr.Name {
 		case "summary":
-			w.Summary = val.AsString()
+			w.Summary, d = stringValue(attr, val)
 		case "description":
-			w.Description = val.AsString()
+			w.Description, d = stringValue(attr, val)
 		case "dependsOn":
-			w.DependsOn = ctyToStringSlice(val)
+			w.DependsOn, d = stringSliceValue(attr, val)
 		case "outputs":
-			w.Outputs = ctyToStringMap(val)
+			w.Outputs, d = stringMapValue(attr, val)
+		case "inputs":
+			w.Inputs = ctyToGo(val)
+		default:
+			d = decodeExtension(attr, val, &w.Extensions)
 		}
+		diags = append(diags, d...)
 	}
 
-	// Process blocks
 	for _, block := range body.Blocks {
 		switch block.Type {
 		case "inputs":
-			w.Inputs = hclBlockToMap(block)
+			inputs, d := hclBlockToMap(block)
+			diags = append(diags, d...)
+			w.Inputs = inputs
 		case "step":
 			step := &Step{}
-			if err := parseStepBlock(block, step); err != nil {
-				errs = append(errs, fmt.Sprintf("step block: %s", err.Error()))
-			}
+			diags = append(diags, parseStepBlock(block, step)...)
 			w.Steps = append(w.Steps, step)
 		case "successAction":
-			action := &SuccessActionOrReusable{SuccessAction: &SuccessAction{}}
-			if len(block.Labels) > 0 {
-				action.SuccessAction.Name = block.Labels[0]
-			}
-			// Parse the block content for successAction
-			if err := parseSuccessActionBlock(block, action.SuccessAction); err != nil {
-				errs = append(errs, fmt.Sprintf("successAction block: %s", err.Error()))
-			}
+			action, d := parseSuccessActionOrReusable(block)
+			diags = append(diags, d...)
 			w.SuccessActions = append(w.SuccessActions, action)
 		case "failureAction":
-			action := &FailureActionOrReusable{FailureAction: &FailureAction{}}
-			if len(block.Labels) > 0 {
-				action.FailureAction.Name = block.Labels[0]
-			}
-			if err := parseFailureActionBlock(block, action.FailureAction); err != nil {
-				errs = append(errs, fmt.Sprintf("failureAction block: %s", err.Error()))
-			}
+			action, d := parseFailureActionOrReusable(block)
+			diags = append(diags, d...)
 			w.FailureActions = append(w.FailureActions, action)
 		case "parameter":
-			param := &ParameterOrReusable{Parameter: &Parameter{}}
-			if len(block.Labels) > 0 {
-				param.Parameter.Name = block.Labels[0]
-			}
-			if err := parseParameterBlock(block, param.Parameter); err != nil {
-				errs = append(errs, fmt.Sprintf("parameter block: %s", err.Error()))
-			}
+			param, d := parseParameterOrReusable(block)
+			diags = append(diags, d...)
 			w.Parameters = append(w.Parameters, param)
+		default:
+			diags = append(diags, unsupportedBlock(block))
 		}
 	}
+	return diags
+}
+
+// sortedAttributes returns the attributes of body in source order, so that
+// diagnostics come out in a stable order.
+func sortedAttributes(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].NameRange.Start.Byte < attrs[j].NameRange.Start.Byte })
+	return attrs
+}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("HCL unmarshal errors: %s", strings.Join(errs, "; "))
+// unsupportedAttribute reports an attribute that the body does not have, as
+// hcl.Body.Content does.
+func unsupportedAttribute(attr *hclsyntax.Attribute) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Unsupported argument",
+		Detail:   fmt.Sprintf("An argument named %q is not expected here.", attr.Name),
+		Subject:  attr.NameRange.Ptr(),
 	}
+}
 
+// unsupportedBlock reports a block that the body does not have.
+func unsupportedBlock(block *hclsyntax.Block) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Unsupported block type",
+		Detail:   fmt.Sprintf("Blocks of type %q are not expected here.", block.Type),
+		Subject:  block.TypeRange.Ptr(),
+	}
+}
+
+// typeMismatch reports an attribute value of the wrong type.
+func typeMismatch(attr *hclsyntax.Attribute, want string) hcl.Diagnostics {
+	return hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Incorrect attribute value type",
+		Detail:   fmt.Sprintf("Inappropriate value for attribute %q: %s required.", attr.Name, want),
+		Subject:  attr.Expr.Range().Ptr(),
+	}}
+}
+
+// decodeExtension stores an x-* attribute in extensions, and reports any
+// other attribute as unsupported.
+func decodeExtension(attr *hclsyntax.Attribute, val cty.Value, extensions *map[string]any) hcl.Diagnostics {
+	if !strings.HasPrefix(attr.Name, "x-") {
+		return hcl.Diagnostics{unsupportedAttribute(attr)}
+	}
+	if *extensions == nil {
+		*extensions = make(map[string]any)
+	}
+	(*extensions)[attr.Name] = ctyToGo(val)
 	return nil
 }
 
-// hclBlockToMap converts an HCL block to a map[string]any.
-// Attributes with evaluation errors are skipped and logged as warnings via the returned error slice.
-func hclBlockToMap(block *hclsyntax.Block) map[string]any {
+// hclBlockToMap converts an HCL block to a map[string]any. Attributes that
+// fail to evaluate are reported.
+func hclBlockToMap(block *hclsyntax.Block) (map[string]any, hcl.Diagnostics) {
 	result := make(map[string]any)
+	var diags hcl.Diagnostics
 
-	// Process attributes - skip those with errors but the calling code
-	// should be aware these attributes were not processed
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			// Attribute requires context evaluation; store placeholder
-			result[name] = nil
+	for _, attr := range sortedAttributes(block.Body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		result[name] = ctyToGo(val)
+		result[attr.Name] = ctyToGo(val)
 	}
 
-	// Process nested blocks
+	// Process nested blocks. Labels are keys of nested maps, as dethcl
+	// writes them: properties "id" { ... } is {"properties": {"id": {...}}}.
+	// Repeated unlabeled blocks make a list.
 	for _, nestedBlock := range block.Body.Blocks {
-		blockName := nestedBlock.Type
-		if len(nestedBlock.Labels) > 0 {
-			blockName = nestedBlock.Labels[0]
+		nested, d := hclBlockToMap(nestedBlock)
+		diags = append(diags, d...)
+		if len(nestedBlock.Labels) == 0 {
+			switch prev := result[nestedBlock.Type].(type) {
+			case nil:
+				result[nestedBlock.Type] = nested
+			case []any:
+				result[nestedBlock.Type] = append(prev, nested)
+			default:
+				result[nestedBlock.Type] = []any{prev, nested}
+			}
+			continue
 		}
-		result[blockName] = hclBlockToMap(nestedBlock)
+		parent, _ := result[nestedBlock.Type].(map[string]any)
+		if parent == nil {
+			parent = make(map[string]any)
+			result[nestedBlock.Type] = parent
+		}
+		for _, label := range nestedBlock.Labels[:len(nestedBlock.Labels)-1] {
+			child, _ := parent[label].(map[string]any)
+			if child == nil {
+				child = make(map[string]any)
+				parent[label] = child
+			}
+			parent = child
+		}
+		parent[nestedBlock.Labels[len(nestedBlock.Labels)-1]] = nested
 	}
 
-	return result
+	return result, diags
 }
 
 // hclBlockToBytes is deprecated and not used.
@@ -267,160 +359,253 @@ func ctyToGo(val cty.Value) any {
 	}
 }
 
-// ctyToStringSlice converts a cty.Value list to []string
-func ctyToStringSlice(val cty.Value) []string {
-	if val.IsNull() || !val.CanIterateElements() {
-		return nil
+// stringValue returns a string attribute value.
+func stringValue(attr *hclsyntax.Attribute, val cty.Value) (string, hcl.Diagnostics) {
+	if val.IsNull() {
+		return "", nil
+	}
+	if val.Type() != cty.String {
+		return "", typeMismatch(attr, "string")
+	}
+	return val.AsString(), nil
+}
+
+// numberValue returns a number attribute value.
+func numberValue(attr *hclsyntax.Attribute, val cty.Value) (float64, hcl.Diagnostics) {
+	if val.IsNull() || val.Type() != cty.Number {
+		return 0, typeMismatch(attr, "number")
+	}
+	f, _ := val.AsBigFloat().Float64()
+	if math.IsInf(f, 0) {
+		return 0, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid attribute value",
+			Detail:   fmt.Sprintf("The value of attribute %q is too large.", attr.Name),
+			Subject:  attr.Expr.Range().Ptr(),
+		}}
+	}
+	return f, nil
+}
+
+// stringSliceValue returns a list of strings attribute value.
+func stringSliceValue(attr *hclsyntax.Attribute, val cty.Value) ([]string, hcl.Diagnostics) {
+	if val.IsNull() {
+		return nil, nil
+	}
+	if !val.Type().IsListType() && !val.Type().IsTupleType() && !val.Type().IsSetType() {
+		return nil, typeMismatch(attr, "list of string")
 	}
 	var result []string
 	for it := val.ElementIterator(); it.Next(); {
 		_, v := it.Element()
-		if v.Type() == cty.String {
-			result = append(result, v.AsString())
+		if v.IsNull() || v.Type() != cty.String {
+			return nil, typeMismatch(attr, "list of string")
 		}
+		result = append(result, v.AsString())
 	}
-	return result
+	return result, nil
 }
 
-// ctyToStringMap converts a cty.Value map to map[string]string
-func ctyToStringMap(val cty.Value) map[string]string {
-	if val.IsNull() || !val.CanIterateElements() {
-		return nil
+// stringMapValue returns a map of strings attribute value.
+func stringMapValue(attr *hclsyntax.Attribute, val cty.Value) (map[string]string, hcl.Diagnostics) {
+	if val.IsNull() {
+		return nil, nil
+	}
+	if !val.Type().IsMapType() && !val.Type().IsObjectType() {
+		return nil, typeMismatch(attr, "map of string")
 	}
 	result := make(map[string]string)
 	for it := val.ElementIterator(); it.Next(); {
 		k, v := it.Element()
-		if v.Type() == cty.String {
-			result[k.AsString()] = v.AsString()
+		if v.IsNull() || v.Type() != cty.String {
+			return nil, typeMismatch(attr, "map of string")
 		}
+		result[k.AsString()] = v.AsString()
 	}
-	return result
+	return result, nil
+}
+
+// parseSuccessActionOrReusable parses a success action block, which holds
+// either the action or a reusable reference to one.
+func parseSuccessActionOrReusable(block *hclsyntax.Block) (*SuccessActionOrReusable, hcl.Diagnostics) {
+	if reusable, ok, diags := parseReusableFromBlock(block); ok {
+		return &SuccessActionOrReusable{Reusable: reusable}, diags
+	}
+	action := &SuccessAction{}
+	if len(block.Labels) > 0 {
+		action.Name = block.Labels[0]
+	}
+	return &SuccessActionOrReusable{SuccessAction: action}, parseSuccessActionBlock(block, action)
+}
+
+// parseFailureActionOrReusable parses a failure action block, which holds
+// either the action or a reusable reference to one.
+func parseFailureActionOrReusable(block *hclsyntax.Block) (*FailureActionOrReusable, hcl.Diagnostics) {
+	if reusable, ok, diags := parseReusableFromBlock(block); ok {
+		return &FailureActionOrReusable{Reusable: reusable}, diags
+	}
+	action := &FailureAction{}
+	if len(block.Labels) > 0 {
+		action.Name = block.Labels[0]
+	}
+	return &FailureActionOrReusable{FailureAction: action}, parseFailureActionBlock(block, action)
+}
+
+// parseParameterOrReusable parses a workflow parameter block, which holds
+// either the parameter or a reusable reference to one.
+func parseParameterOrReusable(block *hclsyntax.Block) (*ParameterOrReusable, hcl.Diagnostics) {
+	if reusable, ok, diags := parseReusableFromBlock(block); ok {
+		return &ParameterOrReusable{Reusable: reusable}, diags
+	}
+	param := &Parameter{}
+	if len(block.Labels) > 0 {
+		param.Name = block.Labels[0]
+	}
+	return &ParameterOrReusable{Parameter: param}, parseParameterBody(block.Body, param)
 }
 
 // parseSuccessActionBlock parses HCL block attributes into a SuccessAction
-func parseSuccessActionBlock(block *hclsyntax.Block, action *SuccessAction) error {
-	var errs []string
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+func parseSuccessActionBlock(block *hclsyntax.Block, action *SuccessAction) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(block.Body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		switch name {
+		var s string
+		switch attr.Name {
+		case "name":
+			action.Name, d = stringValue(attr, val)
 		case "type":
-			action.Type = SuccessActionType(val.AsString())
+			s, d = stringValue(attr, val)
+			action.Type = SuccessActionType(s)
 		case "workflowId":
-			action.WorkflowId = val.AsString()
+			action.WorkflowId, d = stringValue(attr, val)
 		case "stepId":
-			action.StepId = val.AsString()
+			action.StepId, d = stringValue(attr, val)
+		default:
+			d = decodeExtension(attr, val, &action.Extensions)
 		}
+		diags = append(diags, d...)
 	}
 	for _, nestedBlock := range block.Body.Blocks {
 		switch nestedBlock.Type {
 		case "criterion":
 			criterion := &Criterion{}
-			if err := parseCriterionBlock(nestedBlock, criterion); err != nil {
-				errs = append(errs, fmt.Sprintf("criterion: %s", err.Error()))
-				continue
-			}
+			diags = append(diags, parseCriterionBlock(nestedBlock, criterion)...)
 			action.Criteria = append(action.Criteria, criterion)
 		case "successAction":
 			if len(nestedBlock.Labels) > 0 {
 				action.Name = nestedBlock.Labels[0]
 			}
-			if err := parseSuccessActionBlock(nestedBlock, action); err != nil {
-				errs = append(errs, fmt.Sprintf("successAction: %s", err.Error()))
-			}
+			diags = append(diags, parseSuccessActionBlock(nestedBlock, action)...)
+		default:
+			diags = append(diags, unsupportedBlock(nestedBlock))
 		}
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("success action errors: %s", strings.Join(errs, "; "))
-	}
-	return nil
+	return diags
 }
 
 // parseFailureActionBlock parses HCL block attributes into a FailureAction
-func parseFailureActionBlock(block *hclsyntax.Block, action *FailureAction) error {
-	var errs []string
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+func parseFailureActionBlock(block *hclsyntax.Block, action *FailureAction) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(block.Body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		switch name {
+		var s string
+		var f float64
+		switch attr.Name {
+		case "name":
+			action.Name, d = stringValue(attr, val)
 		case "type":
-			action.Type = FailureActionType(val.AsString())
+			s, d = stringValue(attr, val)
+			action.Type = FailureActionType(s)
 		case "workflowId":
-			action.WorkflowId = val.AsString()
+			action.WorkflowId, d = stringValue(attr, val)
 		case "stepId":
-			action.StepId = val.AsString()
+			action.StepId, d = stringValue(attr, val)
 		case "retryAfter":
-			f, acc := val.AsBigFloat().Float64()
-			if acc != 0 && math.IsInf(f, 0) {
-				errs = append(errs, fmt.Sprintf("retryAfter value too large for float64"))
-				continue
+			if f, d = numberValue(attr, val); !d.HasErrors() {
+				action.RetryAfter = &f
 			}
-			action.RetryAfter = &f
 		case "retryLimit":
-			f, acc := val.AsBigFloat().Float64()
-			if acc != 0 && math.IsInf(f, 0) {
-				errs = append(errs, fmt.Sprintf("retryLimit value too large for int"))
-				continue
+			if f, d = numberValue(attr, val); !d.HasErrors() {
+				i := int(f)
+				action.RetryLimit = &i
 			}
-			i := int(f)
-			action.RetryLimit = &i
+		default:
+			d = decodeExtension(attr, val, &action.Extensions)
 		}
+		diags = append(diags, d...)
 	}
 	for _, nestedBlock := range block.Body.Blocks {
 		switch nestedBlock.Type {
 		case "criterion":
 			criterion := &Criterion{}
-			if err := parseCriterionBlock(nestedBlock, criterion); err != nil {
-				errs = append(errs, fmt.Sprintf("criterion: %s", err.Error()))
-				continue
-			}
+			diags = append(diags, parseCriterionBlock(nestedBlock, criterion)...)
 			action.Criteria = append(action.Criteria, criterion)
 		case "failureAction":
 			if len(nestedBlock.Labels) > 0 {
 				action.Name = nestedBlock.Labels[0]
 			}
-			if err := parseFailureActionBlock(nestedBlock, action); err != nil {
-				errs = append(errs, fmt.Sprintf("failureAction: %s", err.Error()))
-			}
+			diags = append(diags, parseFailureActionBlock(nestedBlock, action)...)
+		default:
+			diags = append(diags, unsupportedBlock(nestedBlock))
 		}
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("failure action errors: %s", strings.Join(errs, "; "))
-	}
-	return nil
+	return diags
 }
 
-// parseParameterBlock parses HCL block attributes into a Parameter
-func parseParameterBlock(block *hclsyntax.Block, param *Parameter) error {
-	var errs []string
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+// parseParameterBody parses the body of a parameter block into a Parameter.
+// The parameter may also be nested in a labeled parameter block, as
+// MarshalHCL writes workflow parameters.
+func parseParameterBody(body *hclsyntax.Body, param *Parameter) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		switch name {
+		var s string
+		switch attr.Name {
+		case "name":
+			param.Name, d = stringValue(attr, val)
 		case "in":
-			param.In = ParameterIn(val.AsString())
+			s, d = stringValue(attr, val)
+			param.In = ParameterIn(s)
 		case "value":
 			param.Value = ctyToGo(val)
+		default:
+			d = decodeExtension(attr, val, &param.Extensions)
 		}
+		diags = append(diags, d...)
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("parameter errors: %s", strings.Join(errs, "; "))
+	for _, nestedBlock := range body.Blocks {
+		switch nestedBlock.Type {
+		case "parameter":
+		case "value":
+			// An object value, which dethcl writes as a block.
+			value, d := hclBlockToMap(nestedBlock)
+			diags = append(diags, d...)
+			param.Value = value
+			continue
+		default:
+			diags = append(diags, unsupportedBlock(nestedBlock))
+			continue
+		}
+		if len(nestedBlock.Labels) > 0 {
+			param.Name = nestedBlock.Labels[0]
+		}
+		diags = append(diags, parseParameterBody(nestedBlock.Body, param)...)
 	}
-	return nil
+	return diags
 }
 
 // parseStepBlock parses an HCL step block into a Step struct
-func parseStepBlock(block *hclsyntax.Block, s *Step) error {
-	var errs []string
+func parseStepBlock(block *hclsyntax.Block, s *Step) hcl.Diagnostics {
+	var diags hcl.Diagnostics
 
 	// Set label (stepId) if provided
 	if len(block.Labels) > 0 {
@@ -428,27 +613,33 @@ func parseStepBlock(block *hclsyntax.Block, s *Step) error {
 	}
 
 	// Process attributes
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+	for _, attr := range sortedAttributes(block.Body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
 
-		switch name {
+		switch attr.Name {
 		case "description":
-			s.Description = val.AsString()
+			s.Description, d = stringValue(attr, val)
 		case "operationId":
-			s.OperationId = val.AsString()
+			s.OperationId, d = stringValue(attr, val)
 		case "operationPath":
-			s.OperationPath = val.AsString()
+			s.OperationPath, d = stringValue(attr, val)
 		case "workflowId":
-			s.WorkflowId = val.AsString()
+			s.WorkflowId, d = stringValue(attr, val)
 		case "outputs":
-			s.Outputs = ctyToStringMap(val)
+			s.Outputs, d = stringMapValue(attr, val)
 		case "parameters":
+			if !val.IsNull() && !val.Type().IsListType() && !val.Type().IsTupleType() {
+				d = typeMismatch(attr, "list of parameter objects")
+				break
+			}
 			s.Parameters = ctyToParameters(val)
+		default:
+			d = decodeExtension(attr, val, &s.Extensions)
 		}
+		diags = append(diags, d...)
 	}
 
 	// Process nested blocks
@@ -456,207 +647,200 @@ func parseStepBlock(block *hclsyntax.Block, s *Step) error {
 		switch nestedBlock.Type {
 		case "requestBody":
 			s.RequestBody = &RequestBody{}
-			if err := parseRequestBodyBlock(nestedBlock, s.RequestBody); err != nil {
-				errs = append(errs, fmt.Sprintf("requestBody: %s", err.Error()))
-			}
+			diags = append(diags, parseRequestBodyBlock(nestedBlock, s.RequestBody)...)
 		case "successCriterion":
 			criterion := &Criterion{}
-			if err := parseCriterionBlock(nestedBlock, criterion); err != nil {
-				errs = append(errs, fmt.Sprintf("successCriterion: %s", err.Error()))
-			}
+			diags = append(diags, parseCriterionBlock(nestedBlock, criterion)...)
 			s.SuccessCriteria = append(s.SuccessCriteria, criterion)
 		case "onSuccess":
-			action := &SuccessActionOrReusable{}
-			if reusable, ok, err := parseReusableFromBlock(nestedBlock); ok {
-				if err != nil {
-					errs = append(errs, fmt.Sprintf("onSuccess reusable: %s", err.Error()))
-					break
-				}
-				action.Reusable = reusable
-			} else {
-				action.SuccessAction = &SuccessAction{}
-				if len(nestedBlock.Labels) > 0 {
-					action.SuccessAction.Name = nestedBlock.Labels[0]
-				}
-				if err := parseSuccessActionBlock(nestedBlock, action.SuccessAction); err != nil {
-					errs = append(errs, fmt.Sprintf("onSuccess: %s", err.Error()))
-				}
-			}
+			action, d := parseSuccessActionOrReusable(nestedBlock)
+			diags = append(diags, d...)
 			s.OnSuccess = append(s.OnSuccess, action)
 		case "onFailure":
-			action := &FailureActionOrReusable{}
-			if reusable, ok, err := parseReusableFromBlock(nestedBlock); ok {
-				if err != nil {
-					errs = append(errs, fmt.Sprintf("onFailure reusable: %s", err.Error()))
-					break
-				}
-				action.Reusable = reusable
-			} else {
-				action.FailureAction = &FailureAction{}
-				if len(nestedBlock.Labels) > 0 {
-					action.FailureAction.Name = nestedBlock.Labels[0]
-				}
-				if err := parseFailureActionBlock(nestedBlock, action.FailureAction); err != nil {
-					errs = append(errs, fmt.Sprintf("onFailure: %s", err.Error()))
-				}
-			}
+			action, d := parseFailureActionOrReusable(nestedBlock)
+			diags = append(diags, d...)
 			s.OnFailure = append(s.OnFailure, action)
+		default:
+			diags = append(diags, unsupportedBlock(nestedBlock))
 		}
 	}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("step %q errors: %s", s.StepId, strings.Join(errs, "; "))
-	}
-	return nil
+	return diags
 }
 
-func parseReusableFromBlock(block *hclsyntax.Block) (*ReusableObject, bool, error) {
-	for _, nestedBlock := range block.Body.Blocks {
+// parseReusableFromBlock parses the reusable object of an action or
+// parameter block, written as a nested reusable block or as a reference
+// attribute. It reports false if the block holds no reusable object.
+func parseReusableFromBlock(block *hclsyntax.Block) (*ReusableObject, bool, hcl.Diagnostics) {
+	for i, nestedBlock := range block.Body.Blocks {
 		if nestedBlock.Type != "reusable" {
 			continue
 		}
 		reusable := &ReusableObject{}
-		if err := parseReusableBody(nestedBlock.Body, reusable); err != nil {
-			return reusable, true, err
+		diags := parseReusableBody(nestedBlock.Body, reusable)
+		// Nothing else goes next to the reusable block.
+		for _, attr := range sortedAttributes(block.Body) {
+			diags = append(diags, unsupportedAttribute(attr))
+		}
+		for j, other := range block.Body.Blocks {
+			if j != i {
+				diags = append(diags, unsupportedBlock(other))
+			}
 		}
-		return reusable, true, nil
+		return reusable, true, diags
 	}
 
 	if _, ok := block.Body.Attributes["reference"]; ok {
 		reusable := &ReusableObject{}
-		if err := parseReusableBody(block.Body, reusable); err != nil {
-			return reusable, true, err
-		}
-		return reusable, true, nil
+		return reusable, true, parseReusableBody(block.Body, reusable)
 	}
 
 	return nil, false, nil
 }
 
-func parseReusableBody(body *hclsyntax.Body, reusable *ReusableObject) error {
-	var errs []string
-	for name, attr := range body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+func parseReusableBody(body *hclsyntax.Body, reusable *ReusableObject) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		switch name {
+		switch attr.Name {
 		case "reference":
-			reusable.Reference = val.AsString()
+			reusable.Reference, d = stringValue(attr, val)
 		case "value":
 			reusable.Value = ctyToGo(val)
+		default:
+			d = hcl.Diagnostics{unsupportedAttribute(attr)}
 		}
+		diags = append(diags, d...)
 	}
 	for _, nestedBlock := range body.Blocks {
 		if nestedBlock.Type != "value" {
+			diags = append(diags, unsupportedBlock(nestedBlock))
 			continue
 		}
-		reusable.Value = hclBlockToMap(nestedBlock)
-	}
-	if len(errs) > 0 {
-		return fmt.Errorf("reusable errors: %s", strings.Join(errs, "; "))
+		value, d := hclBlockToMap(nestedBlock)
+		diags = append(diags, d...)
+		reusable.Value = value
 	}
-	return nil
+	return diags
 }
 
 // parseRequestBodyBlock parses HCL block into RequestBody
-func parseRequestBodyBlock(block *hclsyntax.Block, rb *RequestBody) error {
-	var errs []string
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+func parseRequestBodyBlock(block *hclsyntax.Block, rb *RequestBody) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(block.Body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		switch name {
+		switch attr.Name {
 		case "contentType":
-			rb.ContentType = val.AsString()
+			rb.ContentType, d = stringValue(attr, val)
 		case "payload":
 			rb.Payload = ctyToGo(val)
+		default:
+			d = decodeExtension(attr, val, &rb.Extensions)
 		}
+		diags = append(diags, d...)
 	}
 	// Handle payload block
 	for _, nestedBlock := range block.Body.Blocks {
 		switch nestedBlock.Type {
 		case "payload":
-			rb.Payload = hclBlockToMap(nestedBlock)
+			payload, d := hclBlockToMap(nestedBlock)
+			diags = append(diags, d...)
+			rb.Payload = payload
 		case "replacement":
 			replacement := &PayloadReplacement{}
-			if err := parsePayloadReplacementBlock(nestedBlock, replacement); err != nil {
-				errs = append(errs, fmt.Sprintf("replacement: %s", err.Error()))
-				continue
-			}
+			diags = append(diags, parsePayloadReplacementBlock(nestedBlock, replacement)...)
 			rb.Replacements = append(rb.Replacements, replacement)
+		default:
+			diags = append(diags, unsupportedBlock(nestedBlock))
 		}
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("request body errors: %s", strings.Join(errs, "; "))
-	}
-	return nil
+	return diags
 }
 
-func parsePayloadReplacementBlock(block *hclsyntax.Block, replacement *PayloadReplacement) error {
-	var errs []string
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+func parsePayloadReplacementBlock(block *hclsyntax.Block, replacement *PayloadReplacement) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(block.Body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		switch name {
+		switch attr.Name {
 		case "target":
-			replacement.Target = val.AsString()
+			replacement.Target, d = stringValue(attr, val)
 		case "value":
-			replacement.Value = val.AsString()
+			replacement.Value, d = stringValue(attr, val)
+		default:
+			d = decodeExtension(attr, val, &replacement.Extensions)
 		}
+		diags = append(diags, d...)
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("replacement errors: %s", strings.Join(errs, "; "))
+	for _, nestedBlock := range block.Body.Blocks {
+		diags = append(diags, unsupportedBlock(nestedBlock))
 	}
-	return nil
+	return diags
 }
 
 // parseCriterionBlock parses HCL block into Criterion
-func parseCriterionBlock(block *hclsyntax.Block, c *Criterion) error {
-	var errs []string
-	var version string
+func parseCriterionBlock(block *hclsyntax.Block, c *Criterion) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	var version *hclsyntax.Attribute
+	var versionValue string
 	var exprType *CriterionExpressionType
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+	for _, attr := range sortedAttributes(block.Body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		switch name {
+		var s string
+		switch attr.Name {
 		case "context":
-			c.Context = val.AsString()
+			c.Context, d = stringValue(attr, val)
 		case "condition":
-			c.Condition = val.AsString()
+			c.Condition, d = stringValue(attr, val)
 		case "type":
-			c.Type = CriterionType(val.AsString())
+			s, d = stringValue(attr, val)
+			c.Type = CriterionType(s)
 		case "version":
-			version = val.AsString()
+			version = attr
+			versionValue, d = stringValue(attr, val)
+		default:
+			d = decodeExtension(attr, val, &c.Extensions)
 		}
+		diags = append(diags, d...)
 	}
 	for _, nestedBlock := range block.Body.Blocks {
 		if nestedBlock.Type != "expressionType" {
+			diags = append(diags, unsupportedBlock(nestedBlock))
 			continue
 		}
 		exprType = &CriterionExpressionType{}
-		if err := parseCriterionExpressionTypeBlock(nestedBlock, exprType); err != nil {
-			errs = append(errs, fmt.Sprintf("expressionType: %s", err.Error()))
-		}
+		diags = append(diags, parseCriterionExpressionTypeBlock(nestedBlock, exprType)...)
 	}
-	if version != "" {
+	if versionValue != "" {
 		if exprType != nil {
-			errs = append(errs, "expressionType block and version attribute both set")
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Conflicting criterion version",
+				Detail:   "The version attribute cannot be used together with an expressionType block.",
+				Subject:  version.NameRange.Ptr(),
+			})
 		} else if c.Type == "" {
-			errs = append(errs, "version requires type")
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing criterion type",
+				Detail:   "The version attribute requires a type attribute.",
+				Subject:  version.NameRange.Ptr(),
+			})
 		} else {
 			c.ExpressionType = &CriterionExpressionType{
 				Type:    c.Type,
-				Version: version,
+				Version: versionValue,
 			}
 			c.Type = ""
 		}
@@ -665,31 +849,32 @@ func parseCriterionBlock(block *hclsyntax.Block, c *Criterion) error {
 		c.ExpressionType = exprType
 		c.Type = ""
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("criterion errors: %s", strings.Join(errs, "; "))
-	}
-	return nil
+	return diags
 }
 
-func parseCriterionExpressionTypeBlock(block *hclsyntax.Block, expr *CriterionExpressionType) error {
-	var errs []string
-	for name, attr := range block.Body.Attributes {
-		val, diags := attr.Expr.Value(nil)
-		if diags.HasErrors() {
-			errs = append(errs, fmt.Sprintf("attribute %q: %s", name, diags.Error()))
+func parseCriterionExpressionTypeBlock(block *hclsyntax.Block, expr *CriterionExpressionType) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(block.Body) {
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
 			continue
 		}
-		switch name {
+		var s string
+		switch attr.Name {
 		case "type":
-			expr.Type = CriterionType(val.AsString())
+			s, d = stringValue(attr, val)
+			expr.Type = CriterionType(s)
 		case "version":
-			expr.Version = val.AsString()
+			expr.Version, d = stringValue(attr, val)
+		default:
+			d = decodeExtension(attr, val, &expr.Extensions)
 		}
+		diags = append(diags, d...)
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("expressionType errors: %s", strings.Join(errs, "; "))
+	for _, nestedBlock := range block.Body.Blocks {
+		diags = append(diags, unsupportedBlock(nestedBlock))
 	}
-	return nil
+	return diags
 }
 
 // ctyToParameters converts a cty.Value to []any for parameters
@@ -713,19 +898,34 @@ type SuccessActionOrReusable struct {
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (s *SuccessActionOrReusable) UnmarshalJSON(data []byte) error {
-	// Try to unmarshal as ReusableObject first (check for "reference" field)
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
+	return decodeJSON(data, s.decodeJSON)
+}
 
-	if _, hasReference := raw["reference"]; hasReference {
-		s.Reusable = &ReusableObject{}
-		return json.Unmarshal(data, s.Reusable)
-	}
+var successActionOrReusableKnownFields = slices.Concat(successActionKnownFields, []string{"reference", "value"})
 
-	s.SuccessAction = &SuccessAction{}
-	return json.Unmarshal(data, s.SuccessAction)
+// decodeJSON reads the members of a SuccessAction and a ReusableObject at
+// once; a reference makes it a ReusableObject.
+func (s *SuccessActionOrReusable) decodeJSON(d *decoder) error {
+	action := &SuccessAction{}
+	var reference *string
+	var value any
+	err := d.object(reflect.TypeFor[SuccessAction](), successActionOrReusableKnownFields, &action.Extensions, func(key string) error {
+		switch key {
+		case "reference":
+			reference = new(string)
+			return decodeString(d, reference)
+		case "value":
+			v, err := d.value()
+			value = v
+			return err
+		}
+		return action.decodeField(d, key)
+	})
+	*s = SuccessActionOrReusable{SuccessAction: action}
+	if reference != nil {
+		*s = SuccessActionOrReusable{Reusable: &ReusableObject{Reference: *reference, Value: value}}
+	}
+	return err
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -744,19 +944,34 @@ type FailureActionOrReusable struct {
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (f *FailureActionOrReusable) UnmarshalJSON(data []byte) error {
-	// Try to unmarshal as ReusableObject first (check for "reference" field)
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
+	return decodeJSON(data, f.decodeJSON)
+}
 
-	if _, hasReference := raw["reference"]; hasReference {
-		f.Reusable = &ReusableObject{}
-		return json.Unmarshal(data, f.Reusable)
-	}
+var failureActionOrReusableKnownFields = slices.Concat(failureActionKnownFields, []string{"reference", "value"})
 
-	f.FailureAction = &FailureAction{}
-	return json.Unmarshal(data, f.FailureAction)
+// decodeJSON reads the members of a FailureAction and a ReusableObject at
+// once; a reference makes it a ReusableObject.
+func (f *FailureActionOrReusable) decodeJSON(d *decoder) error {
+	action := &FailureAction{}
+	var reference *string
+	var value any
+	err := d.object(reflect.TypeFor[FailureAction](), failureActionOrReusableKnownFields, &action.Extensions, func(key string) error {
+		switch key {
+		case "reference":
+			reference = new(string)
+			return decodeString(d, reference)
+		case "value":
+			v, err := d.value()
+			value = v
+			return err
+		}
+		return action.decodeField(d, key)
+	})
+	*f = FailureActionOrReusable{FailureAction: action}
+	if reference != nil {
+		*f = FailureActionOrReusable{Reusable: &ReusableObject{Reference: *reference, Value: value}}
+	}
+	return err
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -775,19 +990,28 @@ type ParameterOrReusable struct {
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 func (p *ParameterOrReusable) UnmarshalJSON(data []byte) error {
-	// Try to unmarshal as ReusableObject first (check for "reference" field)
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-
-	if _, hasReference := raw["reference"]; hasReference {
-		p.Reusable = &ReusableObject{}
-		return json.Unmarshal(data, p.Reusable)
-	}
+	return decodeJSON(data, p.decodeJSON)
+}
 
-	p.Parameter = &Parameter{}
-	return json.Unmarshal(data, p.Parameter)
+var parameterOrReusableKnownFields = slices.Concat(parameterKnownFields, []string{"reference"})
+
+// decodeJSON reads the members of a Parameter and a ReusableObject at once;
+// a reference makes it a ReusableObject.
+func (p *ParameterOrReusable) decodeJSON(d *decoder) error {
+	param := &Parameter{}
+	var reference *string
+	err := d.object(reflect.TypeFor[Parameter](), parameterOrReusableKnownFields, &param.Extensions, func(key string) error {
+		if key == "reference" {
+			reference = new(string)
+			return decodeString(d, reference)
+		}
+		return param.decodeField(d, key)
+	})
+	*p = ParameterOrReusable{Parameter: param}
+	if reference != nil {
+		*p = ParameterOrReusable{Reusable: &ReusableObject{Reference: *reference, Value: param.Value}}
+	}
+	return err
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -0,0 +1,468 @@
+// Command arazzo generates code from Arazzo workflow descriptions.
+//
+// Usage:
+//
+//	arazzo gogen [-package name] [-o file] [-workflow id] [-source name=file] <arazzo file>
+//	arazzo k6 [-o file] [-workflow id] [-source name=file] [-vus n] [-iterations n] <arazzo file>
+//	arazzo init [-o file] [-format yaml|hcl] [-name provider] [-tag tag] [-path prefix] <openapi file>
+//	arazzo fmt [-check] [-w] <arazzo file or directory>...
+//	arazzo plan [-o file] [-workflow id] [-source name=file] [-server name=url] [-input name=value] [-format text|json|curl] <arazzo file>
+//
+// The Arazzo file may be JSON, YAML or HCL, chosen by its extension, or a
+// directory of *.arazzo.hcl files forming one HCL module. OpenAPI source
+// descriptions are loaded from -source flags, or else from the url of each
+// source description, resolved relative to the Arazzo file or directory.
+//
+// The init subcommand scaffolds a generator config for an OpenAPI document
+// instead; its format defaults to HCL when the -o file ends in .hcl.
+//
+// The fmt subcommand prints Arazzo files in their canonical layout, see
+// convert.Format; for a directory it formats the *.arazzo.json, *.arazzo.yaml,
+// *.arazzo.yml and *.arazzo.hcl files in it. YAML files keep their comments.
+// HCL files only have their whitespace formatted; their blocks and attributes
+// keep their order. With -w it rewrites the files instead, and with -check,
+// meant for CI, it lists the files that are not formatted, with the first
+// line formatting changes, and fails if there are any, naming the fields of
+// JSON and YAML files that formatting would drop because they are neither in
+// the specification nor extensions.
+//
+// The plan subcommand prints the requests the workflows would send without
+// sending any, see runner.Runner.Plan. Inputs given with -input are passed to
+// every workflow; values are parsed as JSON when they can be, and as strings
+// otherwise. -server sets the base URL of a source description, instead of
+// the first server of its OpenAPI document.
+//
+// The gogen subcommand is designed for go generate:
+//
+//	//go:generate go run github.com/genelet/arazzo/cmd/arazzo gogen -package flows -o flows_gen.go flows.arazzo.yaml
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/codegen"
+	"github.com/genelet/arazzo/convert"
+	"github.com/genelet/arazzo/generator"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/arazzo/runner"
+	"github.com/genelet/oas/openapi31"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// command is a subcommand of the arazzo tool.
+type command struct {
+	summary string
+	run     func(args []string, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"gogen": {"generate a typed Go client for the workflows", runGoGen},
+	"k6":    {"generate a k6 load-test script for the workflows", runK6},
+	"init":  {"scaffold a generator config from an OpenAPI document", runInit},
+	"fmt":   {"rewrite Arazzo documents in their canonical layout", runFmt},
+	"plan":  {"print the requests of the workflows without sending them", runPlan},
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		printError(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// printError prints an error; HCL diagnostics are printed with their source
+// snippets, the way Terraform prints them.
+func printError(w io.Writer, err error) {
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) {
+		convert.WriteDiagnostics(w, diags, nil)
+		return
+	}
+	fmt.Fprintln(w, "arazzo:", err)
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stdout)
+		return nil
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage(os.Stderr)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd.run(args[1:], stdout)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: arazzo <command> [flags] <arazzo file>")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+}
+
+// listFlag collects repeated or comma-separated flag values.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// generateFlags are the flags shared by the code generation subcommands.
+type generateFlags struct {
+	output    string
+	workflows listFlag
+	sources   listFlag
+}
+
+func (f *generateFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.output, "o", "", "output file (default standard output)")
+	fs.Var(&f.workflows, "workflow", "workflowId to generate; may be repeated (default all)")
+	fs.Var(&f.sources, "source", "OpenAPI document for a source description, as name=file; may be repeated")
+}
+
+func runGoGen(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("gogen", flag.ContinueOnError)
+	var gf generateFlags
+	gf.register(fs)
+	pkg := fs.String("package", "", "package name of the generated file (default $GOPACKAGE, then \"client\")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	doc, sources, err := loadInputs(fs, gf.sources)
+	if err != nil {
+		return err
+	}
+	opts := &codegen.GoOptions{Package: *pkg, Workflows: gf.workflows}
+	if opts.Package == "" {
+		// go generate sets GOPACKAGE to the package of the file with the directive.
+		opts.Package = os.Getenv("GOPACKAGE")
+	}
+	out, err := codegen.GenerateGo(doc, sources, opts)
+	if err != nil {
+		return err
+	}
+	return writeOutput(gf.output, out, stdout)
+}
+
+func runK6(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("k6", flag.ContinueOnError)
+	var gf generateFlags
+	gf.register(fs)
+	vus := fs.Int("vus", 1, "virtual users per scenario")
+	iterations := fs.Int("iterations", 1, "iterations per virtual user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	doc, sources, err := loadInputs(fs, gf.sources)
+	if err != nil {
+		return err
+	}
+	out, err := codegen.GenerateK6(doc, sources, &codegen.K6Options{Workflows: gf.workflows, VUs: *vus, Iterations: *iterations})
+	if err != nil {
+		return err
+	}
+	return writeOutput(gf.output, out, stdout)
+}
+
+func runPlan(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	var gf generateFlags
+	gf.register(fs)
+	var servers, inputFlags listFlag
+	fs.Var(&servers, "server", "base URL of a source description, as name=url; may be repeated")
+	fs.Var(&inputFlags, "input", "workflow input, as name=value; may be repeated")
+	format := fs.String("format", "text", "output format: text, json or curl")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	doc, sources, err := loadInputs(fs, gf.sources)
+	if err != nil {
+		return err
+	}
+	r := runner.New(doc, sources)
+	r.BaseURLs = make(map[string]string)
+	for _, s := range servers {
+		name, u, ok := strings.Cut(s, "=")
+		if !ok || name == "" || u == "" {
+			return fmt.Errorf("invalid -server %q, expected name=url", s)
+		}
+		r.BaseURLs[name] = u
+	}
+	values := make(map[string]any)
+	for _, in := range inputFlags {
+		name, v, ok := strings.Cut(in, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid -input %q, expected name=value", in)
+		}
+		var value any
+		if err := json.Unmarshal([]byte(v), &value); err != nil {
+			value = v
+		}
+		values[name] = value
+	}
+	inputs := make(map[string]map[string]any)
+	for _, wf := range doc.Workflows {
+		if wf != nil {
+			inputs[wf.WorkflowId] = values
+		}
+	}
+
+	plan, err := r.Plan(inputs, gf.workflows...)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	switch *format {
+	case "text":
+		err = plan.WriteText(&out)
+	case "json":
+		err = plan.WriteJSON(&out)
+	case "curl":
+		err = plan.WriteCurl(&out)
+	default:
+		return fmt.Errorf("plan: unknown format %q, expected text, json or curl", *format)
+	}
+	if err != nil {
+		return err
+	}
+	return writeOutput(gf.output, out.Bytes(), stdout)
+}
+
+func runInit(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	output := fs.String("o", "", "output file (default standard output)")
+	format := fs.String("format", "", "config format, yaml or hcl (default from the -o extension, then yaml)")
+	name := fs.String("name", "", "provider name (default \"openapi\")")
+	var tags, paths listFlag
+	fs.Var(&tags, "tag", "select operations with the tag; may be repeated")
+	fs.Var(&paths, "path", "select operations under the path prefix; may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("init: expected exactly one openapi file")
+	}
+	if *format == "" && strings.EqualFold(filepath.Ext(*output), ".hcl") {
+		*format = "hcl"
+	}
+	out, err := generator.InitFileFromOpenAPI(fs.Arg(0), &generator.InitOptions{Name: *name, Tags: tags, Paths: paths, Format: *format})
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, out, stdout)
+}
+
+func runFmt(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	check := fs.Bool("check", false, "list the files that are not formatted, and the unknown fields formatting would drop, and fail if there are any")
+	write := fs.Bool("w", false, "write the result to the files instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("fmt: expected arazzo files or directories")
+	}
+	files, err := fmtFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	var unformatted int
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		out, err := convert.Format(file, data)
+		if err != nil {
+			return fmt.Errorf("formatting %s: %w", file, err)
+		}
+		switch {
+		case *check:
+			if !bytes.Equal(data, out) {
+				fmt.Fprintf(stdout, "%s:%d: not formatted\n", file, firstDifference(data, out))
+				unformatted++
+			}
+			fields, err := convert.UnknownFields(file, data)
+			if err != nil {
+				return fmt.Errorf("formatting %s: %w", file, err)
+			}
+			for _, field := range fields {
+				fmt.Fprintf(stdout, "%s: unknown field %s would be dropped\n", file, field)
+			}
+		case *write:
+			if !bytes.Equal(data, out) {
+				if err := os.WriteFile(file, out, 0644); err != nil {
+					return err
+				}
+			}
+		default:
+			if _, err := stdout.Write(out); err != nil {
+				return err
+			}
+		}
+	}
+	if unformatted > 0 {
+		return fmt.Errorf("fmt: %d of %d files are not formatted", unformatted, len(files))
+	}
+	return nil
+}
+
+// firstDifference returns the number of the first line that differs
+// between a and b.
+func firstDifference(a, b []byte) int {
+	line := 1
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '\n' {
+			line++
+		}
+	}
+	return line
+}
+
+// fmtSuffixes are the names of Arazzo files fmt looks for in directories.
+var fmtSuffixes = []string{".arazzo.json", ".arazzo.yaml", ".arazzo.yml", convert.ModuleSuffix}
+
+// fmtFiles expands directories among args to the Arazzo files in them.
+func fmtFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			for _, suffix := range fmtSuffixes {
+				if !e.IsDir() && strings.HasSuffix(e.Name(), suffix) {
+					files = append(files, filepath.Join(arg, e.Name()))
+					break
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+func loadInputs(fs *flag.FlagSet, sourceFlags []string) (*arazzo1.Arazzo, map[string]*openapi31.OpenAPI, error) {
+	if fs.NArg() != 1 {
+		return nil, nil, fmt.Errorf("%s: expected exactly one arazzo file", fs.Name())
+	}
+	filename := fs.Arg(0)
+	doc, err := loadArazzo(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	base := filepath.Dir(filename)
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		base = filename
+	}
+	sources, err := loadSources(doc, base, sourceFlags)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, sources, nil
+}
+
+// loadArazzo reads an Arazzo document in JSON, YAML or HCL format, or an HCL
+// module directory.
+func loadArazzo(filename string) (*arazzo1.Arazzo, error) {
+	doc := new(arazzo1.Arazzo)
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		if err := convert.LoadHCLDir(filename, doc); err != nil {
+			return nil, fmt.Errorf("loading %s: %w", filename, err)
+		}
+		return doc, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".hcl":
+		err = convert.UnmarshalHCLFiles(map[string][]byte{filename: data}, doc)
+	case ".json":
+		err = convert.UnmarshalJSON(data, doc)
+	default:
+		err = convert.UnmarshalYAML(data, doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+	return doc, nil
+}
+
+// loadSources parses the OpenAPI source descriptions. Explicit name=file flags
+// take precedence; other sources are read from their url when it is a local path.
+func loadSources(doc *arazzo1.Arazzo, dir string, flags []string) (map[string]*openapi31.OpenAPI, error) {
+	files := make(map[string]string)
+	for _, f := range flags {
+		name, file, ok := strings.Cut(f, "=")
+		if !ok || name == "" || file == "" {
+			return nil, fmt.Errorf("invalid -source %q, expected name=file", f)
+		}
+		files[name] = file
+	}
+	for _, sd := range doc.SourceDescriptions {
+		if _, ok := files[sd.Name]; ok || (sd.Type != "" && sd.Type != arazzo1.SourceDescriptionTypeOpenAPI) {
+			continue
+		}
+		if strings.Contains(sd.URL, "://") {
+			continue
+		}
+		if filepath.IsAbs(sd.URL) {
+			files[sd.Name] = sd.URL
+		} else {
+			files[sd.Name] = filepath.Join(dir, sd.URL)
+		}
+	}
+
+	sources := make(map[string]*openapi31.OpenAPI)
+	for name, file := range files {
+		oa, err := oasutil.ParseFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("source %q: %w", name, err)
+		}
+		sources[name] = oa
+	}
+	return sources, nil
+}
+
+func writeOutput(filename string, data []byte, stdout io.Writer) error {
+	if filename == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
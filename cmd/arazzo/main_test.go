@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const examplesDir = "../../convert/examples/1.0.0"
+
+func TestRunGoGen(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "client_gen.go")
+	err := run([]string{"gogen", "-package", "pets", "-workflow", "buy-available-pet", "-o", out,
+		filepath.Join(examplesDir, "pet-coupons.arazzo.yaml")}, os.Stdout)
+	if err != nil {
+		t.Fatalf("gogen failed: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	src := string(data)
+	for _, want := range []string{"package pets", "func (c *Client) BuyAvailablePet(", "func (c *Client) PlaceOrder("} {
+		if !strings.Contains(src, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if strings.Contains(src, "func (c *Client) ApplyCoupon(") {
+		t.Error("unselected workflow should not be generated")
+	}
+}
+
+func TestRunK6HCL(t *testing.T) {
+	var stdout bytes.Buffer
+	err := run([]string{"k6", "-source", "pet-coupons=" + filepath.Join(examplesDir, "pet-coupons.openapi.yaml"),
+		filepath.Join(examplesDir, "pet-coupons.arazzo.hcl")}, &stdout)
+	if err != nil {
+		t.Fatalf("k6 failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "export function applyCoupon(") {
+		t.Errorf("unexpected script:\n%s", stdout.String())
+	}
+}
+
+func TestRunK6Module(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile(filepath.Join(examplesDir, "pet-coupons.arazzo.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pet-coupons.arazzo.hcl"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	err = run([]string{"k6", "-source", "pet-coupons=" + filepath.Join(examplesDir, "pet-coupons.openapi.yaml"), dir}, &stdout)
+	if err != nil {
+		t.Fatalf("k6 failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "export function applyCoupon(") {
+		t.Errorf("unexpected script:\n%s", stdout.String())
+	}
+}
+
+func TestRunInit(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "petstore.generator.hcl")
+	err := run([]string{"init", "-tag", "pet", "-name", "petstore", "-o", out,
+		filepath.Join("..", "..", "generator", "testdata", "petstore.openapi.yaml")}, os.Stdout)
+	if err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	src := string(data)
+	for _, want := range []string{`workflow "pet-lifecycle" {`, `operation_id = "addPet"`} {
+		if !strings.Contains(src, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if strings.Contains(src, "placeOrder") {
+		t.Error("operations without the tag should not be listed")
+	}
+}
+
+func TestRunFmt(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.arazzo.json": `{"info": {"version": "1", "title": "A", "owner": "ann"}, "arazzo": "1.0.0", "workflows": []}`,
+		"b.arazzo.hcl":  "arazzo=\"1.0.0\"\n",
+		"notes.txt":     "not an arazzo file",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	if err := run([]string{"fmt", "-check", dir}, &stdout); err == nil {
+		t.Fatal("check succeeded on unformatted files")
+	}
+	a := filepath.Join(dir, "a.arazzo.json")
+	if got := stdout.String(); got != a+":1: not formatted\n"+a+": unknown field /info/owner would be dropped\n"+filepath.Join(dir, "b.arazzo.hcl")+":1: not formatted\n" {
+		t.Errorf("check listed:\n%s", got)
+	}
+
+	if err := run([]string{"fmt", "-w", dir}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("fmt -w failed: %v", err)
+	}
+	stdout.Reset()
+	if err := run([]string{"fmt", "-check", dir}, &stdout); err != nil || stdout.Len() != 0 {
+		t.Errorf("check after -w: %v\n%s", err, stdout.String())
+	}
+
+	stdout.Reset()
+	if err := run([]string{"fmt", filepath.Join(dir, "b.arazzo.hcl")}, &stdout); err != nil {
+		t.Fatalf("fmt failed: %v", err)
+	}
+	if stdout.String() != "arazzo = \"1.0.0\"\n" {
+		t.Errorf("fmt printed %q", stdout.String())
+	}
+}
+
+func TestRunPlan(t *testing.T) {
+	var stdout bytes.Buffer
+	err := run([]string{"plan", "-format", "curl", "-workflow", "place-order", "-server", "pet-coupons=https://pets.test",
+		"-input", "pet_id=5", "-input", "coupon_code=SAVE10", filepath.Join(examplesDir, "pet-coupons.arazzo.yaml")}, &stdout)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	for _, want := range []string{
+		"curl -g -X POST 'https://pets.test/store/order'",
+		`"couponCode":"SAVE10"`,
+		`"petId":5`,
+		`"quantity":"{$inputs.quantity}"`,
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("plan missing %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"unknown"},
+		{"gogen"},
+		{"gogen", "-source", "broken", filepath.Join(examplesDir, "pet-coupons.arazzo.yaml")},
+		{"gogen", filepath.Join(examplesDir, "missing.arazzo.yaml")},
+		{"init", "-format", "json", filepath.Join(examplesDir, "pet-coupons.openapi.yaml")},
+		{"fmt"},
+		{"fmt", filepath.Join(examplesDir, "missing.arazzo.yaml")},
+		{"plan", filepath.Join(examplesDir, "pet-coupons.arazzo.yaml")},
+		{"plan", "-format", "xml", "-server", "pet-coupons=https://pets.test", filepath.Join(examplesDir, "pet-coupons.arazzo.yaml")},
+	} {
+		if err := run(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestPrintErrorDiagnostics(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bad.arazzo.hcl")
+	src := "arazzo = \"1.0.0\"\nworkflow \"w\" {\n  step \"s\" {\n    operationID = \"op\"\n  }\n}\n"
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := run([]string{"k6", file}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	var stderr bytes.Buffer
+	printError(&stderr, err)
+	for _, want := range []string{"Error: Unsupported argument", "on " + file + " line 4", `operationID = "op"`} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, stderr.String())
+		}
+	}
+}
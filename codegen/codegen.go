@@ -0,0 +1,100 @@
+// Package codegen renders Arazzo workflows as source code for other tools,
+// resolving each step's operation against the OpenAPI source descriptions.
+package codegen
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/genelet/arazzo/arazzo1"
+)
+
+// camelIdent converts an Arazzo id such as "find-pet" or "place_order" to a
+// camelCase identifier ("findPet", "placeOrder"). When upper is true the first
+// letter is capitalised.
+func camelIdent(s string, upper bool) string {
+	var b strings.Builder
+	nextUpper := upper
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			nextUpper = b.Len() > 0 || upper
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			if upper {
+				b.WriteString("N")
+			} else {
+				b.WriteString("n")
+			}
+		}
+		if nextUpper {
+			b.WriteRune(unicode.ToUpper(r))
+			nextUpper = false
+		} else if b.Len() == 0 && !upper {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		if upper {
+			return "X"
+		}
+		return "x"
+	}
+	return b.String()
+}
+
+// sortedKeys returns the keys of a map in lexical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isJSONContentType reports whether a media type carries JSON.
+func isJSONContentType(ct string) bool {
+	ct = strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+	return ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
+
+// requestContentType returns the content type for a step's request body,
+// falling back to the first media type declared by the operation.
+func requestContentType(rb *arazzo1.RequestBody, declared []string) string {
+	if rb != nil && rb.ContentType != "" {
+		return rb.ContentType
+	}
+	sort.Strings(declared)
+	for _, ct := range declared {
+		if isJSONContentType(ct) {
+			return ct
+		}
+	}
+	if len(declared) > 0 {
+		return declared[0]
+	}
+	return "application/json"
+}
+
+// normalizeValue converts typed values to the generic JSON model
+// (map[string]any, []any, float64, string, bool, nil).
+func normalizeValue(v any) any {
+	switch v.(type) {
+	case nil, string, bool:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
@@ -0,0 +1,1781 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+// GoOptions configures Go client generation.
+type GoOptions struct {
+	// Package is the package name of the generated file (default "client").
+	Package string
+
+	// Workflows restricts generation to the listed workflowIds. Workflows they
+	// call through workflow steps or dependsOn are always included.
+	Workflows []string
+}
+
+// goReserved are exported names declared by the generated file itself.
+var goReserved = []string{"Client", "NewClient", "StepError"}
+
+// goReservedLocals are names the generated workflow functions use for their
+// own variables, parameters and imported packages.
+var goReservedLocals = []string{
+	"c", "ctx", "in", "err", "req", "res", "out", "sub", "payload", "attempts", "depIn",
+	"bytes", "context", "json", "fmt", "io", "http", "url", "reflect", "regexp", "strconv", "strings", "time",
+	"any", "bool", "int", "string", "nil", "true", "false", "len", "append", "make", "new",
+}
+
+var (
+	inputsRefPattern    = regexp.MustCompile(`\$inputs\.([A-Za-z0-9_\-]+)`)
+	workflowsRefPattern = regexp.MustCompile(`\$workflows\.([A-Za-z0-9_\-]+)\.outputs`)
+)
+
+// GenerateGo renders the workflows of an Arazzo document as a typed Go client.
+// sources maps source description names to their parsed OpenAPI documents; they
+// are used to resolve each step's HTTP method, path and server, and to infer
+// the Go types of step outputs from response schemas.
+//
+// Every workflow becomes a method on Client that takes an input struct derived
+// from Workflow.Inputs and returns an output struct derived from
+// Workflow.Outputs. Requests are sent with net/http. successCriteria are checked
+// after every request, retry failure actions become loops, and goto and end
+// actions within the workflow become jumps.
+//
+// The result is gofmt-formatted and can be written directly to a _gen.go file.
+func GenerateGo(doc *arazzo1.Arazzo, sources map[string]*openapi31.OpenAPI, opts *GoOptions) ([]byte, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("arazzo document is nil")
+	}
+	if opts == nil {
+		opts = &GoOptions{}
+	}
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "client"
+	}
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+	g := &goGenerator{
+		doc:       doc,
+		sources:   sources,
+		resolver:  oasutil.NewResolver(sources),
+		workflows: make(map[string]*goWorkflow),
+		used:      make(map[string]bool),
+	}
+	for _, name := range goReserved {
+		g.used[name] = true
+	}
+
+	for _, wf := range doc.Workflows {
+		if _, ok := g.workflows[wf.WorkflowId]; ok {
+			return nil, fmt.Errorf("duplicate workflowId %q", wf.WorkflowId)
+		}
+		base := camelIdent(wf.WorkflowId, true)
+		w := &goWorkflow{
+			wf:     wf,
+			method: g.unique(base),
+			input:  g.unique(base + "Input"),
+			output: g.unique(base + "Output"),
+		}
+		g.workflows[wf.WorkflowId] = w
+	}
+
+	selected, err := g.selectWorkflows(opts.Workflows)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range selected {
+		if err := g.planInputs(w); err != nil {
+			return nil, fmt.Errorf("workflow %q: %w", w.wf.WorkflowId, err)
+		}
+	}
+	for _, w := range selected {
+		if err := g.planWorkflow(w); err != nil {
+			return nil, err
+		}
+	}
+
+	g.writePrelude(pkg)
+	for _, w := range selected {
+		if err := g.writeWorkflow(w); err != nil {
+			return nil, fmt.Errorf("workflow %q: %w", w.wf.WorkflowId, err)
+		}
+	}
+	g.b.WriteString(goHelpers)
+
+	out, err := format.Source([]byte(g.b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+type goGenerator struct {
+	doc       *arazzo1.Arazzo
+	sources   map[string]*openapi31.OpenAPI
+	resolver  *oasutil.Resolver
+	workflows map[string]*goWorkflow
+	used      map[string]bool
+	b         strings.Builder
+}
+
+// goWorkflow holds the names and types planned for one workflow.
+type goWorkflow struct {
+	wf     *arazzo1.Workflow
+	method string
+	input  string
+	output string
+
+	inputs  []*goField
+	outputs []*goField
+
+	locals map[string]bool
+	steps  map[string]*goStep
+	deps   map[string]string // dependsOn workflowId -> local variable, when referenced
+	labels map[string]string // stepId -> label, for goto targets
+	done   bool              // whether an end action jumps to the return statement
+	state  int               // 0 unplanned, 1 planning, 2 planned
+}
+
+// goField is a struct field generated for an input, a step output or a workflow output.
+type goField struct {
+	key      string
+	name     string
+	typ      string
+	doc      string
+	required bool
+}
+
+// goStep holds the planned variable and output fields of a step.
+type goStep struct {
+	step    *arazzo1.Step
+	op      *oasutil.Operation
+	varName string
+	outputs []*goField
+	success []*arazzo1.SuccessAction
+	failure []*arazzo1.FailureAction
+}
+
+// goScope tracks what runtime expressions can refer to at a point in a workflow function.
+type goScope struct {
+	w       *goWorkflow
+	res     string
+	sub     *goWorkflow
+	request map[string]goExpr
+}
+
+// goExpr is a Go expression with its static type. lit marks untyped constants.
+type goExpr struct {
+	code string
+	typ  string
+	lit  bool
+}
+
+func (g *goGenerator) unique(name string) string {
+	candidate := name
+	for i := 2; g.used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	g.used[candidate] = true
+	return candidate
+}
+
+func (w *goWorkflow) local(name string) string {
+	candidate := name
+	for i := 2; w.locals[candidate] || token.IsKeyword(candidate); i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	w.locals[candidate] = true
+	return candidate
+}
+
+func findField(fields []*goField, key string) *goField {
+	for _, f := range fields {
+		if f.key == key {
+			return f
+		}
+	}
+	return nil
+}
+
+// addField appends a field named after key, keeping Go field names unique.
+func addField(fields []*goField, key, typ string) ([]*goField, *goField) {
+	name := camelIdent(key, true)
+	candidate := name
+	for i := 2; ; i++ {
+		clash := false
+		for _, f := range fields {
+			if f.name == candidate {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			break
+		}
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	f := &goField{key: key, name: candidate, typ: typ}
+	return append(fields, f), f
+}
+
+// selectWorkflows returns the requested workflows plus the workflows they
+// depend on, in document order.
+func (g *goGenerator) selectWorkflows(ids []string) ([]*goWorkflow, error) {
+	if len(ids) == 0 {
+		var all []*goWorkflow
+		for _, wf := range g.doc.Workflows {
+			all = append(all, g.workflows[wf.WorkflowId])
+		}
+		return all, nil
+	}
+	keep := make(map[string]bool)
+	var visit func(id string) error
+	visit = func(id string) error {
+		w, ok := g.workflows[id]
+		if !ok {
+			return fmt.Errorf("unknown workflow %q", id)
+		}
+		if keep[id] {
+			return nil
+		}
+		keep[id] = true
+		for _, dep := range w.wf.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		for _, step := range w.wf.Steps {
+			if step.WorkflowId != "" {
+				if err := visit(step.WorkflowId); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, id := range ids {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	var result []*goWorkflow
+	for _, wf := range g.doc.Workflows {
+		if keep[wf.WorkflowId] {
+			result = append(result, g.workflows[wf.WorkflowId])
+		}
+	}
+	return result, nil
+}
+
+// planInputs derives the input struct fields from the workflow's inputs schema,
+// adding an untyped field for every other $inputs name the workflow uses.
+func (g *goGenerator) planInputs(w *goWorkflow) error {
+	if w.wf.Inputs != nil {
+		schema, err := g.inputsSchema(w.wf.Inputs)
+		if err != nil {
+			return fmt.Errorf("inputs: %w", err)
+		}
+		required := make(map[string]bool)
+		properties := make(map[string]*openapi31.Schema)
+		for _, s := range append([]*openapi31.Schema{schema}, schema.AllOf...) {
+			if s == nil {
+				continue
+			}
+			for _, r := range s.Required {
+				required[r] = true
+			}
+			for k, v := range s.Properties {
+				properties[k] = v
+			}
+		}
+		for _, key := range sortedKeys(properties) {
+			var f *goField
+			w.inputs, f = addField(w.inputs, key, goType(nil, properties[key]))
+			f.required = required[key]
+			if properties[key] != nil {
+				f.doc = properties[key].Description
+			}
+		}
+	}
+
+	data, err := json.Marshal(w.wf)
+	if err != nil {
+		return err
+	}
+	for _, m := range inputsRefPattern.FindAllStringSubmatch(string(data), -1) {
+		if findField(w.inputs, m[1]) == nil {
+			w.inputs, _ = addField(w.inputs, m[1], "any")
+		}
+	}
+	return nil
+}
+
+// inputsSchema converts a workflow inputs JSON Schema to an OpenAPI schema,
+// inlining references to components.inputs.
+func (g *goGenerator) inputsSchema(v any) (*openapi31.Schema, error) {
+	resolved, err := g.resolveInputRefs(normalizeValue(v), 0)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(resolved)
+	if err != nil {
+		return nil, err
+	}
+	var schema openapi31.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (g *goGenerator) resolveInputRefs(v any, depth int) (any, error) {
+	if depth > 32 {
+		return nil, fmt.Errorf("too many nested $ref")
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		if ref, ok := val["$ref"].(string); ok {
+			name, found := strings.CutPrefix(ref, "#/components/inputs/")
+			if !found {
+				return nil, fmt.Errorf("unsupported $ref %q", ref)
+			}
+			if g.doc.Components == nil || g.doc.Components.Inputs[name] == nil {
+				return nil, fmt.Errorf("$ref %q not found in components.inputs", ref)
+			}
+			return g.resolveInputRefs(normalizeValue(g.doc.Components.Inputs[name]), depth+1)
+		}
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			r, err := g.resolveInputRefs(item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			r, err := g.resolveInputRefs(item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// planWorkflow assigns variables to steps and infers the types of step and
+// workflow outputs. Called workflows are planned first so that $outputs
+// references have known types.
+func (g *goGenerator) planWorkflow(w *goWorkflow) error {
+	switch w.state {
+	case 2:
+		return nil
+	case 1:
+		return fmt.Errorf("workflow %q calls itself through workflow steps or dependsOn", w.wf.WorkflowId)
+	}
+	w.state = 1
+	wf := w.wf
+	w.locals = make(map[string]bool)
+	for _, name := range goReservedLocals {
+		w.locals[name] = true
+	}
+	w.steps = make(map[string]*goStep)
+	w.deps = make(map[string]string)
+	w.labels = make(map[string]string)
+
+	data, err := json.Marshal(wf)
+	if err != nil {
+		return err
+	}
+	referenced := make(map[string]bool)
+	for _, m := range workflowsRefPattern.FindAllStringSubmatch(string(data), -1) {
+		referenced[m[1]] = true
+	}
+	for _, dep := range wf.DependsOn {
+		d, ok := g.workflows[dep]
+		if !ok {
+			return fmt.Errorf("workflow %q: dependsOn references unknown workflow %q", wf.WorkflowId, dep)
+		}
+		if err := g.planWorkflow(d); err != nil {
+			return err
+		}
+		if referenced[dep] {
+			w.deps[dep] = w.local(camelIdent(dep, false) + "Outputs")
+		}
+	}
+
+	for _, step := range wf.Steps {
+		if _, ok := w.steps[step.StepId]; ok {
+			return fmt.Errorf("workflow %q: duplicate stepId %q", wf.WorkflowId, step.StepId)
+		}
+		w.steps[step.StepId] = &goStep{step: step, varName: w.local(camelIdent(step.StepId, false))}
+	}
+
+	for _, step := range wf.Steps {
+		gs := w.steps[step.StepId]
+		if err := g.planStep(w, gs); err != nil {
+			return fmt.Errorf("workflow %q: step %q: %w", wf.WorkflowId, step.StepId, err)
+		}
+	}
+
+	scope := &goScope{w: w}
+	for _, key := range sortedKeys(wf.Outputs) {
+		expr, err := g.goValue(wf.Outputs[key], scope)
+		if err != nil {
+			return fmt.Errorf("workflow %q: outputs.%s: %w", wf.WorkflowId, key, err)
+		}
+		w.outputs, _ = addField(w.outputs, key, literalType(expr.typ))
+	}
+	w.state = 2
+	return nil
+}
+
+func (g *goGenerator) planStep(w *goWorkflow, gs *goStep) error {
+	step := gs.step
+	scope := &goScope{w: w}
+	if step.WorkflowId != "" {
+		sub, ok := g.workflows[step.WorkflowId]
+		if !ok {
+			return fmt.Errorf("unknown workflow %q", step.WorkflowId)
+		}
+		if err := g.planWorkflow(sub); err != nil {
+			return err
+		}
+		scope.sub = sub
+	} else {
+		op, err := g.resolver.ResolveStep(step)
+		if err != nil {
+			return err
+		}
+		gs.op = op
+		scope.res = "res"
+
+		success, failure, err := g.stepActions(w.wf, step)
+		if err != nil {
+			return err
+		}
+		gs.success, gs.failure = success, failure
+		for _, a := range success {
+			switch a.Type {
+			case arazzo1.SuccessActionTypeEnd:
+				w.done = true
+			case arazzo1.SuccessActionTypeGoto:
+				if a.StepId != "" {
+					if err := g.addLabel(w, a.StepId); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		for _, a := range failure {
+			if a.Type == arazzo1.FailureActionTypeGoto && a.StepId != "" {
+				if err := g.addLabel(w, a.StepId); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(step.Outputs) {
+		typ, err := g.outputType(step.Outputs[key], gs.op, scope)
+		if err != nil {
+			return fmt.Errorf("outputs.%s: %w", key, err)
+		}
+		gs.outputs, _ = addField(gs.outputs, key, typ)
+	}
+	return nil
+}
+
+func (g *goGenerator) addLabel(w *goWorkflow, stepId string) error {
+	if _, ok := w.steps[stepId]; !ok {
+		return fmt.Errorf("goto references unknown step %q", stepId)
+	}
+	if _, ok := w.labels[stepId]; !ok {
+		w.labels[stepId] = "step" + camelIdent(stepId, true)
+	}
+	return nil
+}
+
+// stepActions resolves the success and failure actions that apply to a step:
+// its own actions followed by the workflow-level ones. Each list stops at the
+// first action without criteria, since later actions can never run.
+func (g *goGenerator) stepActions(wf *arazzo1.Workflow, step *arazzo1.Step) ([]*arazzo1.SuccessAction, []*arazzo1.FailureAction, error) {
+	var success []*arazzo1.SuccessAction
+	for _, a := range append(append([]*arazzo1.SuccessActionOrReusable{}, step.OnSuccess...), wf.SuccessActions...) {
+		action, err := g.doc.ResolveSuccessAction(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		if action == nil {
+			continue
+		}
+		success = append(success, action)
+		if len(action.Criteria) == 0 {
+			break
+		}
+	}
+	var failure []*arazzo1.FailureAction
+	for _, a := range append(append([]*arazzo1.FailureActionOrReusable{}, step.OnFailure...), wf.FailureActions...) {
+		action, err := g.doc.ResolveFailureAction(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		if action == nil {
+			continue
+		}
+		failure = append(failure, action)
+		if len(action.Criteria) == 0 && action.Type != arazzo1.FailureActionTypeRetry {
+			break
+		}
+	}
+	return success, failure, nil
+}
+
+// outputType infers the Go type of a step output. Response body references are
+// typed from the operation's success response schema.
+func (g *goGenerator) outputType(v string, op *oasutil.Operation, scope *goScope) (string, error) {
+	if op != nil {
+		if expr, err := arazzo1.ParseExpression(v); err == nil && expr.Root == arazzo1.ExpressionResponse && expr.Segments[0] == "body" {
+			resp := op.SuccessResponse()
+			if resp == nil {
+				return "any", nil
+			}
+			tokens := append(append([]string{}, expr.Segments[1:]...), pointerTokens(expr.Pointer)...)
+			schema := oasutil.SchemaAt(op.Doc, oasutil.ContentSchema(resp.Content), tokens)
+			if schema == nil {
+				return "any", nil
+			}
+			return goType(op.Doc, schema), nil
+		}
+	}
+	expr, err := g.goValue(v, scope)
+	if err != nil {
+		return "", err
+	}
+	return literalType(expr.typ), nil
+}
+
+// goType maps a JSON Schema to a Go type. Objects become json.RawMessage so
+// callers can decode them into their own types.
+func goType(doc *openapi31.OpenAPI, s *openapi31.Schema) string {
+	s = oasutil.ResolveSchema(doc, s)
+	if s == nil {
+		return "any"
+	}
+	if s.Type == nil && len(s.AllOf) == 1 {
+		return goType(doc, s.AllOf[0])
+	}
+	switch oasutil.SchemaType(s) {
+	case "string":
+		return "string"
+	case "integer":
+		if s.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		if s.Items == nil {
+			return "[]any"
+		}
+		return "[]" + goType(doc, s.Items)
+	case "object":
+		return "json.RawMessage"
+	}
+	if len(s.Properties) > 0 || len(s.AllOf) > 0 || len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return "json.RawMessage"
+	}
+	return "any"
+}
+
+// literalType returns the default type of an untyped constant.
+func literalType(typ string) string {
+	if typ == "untyped int" {
+		return "int"
+	}
+	return typ
+}
+
+func (g *goGenerator) line(format string, args ...any) {
+	fmt.Fprintf(&g.b, format, args...)
+	g.b.WriteString("\n")
+}
+
+func (g *goGenerator) writePrelude(pkg string) {
+	title := ""
+	if g.doc.Info != nil {
+		title = fmt.Sprintf(" %q %s", g.doc.Info.Title, g.doc.Info.Version)
+	}
+	g.line("// Code generated from Arazzo%s. DO NOT EDIT.", title)
+	g.line("")
+	g.line("package %s", pkg)
+	g.line("")
+	g.line("import (")
+	for _, imp := range []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "reflect", "regexp", "strconv", "strings", "time"} {
+		g.line("%q", imp)
+	}
+	g.line(")")
+	g.line("")
+	g.line("// Client runs the Arazzo workflows.")
+	g.line("type Client struct {")
+	g.line("// HTTPClient sends the requests. http.DefaultClient is used when nil.")
+	g.line("HTTPClient *http.Client")
+	g.line("")
+	g.line("// BaseURLs maps source description names to base URLs.")
+	g.line("BaseURLs map[string]string")
+	g.line("}")
+	g.line("")
+	g.line("// NewClient returns a Client that uses the servers declared by the OpenAPI source descriptions.")
+	g.line("func NewClient() *Client {")
+	g.line("return &Client{BaseURLs: map[string]string{")
+	for _, sd := range g.doc.SourceDescriptions {
+		if sd.Type != "" && sd.Type != arazzo1.SourceDescriptionTypeOpenAPI {
+			continue
+		}
+		base := ""
+		if oa := g.sources[sd.Name]; oa != nil {
+			base = (&oasutil.Operation{Doc: oa}).ServerURL()
+		}
+		g.line("%q: %q,", sd.Name, base)
+	}
+	g.line("}}")
+	g.line("}")
+	g.line("")
+	g.line("// StepError reports a step whose success criteria were not met.")
+	g.line("type StepError struct {")
+	g.line("Workflow   string")
+	g.line("Step       string")
+	g.line("StatusCode int")
+	g.line("Body       []byte")
+	g.line("}")
+	g.line("")
+	g.line("func (e *StepError) Error() string {")
+	g.line(`return fmt.Sprintf("workflow %%s: step %%s failed with status %%d", e.Workflow, e.Step, e.StatusCode)`)
+	g.line("}")
+}
+
+func (g *goGenerator) writeStruct(name, doc string, fields []*goField, omitOptional bool) {
+	g.line("")
+	g.line("// %s %s", name, doc)
+	g.line("type %s struct {", name)
+	for _, f := range fields {
+		if f.doc != "" {
+			g.line("// %s", oneLine(f.doc))
+		}
+		tag := f.key
+		if omitOptional && !f.required {
+			tag += ",omitempty"
+		}
+		g.line("%s %s `json:%q`", f.name, f.typ, tag)
+	}
+	g.line("}")
+}
+
+func (g *goGenerator) writeWorkflow(w *goWorkflow) error {
+	wf := w.wf
+	g.writeStruct(w.input, fmt.Sprintf("is the input of workflow %s.", wf.WorkflowId), w.inputs, true)
+	g.writeStruct(w.output, fmt.Sprintf("is the output of workflow %s.", wf.WorkflowId), w.outputs, false)
+
+	g.line("")
+	g.line("// %s runs workflow %s.", w.method, wf.WorkflowId)
+	if wf.Summary != "" {
+		g.line("// %s", oneLine(wf.Summary))
+	}
+	g.line("func (c *Client) %s(ctx context.Context, in *%s) (*%s, error) {", w.method, w.input, w.output)
+	g.line("if in == nil {")
+	g.line("in = &%s{}", w.input)
+	g.line("}")
+
+	for _, dep := range wf.DependsOn {
+		d := g.workflows[dep]
+		prefix := errorPrefix(wf.WorkflowId, "dependsOn "+dep)
+		g.line("")
+		g.line("// dependsOn: %s", dep)
+		if v, ok := w.deps[dep]; ok {
+			g.line("var %s *%s", v, d.output)
+		}
+		g.line("{")
+		g.line("depIn := &%s{}", d.input)
+		g.line("if err := convertValue(in, depIn); err != nil {")
+		g.line("return nil, fmt.Errorf(%s, err)", prefix)
+		g.line("}")
+		if v, ok := w.deps[dep]; ok {
+			g.line("out, err := c.%s(ctx, depIn)", d.method)
+			g.line("if err != nil {")
+			g.line("return nil, fmt.Errorf(%s, err)", prefix)
+			g.line("}")
+			g.line("%s = out", v)
+		} else {
+			g.line("if _, err := c.%s(ctx, depIn); err != nil {", d.method)
+			g.line("return nil, fmt.Errorf(%s, err)", prefix)
+			g.line("}")
+		}
+		g.line("}")
+	}
+
+	declared := false
+	for _, step := range wf.Steps {
+		gs := w.steps[step.StepId]
+		if len(gs.outputs) == 0 {
+			continue
+		}
+		if !declared {
+			g.line("")
+			declared = true
+		}
+		var fields []string
+		for _, f := range gs.outputs {
+			fields = append(fields, f.name+" "+f.typ)
+		}
+		g.line("var %s struct{ %s }", gs.varName, strings.Join(fields, "; "))
+	}
+
+	for _, step := range wf.Steps {
+		gs := w.steps[step.StepId]
+		g.line("")
+		var err error
+		if step.WorkflowId != "" {
+			err = g.writeWorkflowStep(w, gs)
+		} else {
+			err = g.writeOperationStep(w, gs)
+		}
+		if err != nil {
+			return fmt.Errorf("step %q: %w", step.StepId, err)
+		}
+	}
+
+	g.line("")
+	if w.done {
+		g.line("done:")
+	}
+	scope := &goScope{w: w}
+	g.line("return &%s{", w.output)
+	for _, f := range w.outputs {
+		expr, err := g.goValue(wf.Outputs[f.key], scope)
+		if err != nil {
+			return fmt.Errorf("outputs.%s: %w", f.key, err)
+		}
+		g.line("%s: %s,", f.name, expr.code)
+	}
+	g.line("}, nil")
+	g.line("}")
+	return nil
+}
+
+// errorPrefix returns a quoted fmt.Errorf format wrapping an error with its location.
+func errorPrefix(workflowId, where string) string {
+	return strconv.Quote(strings.ReplaceAll("workflow "+workflowId+": "+where, "%", "%%") + ": %w")
+}
+
+func (g *goGenerator) stepHeader(w *goWorkflow, gs *goStep, summary string) {
+	step := gs.step
+	g.line("// %s: %s", step.StepId, summary)
+	if step.Description != "" {
+		g.line("// %s", oneLine(step.Description))
+	}
+	if label, ok := w.labels[step.StepId]; ok {
+		g.line("%s:", label)
+	}
+}
+
+func (g *goGenerator) writeWorkflowStep(w *goWorkflow, gs *goStep) error {
+	step := gs.step
+	sub := g.workflows[step.WorkflowId]
+	prefix := errorPrefix(w.wf.WorkflowId, "step "+step.StepId)
+	params, err := g.doc.StepParameters(w.wf, step)
+	if err != nil {
+		return err
+	}
+
+	g.stepHeader(w, gs, "workflow "+step.WorkflowId)
+	g.line("{")
+	scope := &goScope{w: w}
+	g.line("sub := &%s{}", sub.input)
+	if len(params) > 0 {
+		g.line("if err := convertValue(map[string]any{")
+		for _, p := range params {
+			expr, err := g.goValue(p.Value, scope)
+			if err != nil {
+				return fmt.Errorf("parameter %q: %w", p.Name, err)
+			}
+			g.line("%q: %s,", p.Name, expr.code)
+		}
+		g.line("}, sub); err != nil {")
+		g.line("return nil, fmt.Errorf(%s, err)", prefix)
+		g.line("}")
+	}
+	if len(gs.outputs) == 0 {
+		g.line("if _, err := c.%s(ctx, sub); err != nil {", sub.method)
+		g.line("return nil, fmt.Errorf(%s, err)", prefix)
+		g.line("}")
+	} else {
+		g.line("out, err := c.%s(ctx, sub)", sub.method)
+		g.line("if err != nil {")
+		g.line("return nil, fmt.Errorf(%s, err)", prefix)
+		g.line("}")
+		outScope := &goScope{w: w, sub: sub}
+		for _, f := range gs.outputs {
+			expr, err := g.goValue(step.Outputs[f.key], outScope)
+			if err != nil {
+				return fmt.Errorf("outputs.%s: %w", f.key, err)
+			}
+			g.line("%s.%s = %s", gs.varName, f.name, expr.code)
+		}
+	}
+	if len(step.SuccessCriteria) > 0 || len(step.OnSuccess) > 0 || len(step.OnFailure) > 0 {
+		g.line("// criteria and actions of workflow steps are not supported by the Go export")
+	}
+	g.line("}")
+	return nil
+}
+
+func (g *goGenerator) writeOperationStep(w *goWorkflow, gs *goStep) error {
+	step := gs.step
+	op := gs.op
+	wfId := w.wf.WorkflowId
+	prefix := errorPrefix(wfId, "step "+step.StepId)
+	params, err := g.doc.StepParameters(w.wf, step)
+	if err != nil {
+		return err
+	}
+
+	g.stepHeader(w, gs, strings.ToUpper(op.Method)+" "+op.Path)
+	g.line("{")
+
+	request := make(map[string]goExpr)
+	reqScope := &goScope{w: w, request: request}
+
+	// URL: server + path template with path parameters substituted.
+	base := fmt.Sprintf("c.baseURL(%q)", op.Source)
+	if server := op.ServerURL(); server != (&oasutil.Operation{Doc: op.Doc}).ServerURL() {
+		base = strconv.Quote(server)
+	}
+	pathValues := make(map[string]goExpr)
+	var stmts []string
+	for _, p := range params {
+		expr, err := g.goValue(p.Value, reqScope)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %w", p.Name, err)
+		}
+		request[string(p.In)+":"+p.Name] = expr
+		switch p.In {
+		case arazzo1.ParameterInPath:
+			pathValues[p.Name] = expr
+		case arazzo1.ParameterInQuery:
+			stmts = append(stmts, fmt.Sprintf("req.addQuery(%q, %s)", p.Name, expr.code))
+		case arazzo1.ParameterInHeader:
+			stmts = append(stmts, fmt.Sprintf("req.header.Set(%q, %s)", p.Name, stringExpr(expr)))
+		case arazzo1.ParameterInCookie:
+			stmts = append(stmts, fmt.Sprintf("req.cookies = append(req.cookies, &http.Cookie{Name: %q, Value: %s})", p.Name, stringExpr(expr)))
+		}
+	}
+	g.line("req := newRequest(%q, %s)", strings.ToUpper(op.Method), joinConcat(base, pathExpr(op.Path, pathValues)))
+	for _, s := range stmts {
+		g.line("%s", s)
+	}
+
+	// Request body.
+	if step.RequestBody != nil && step.RequestBody.Payload != nil {
+		var declared []string
+		if rb := op.RequestBody(); rb != nil {
+			declared = sortedKeys(rb.Content)
+		}
+		contentType := requestContentType(step.RequestBody, declared)
+		payload, err := g.goValue(step.RequestBody.Payload, reqScope)
+		if err != nil {
+			return fmt.Errorf("requestBody: %w", err)
+		}
+		g.line("var payload any = %s", payload.code)
+		for _, r := range step.RequestBody.Replacements {
+			val, err := g.goValue(r.Value, reqScope)
+			if err != nil {
+				return fmt.Errorf("replacement %q: %w", r.Target, err)
+			}
+			g.line("setPointer(payload, %q, %s)", r.Target, val.code)
+		}
+		g.line("if err := req.setBody(%q, payload); err != nil {", contentType)
+		g.line("return nil, fmt.Errorf(%s, err)", prefix)
+		g.line("}")
+		request["body"] = goExpr{code: "payload", typ: "any"}
+	}
+
+	resScope := &goScope{w: w, res: "res", request: request}
+	success, err := g.successCondition(step, resScope)
+	if err != nil {
+		return err
+	}
+
+	var retries, jumps []string
+	for _, action := range gs.failure {
+		cond, err := g.actionCondition(action.Criteria, resScope)
+		if err != nil {
+			return fmt.Errorf("failure action %q: %w", action.Name, err)
+		}
+		switch action.Type {
+		case arazzo1.FailureActionTypeRetry:
+			limit := 1
+			if action.RetryLimit != nil {
+				limit = *action.RetryLimit
+			}
+			// Each retry action counts its own attempts, as the runner does.
+			n := len(retries)
+			guard := fmt.Sprintf("attempts[%d] < %d", n, limit)
+			if cond != "" {
+				guard += " && (" + cond + ")"
+			}
+			body := fmt.Sprintf("attempts[%d]++\ncontinue", n)
+			if action.RetryAfter != nil && *action.RetryAfter > 0 {
+				body = fmt.Sprintf("attempts[%d]++\nif err := sleep(ctx, %s); err != nil {\nreturn nil, fmt.Errorf(%s, err)\n}\ncontinue",
+					n, strconv.FormatFloat(*action.RetryAfter, 'f', -1, 64), prefix)
+			}
+			retries = append(retries, fmt.Sprintf("if %s {\n%s\n}", guard, body))
+		case arazzo1.FailureActionTypeGoto:
+			if action.StepId == "" {
+				jumps = append(jumps, fmt.Sprintf("// failure action %q (goto workflow %s) is not supported by the Go export", action.Name, action.WorkflowId))
+				continue
+			}
+			jumps = append(jumps, conditional(cond, "goto "+w.labels[action.StepId]))
+		case arazzo1.FailureActionTypeEnd:
+			if cond != "" {
+				jumps = append(jumps, conditional(cond, fmt.Sprintf("return nil, res.stepError(%q, %q)", wfId, step.StepId)))
+			}
+		}
+	}
+
+	if len(retries) > 0 {
+		g.line("var res *response")
+		g.line("var attempts [%d]int", len(retries))
+		g.line("for {")
+		g.line("var err error")
+		g.line("if res, err = c.do(ctx, req); err != nil {")
+		g.line("return nil, fmt.Errorf(%s, err)", prefix)
+		g.line("}")
+		g.line("if %s {", success)
+		g.line("break")
+		g.line("}")
+		for _, r := range retries {
+			g.line("%s", r)
+		}
+		for _, j := range jumps {
+			g.line("%s", j)
+		}
+		g.line("return nil, res.stepError(%q, %q)", wfId, step.StepId)
+		g.line("}")
+	} else {
+		g.line("res, err := c.do(ctx, req)")
+		g.line("if err != nil {")
+		g.line("return nil, fmt.Errorf(%s, err)", prefix)
+		g.line("}")
+		g.line("if !(%s) {", success)
+		for _, j := range jumps {
+			g.line("%s", j)
+		}
+		g.line("return nil, res.stepError(%q, %q)", wfId, step.StepId)
+		g.line("}")
+	}
+
+	for _, f := range gs.outputs {
+		value := step.Outputs[f.key]
+		if expr, err := arazzo1.ParseExpression(value); err == nil && expr.Root == arazzo1.ExpressionResponse && expr.Segments[0] == "body" {
+			g.line("if err := res.extract(%q, &%s.%s); err != nil {", expressionPointer(expr), gs.varName, f.name)
+			g.line("return nil, fmt.Errorf(%s, err)", errorPrefix(wfId, "step "+step.StepId+": output "+f.key))
+			g.line("}")
+			continue
+		}
+		expr, err := g.goValue(value, resScope)
+		if err != nil {
+			return fmt.Errorf("outputs.%s: %w", f.key, err)
+		}
+		g.line("%s.%s = %s", gs.varName, f.name, expr.code)
+	}
+
+	for _, action := range gs.success {
+		cond, err := g.actionCondition(action.Criteria, resScope)
+		if err != nil {
+			return fmt.Errorf("success action %q: %w", action.Name, err)
+		}
+		switch action.Type {
+		case arazzo1.SuccessActionTypeEnd:
+			g.line("%s", conditional(cond, "goto done"))
+		case arazzo1.SuccessActionTypeGoto:
+			if action.StepId == "" {
+				g.line("// success action %q (goto workflow %s) is not supported by the Go export", action.Name, action.WorkflowId)
+				continue
+			}
+			g.line("%s", conditional(cond, "goto "+w.labels[action.StepId]))
+		}
+	}
+	g.line("}")
+	return nil
+}
+
+// conditional guards stmt with cond, or returns stmt alone when cond is empty.
+func conditional(cond, stmt string) string {
+	if cond == "" {
+		return stmt
+	}
+	return fmt.Sprintf("if %s {\n%s\n}", cond, stmt)
+}
+
+// pathExpr renders a path template as a string concatenation, escaping the
+// values of path parameters.
+func pathExpr(path string, values map[string]goExpr) string {
+	var parts []string
+	rest := path
+	for {
+		start := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if start == -1 || end < start {
+			break
+		}
+		val, ok := values[rest[start+1:end]]
+		if !ok {
+			parts = append(parts, strconv.Quote(rest[:end+1]))
+			rest = rest[end+1:]
+			continue
+		}
+		if start > 0 {
+			parts = append(parts, strconv.Quote(rest[:start]))
+		}
+		parts = append(parts, "url.PathEscape("+stringExpr(val)+")")
+		rest = rest[end+1:]
+	}
+	if rest != "" {
+		parts = append(parts, strconv.Quote(rest))
+	}
+	return joinConcat(parts...)
+}
+
+// joinConcat joins string expressions with +, merging adjacent string literals.
+func joinConcat(parts ...string) string {
+	var merged []string
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if n := len(merged); n > 0 && strings.HasPrefix(p, `"`) && strings.HasPrefix(merged[n-1], `"`) {
+			a, errA := strconv.Unquote(merged[n-1])
+			b, errB := strconv.Unquote(p)
+			if errA == nil && errB == nil {
+				merged[n-1] = strconv.Quote(a + b)
+				continue
+			}
+		}
+		merged = append(merged, p)
+	}
+	if len(merged) == 0 {
+		return `""`
+	}
+	return strings.Join(merged, " + ")
+}
+
+// stringExpr converts an expression to a Go string expression.
+func stringExpr(e goExpr) string {
+	if e.typ == "string" {
+		return e.code
+	}
+	return "fmt.Sprint(" + e.code + ")"
+}
+
+// expressionPointer returns the JSON Pointer addressed by a body expression,
+// treating dot-separated segments after "body" as pointer tokens.
+func expressionPointer(e *arazzo1.Expression) string {
+	var b strings.Builder
+	for _, seg := range e.Segments[1:] {
+		b.WriteString("/" + strings.ReplaceAll(strings.ReplaceAll(seg, "~", "~0"), "/", "~1"))
+	}
+	b.WriteString(e.Pointer)
+	return b.String()
+}
+
+func (g *goGenerator) successCondition(step *arazzo1.Step, scope *goScope) (string, error) {
+	if len(step.SuccessCriteria) == 0 {
+		return fmt.Sprintf("%s.StatusCode >= 200 && %s.StatusCode < 300", scope.res, scope.res), nil
+	}
+	var parts []string
+	for i, c := range step.SuccessCriteria {
+		cond, ok, err := g.criterion(c, scope)
+		if err != nil {
+			return "", fmt.Errorf("successCriteria[%d]: %w", i, err)
+		}
+		if ok {
+			parts = append(parts, cond)
+		}
+	}
+	if len(parts) == 0 {
+		return "true", nil
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	for i, p := range parts {
+		parts[i] = "(" + p + ")"
+	}
+	return strings.Join(parts, " && "), nil
+}
+
+// actionCondition renders the criteria of an action. Criteria that cannot be
+// evaluated by the generated code are never met, so the action is skipped.
+func (g *goGenerator) actionCondition(criteria []*arazzo1.Criterion, scope *goScope) (string, error) {
+	var parts []string
+	for _, c := range criteria {
+		cond, ok, err := g.criterion(c, scope)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fmt.Sprintf("false /* %s criterion is not supported */", criterionType(c)), nil
+		}
+		parts = append(parts, cond)
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	for i, p := range parts {
+		parts[i] = "(" + p + ")"
+	}
+	return strings.Join(parts, " && "), nil
+}
+
+// criterion translates a Criterion to a Go boolean expression. It reports
+// false for criterion types the generated code cannot evaluate.
+func (g *goGenerator) criterion(c *arazzo1.Criterion, scope *goScope) (string, bool, error) {
+	switch criterionType(c) {
+	case arazzo1.CriterionTypeSimple:
+		node, err := arazzo1.ParseCondition(c.Condition)
+		if err != nil {
+			return "", false, err
+		}
+		cond, err := g.goCondition(node, scope)
+		return cond, err == nil, err
+	case arazzo1.CriterionTypeRegex:
+		ctx, err := g.goValue(c.Context, scope)
+		if err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("matches(%q, %s)", c.Condition, ctx.code), true, nil
+	}
+	return "", false, nil
+}
+
+func (g *goGenerator) goCondition(node arazzo1.ConditionNode, scope *goScope) (string, error) {
+	switch n := node.(type) {
+	case *arazzo1.ConditionNot:
+		operand, err := g.goCondition(n.Operand, scope)
+		if err != nil {
+			return "", err
+		}
+		return "!(" + operand + ")", nil
+	case *arazzo1.ConditionBinary:
+		if n.Op == "&&" || n.Op == "||" {
+			left, err := g.goCondition(n.Left, scope)
+			if err != nil {
+				return "", err
+			}
+			right, err := g.goCondition(n.Right, scope)
+			if err != nil {
+				return "", err
+			}
+			if b, ok := n.Left.(*arazzo1.ConditionBinary); ok && b.Op == "||" && n.Op == "&&" {
+				left = "(" + left + ")"
+			}
+			if b, ok := n.Right.(*arazzo1.ConditionBinary); ok && (b.Op == "||" || b.Op == "&&") {
+				right = "(" + right + ")"
+			}
+			return left + " " + n.Op + " " + right, nil
+		}
+		left, err := g.goOperand(n.Left, scope)
+		if err != nil {
+			return "", err
+		}
+		right, err := g.goOperand(n.Right, scope)
+		if err != nil {
+			return "", err
+		}
+		if nativeComparable(left, right, n.Op) {
+			return left.code + " " + n.Op + " " + right.code, nil
+		}
+		return fmt.Sprintf("compare(%s, %q, %s)", left.code, n.Op, right.code), nil
+	}
+	operand, err := g.goOperand(node, scope)
+	if err != nil {
+		return "", err
+	}
+	if operand.typ == "bool" {
+		return operand.code, nil
+	}
+	return "truthy(" + operand.code + ")", nil
+}
+
+func (g *goGenerator) goOperand(node arazzo1.ConditionNode, scope *goScope) (goExpr, error) {
+	switch n := node.(type) {
+	case *arazzo1.ConditionLiteral:
+		return goLiteral(n.Value), nil
+	case *arazzo1.ConditionExpression:
+		return g.goExpression(n.Expression, scope)
+	}
+	cond, err := g.goCondition(node, scope)
+	if err != nil {
+		return goExpr{}, err
+	}
+	return goExpr{code: "(" + cond + ")", typ: "bool"}, nil
+}
+
+var goNumericTypes = map[string]bool{
+	"int": true, "int32": true, "int64": true, "float32": true, "float64": true,
+}
+
+// nativeComparable reports whether two operands can be compared with Go operators.
+func nativeComparable(a, b goExpr, op string) bool {
+	if a.lit && b.lit {
+		return false
+	}
+	if a.lit {
+		a, b = b, a
+	}
+	switch {
+	case goNumericTypes[a.typ]:
+		if b.lit {
+			return b.typ == "untyped int" || (b.typ == "float64" && strings.HasPrefix(a.typ, "float"))
+		}
+		return a.typ == b.typ
+	case a.typ == "string":
+		return b.typ == "string"
+	case a.typ == "bool":
+		return b.typ == "bool" && (op == "==" || op == "!=")
+	}
+	return false
+}
+
+// goLiteral renders a JSON scalar as a Go constant.
+func goLiteral(v any) goExpr {
+	switch val := v.(type) {
+	case nil:
+		return goExpr{code: "nil", typ: "any", lit: true}
+	case bool:
+		return goExpr{code: strconv.FormatBool(val), typ: "bool", lit: true}
+	case float64:
+		if val == float64(int64(val)) {
+			return goExpr{code: strconv.FormatInt(int64(val), 10), typ: "untyped int", lit: true}
+		}
+		return goExpr{code: strconv.FormatFloat(val, 'g', -1, 64), typ: "float64", lit: true}
+	case string:
+		return goExpr{code: strconv.Quote(val), typ: "string", lit: true}
+	}
+	return goExpr{code: "nil", typ: "any", lit: true}
+}
+
+// goValue renders a literal value, translating any runtime expressions it contains.
+func (g *goGenerator) goValue(v any, scope *goScope) (goExpr, error) {
+	switch val := normalizeValue(v).(type) {
+	case string:
+		parts, err := arazzo1.ParseTemplate(val)
+		if err != nil {
+			return goLiteral(val), nil
+		}
+		if len(parts) == 1 {
+			if parts[0].Expression != nil {
+				return g.goExpression(parts[0].Expression, scope)
+			}
+			return goLiteral(val), nil
+		}
+		var format strings.Builder
+		var args []string
+		for _, p := range parts {
+			if p.Expression == nil {
+				format.WriteString(strings.ReplaceAll(p.Literal, "%", "%%"))
+				continue
+			}
+			expr, err := g.goExpression(p.Expression, scope)
+			if err != nil {
+				return goExpr{}, err
+			}
+			format.WriteString("%v")
+			args = append(args, expr.code)
+		}
+		return goExpr{code: fmt.Sprintf("fmt.Sprintf(%q, %s)", format.String(), strings.Join(args, ", ")), typ: "string"}, nil
+	case []any:
+		var items []string
+		for _, item := range val {
+			expr, err := g.goValue(item, scope)
+			if err != nil {
+				return goExpr{}, err
+			}
+			items = append(items, expr.code)
+		}
+		return goExpr{code: "[]any{" + strings.Join(items, ", ") + "}", typ: "[]any"}, nil
+	case map[string]any:
+		if len(val) == 0 {
+			return goExpr{code: "map[string]any{}", typ: "map[string]any"}, nil
+		}
+		var b strings.Builder
+		b.WriteString("map[string]any{\n")
+		for _, k := range sortedKeys(val) {
+			expr, err := g.goValue(val[k], scope)
+			if err != nil {
+				return goExpr{}, err
+			}
+			fmt.Fprintf(&b, "%q: %s,\n", k, expr.code)
+		}
+		b.WriteString("}")
+		return goExpr{code: b.String(), typ: "map[string]any"}, nil
+	default:
+		return goLiteral(val), nil
+	}
+}
+
+// goExpression translates a runtime expression to Go in the given scope.
+func (g *goGenerator) goExpression(e *arazzo1.Expression, scope *goScope) (goExpr, error) {
+	needResponse := func() error {
+		if scope.res == "" {
+			return fmt.Errorf("%s is only available after a request", e.Raw)
+		}
+		return nil
+	}
+	// deref applies dot segments and the JSON Pointer that follow a value.
+	deref := func(base goExpr, segments []string) goExpr {
+		tokens := append(append([]string{}, segments...), pointerTokens(e.Pointer)...)
+		if len(tokens) == 0 {
+			return base
+		}
+		var b strings.Builder
+		for _, t := range tokens {
+			b.WriteString("/" + strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+		}
+		return goExpr{code: fmt.Sprintf("lookup(%s, %q)", base.code, b.String()), typ: "any"}
+	}
+
+	switch e.Root {
+	case arazzo1.ExpressionStatusCode:
+		if err := needResponse(); err != nil {
+			return goExpr{}, err
+		}
+		return goExpr{code: scope.res + ".StatusCode", typ: "int"}, nil
+	case arazzo1.ExpressionURL:
+		if err := needResponse(); err != nil {
+			return goExpr{}, err
+		}
+		return goExpr{code: scope.res + ".URL", typ: "string"}, nil
+	case arazzo1.ExpressionMethod:
+		if err := needResponse(); err != nil {
+			return goExpr{}, err
+		}
+		return goExpr{code: scope.res + ".Method", typ: "string"}, nil
+	case arazzo1.ExpressionResponse:
+		if err := needResponse(); err != nil {
+			return goExpr{}, err
+		}
+		switch e.Segments[0] {
+		case "header":
+			return goExpr{code: fmt.Sprintf("%s.Header.Get(%q)", scope.res, textproto.CanonicalMIMEHeaderKey(e.Segments[1])), typ: "string"}, nil
+		case "body":
+			return goExpr{code: fmt.Sprintf("%s.body(%q)", scope.res, expressionPointer(e)), typ: "any"}, nil
+		}
+		return goExpr{}, fmt.Errorf("%s is not supported by the Go export", e.Raw)
+	case arazzo1.ExpressionRequest:
+		key := "body"
+		if e.Segments[0] != "body" {
+			key = e.Segments[0] + ":" + e.Segments[1]
+		}
+		val, ok := scope.request[key]
+		if !ok {
+			return goExpr{}, fmt.Errorf("%s does not refer to a value set by this step", e.Raw)
+		}
+		return deref(val, nil), nil
+	case arazzo1.ExpressionInputs:
+		f := findField(scope.w.inputs, e.Segments[0])
+		if f == nil {
+			return goExpr{}, fmt.Errorf("%s refers to an unknown input", e.Raw)
+		}
+		return deref(goExpr{code: "in." + f.name, typ: f.typ}, e.Segments[1:]), nil
+	case arazzo1.ExpressionOutputs:
+		if scope.sub == nil {
+			return goExpr{}, fmt.Errorf("%s is only available in workflow step outputs", e.Raw)
+		}
+		f := findField(scope.sub.outputs, e.Segments[0])
+		if f == nil {
+			return goExpr{}, fmt.Errorf("%s refers to an unknown output of workflow %q", e.Raw, scope.sub.wf.WorkflowId)
+		}
+		return deref(goExpr{code: "out." + f.name, typ: f.typ}, e.Segments[1:]), nil
+	case arazzo1.ExpressionSteps:
+		gs, ok := scope.w.steps[e.Segments[0]]
+		if !ok {
+			return goExpr{}, fmt.Errorf("%s refers to an unknown step", e.Raw)
+		}
+		rest := e.Segments[1:]
+		if len(rest) < 2 || rest[0] != "outputs" {
+			return goExpr{}, fmt.Errorf("%s must reference step outputs", e.Raw)
+		}
+		f := findField(gs.outputs, rest[1])
+		if f == nil {
+			return goExpr{}, fmt.Errorf("%s refers to an unknown or later output of step %q", e.Raw, gs.step.StepId)
+		}
+		return deref(goExpr{code: gs.varName + "." + f.name, typ: f.typ}, rest[2:]), nil
+	case arazzo1.ExpressionWorkflows:
+		rest := e.Segments[1:]
+		if len(rest) < 2 || rest[0] != "outputs" {
+			return goExpr{}, fmt.Errorf("%s must reference workflow outputs", e.Raw)
+		}
+		v, ok := scope.w.deps[e.Segments[0]]
+		if !ok {
+			return goExpr{}, fmt.Errorf("%s refers to a workflow not listed in dependsOn", e.Raw)
+		}
+		f := findField(g.workflows[e.Segments[0]].outputs, rest[1])
+		if f == nil {
+			return goExpr{}, fmt.Errorf("%s refers to an unknown workflow output", e.Raw)
+		}
+		return deref(goExpr{code: v + "." + f.name, typ: f.typ}, rest[2:]), nil
+	case arazzo1.ExpressionSourceDescriptions:
+		for _, sd := range g.doc.SourceDescriptions {
+			if sd.Name == e.Segments[0] && len(e.Segments) == 2 && e.Segments[1] == "url" {
+				return goExpr{code: strconv.Quote(sd.URL), typ: "string"}, nil
+			}
+		}
+		return goExpr{}, fmt.Errorf("%s does not resolve to a source description url", e.Raw)
+	case arazzo1.ExpressionComponents:
+		if len(e.Segments) == 2 && e.Segments[0] == "parameters" {
+			param, err := g.doc.ResolveParameter(&arazzo1.ParameterOrReusable{Reusable: &arazzo1.ReusableObject{Reference: e.Raw}})
+			if err != nil {
+				return goExpr{}, err
+			}
+			return g.goValue(param.Value, scope)
+		}
+		if len(e.Segments) == 2 && e.Segments[0] == "inputs" && g.doc.Components != nil {
+			if v, ok := g.doc.Components.Inputs[e.Segments[1]]; ok {
+				return g.goValue(v, scope)
+			}
+		}
+		return goExpr{}, fmt.Errorf("%s is not supported by the Go export", e.Raw)
+	}
+	return goExpr{}, fmt.Errorf("%s is not supported by the Go export", e.Raw)
+}
+
+// goHelpers is the runtime support emitted at the end of every generated file.
+const goHelpers = `
+func (c *Client) baseURL(source string) string {
+	return strings.TrimSuffix(c.BaseURLs[source], "/")
+}
+
+// request is an HTTP request assembled by a workflow step.
+type request struct {
+	method  string
+	url     string
+	query   url.Values
+	header  http.Header
+	cookies []*http.Cookie
+	body    []byte
+}
+
+func newRequest(method, rawURL string) *request {
+	return &request{method: method, url: rawURL, query: url.Values{}, header: http.Header{}}
+}
+
+// addQuery adds a query parameter. Slices add one value per element.
+func (r *request) addQuery(name string, v any) {
+	if v == nil {
+		return
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < rv.Len(); i++ {
+			r.query.Add(name, fmt.Sprint(rv.Index(i).Interface()))
+		}
+		return
+	}
+	r.query.Add(name, fmt.Sprint(v))
+}
+
+// setBody encodes the payload according to the content type.
+func (r *request) setBody(contentType string, payload any) error {
+	r.header.Set("Content-Type", contentType)
+	if s, ok := payload.(string); ok {
+		r.body = []byte(s)
+		return nil
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form := url.Values{}
+		if m, ok := payload.(map[string]any); ok {
+			for k, v := range m {
+				form.Set(k, fmt.Sprint(v))
+			}
+		}
+		r.body = []byte(form.Encode())
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	r.body = data
+	return nil
+}
+
+// response is a completed HTTP exchange.
+type response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	URL        string
+	Method     string
+
+	decoded bool
+	data    any
+}
+
+func (c *Client) do(ctx context.Context, r *request) (*response, error) {
+	u := r.url
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(u, "?") {
+			sep = "&"
+		}
+		u += sep + r.query.Encode()
+	}
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.header.Clone()
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data, URL: u, Method: r.method}, nil
+}
+
+// body returns the value at a JSON Pointer in the decoded response body.
+// Bodies that are not JSON are returned as a string.
+func (r *response) body(pointer string) any {
+	if !r.decoded {
+		r.decoded = true
+		if err := json.Unmarshal(r.Body, &r.data); err != nil {
+			r.data = string(r.Body)
+		}
+	}
+	return lookup(r.data, pointer)
+}
+
+// extract decodes the value at a JSON Pointer in the response body into dst.
+func (r *response) extract(pointer string, dst any) error {
+	v := r.body(pointer)
+	if v == nil {
+		return nil
+	}
+	return convertValue(v, dst)
+}
+
+func (r *response) stepError(workflow, step string) error {
+	return &StepError{Workflow: workflow, Step: step, StatusCode: r.StatusCode, Body: r.Body}
+}
+
+// convertValue converts between Go values through their JSON encoding.
+func convertValue(src, dst any) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// normalize converts a typed value to the generic JSON model.
+func normalize(v any) any {
+	switch v.(type) {
+	case nil, string, bool, float64, map[string]any, []any:
+		return v
+	}
+	var out any
+	if err := convertValue(v, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// lookup returns the value at a JSON Pointer, or nil when it does not exist.
+func lookup(v any, pointer string) any {
+	node := normalize(v)
+	if pointer == "" {
+		return node
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch n := node.(type) {
+		case map[string]any:
+			node = n[token]
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil
+			}
+			node = n[i]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// setPointer sets the value at a JSON Pointer inside maps and slices.
+func setPointer(target any, pointer string, value any) {
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	node := target
+	for i, token := range tokens {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		last := i == len(tokens)-1
+		switch n := node.(type) {
+		case map[string]any:
+			if last {
+				n[token] = value
+				return
+			}
+			if n[token] == nil {
+				n[token] = map[string]any{}
+			}
+			node = n[token]
+		case []any:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(n) {
+				return
+			}
+			if last {
+				n[idx] = value
+				return
+			}
+			node = n[idx]
+		default:
+			return
+		}
+	}
+}
+
+// compare evaluates a comparison of a simple criterion condition.
+func compare(a any, op string, b any) bool {
+	a, b = normalize(a), normalize(b)
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		x, xok := toNumber(a)
+		y, yok := toNumber(b)
+		if xok && yok {
+			switch op {
+			case "==":
+				return x == y
+			case "!=":
+				return x != y
+			case "<":
+				return x < y
+			case "<=":
+				return x <= y
+			case ">":
+				return x > y
+			case ">=":
+				return x >= y
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return reflect.DeepEqual(a, b)
+	case "!=":
+		return !reflect.DeepEqual(a, b)
+	}
+	x, xok := a.(string)
+	y, yok := b.(string)
+	if !xok || !yok {
+		return false
+	}
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return false
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// truthy reports whether a value counts as true in a condition.
+func truthy(v any) bool {
+	switch n := normalize(v).(type) {
+	case nil:
+		return false
+	case bool:
+		return n
+	case float64:
+		return n != 0
+	case string:
+		return n != ""
+	}
+	return true
+}
+
+// matches evaluates a regex criterion.
+func matches(pattern string, v any) bool {
+	ok, err := regexp.MatchString(pattern, fmt.Sprint(normalize(v)))
+	return err == nil && ok
+}
+
+// sleep waits for the given number of seconds or until ctx is done.
+func sleep(ctx context.Context, seconds float64) error {
+	timer := time.NewTimer(time.Duration(seconds * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+`
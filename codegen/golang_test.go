@@ -0,0 +1,227 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/oas/openapi31"
+)
+
+// typeCheck parses and type-checks generated Go source.
+func typeCheck(t *testing.T, src []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("parsing generated code: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("client", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("type-checking generated code: %v\n%s", err, src)
+	}
+}
+
+func TestGenerateGoGolden(t *testing.T) {
+	doc, sources := loadExample(t, "pet-coupons.arazzo.yaml", map[string]string{"pet-coupons": "pet-coupons.openapi.yaml"})
+	got, err := GenerateGo(doc, sources, &GoOptions{Package: "petcoupons"})
+	if err != nil {
+		t.Fatalf("GenerateGo failed: %v", err)
+	}
+	checkGolden(t, "pet-coupons.go.golden", got)
+	typeCheck(t, got)
+
+	src := string(got)
+	for _, want := range []string{
+		"MyPetTags []string `json:\"my_pet_tags,omitempty\"`",
+		"PetId int64 `json:\"pet_id,omitempty\"`",
+		"Quantity int32 `json:\"quantity,omitempty\"`",
+		"ApplyCouponPetOrderId int64 `json:\"apply_coupon_pet_order_id\"`",
+		"func (c *Client) ApplyCoupon(ctx context.Context, in *ApplyCouponInput) (*ApplyCouponOutput, error) {",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+}
+
+func TestGenerateGoActions(t *testing.T) {
+	limit := 3
+	after := 0.5
+	doc := &arazzo1.Arazzo{
+		Arazzo: "1.0.0",
+		Info:   &arazzo1.Info{Title: "Loans", Version: "1.0.0"},
+		SourceDescriptions: []*arazzo1.SourceDescription{
+			{Name: "bnpl", URL: "openapi.yaml", Type: arazzo1.SourceDescriptionTypeOpenAPI},
+		},
+		Workflows: []*arazzo1.Workflow{
+			{
+				WorkflowId: "apply-for-loan",
+				Inputs: map[string]any{
+					"type":     "object",
+					"required": []any{"customer"},
+					"properties": map[string]any{
+						"customer": map[string]any{"type": "string"},
+					},
+				},
+				Steps: []*arazzo1.Step{
+					{
+						StepId:      "check",
+						OperationId: "checkEligibility",
+						RequestBody: &arazzo1.RequestBody{
+							ContentType: "application/json",
+							Payload:     map[string]any{"customer": "$inputs.customer", "amount": "$inputs.amount"},
+						},
+						SuccessCriteria: []*arazzo1.Criterion{{Condition: "$statusCode == 200"}},
+						OnSuccess: []*arazzo1.SuccessActionOrReusable{
+							{SuccessAction: &arazzo1.SuccessAction{
+								Name: "not-eligible", Type: arazzo1.SuccessActionTypeEnd,
+								Criteria: []*arazzo1.Criterion{{Condition: "$response.body#/eligible == false"}},
+							}},
+						},
+						Outputs: map[string]string{"eligible": "$response.body#/eligible"},
+					},
+					{
+						StepId:      "get-loan",
+						OperationId: "getLoan",
+						Parameters: []any{
+							&arazzo1.Parameter{Name: "id", In: arazzo1.ParameterInPath, Value: "$inputs.customer"},
+							&arazzo1.Parameter{Name: "Authorization", In: arazzo1.ParameterInHeader, Value: "Bearer {$inputs.token}"},
+						},
+						SuccessCriteria: []*arazzo1.Criterion{
+							{Condition: "$statusCode == 200 && $response.body#/state == 'done'"},
+						},
+						OnFailure: []*arazzo1.FailureActionOrReusable{
+							{FailureAction: &arazzo1.FailureAction{
+								Name: "wait", Type: arazzo1.FailureActionTypeRetry,
+								RetryLimit: &limit, RetryAfter: &after,
+								Criteria: []*arazzo1.Criterion{{Condition: "$statusCode == 200"}},
+							}},
+							{FailureAction: &arazzo1.FailureAction{
+								Name: "unavailable", Type: arazzo1.FailureActionTypeRetry,
+								Criteria: []*arazzo1.Criterion{{Condition: "$statusCode == 503"}},
+							}},
+							{FailureAction: &arazzo1.FailureAction{
+								Name: "recheck", Type: arazzo1.FailureActionTypeGoto, StepId: "check",
+								Criteria: []*arazzo1.Criterion{{Condition: "$statusCode == 409"}},
+							}},
+						},
+						Outputs: map[string]string{
+							"amount":   "$response.body#/amount",
+							"location": "$response.header.location",
+						},
+					},
+				},
+				Outputs: map[string]string{
+					"amount":   "$steps.get-loan.outputs.amount",
+					"eligible": "$steps.check.outputs.eligible",
+				},
+			},
+		},
+	}
+	schemaType := func(typ string) *openapi31.StringOrStringArray {
+		return &openapi31.StringOrStringArray{String: typ}
+	}
+	jsonResponse := func(schema *openapi31.Schema) *openapi31.Responses {
+		return &openapi31.Responses{StatusCode: map[string]*openapi31.Response{
+			"200": {Content: map[string]*openapi31.MediaType{"application/json": {Schema: schema}}},
+		}}
+	}
+	sources := map[string]*openapi31.OpenAPI{
+		"bnpl": {
+			Servers: []*openapi31.Server{{URL: "https://bnpl.example.com/v1/"}},
+			Paths: &openapi31.Paths{Paths: map[string]*openapi31.PathItem{
+				"/eligibility": {Post: &openapi31.Operation{
+					OperationID: "checkEligibility",
+					Responses: jsonResponse(&openapi31.Schema{Type: schemaType("object"), Properties: map[string]*openapi31.Schema{
+						"eligible": {Type: schemaType("boolean")},
+					}}),
+				}},
+				"/loans/{id}": {Get: &openapi31.Operation{
+					OperationID: "getLoan",
+					Responses:   jsonResponse(&openapi31.Schema{Ref: "#/components/schemas/Loan"}),
+				}},
+			}},
+			Components: &openapi31.Components{Schemas: map[string]*openapi31.Schema{
+				"Loan": {Type: schemaType("object"), Properties: map[string]*openapi31.Schema{
+					"amount": {Type: schemaType("number")},
+				}},
+			}},
+		},
+	}
+
+	got, err := GenerateGo(doc, sources, nil)
+	if err != nil {
+		t.Fatalf("GenerateGo failed: %v", err)
+	}
+	typeCheck(t, got)
+
+	src := string(got)
+	for _, want := range []string{
+		"Customer string `json:\"customer\"`",
+		"Amount   any    `json:\"amount,omitempty\"`",
+		"Amount   float64 `json:\"amount\"`",
+		"Eligible bool    `json:\"eligible\"`",
+		`"bnpl": "https://bnpl.example.com/v1",`,
+		`req := newRequest("GET", c.baseURL("bnpl")+"/loans/"+url.PathEscape(in.Customer))`,
+		`req.header.Set("Authorization", fmt.Sprintf("Bearer %v", in.Token))`,
+		"stepCheck:",
+		`if compare(res.body("/eligible"), "==", false) {`,
+		"goto done",
+		"var attempts [2]int",
+		"for {",
+		"if res.StatusCode == 200 && compare(res.body(\"/state\"), \"==\", \"done\") {",
+		"if attempts[0] < 3 && (res.StatusCode == 200) {",
+		"attempts[0]++",
+		"if attempts[1] < 1 && (res.StatusCode == 503) {",
+		"attempts[1]++",
+		"if err := sleep(ctx, 0.5); err != nil {",
+		"goto stepCheck",
+		`getLoan.Location = res.Header.Get("Location")`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code missing %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateGoErrors(t *testing.T) {
+	sources := map[string]*openapi31.OpenAPI{"api": {
+		Paths: &openapi31.Paths{Paths: map[string]*openapi31.PathItem{
+			"/a": {Get: &openapi31.Operation{OperationID: "getA"}},
+		}},
+	}}
+	tests := []struct {
+		name string
+		wf   *arazzo1.Workflow
+	}{
+		{"unknown operation", &arazzo1.Workflow{WorkflowId: "wf", Steps: []*arazzo1.Step{{StepId: "s", OperationId: "missing"}}}},
+		{"unknown step output", &arazzo1.Workflow{
+			WorkflowId: "wf",
+			Steps:      []*arazzo1.Step{{StepId: "s", OperationId: "getA"}},
+			Outputs:    map[string]string{"x": "$steps.s.outputs.missing"},
+		}},
+		{"unknown goto target", &arazzo1.Workflow{
+			WorkflowId: "wf",
+			Steps: []*arazzo1.Step{{StepId: "s", OperationId: "getA", OnSuccess: []*arazzo1.SuccessActionOrReusable{
+				{SuccessAction: &arazzo1.SuccessAction{Name: "jump", Type: arazzo1.SuccessActionTypeGoto, StepId: "nowhere"}},
+			}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &arazzo1.Arazzo{Workflows: []*arazzo1.Workflow{tt.wf}}
+			if _, err := GenerateGo(doc, sources, nil); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+	if _, err := GenerateGo(&arazzo1.Arazzo{}, nil, &GoOptions{Package: "not-valid"}); err == nil {
+		t.Error("expected error for invalid package name")
+	}
+}
@@ -0,0 +1,839 @@
+package codegen
+
+import (
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+// K6Options configures k6 script generation.
+type K6Options struct {
+	// Workflows restricts the scenarios to the listed workflowIds.
+	// All workflows are still emitted as functions so they can be called as sub-workflows.
+	Workflows []string
+
+	// VUs is the number of virtual users per scenario (default 1).
+	VUs int
+
+	// Iterations is the number of iterations per virtual user (default 1).
+	Iterations int
+}
+
+// k6Reserved are identifiers used by the generated prelude; step variables
+// and workflow functions must not shadow them.
+var k6Reserved = []string{
+	"http", "check", "fail", "sleep", "options", "qs", "setPointer",
+	"INPUTS", "BASE_URLS", "WORKFLOW_OUTPUTS",
+	"inputs", "overrides", "outputs", "url", "params", "body", "res", "r", "attempts", "passed",
+}
+
+var jsIdentPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// GenerateK6 renders the workflows of an Arazzo document as a k6 load-test script.
+// sources maps source description names to their parsed OpenAPI documents; they are
+// used to resolve each step's HTTP method, path and server.
+//
+// Every workflow becomes an exported function and a k6 scenario. Step outputs become
+// JavaScript variables, successCriteria become check() calls, and retry failure
+// actions become loops around the request.
+func GenerateK6(doc *arazzo1.Arazzo, sources map[string]*openapi31.OpenAPI, opts *K6Options) ([]byte, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("arazzo document is nil")
+	}
+	if opts == nil {
+		opts = &K6Options{}
+	}
+	g := &k6Generator{
+		doc:      doc,
+		sources:  sources,
+		resolver: oasutil.NewResolver(sources),
+		opts:     opts,
+		funcs:    make(map[string]string),
+		used:     make(map[string]bool),
+	}
+	for _, name := range k6Reserved {
+		g.used[name] = true
+	}
+	for _, wf := range doc.Workflows {
+		name := g.unique(camelIdent(wf.WorkflowId, false))
+		g.funcs[wf.WorkflowId] = name
+	}
+
+	g.writePrelude()
+	for _, wf := range doc.Workflows {
+		if err := g.writeWorkflow(wf); err != nil {
+			return nil, fmt.Errorf("workflow %q: %w", wf.WorkflowId, err)
+		}
+	}
+	return []byte(g.b.String()), nil
+}
+
+type k6Generator struct {
+	doc      *arazzo1.Arazzo
+	sources  map[string]*openapi31.OpenAPI
+	resolver *oasutil.Resolver
+	opts     *K6Options
+	funcs    map[string]string
+	used     map[string]bool
+	b        strings.Builder
+}
+
+// k6Scope tracks what runtime expressions can refer to at a point in the script.
+type k6Scope struct {
+	steps   map[string]string
+	resp    string
+	outputs string
+	request map[string]string
+}
+
+func (g *k6Generator) unique(name string) string {
+	candidate := name
+	for i := 2; g.used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	g.used[candidate] = true
+	return candidate
+}
+
+func (g *k6Generator) line(indent int, format string, args ...any) {
+	g.b.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(&g.b, format, args...)
+	g.b.WriteString("\n")
+}
+
+func (g *k6Generator) writePrelude() {
+	title := ""
+	if g.doc.Info != nil {
+		title = fmt.Sprintf(" %q %s", g.doc.Info.Title, g.doc.Info.Version)
+	}
+	g.line(0, "// Code generated from Arazzo%s. DO NOT EDIT.", title)
+	g.line(0, "//")
+	g.line(0, "// Workflow inputs are read from the ARAZZO_INPUTS environment variable as a JSON")
+	g.line(0, "// object keyed by workflowId. Base URLs can be overridden per source description")
+	g.line(0, "// with <SOURCE>_BASE_URL.")
+	g.line(0, "import http from 'k6/http';")
+	g.line(0, "import { check, fail, sleep } from 'k6';")
+	g.line(0, "")
+
+	vus, iterations := g.opts.VUs, g.opts.Iterations
+	if vus <= 0 {
+		vus = 1
+	}
+	if iterations <= 0 {
+		iterations = 1
+	}
+	selected := make(map[string]bool)
+	for _, id := range g.opts.Workflows {
+		selected[id] = true
+	}
+	g.line(0, "export const options = {")
+	g.line(1, "scenarios: {")
+	for _, wf := range g.doc.Workflows {
+		if len(selected) > 0 && !selected[wf.WorkflowId] {
+			continue
+		}
+		g.line(2, "%s: { executor: 'per-vu-iterations', vus: %d, iterations: %d, exec: %s },",
+			jsKey(wf.WorkflowId), vus, iterations, jsString(g.funcs[wf.WorkflowId]))
+	}
+	g.line(1, "},")
+	g.line(0, "};")
+	g.line(0, "")
+
+	g.line(0, "const INPUTS = JSON.parse(__ENV.ARAZZO_INPUTS || '{}');")
+	g.line(0, "")
+	g.line(0, "const BASE_URLS = {")
+	for _, sd := range g.doc.SourceDescriptions {
+		if sd.Type != "" && sd.Type != arazzo1.SourceDescriptionTypeOpenAPI {
+			continue
+		}
+		base := ""
+		if oa := g.sources[sd.Name]; oa != nil {
+			base = (&oasutil.Operation{Doc: oa}).ServerURL()
+		}
+		g.line(1, "%s: __ENV.%s || %s,", jsKey(sd.Name), envName(sd.Name)+"_BASE_URL", jsString(base))
+	}
+	g.line(0, "};")
+	g.line(0, "")
+	g.line(0, "const WORKFLOW_OUTPUTS = {};")
+	g.line(0, "")
+	g.b.WriteString(k6Helpers)
+}
+
+const k6Helpers = `function qs(params) {
+  const parts = [];
+  for (const [k, v] of Object.entries(params)) {
+    if (v === undefined || v === null) continue;
+    for (const item of Array.isArray(v) ? v : [v]) {
+      parts.push(encodeURIComponent(k) + '=' + encodeURIComponent(item));
+    }
+  }
+  return parts.length ? '?' + parts.join('&') : '';
+}
+
+function setPointer(target, pointer, value) {
+  const tokens = pointer.split('/').slice(1).map((t) => t.replace(/~1/g, '/').replace(/~0/g, '~'));
+  let node = target;
+  for (let i = 0; i < tokens.length - 1; i++) {
+    if (node[tokens[i]] === undefined) node[tokens[i]] = {};
+    node = node[tokens[i]];
+  }
+  node[tokens[tokens.length - 1]] = value;
+}
+`
+
+func (g *k6Generator) writeWorkflow(wf *arazzo1.Workflow) error {
+	g.line(0, "")
+	if wf.Summary != "" {
+		g.line(0, "// %s: %s", wf.WorkflowId, oneLine(wf.Summary))
+	} else {
+		g.line(0, "// %s", wf.WorkflowId)
+	}
+	g.line(0, "export function %s(overrides) {", g.funcs[wf.WorkflowId])
+	g.line(1, "const inputs = Object.assign({}, INPUTS[%s], overrides);", jsString(wf.WorkflowId))
+	for _, dep := range wf.DependsOn {
+		fn, ok := g.funcs[dep]
+		if !ok {
+			return fmt.Errorf("dependsOn references unknown workflow %q", dep)
+		}
+		g.line(1, "if (!WORKFLOW_OUTPUTS[%s]) %s();", jsString(dep), fn)
+	}
+
+	scope := &k6Scope{steps: make(map[string]string)}
+	local := make(map[string]bool)
+	for k := range g.used {
+		local[k] = true
+	}
+	for _, step := range wf.Steps {
+		name := camelIdent(step.StepId, false)
+		candidate := name
+		for i := 2; local[candidate]; i++ {
+			candidate = fmt.Sprintf("%s%d", name, i)
+		}
+		local[candidate] = true
+		scope.steps[step.StepId] = candidate
+	}
+
+	for _, step := range wf.Steps {
+		g.line(0, "")
+		var err error
+		if step.WorkflowId != "" {
+			err = g.writeWorkflowStep(wf, step, scope)
+		} else {
+			err = g.writeOperationStep(wf, step, scope)
+		}
+		if err != nil {
+			return fmt.Errorf("step %q: %w", step.StepId, err)
+		}
+	}
+
+	g.line(0, "")
+	g.line(1, "const outputs = {")
+	for _, key := range sortedKeys(wf.Outputs) {
+		val, err := g.jsValue(wf.Outputs[key], scope, 2)
+		if err != nil {
+			return fmt.Errorf("outputs.%s: %w", key, err)
+		}
+		g.line(2, "%s: %s,", jsKey(key), val)
+	}
+	g.line(1, "};")
+	g.line(1, "WORKFLOW_OUTPUTS[%s] = outputs;", jsString(wf.WorkflowId))
+	g.line(1, "return outputs;")
+	g.line(0, "}")
+	return nil
+}
+
+func (g *k6Generator) writeWorkflowStep(wf *arazzo1.Workflow, step *arazzo1.Step, scope *k6Scope) error {
+	fn, ok := g.funcs[step.WorkflowId]
+	if !ok {
+		return fmt.Errorf("unknown workflow %q", step.WorkflowId)
+	}
+	g.line(1, "// %s: workflow %s", step.StepId, step.WorkflowId)
+	if step.Description != "" {
+		g.line(1, "// %s", oneLine(step.Description))
+	}
+	params, err := g.doc.StepParameters(wf, step)
+	if err != nil {
+		return err
+	}
+	varName := scope.steps[step.StepId]
+	g.line(1, "let %s;", varName)
+	g.line(1, "{")
+	g.line(2, "const outputs = %s({", fn)
+	for _, p := range params {
+		val, err := g.jsValue(p.Value, scope, 3)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %w", p.Name, err)
+		}
+		g.line(3, "%s: %s,", jsKey(p.Name), val)
+	}
+	g.line(2, "});")
+	inner := &k6Scope{steps: scope.steps, outputs: "outputs"}
+	if err := g.writeStepOutputs(step, varName, inner, 2); err != nil {
+		return err
+	}
+	g.line(1, "}")
+	return nil
+}
+
+func (g *k6Generator) writeOperationStep(wf *arazzo1.Workflow, step *arazzo1.Step, scope *k6Scope) error {
+	op, err := g.resolver.ResolveStep(step)
+	if err != nil {
+		return err
+	}
+	params, err := g.doc.StepParameters(wf, step)
+	if err != nil {
+		return err
+	}
+
+	g.line(1, "// %s: %s %s", step.StepId, strings.ToUpper(op.Method), op.Path)
+	if step.Description != "" {
+		g.line(1, "// %s", oneLine(step.Description))
+	}
+	varName := scope.steps[step.StepId]
+	g.line(1, "let %s;", varName)
+	g.line(1, "{")
+
+	request := make(map[string]string)
+	reqScope := &k6Scope{steps: scope.steps, request: request}
+
+	// URL: server + path template with path parameters substituted.
+	path := escapeTemplate(op.Path)
+	var query, headers, cookies []string
+	for _, p := range params {
+		val, err := g.jsValue(p.Value, reqScope, 3)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %w", p.Name, err)
+		}
+		request[string(p.In)+":"+p.Name] = val
+		switch p.In {
+		case arazzo1.ParameterInPath:
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", "${encodeURIComponent("+val+")}")
+		case arazzo1.ParameterInQuery:
+			query = append(query, fmt.Sprintf("%s: %s", jsKey(p.Name), val))
+		case arazzo1.ParameterInHeader:
+			headers = append(headers, fmt.Sprintf("%s: %s", jsKey(p.Name), val))
+		case arazzo1.ParameterInCookie:
+			cookies = append(cookies, fmt.Sprintf("%s: %s", jsKey(p.Name), val))
+		}
+	}
+
+	base := fmt.Sprintf("${BASE_URLS[%s]}", jsString(op.Source))
+	if server := op.ServerURL(); server != (&oasutil.Operation{Doc: op.Doc}).ServerURL() {
+		base = escapeTemplate(server)
+	}
+	url := "`" + base + path + "`"
+	if len(query) > 0 {
+		url += " + qs({ " + strings.Join(query, ", ") + " })"
+	}
+	g.line(2, "const url = %s;", url)
+
+	// Request body.
+	hasBody := step.RequestBody != nil && step.RequestBody.Payload != nil
+	contentType := ""
+	if hasBody {
+		var declared []string
+		if rb := op.RequestBody(); rb != nil {
+			declared = sortedKeys(rb.Content)
+		}
+		contentType = requestContentType(step.RequestBody, declared)
+		payload, err := g.jsValue(step.RequestBody.Payload, reqScope, 2)
+		if err != nil {
+			return fmt.Errorf("requestBody: %w", err)
+		}
+		g.line(2, "const payload = %s;", payload)
+		for _, r := range step.RequestBody.Replacements {
+			val, err := g.jsValue(r.Value, reqScope, 2)
+			if err != nil {
+				return fmt.Errorf("replacement %q: %w", r.Target, err)
+			}
+			g.line(2, "setPointer(payload, %s, %s);", jsString(r.Target), val)
+		}
+		switch {
+		case isJSONContentType(contentType):
+			g.line(2, "const body = JSON.stringify(payload);")
+		case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"), strings.HasPrefix(contentType, "multipart/form-data"):
+			g.line(2, "const body = payload;")
+		default:
+			g.line(2, "const body = typeof payload === 'string' ? payload : JSON.stringify(payload);")
+		}
+		headers = append([]string{fmt.Sprintf("'Content-Type': %s", jsString(contentType))}, headers...)
+		request["body"] = "payload"
+	} else {
+		g.line(2, "const body = null;")
+	}
+
+	g.line(2, "const params = {")
+	if len(headers) > 0 {
+		g.line(3, "headers: { %s },", strings.Join(headers, ", "))
+	}
+	if len(cookies) > 0 {
+		g.line(3, "cookies: { %s },", strings.Join(cookies, ", "))
+	}
+	g.line(3, "tags: { name: %s },", jsString(step.StepId))
+	g.line(2, "};")
+
+	// Success criteria become checks.
+	checks, err := g.checks(step, &k6Scope{steps: scope.steps, resp: "r", request: request})
+	if err != nil {
+		return err
+	}
+
+	// Failure actions: retry becomes a loop, the default is to fail the iteration.
+	var retries []string
+	var notes []string
+	resScope := &k6Scope{steps: scope.steps, resp: "res", request: request}
+	actions := append(append([]*arazzo1.FailureActionOrReusable{}, step.OnFailure...), wf.FailureActions...)
+	for _, a := range actions {
+		action, err := g.doc.ResolveFailureAction(a)
+		if err != nil {
+			return err
+		}
+		if action == nil {
+			continue
+		}
+		cond, err := g.criteriaCondition(action.Criteria, resScope)
+		if err != nil {
+			return fmt.Errorf("failure action %q: %w", action.Name, err)
+		}
+		switch action.Type {
+		case arazzo1.FailureActionTypeRetry:
+			limit := 1
+			if action.RetryLimit != nil {
+				limit = *action.RetryLimit
+			}
+			after := 0.0
+			if action.RetryAfter != nil {
+				after = *action.RetryAfter
+			}
+			// Each retry action counts its own attempts, as the runner does.
+			n := len(retries)
+			guard := fmt.Sprintf("attempts[%d] < %d", n, limit)
+			if cond != "" {
+				guard += " && " + cond
+			}
+			stmt := fmt.Sprintf("attempts[%d]++; continue;", n)
+			if after > 0 {
+				stmt = fmt.Sprintf("attempts[%d]++; sleep(%s); continue;", n, strconv.FormatFloat(after, 'f', -1, 64))
+			}
+			retries = append(retries, fmt.Sprintf("if (%s) { %s }", guard, stmt))
+		case arazzo1.FailureActionTypeGoto:
+			notes = append(notes, fmt.Sprintf("// failure action %q (goto) is not supported by the k6 export", action.Name))
+		}
+	}
+	for _, a := range step.OnSuccess {
+		if action, err := g.doc.ResolveSuccessAction(a); err == nil && action != nil {
+			notes = append(notes, fmt.Sprintf("// success action %q (%s) is not supported by the k6 export", action.Name, action.Type))
+		}
+	}
+
+	method := jsString(strings.ToUpper(op.Method))
+	if len(retries) > 0 {
+		g.line(2, "let res;")
+		g.line(2, "const attempts = [%s];", strings.TrimSuffix(strings.Repeat("0, ", len(retries)), ", "))
+		g.line(2, "for (;;) {")
+		g.line(3, "res = http.request(%s, url, body, params);", method)
+		g.writeChecks(checks, 3)
+		g.line(3, "if (passed) break;")
+		for _, r := range retries {
+			g.line(3, "%s", r)
+		}
+		g.line(3, "fail(%s);", jsString("step "+step.StepId+" failed"))
+		g.line(2, "}")
+	} else {
+		g.line(2, "const res = http.request(%s, url, body, params);", method)
+		g.writeChecks(checks, 2)
+		g.line(2, "if (!passed) fail(%s);", jsString("step "+step.StepId+" failed"))
+	}
+	for _, n := range notes {
+		g.line(2, "%s", n)
+	}
+	if err := g.writeStepOutputs(step, varName, resScope, 2); err != nil {
+		return err
+	}
+	g.line(1, "}")
+	return nil
+}
+
+func (g *k6Generator) writeChecks(checks []string, indent int) {
+	g.line(indent, "const passed = check(res, {")
+	for _, c := range checks {
+		g.line(indent+1, "%s", c)
+	}
+	g.line(indent, "});")
+}
+
+func (g *k6Generator) checks(step *arazzo1.Step, scope *k6Scope) ([]string, error) {
+	if len(step.SuccessCriteria) == 0 {
+		return []string{fmt.Sprintf("%s: (r) => r.status >= 200 && r.status < 300,", jsString(step.StepId+": status is 2xx"))}, nil
+	}
+	var checks []string
+	for i, c := range step.SuccessCriteria {
+		expr, err := g.criterion(c, scope)
+		if err != nil {
+			return nil, fmt.Errorf("successCriteria[%d]: %w", i, err)
+		}
+		if expr == "" {
+			checks = append(checks, fmt.Sprintf("// %s criterion %q is not supported by the k6 export", criterionType(c), c.Condition))
+			continue
+		}
+		checks = append(checks, fmt.Sprintf("%s: (r) => %s,", jsString(step.StepId+": "+c.Condition), expr))
+	}
+	return checks, nil
+}
+
+// criterion translates a Criterion to a JavaScript boolean expression.
+// It returns "" for criterion types that cannot be evaluated in k6.
+func (g *k6Generator) criterion(c *arazzo1.Criterion, scope *k6Scope) (string, error) {
+	switch criterionType(c) {
+	case arazzo1.CriterionTypeSimple:
+		return g.jsCondition(c.Condition, scope)
+	case arazzo1.CriterionTypeRegex:
+		ctx, err := g.jsValue(c.Context, scope, 0)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("new RegExp(%s).test(String(%s))", jsString(c.Condition), ctx), nil
+	}
+	return "", nil
+}
+
+func (g *k6Generator) criteriaCondition(criteria []*arazzo1.Criterion, scope *k6Scope) (string, error) {
+	var parts []string
+	for _, c := range criteria {
+		expr, err := g.criterion(c, scope)
+		if err != nil {
+			return "", err
+		}
+		if expr != "" {
+			parts = append(parts, "("+expr+")")
+		}
+	}
+	return strings.Join(parts, " && "), nil
+}
+
+func criterionType(c *arazzo1.Criterion) arazzo1.CriterionType {
+	if c.ExpressionType != nil {
+		return c.ExpressionType.Type
+	}
+	if c.Type == "" {
+		return arazzo1.CriterionTypeSimple
+	}
+	return c.Type
+}
+
+func (g *k6Generator) writeStepOutputs(step *arazzo1.Step, varName string, scope *k6Scope, indent int) error {
+	if len(step.Outputs) == 0 {
+		g.line(indent, "%s = {};", varName)
+		return nil
+	}
+	g.line(indent, "%s = {", varName)
+	for _, key := range sortedKeys(step.Outputs) {
+		val, err := g.jsValue(step.Outputs[key], scope, indent+1)
+		if err != nil {
+			return fmt.Errorf("outputs.%s: %w", key, err)
+		}
+		g.line(indent+1, "%s: %s,", jsKey(key), val)
+	}
+	g.line(indent, "};")
+	return nil
+}
+
+// jsCondition rewrites the runtime expressions inside a simple condition
+// into JavaScript, leaving operators and literals untouched.
+func (g *k6Generator) jsCondition(cond string, scope *k6Scope) (string, error) {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(cond); {
+		c := cond[i]
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			i++
+			continue
+		}
+		if c == '\'' || c == '"' {
+			quote = c
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if c == '$' {
+			n := arazzo1.ScanExpression(cond[i:])
+			expr, err := arazzo1.ParseExpression(cond[i : i+n])
+			if err != nil {
+				return "", err
+			}
+			js, err := g.jsExpression(expr, scope)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(js)
+			i += n
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String(), nil
+}
+
+// jsExpression translates a runtime expression to JavaScript in the given scope.
+func (g *k6Generator) jsExpression(e *arazzo1.Expression, scope *k6Scope) (string, error) {
+	needResponse := func() error {
+		if scope.resp == "" {
+			return fmt.Errorf("%s is only available after a request", e.Raw)
+		}
+		return nil
+	}
+	switch e.Root {
+	case arazzo1.ExpressionStatusCode:
+		if err := needResponse(); err != nil {
+			return "", err
+		}
+		return scope.resp + ".status", nil
+	case arazzo1.ExpressionURL:
+		if err := needResponse(); err != nil {
+			return "", err
+		}
+		return scope.resp + ".url", nil
+	case arazzo1.ExpressionMethod:
+		if err := needResponse(); err != nil {
+			return "", err
+		}
+		return scope.resp + ".request.method", nil
+	case arazzo1.ExpressionResponse:
+		if err := needResponse(); err != nil {
+			return "", err
+		}
+		switch e.Segments[0] {
+		case "header":
+			return fmt.Sprintf("%s.headers[%s]", scope.resp, jsString(textproto.CanonicalMIMEHeaderKey(e.Segments[1]))), nil
+		case "body":
+			tokens := append([]string{}, e.Segments[1:]...)
+			tokens = append(tokens, pointerTokens(e.Pointer)...)
+			if len(tokens) == 0 {
+				return scope.resp + ".json()", nil
+			}
+			for i, t := range tokens {
+				tokens[i] = strings.ReplaceAll(t, ".", `\.`)
+			}
+			return fmt.Sprintf("%s.json(%s)", scope.resp, jsString(strings.Join(tokens, "."))), nil
+		}
+		return "", fmt.Errorf("%s is not supported by the k6 export", e.Raw)
+	case arazzo1.ExpressionRequest:
+		key := "body"
+		if e.Segments[0] != "body" {
+			key = e.Segments[0] + ":" + e.Segments[1]
+		}
+		val, ok := scope.request[key]
+		if !ok {
+			return "", fmt.Errorf("%s does not refer to a value set by this step", e.Raw)
+		}
+		return val + jsAccess(pointerTokens(e.Pointer)), nil
+	case arazzo1.ExpressionInputs:
+		return "inputs" + jsAccess(e.Segments) + jsAccess(pointerTokens(e.Pointer)), nil
+	case arazzo1.ExpressionOutputs:
+		if scope.outputs == "" {
+			return "", fmt.Errorf("%s is only available in workflow step outputs", e.Raw)
+		}
+		return scope.outputs + jsAccess(e.Segments) + jsAccess(pointerTokens(e.Pointer)), nil
+	case arazzo1.ExpressionSteps:
+		varName, ok := scope.steps[e.Segments[0]]
+		if !ok {
+			return "", fmt.Errorf("%s refers to an unknown step", e.Raw)
+		}
+		rest := e.Segments[1:]
+		if len(rest) == 0 || rest[0] != "outputs" {
+			return "", fmt.Errorf("%s must reference step outputs", e.Raw)
+		}
+		return varName + jsAccess(rest[1:]) + jsAccess(pointerTokens(e.Pointer)), nil
+	case arazzo1.ExpressionWorkflows:
+		rest := e.Segments[1:]
+		if len(rest) == 0 || rest[0] != "outputs" {
+			return "", fmt.Errorf("%s must reference workflow outputs", e.Raw)
+		}
+		return fmt.Sprintf("WORKFLOW_OUTPUTS[%s]", jsString(e.Segments[0])) + jsAccess(rest[1:]) + jsAccess(pointerTokens(e.Pointer)), nil
+	case arazzo1.ExpressionSourceDescriptions:
+		for _, sd := range g.doc.SourceDescriptions {
+			if sd.Name == e.Segments[0] && len(e.Segments) == 2 && e.Segments[1] == "url" {
+				return jsString(sd.URL), nil
+			}
+		}
+		return "", fmt.Errorf("%s does not resolve to a source description url", e.Raw)
+	case arazzo1.ExpressionComponents:
+		if len(e.Segments) == 2 && e.Segments[0] == "parameters" {
+			param, err := g.doc.ResolveParameter(&arazzo1.ParameterOrReusable{Reusable: &arazzo1.ReusableObject{Reference: e.Raw}})
+			if err != nil {
+				return "", err
+			}
+			return g.jsValue(param.Value, scope, 0)
+		}
+		if len(e.Segments) == 2 && e.Segments[0] == "inputs" && g.doc.Components != nil {
+			if v, ok := g.doc.Components.Inputs[e.Segments[1]]; ok {
+				return g.jsValue(v, scope, 0)
+			}
+		}
+		return "", fmt.Errorf("%s is not supported by the k6 export", e.Raw)
+	}
+	return "", fmt.Errorf("%s is not supported by the k6 export", e.Raw)
+}
+
+// jsValue renders a literal value, translating any runtime expressions it contains.
+func (g *k6Generator) jsValue(v any, scope *k6Scope, indent int) (string, error) {
+	switch val := normalizeValue(v).(type) {
+	case nil:
+		return "null", nil
+	case string:
+		parts, err := arazzo1.ParseTemplate(val)
+		if err != nil {
+			return jsString(val), nil
+		}
+		if len(parts) == 1 {
+			if parts[0].Expression != nil {
+				return g.jsExpression(parts[0].Expression, scope)
+			}
+			return jsString(val), nil
+		}
+		var b strings.Builder
+		b.WriteString("`")
+		for _, p := range parts {
+			if p.Expression == nil {
+				b.WriteString(escapeTemplate(p.Literal))
+				continue
+			}
+			js, err := g.jsExpression(p.Expression, scope)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("${" + js + "}")
+		}
+		b.WriteString("`")
+		return b.String(), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case []any:
+		if len(val) == 0 {
+			return "[]", nil
+		}
+		var items []string
+		for _, item := range val {
+			s, err := g.jsValue(item, scope, indent+1)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]any:
+		if len(val) == 0 {
+			return "{}", nil
+		}
+		pad := strings.Repeat("  ", indent+1)
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, k := range sortedKeys(val) {
+			s, err := g.jsValue(val[k], scope, indent+1)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(pad + jsKey(k) + ": " + s + ",\n")
+		}
+		b.WriteString(strings.Repeat("  ", indent) + "}")
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", v)
+}
+
+// jsString renders s as a single-quoted JavaScript string literal.
+func jsString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// jsKey renders an object key, quoting it when it is not a valid identifier.
+func jsKey(s string) string {
+	if jsIdentPattern.MatchString(s) {
+		return s
+	}
+	return jsString(s)
+}
+
+// jsAccess renders property accessors for a list of names or array indices.
+func jsAccess(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		if _, err := strconv.Atoi(t); err == nil {
+			b.WriteString("[" + t + "]")
+		} else if jsIdentPattern.MatchString(t) {
+			b.WriteString("." + t)
+		} else {
+			b.WriteString("[" + jsString(t) + "]")
+		}
+	}
+	return b.String()
+}
+
+// escapeTemplate escapes literal text for use inside a JavaScript template literal.
+func escapeTemplate(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "`", "\\`")
+	return strings.ReplaceAll(s, "${", "\\${")
+}
+
+// pointerTokens splits a JSON Pointer into unescaped reference tokens.
+func pointerTokens(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, t := range tokens {
+		tokens[i] = oasutil.UnescapePointer(t)
+	}
+	return tokens
+}
+
+// envName converts a source description name to an environment variable prefix.
+func envName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// oneLine collapses whitespace so text fits in a single-line comment.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
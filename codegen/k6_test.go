@@ -0,0 +1,174 @@
+package codegen
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+	"gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+const examplesDir = "../convert/examples/1.0.0"
+
+func loadExample(t *testing.T, arazzoFile string, sources map[string]string) (*arazzo1.Arazzo, map[string]*openapi31.OpenAPI) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(examplesDir, arazzoFile))
+	if err != nil {
+		t.Fatalf("reading arazzo file: %v", err)
+	}
+	var doc arazzo1.Arazzo
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("parsing arazzo file: %v", err)
+	}
+	docs := make(map[string]*openapi31.OpenAPI)
+	for name, file := range sources {
+		oa, err := oasutil.ParseFile(filepath.Join(examplesDir, file))
+		if err != nil {
+			t.Fatalf("parsing openapi file %s: %v", file, err)
+		}
+		docs[name] = oa
+	}
+	return &doc, docs
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("output does not match %s (run with -update to refresh):\n%s", golden, got)
+	}
+}
+
+func TestGenerateK6Golden(t *testing.T) {
+	tests := []struct {
+		arazzoFile string
+		sources    map[string]string
+		golden     string
+	}{
+		{
+			arazzoFile: "pet-coupons.arazzo.yaml",
+			sources:    map[string]string{"pet-coupons": "pet-coupons.openapi.yaml"},
+			golden:     "pet-coupons.k6.js",
+		},
+		{
+			arazzoFile: "oauth.arazzo.yaml",
+			sources:    map[string]string{"apim-auth": "oauth.openapi.yaml"},
+			golden:     "oauth.k6.js",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arazzoFile, func(t *testing.T) {
+			doc, sources := loadExample(t, tt.arazzoFile, tt.sources)
+			got, err := GenerateK6(doc, sources, nil)
+			if err != nil {
+				t.Fatalf("GenerateK6 failed: %v", err)
+			}
+			checkGolden(t, tt.golden, got)
+		})
+	}
+}
+
+func TestGenerateK6Retry(t *testing.T) {
+	limit := 3
+	after := 0.5
+	doc := &arazzo1.Arazzo{
+		Arazzo: "1.0.0",
+		Info:   &arazzo1.Info{Title: "Retry", Version: "1.0.0"},
+		SourceDescriptions: []*arazzo1.SourceDescription{
+			{Name: "api", URL: "openapi.yaml", Type: arazzo1.SourceDescriptionTypeOpenAPI},
+		},
+		Workflows: []*arazzo1.Workflow{
+			{
+				WorkflowId: "poll-job",
+				Steps: []*arazzo1.Step{
+					{
+						StepId:      "get-job",
+						OperationId: "getJob",
+						Parameters: []any{
+							&arazzo1.Parameter{Name: "id", In: arazzo1.ParameterInPath, Value: "$inputs.jobId"},
+							&arazzo1.Parameter{Name: "Authorization", In: arazzo1.ParameterInHeader, Value: "Bearer {$inputs.token}"},
+						},
+						SuccessCriteria: []*arazzo1.Criterion{
+							{Condition: "$statusCode == 200 && $response.body#/state == 'done'"},
+						},
+						OnFailure: []*arazzo1.FailureActionOrReusable{
+							{FailureAction: &arazzo1.FailureAction{
+								Name:       "wait",
+								Type:       arazzo1.FailureActionTypeRetry,
+								RetryLimit: &limit,
+								RetryAfter: &after,
+								Criteria:   []*arazzo1.Criterion{{Condition: "$statusCode == 200"}},
+							}},
+							{FailureAction: &arazzo1.FailureAction{
+								Name:     "unavailable",
+								Type:     arazzo1.FailureActionTypeRetry,
+								Criteria: []*arazzo1.Criterion{{Condition: "$statusCode == 503"}},
+							}},
+						},
+						Outputs: map[string]string{"result": "$response.body#/result"},
+					},
+				},
+				Outputs: map[string]string{"result": "$steps.get-job.outputs.result"},
+			},
+		},
+	}
+	sources := map[string]*openapi31.OpenAPI{
+		"api": {
+			Servers: []*openapi31.Server{{URL: "https://jobs.example.com/"}},
+			Paths: &openapi31.Paths{Paths: map[string]*openapi31.PathItem{
+				"/jobs/{id}": {Get: &openapi31.Operation{OperationID: "getJob"}},
+			}},
+		},
+	}
+
+	out, err := GenerateK6(doc, sources, &K6Options{VUs: 5, Iterations: 10})
+	if err != nil {
+		t.Fatalf("GenerateK6 failed: %v", err)
+	}
+	script := string(out)
+	for _, want := range []string{
+		"'poll-job': { executor: 'per-vu-iterations', vus: 5, iterations: 10, exec: 'pollJob' },",
+		"api: __ENV.API_BASE_URL || 'https://jobs.example.com',",
+		"const url = `${BASE_URLS['api']}/jobs/${encodeURIComponent(inputs.jobId)}`;",
+		"headers: { Authorization: `Bearer ${inputs.token}` },",
+		"const attempts = [0, 0];",
+		"for (;;) {",
+		"'get-job: $statusCode == 200 && $response.body#/state == \\'done\\'': (r) => r.status == 200 && r.json('state') == 'done',",
+		"if (attempts[0] < 3 && (res.status == 200)) { attempts[0]++; sleep(0.5); continue; }",
+		"if (attempts[1] < 1 && (res.status == 503)) { attempts[1]++; continue; }",
+		"result: res.json('result'),",
+		"result: getJob.result,",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestGenerateK6UnknownOperation(t *testing.T) {
+	doc := &arazzo1.Arazzo{
+		Workflows: []*arazzo1.Workflow{
+			{WorkflowId: "wf", Steps: []*arazzo1.Step{{StepId: "s", OperationId: "missing"}}},
+		},
+	}
+	if _, err := GenerateK6(doc, map[string]*openapi31.OpenAPI{"api": {}}, nil); err == nil {
+		t.Fatal("expected error for unresolvable operation")
+	}
+}
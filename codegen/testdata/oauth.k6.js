@@ -0,0 +1,198 @@
+// Code generated from Arazzo "Example OAuth service" 1.0.0. DO NOT EDIT.
+//
+// Workflow inputs are read from the ARAZZO_INPUTS environment variable as a JSON
+// object keyed by workflowId. Base URLs can be overridden per source description
+// with <SOURCE>_BASE_URL.
+import http from 'k6/http';
+import { check, fail, sleep } from 'k6';
+
+export const options = {
+  scenarios: {
+    'refresh-token-flow': { executor: 'per-vu-iterations', vus: 1, iterations: 1, exec: 'refreshTokenFlow' },
+    'client-credentials-flow': { executor: 'per-vu-iterations', vus: 1, iterations: 1, exec: 'clientCredentialsFlow' },
+    'authorization-code-flow': { executor: 'per-vu-iterations', vus: 1, iterations: 1, exec: 'authorizationCodeFlow' },
+  },
+};
+
+const INPUTS = JSON.parse(__ENV.ARAZZO_INPUTS || '{}');
+
+const BASE_URLS = {
+  'apim-auth': __ENV.APIM_AUTH_BASE_URL || 'https://auth.example.com',
+};
+
+const WORKFLOW_OUTPUTS = {};
+
+function qs(params) {
+  const parts = [];
+  for (const [k, v] of Object.entries(params)) {
+    if (v === undefined || v === null) continue;
+    for (const item of Array.isArray(v) ? v : [v]) {
+      parts.push(encodeURIComponent(k) + '=' + encodeURIComponent(item));
+    }
+  }
+  return parts.length ? '?' + parts.join('&') : '';
+}
+
+function setPointer(target, pointer, value) {
+  const tokens = pointer.split('/').slice(1).map((t) => t.replace(/~1/g, '/').replace(/~0/g, '~'));
+  let node = target;
+  for (let i = 0; i < tokens.length - 1; i++) {
+    if (node[tokens[i]] === undefined) node[tokens[i]] = {};
+    node = node[tokens[i]];
+  }
+  node[tokens[tokens.length - 1]] = value;
+}
+
+// refresh-token-flow: Refresh an access token
+export function refreshTokenFlow(overrides) {
+  const inputs = Object.assign({}, INPUTS['refresh-token-flow'], overrides);
+
+  // do-the-auth-flow: workflow authorization-code-flow
+  // This is where you do the authorization code flow
+  let doTheAuthFlow;
+  {
+    const outputs = authorizationCodeFlow({
+      client_id: inputs.my_client_id,
+      redirect_uri: inputs.my_redirect_uri,
+      client_secret: inputs.my_client_secret,
+    });
+    doTheAuthFlow = {
+      my_refresh_token: outputs.refresh_token,
+    };
+  }
+
+  // do-the-refresh: POST /oauth/token
+  // This is where you do the refresh
+  let doTheRefresh;
+  {
+    const url = `${BASE_URLS['apim-auth']}/oauth/token`;
+    const payload = {
+      grant_type: 'refresh_token',
+      refresh_token: doTheAuthFlow.my_refresh_token,
+    };
+    const body = payload;
+    const params = {
+      headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+      tags: { name: 'do-the-refresh' },
+    };
+    const res = http.request('POST', url, body, params);
+    const passed = check(res, {
+      'do-the-refresh: $statusCode == 200': (r) => r.status == 200,
+      // jsonpath criterion "$.access_token != null" is not supported by the k6 export
+    });
+    if (!passed) fail('step do-the-refresh failed');
+    doTheRefresh = {
+      access_token: res.json('access_token'),
+      expires_in: res.json('expires_in'),
+      refresh_token: res.json('refresh_token'),
+    };
+  }
+
+  const outputs = {
+    access_token: doTheRefresh.access_token,
+    expires_in: doTheRefresh.expires_in,
+    refresh_token: doTheRefresh.refresh_token,
+  };
+  WORKFLOW_OUTPUTS['refresh-token-flow'] = outputs;
+  return outputs;
+}
+
+// client-credentials-flow: Get an access token using client credentials
+export function clientCredentialsFlow(overrides) {
+  const inputs = Object.assign({}, INPUTS['client-credentials-flow'], overrides);
+
+  // get-client-creds-token: POST /oauth/token
+  // This is where you get the token
+  let getClientCredsToken;
+  {
+    const url = `${BASE_URLS['apim-auth']}/oauth/token`;
+    const payload = {
+      client_id: inputs.client_id,
+      client_secret: inputs.client_secret,
+      grant_type: 'client_credentials',
+    };
+    const body = payload;
+    const params = {
+      headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+      tags: { name: 'get-client-creds-token' },
+    };
+    const res = http.request('POST', url, body, params);
+    const passed = check(res, {
+      'get-client-creds-token: $statusCode == 200': (r) => r.status == 200,
+      // jsonpath criterion "$.access_token != null" is not supported by the k6 export
+    });
+    if (!passed) fail('step get-client-creds-token failed');
+    getClientCredsToken = {
+      access_token: res.json('access_token'),
+    };
+  }
+
+  const outputs = {
+    access_token: getClientCredsToken.access_token,
+  };
+  WORKFLOW_OUTPUTS['client-credentials-flow'] = outputs;
+  return outputs;
+}
+
+// authorization-code-flow: Get an access token using an authorization code
+export function authorizationCodeFlow(overrides) {
+  const inputs = Object.assign({}, INPUTS['authorization-code-flow'], overrides);
+
+  // browser-authorize: GET /authorize
+  // This URL is opened in the browser and redirects you back to the registered redirect URI with an authorization code.
+  let browserAuthorize;
+  {
+    const url = `${BASE_URLS['apim-auth']}/authorize` + qs({ client_id: inputs.client_id, redirect_uri: inputs.redirect_uri, response_type: 'code', scope: 'read', state: '12345' });
+    const body = null;
+    const params = {
+      tags: { name: 'browser-authorize' },
+    };
+    const res = http.request('GET', url, body, params);
+    const passed = check(res, {
+      'browser-authorize: $statusCode == 200': (r) => r.status == 200,
+      // jsonpath criterion "$.access_token != null" is not supported by the k6 export
+    });
+    if (!passed) fail('step browser-authorize failed');
+    browserAuthorize = {
+      code: res.json('code'),
+    };
+  }
+
+  // get-access-token: POST /oauth/token
+  // This is where you get the token
+  let getAccessToken;
+  {
+    const url = `${BASE_URLS['apim-auth']}/oauth/token`;
+    const payload = {
+      client_id: inputs.client_id,
+      client_secret: inputs.client_secret,
+      code: browserAuthorize.code,
+      grant_type: 'authorization_code',
+      redirect_uri: inputs.redirect_uri,
+    };
+    const body = payload;
+    const params = {
+      headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+      tags: { name: 'get-access-token' },
+    };
+    const res = http.request('POST', url, body, params);
+    const passed = check(res, {
+      'get-access-token: $statusCode == 200': (r) => r.status == 200,
+      // jsonpath criterion "$.access_token != null" is not supported by the k6 export
+    });
+    if (!passed) fail('step get-access-token failed');
+    getAccessToken = {
+      access_token: res.json('access_token'),
+      expires_in: res.json('expires_in'),
+      refresh_token: res.json('refresh_token'),
+    };
+  }
+
+  const outputs = {
+    access_token: getAccessToken.access_token,
+    expires_in: getAccessToken.expires_in,
+    refresh_token: getAccessToken.refresh_token,
+  };
+  WORKFLOW_OUTPUTS['authorization-code-flow'] = outputs;
+  return outputs;
+}
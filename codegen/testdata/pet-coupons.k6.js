@@ -0,0 +1,186 @@
+// Code generated from Arazzo "Petstore - Apply Coupons" 1.0.0. DO NOT EDIT.
+//
+// Workflow inputs are read from the ARAZZO_INPUTS environment variable as a JSON
+// object keyed by workflowId. Base URLs can be overridden per source description
+// with <SOURCE>_BASE_URL.
+import http from 'k6/http';
+import { check, fail, sleep } from 'k6';
+
+export const options = {
+  scenarios: {
+    'apply-coupon': { executor: 'per-vu-iterations', vus: 1, iterations: 1, exec: 'applyCoupon' },
+    'buy-available-pet': { executor: 'per-vu-iterations', vus: 1, iterations: 1, exec: 'buyAvailablePet' },
+    'place-order': { executor: 'per-vu-iterations', vus: 1, iterations: 1, exec: 'placeOrder' },
+  },
+};
+
+const INPUTS = JSON.parse(__ENV.ARAZZO_INPUTS || '{}');
+
+const BASE_URLS = {
+  'pet-coupons': __ENV.PET_COUPONS_BASE_URL || '',
+};
+
+const WORKFLOW_OUTPUTS = {};
+
+function qs(params) {
+  const parts = [];
+  for (const [k, v] of Object.entries(params)) {
+    if (v === undefined || v === null) continue;
+    for (const item of Array.isArray(v) ? v : [v]) {
+      parts.push(encodeURIComponent(k) + '=' + encodeURIComponent(item));
+    }
+  }
+  return parts.length ? '?' + parts.join('&') : '';
+}
+
+function setPointer(target, pointer, value) {
+  const tokens = pointer.split('/').slice(1).map((t) => t.replace(/~1/g, '/').replace(/~0/g, '~'));
+  let node = target;
+  for (let i = 0; i < tokens.length - 1; i++) {
+    if (node[tokens[i]] === undefined) node[tokens[i]] = {};
+    node = node[tokens[i]];
+  }
+  node[tokens[tokens.length - 1]] = value;
+}
+
+// apply-coupon: Apply a coupon to a pet order.
+export function applyCoupon(overrides) {
+  const inputs = Object.assign({}, INPUTS['apply-coupon'], overrides);
+
+  // find-pet: GET /pet/findByTags
+  // Find a pet based on the provided tags.
+  let findPet;
+  {
+    const url = `${BASE_URLS['pet-coupons']}/pet/findByTags` + qs({ pet_tags: inputs.my_pet_tags });
+    const body = null;
+    const params = {
+      tags: { name: 'find-pet' },
+    };
+    const res = http.request('GET', url, body, params);
+    const passed = check(res, {
+      'find-pet: $statusCode == 200': (r) => r.status == 200,
+    });
+    if (!passed) fail('step find-pet failed');
+    findPet = {
+      my_pet_id: res.json('0.id'),
+    };
+  }
+
+  // find-coupons: GET /pet/{petId}/coupons
+  // Find a coupon available for the selected pet.
+  let findCoupons;
+  {
+    const url = `${BASE_URLS['pet-coupons']}/pet/{petId}/coupons`;
+    const body = null;
+    const params = {
+      tags: { name: 'find-coupons' },
+    };
+    const res = http.request('GET', url, body, params);
+    const passed = check(res, {
+      'find-coupons: $statusCode == 200': (r) => r.status == 200,
+    });
+    if (!passed) fail('step find-coupons failed');
+    findCoupons = {
+      my_coupon_code: res.json('couponCode'),
+    };
+  }
+
+  // place-order: workflow place-order
+  // Place an order for the pet, applying the coupon.
+  let placeOrder2;
+  {
+    const outputs = placeOrder({
+      pet_id: findPet.my_pet_id,
+      coupon_code: findCoupons.my_coupon_code,
+    });
+    placeOrder2 = {
+      my_order_id: outputs.workflow_order_id,
+    };
+  }
+
+  const outputs = {
+    apply_coupon_pet_order_id: placeOrder2.my_order_id,
+  };
+  WORKFLOW_OUTPUTS['apply-coupon'] = outputs;
+  return outputs;
+}
+
+// buy-available-pet: Buy an available pet if one is available.
+export function buyAvailablePet(overrides) {
+  const inputs = Object.assign({}, INPUTS['buy-available-pet'], overrides);
+
+  // find-pet: GET /pet/findByStatus
+  // Find a pet that is available for purchase.
+  let findPet;
+  {
+    const url = `${BASE_URLS['pet-coupons']}/pet/findByStatus` + qs({ status: 'available', page: 1, pageSize: 10 });
+    const body = null;
+    const params = {
+      tags: { name: 'find-pet' },
+    };
+    const res = http.request('GET', url, body, params);
+    const passed = check(res, {
+      'find-pet: $statusCode == 200': (r) => r.status == 200,
+    });
+    if (!passed) fail('step find-pet failed');
+    findPet = {
+      my_pet_id: res.json('0.id'),
+    };
+  }
+
+  // place-order: workflow place-order
+  // Place an order for the pet.
+  let placeOrder2;
+  {
+    const outputs = placeOrder({
+      pet_id: findPet.my_pet_id,
+    });
+    placeOrder2 = {
+      my_order_id: outputs.workflow_order_id,
+    };
+  }
+
+  const outputs = {
+    buy_pet_order_id: placeOrder2.my_order_id,
+  };
+  WORKFLOW_OUTPUTS['buy-available-pet'] = outputs;
+  return outputs;
+}
+
+// place-order: Place an order for a pet.
+export function placeOrder(overrides) {
+  const inputs = Object.assign({}, INPUTS['place-order'], overrides);
+
+  // place-order: POST /store/order
+  // Place an order for the pet.
+  let placeOrder2;
+  {
+    const url = `${BASE_URLS['pet-coupons']}/store/order`;
+    const payload = {
+      complete: false,
+      couponCode: inputs.coupon_code,
+      petId: inputs.pet_id,
+      quantity: inputs.quantity,
+      status: 'placed',
+    };
+    const body = JSON.stringify(payload);
+    const params = {
+      headers: { 'Content-Type': 'application/json' },
+      tags: { name: 'place-order' },
+    };
+    const res = http.request('POST', url, body, params);
+    const passed = check(res, {
+      'place-order: $statusCode == 200': (r) => r.status == 200,
+    });
+    if (!passed) fail('step place-order failed');
+    placeOrder2 = {
+      step_order_id: res.json('id'),
+    };
+  }
+
+  const outputs = {
+    workflow_order_id: placeOrder2.step_order_id,
+  };
+  WORKFLOW_OUTPUTS['place-order'] = outputs;
+  return outputs;
+}
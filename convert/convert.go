@@ -2,11 +2,15 @@
 package convert
 
 import (
+	"bytes"
 	"encoding/json"
+	"reflect"
 	"strings"
 
 	"github.com/genelet/arazzo/arazzo1"
 	"github.com/genelet/horizon/dethcl"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 )
 
 const hclDollarKeyPrefix = "__dollar__"
@@ -46,181 +50,6 @@ func fromHCLKey(key string) string {
 	return key
 }
 
-// transformValue recursively transforms values for HCL compatibility.
-// When toHCL is true:
-//   - Converts $-prefixed keys to HCL-safe identifiers
-//   - Escapes newlines in strings (HCL quoted strings cannot span multiple lines)
-//
-// When toHCL is false:
-//   - Converts HCL-safe identifiers back to $-prefixed keys
-//   - Unescapes newlines in strings
-func transformValue(v any, toHCL bool) any {
-	switch val := v.(type) {
-	case string:
-		if toHCL {
-			// Escape newlines and quotes for HCL compatibility
-			return escapeForHCL(val)
-		}
-		// Unescape newlines and quotes when converting back from HCL
-		return unescapeFromHCL(val)
-	case map[string]any:
-		result := make(map[string]any)
-		for k, v := range val {
-			newKey := k
-			if toHCL {
-				newKey = toHCLKey(k)
-			} else {
-				newKey = fromHCLKey(k)
-			}
-			result[newKey] = transformValue(v, toHCL)
-		}
-		return result
-	case []any:
-		result := make([]any, len(val))
-		for i, item := range val {
-			result[i] = transformValue(item, toHCL)
-		}
-		return result
-	default:
-		return v
-	}
-}
-
-// escapeForHCL escapes special characters in strings for HCL compatibility.
-// HCL quoted strings cannot span multiple lines, so newlines are escaped as \n.
-// Internal double quotes are escaped as \".
-func escapeForHCL(s string) string {
-	// First protect already-escaped sequences
-	s = strings.ReplaceAll(s, "\\n", "\x00ESCAPED_N\x00")
-	s = strings.ReplaceAll(s, "\\\"", "\x00ESCAPED_Q\x00")
-
-	// Escape newlines and quotes
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	s = strings.ReplaceAll(s, "\"", "\\\"")
-
-	// Restore double-escaped sequences
-	s = strings.ReplaceAll(s, "\x00ESCAPED_N\x00", "\\\\n")
-	s = strings.ReplaceAll(s, "\x00ESCAPED_Q\x00", "\\\\\"")
-	return s
-}
-
-// escapeNewlines replaces actual newlines with escaped \n sequences
-// so that HCL quoted strings remain on a single line.
-// This is used for string fields that go through standard JSON marshaling.
-func escapeNewlines(s string) string {
-	// Replace actual newlines with escaped sequence
-	// We use a placeholder first to avoid double-escaping existing \n
-	s = strings.ReplaceAll(s, "\\n", "\x00ESCAPED_N\x00")
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	s = strings.ReplaceAll(s, "\x00ESCAPED_N\x00", "\\\\n")
-	return s
-}
-
-// unescapeNewlines converts escaped \n sequences back to actual newlines.
-func unescapeNewlines(s string) string {
-	// First protect already escaped backslashes followed by n
-	s = strings.ReplaceAll(s, "\\\\n", "\x00ESCAPED_N\x00")
-	// Then convert \n to actual newline
-	s = strings.ReplaceAll(s, "\\n", "\n")
-	// Restore escaped sequences
-	s = strings.ReplaceAll(s, "\x00ESCAPED_N\x00", "\\n")
-	return s
-}
-
-// unescapeFromHCL unescapes special characters from HCL strings.
-func unescapeFromHCL(s string) string {
-	// First protect double-escaped sequences
-	s = strings.ReplaceAll(s, "\\\\n", "\x00ESCAPED_N\x00")
-	s = strings.ReplaceAll(s, "\\\\\"", "\x00ESCAPED_Q\x00")
-
-	// Unescape newlines and quotes
-	s = strings.ReplaceAll(s, "\\n", "\n")
-	s = strings.ReplaceAll(s, "\\\"", "\"")
-
-	// Restore double-escaped as single-escaped
-	s = strings.ReplaceAll(s, "\x00ESCAPED_N\x00", "\\n")
-	s = strings.ReplaceAll(s, "\x00ESCAPED_Q\x00", "\\\"")
-	return s
-}
-
-// transformArazzoForHCL transforms an Arazzo document's dynamic fields ($ref -> _ref) for HCL compatibility.
-// It also escapes newlines in string fields since HCL quoted strings cannot span multiple lines.
-func transformArazzoForHCL(doc *arazzo1.Arazzo) {
-	// Transform string fields with potential newlines
-	if doc.Info != nil {
-		doc.Info.Description = escapeNewlines(doc.Info.Description)
-		doc.Info.Summary = escapeNewlines(doc.Info.Summary)
-	}
-	for _, wf := range doc.Workflows {
-		wf.Description = escapeNewlines(wf.Description)
-		wf.Summary = escapeNewlines(wf.Summary)
-		// Transform workflow inputs
-		if wf.Inputs != nil {
-			wf.Inputs = transformValue(wf.Inputs, true)
-		}
-		for _, step := range wf.Steps {
-			step.Description = escapeNewlines(step.Description)
-			// Transform step parameters ([]any may contain maps with $ref keys and numeric values)
-			if step.Parameters != nil {
-				for i, param := range step.Parameters {
-					step.Parameters[i] = transformValue(param, true)
-				}
-			}
-			if step.RequestBody != nil {
-				// Transform any-typed Payload and Replacements
-				if step.RequestBody.Payload != nil {
-					step.RequestBody.Payload = transformValue(step.RequestBody.Payload, true)
-				}
-			}
-		}
-	}
-	// Transform component inputs
-	if doc.Components != nil && doc.Components.Inputs != nil {
-		for k, v := range doc.Components.Inputs {
-			doc.Components.Inputs[k] = transformValue(v, true)
-		}
-	}
-}
-
-// transformArazzoFromHCL transforms an Arazzo document's dynamic fields (_ref -> $ref) back from HCL.
-// It also unescapes newlines in string fields.
-func transformArazzoFromHCL(doc *arazzo1.Arazzo) {
-	// Transform string fields with escaped newlines
-	if doc.Info != nil {
-		doc.Info.Description = unescapeNewlines(doc.Info.Description)
-		doc.Info.Summary = unescapeNewlines(doc.Info.Summary)
-	}
-	for _, wf := range doc.Workflows {
-		wf.Description = unescapeNewlines(wf.Description)
-		wf.Summary = unescapeNewlines(wf.Summary)
-		// Transform workflow inputs
-		if wf.Inputs != nil {
-			wf.Inputs = transformValue(wf.Inputs, false)
-		}
-		for _, step := range wf.Steps {
-			step.Description = unescapeNewlines(step.Description)
-			// Transform step parameters ([]any may contain maps with _ref keys)
-			if step.Parameters != nil {
-				for i, param := range step.Parameters {
-					step.Parameters[i] = transformValue(param, false)
-				}
-			}
-			if step.RequestBody != nil {
-				// Transform any-typed Payload and Replacements
-				if step.RequestBody.Payload != nil {
-					step.RequestBody.Payload = transformValue(step.RequestBody.Payload, false)
-				}
-			}
-		}
-	}
-	// Transform component inputs
-	if doc.Components != nil && doc.Components.Inputs != nil {
-		for k, v := range doc.Components.Inputs {
-			doc.Components.Inputs[k] = transformValue(v, false)
-		}
-	}
-}
-
 // JSONToHCL converts an Arazzo document from JSON format to HCL format.
 // It first unmarshals the JSON into an Arazzo struct, then marshals it to HCL.
 // JSON Schema keys like $ref are transformed to _ref for HCL compatibility.
@@ -229,8 +58,7 @@ func JSONToHCL(jsonData []byte) ([]byte, error) {
 	if err := json.Unmarshal(jsonData, &doc); err != nil {
 		return nil, err
 	}
-	transformArazzoForHCL(&doc)
-	return dethcl.Marshal(&doc)
+	return MarshalHCL(&doc)
 }
 
 // HCLToJSON converts an Arazzo document from HCL format to JSON format.
@@ -238,10 +66,9 @@ func JSONToHCL(jsonData []byte) ([]byte, error) {
 // HCL keys like _ref are transformed back to $ref for JSON compatibility.
 func HCLToJSON(hclData []byte) ([]byte, error) {
 	var doc arazzo1.Arazzo
-	if err := dethcl.Unmarshal(hclData, &doc); err != nil {
+	if err := UnmarshalHCL(hclData, &doc); err != nil {
 		return nil, err
 	}
-	transformArazzoFromHCL(&doc)
 	return json.Marshal(&doc)
 }
 
@@ -249,30 +76,61 @@ func HCLToJSON(hclData []byte) ([]byte, error) {
 // HCL keys like _ref are transformed back to $ref for JSON compatibility.
 func HCLToJSONIndent(hclData []byte, prefix, indent string) ([]byte, error) {
 	var doc arazzo1.Arazzo
-	if err := dethcl.Unmarshal(hclData, &doc); err != nil {
+	if err := UnmarshalHCL(hclData, &doc); err != nil {
 		return nil, err
 	}
-	transformArazzoFromHCL(&doc)
 	return json.MarshalIndent(&doc, prefix, indent)
 }
 
 // MarshalHCL marshals an Arazzo document to HCL format.
-// JSON Schema keys like $ref are transformed to _ref for HCL compatibility.
+// JSON Schema keys like $ref are transformed to _ref for HCL compatibility,
+// extensions are written as x- attributes of their blocks,
+// and runtime expressions are written as native references, e.g.
+// steps.login.outputs.token for "$steps.login.outputs.token", unless they
+// refer to a step, workflow or source description the document does not
+// define, which UnmarshalHCL would reject. Typed step
+// parameters, such as *arazzo1.Parameter, are written as the objects they
+// encode to, like those decoded from JSON.
 // Note: This function modifies the document in place. If you need to preserve
 // the original, make a copy before calling this function.
 func MarshalHCL(doc *arazzo1.Arazzo) ([]byte, error) {
+	return marshalHCL(doc, nil)
+}
+
+// marshalHCL is MarshalHCL for a document written among the names of outer,
+// which may be nil; see nativeReferences.
+func marshalHCL(doc *arazzo1.Arazzo, outer *referenceScope) ([]byte, error) {
+	if err := hclParameters(doc); err != nil {
+		return nil, err
+	}
 	transformArazzoForHCL(doc)
-	return dethcl.Marshal(doc)
+	hclData, err := dethcl.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	// Extensions are appended to the bodies, the root one included.
+	if !bytes.HasSuffix(hclData, []byte("\n")) {
+		hclData = append(hclData, '\n')
+	}
+	f, diags := hclwrite.ParseConfig(hclData, "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	if err := writeExtensions(f.Body(), reflect.ValueOf(doc)); err != nil {
+		return nil, err
+	}
+	return nativeReferences(f.Bytes(), outer)
 }
 
 // UnmarshalHCL unmarshals HCL data into an Arazzo document.
 // HCL keys like _ref are transformed back to $ref for JSON compatibility.
+// Runtime expressions may be quoted, as in "$inputs.username", or written as
+// native references such as inputs.username and
+// "Bearer ${steps.login.outputs.token}". Undefined references are reported
+// as hcl.Diagnostics. The document is a module of one file, so it may define
+// locals and variables; see UnmarshalHCLFiles.
 func UnmarshalHCL(hclData []byte, doc *arazzo1.Arazzo) error {
-	if err := dethcl.Unmarshal(hclData, doc); err != nil {
-		return err
-	}
-	transformArazzoFromHCL(doc)
-	return nil
+	return UnmarshalHCLFiles(map[string][]byte{"": hclData}, doc)
 }
 
 // MarshalJSON marshals an Arazzo document to JSON format.
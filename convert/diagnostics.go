@@ -0,0 +1,37 @@
+package convert
+
+import (
+	"io"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// WriteDiagnostics writes diagnostics the way Terraform prints them: the
+// severity and summary, the file and line with the source snippet
+// underlined, and the detail. Sources are taken from files, keyed by file
+// name, and otherwise read from disk; without a source the snippet is left
+// out.
+func WriteDiagnostics(w io.Writer, diags hcl.Diagnostics, files map[string][]byte) error {
+	sources := make(map[string]*hcl.File)
+	for _, diag := range diags {
+		if diag.Subject == nil {
+			continue
+		}
+		name := diag.Subject.Filename
+		if _, ok := sources[name]; ok {
+			continue
+		}
+		src, ok := files[name]
+		if !ok && name != "" {
+			var err error
+			if src, err = os.ReadFile(name); err == nil {
+				ok = true
+			}
+		}
+		if ok {
+			sources[name] = &hcl.File{Bytes: src}
+		}
+	}
+	return hcl.NewDiagnosticTextWriter(w, sources, 78, false).WriteDiagnostics(diags)
+}
@@ -0,0 +1,105 @@
+package convert
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/hashicorp/hcl/v2"
+)
+
+const diagnosticsHCL = `arazzo = "1.0.0"
+
+locals {
+  id = "petId"
+}
+
+info {
+  title   = "Diagnostics"
+  version = "1.0.0"
+}
+
+workflow "adopt" {
+  step "find" {
+    operationId = "findPet"
+    outputs     = { id = response.body.id, n = 5 }
+    retries     = 3
+  }
+}
+`
+
+func TestUnmarshalHCLFilesDiagnostics(t *testing.T) {
+	files := map[string][]byte{"adopt.arazzo.hcl": []byte(diagnosticsHCL)}
+	var doc arazzo1.Arazzo
+	err := UnmarshalHCLFiles(files, &doc)
+	var diags hcl.Diagnostics
+	if !errors.As(err, &diags) {
+		t.Fatalf("expected hcl.Diagnostics, got %v", err)
+	}
+	if len(diags) != 2 {
+		t.Fatalf("diagnostics = %v", diags)
+	}
+	// Positions refer to the file as written, before the locals block was
+	// dropped and the native references were quoted.
+	for i, tt := range []struct {
+		summary   string
+		line      int
+		startCol  int
+		endCol    int
+		subjectOf string
+	}{
+		{"Incorrect attribute value type", 15, 19, 51, "{ id = response.body.id, n = 5 }"},
+		{"Unsupported argument", 16, 5, 12, "retries"},
+	} {
+		d := diags[i]
+		if d.Summary != tt.summary {
+			t.Errorf("diagnostic %d summary = %q, want %q", i, d.Summary, tt.summary)
+			continue
+		}
+		r := d.Subject
+		if r == nil || r.Filename != "adopt.arazzo.hcl" || r.Start.Line != tt.line ||
+			r.Start.Column != tt.startCol || r.End.Column != tt.endCol {
+			t.Errorf("diagnostic %d subject = %v", i, r)
+			continue
+		}
+		if got := string(r.SliceBytes(files["adopt.arazzo.hcl"])); got != tt.subjectOf {
+			t.Errorf("diagnostic %d covers %q, want %q", i, got, tt.subjectOf)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiagnostics(&buf, diags, files); err != nil {
+		t.Fatalf("WriteDiagnostics failed: %v", err)
+	}
+	for _, s := range []string{
+		"Error: Unsupported argument",
+		"on adopt.arazzo.hcl line 16",
+		"retries     = 3",
+		`An argument named "retries" is not expected here.`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("output does not contain %q:\n%s", s, buf.String())
+		}
+	}
+}
+
+func TestUnmarshalHCLFilesDiagnosticsAcrossFiles(t *testing.T) {
+	files := map[string][]byte{
+		"a.arazzo.hcl": []byte("arazzo = \"1.0.0\"\nworkflow \"a\" {\n  summary = 1\n}\n"),
+		"b.arazzo.hcl": []byte("workflow \"b\" {\n  step \"s\" {\n    operationId = \"op\"\n    kind = \"x\"\n  }\n}\n"),
+	}
+	var doc arazzo1.Arazzo
+	err := UnmarshalHCLFiles(files, &doc)
+	var diags hcl.Diagnostics
+	if !errors.As(err, &diags) || len(diags) != 2 {
+		t.Fatalf("diagnostics = %v", err)
+	}
+	if r := diags[0].Subject; r == nil || r.Filename != "a.arazzo.hcl" || r.Start.Line != 3 {
+		t.Errorf("first subject = %v", r)
+	}
+	if r := diags[1].Subject; r == nil || r.Filename != "b.arazzo.hcl" || r.Start.Line != 4 {
+		t.Errorf("second subject = %v", r)
+	}
+}
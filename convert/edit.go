@@ -0,0 +1,653 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// HCLEditor applies changes to an HCL Arazzo document through its syntax
+// tree, so that comments, attribute order, heredocs and hand formatting
+// outside the edited parts are kept. New content is written in the layout
+// of MarshalHCL, indented like its siblings.
+type HCLEditor struct {
+	filename string
+	file     *hclwrite.File
+}
+
+// NewHCLEditor parses an HCL Arazzo document for editing.
+func NewHCLEditor(src []byte, filename string) (*HCLEditor, error) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return &HCLEditor{filename: filename, file: file}, nil
+}
+
+// Bytes returns the edited document. Unlike hclwrite.File.Bytes, it does
+// not reformat the file, so hand alignment is kept.
+func (e *HCLEditor) Bytes() []byte {
+	var buf bytes.Buffer
+	e.file.BuildTokens(nil).WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// AddStep adds step to a workflow after the step named after, or after the
+// last step if after is empty.
+func (e *HCLEditor) AddStep(workflowID string, step *arazzo1.Step, after string) error {
+	wf, err := e.workflow(workflowID)
+	if err != nil {
+		return err
+	}
+	if step.StepId == "" {
+		return fmt.Errorf("step without stepId")
+	}
+	steps := stepBlocks(wf)
+	if findBlock(steps, step.StepId) != nil {
+		return fmt.Errorf("step %q already exists in workflow %q", step.StepId, workflowID)
+	}
+	anchor := findBlock(steps, after)
+	if after != "" && anchor == nil {
+		return fmt.Errorf("step %q not found in workflow %q", after, workflowID)
+	}
+	if anchor == nil && len(steps) > 0 {
+		anchor = steps[len(steps)-1]
+	}
+
+	indent := blockIndent(wf) + 2
+	if anchor != nil {
+		indent = blockIndent(anchor)
+	}
+	tokens, err := renderStep(step, indent, e.scope(wf, step.StepId))
+	if err != nil {
+		return err
+	}
+
+	body := wf.Body().BuildTokens(nil)
+	at := len(body)
+	if anchor != nil {
+		anchorTokens := anchor.BuildTokens(nil)
+		at = tokenIndex(body, anchorTokens[len(anchorTokens)-1]) + 1
+	}
+	spliced := append(hclwrite.Tokens{}, body[:at]...)
+	spliced = append(spliced, newlineToken())
+	spliced = append(spliced, tokens...)
+	spliced = append(spliced, body[at:]...)
+	wf.Body().Clear()
+	wf.Body().AppendUnstructuredTokens(spliced)
+	return e.reparse()
+}
+
+// RemoveStep removes a step from a workflow.
+func (e *HCLEditor) RemoveStep(workflowID, stepID string) error {
+	wf, step, err := e.step(workflowID, stepID)
+	if err != nil {
+		return err
+	}
+	wf.Body().RemoveBlock(step)
+	return e.reparse()
+}
+
+// stepReference matches the step name of a quoted $steps expression.
+func stepReference(stepID string) *regexp.Regexp {
+	return regexp.MustCompile(`(\$steps\.)` + regexp.QuoteMeta(stepID) + `([^A-Za-z0-9_\-]|$)`)
+}
+
+// RenameStep renames a step, together with the references to it in its
+// workflow: quoted and native $steps expressions and goto actions.
+func (e *HCLEditor) RenameStep(workflowID, oldID, newID string) error {
+	wf, step, err := e.step(workflowID, oldID)
+	if err != nil {
+		return err
+	}
+	if findBlock(stepBlocks(wf), newID) != nil {
+		return fmt.Errorf("step %q already exists in workflow %q", newID, workflowID)
+	}
+	labels := step.Labels()
+	labels[0] = newID
+	step.SetLabels(labels)
+	// SetLabels drops the space between the block type and its label.
+	for _, t := range step.BuildTokens(nil) {
+		if t.Type == hclsyntax.TokenOQuote {
+			t.SpacesBefore = 1
+			break
+		}
+	}
+
+	re := stepReference(oldID)
+	tokens := wf.Body().BuildTokens(nil)
+	for i, t := range tokens {
+		switch t.Type {
+		case hclsyntax.TokenQuotedLit, hclsyntax.TokenStringLit:
+			t.Bytes = re.ReplaceAll(t.Bytes, []byte("${1}"+newID+"${2}"))
+		case hclsyntax.TokenIdent:
+			if i+3 >= len(tokens) || (i > 0 && tokens[i-1].Type == hclsyntax.TokenDot) {
+				continue
+			}
+			switch string(t.Bytes) {
+			case "steps":
+				// steps.old and steps["old"], where steps is the root.
+				if tokens[i+1].Type == hclsyntax.TokenDot && tokens[i+2].Type == hclsyntax.TokenIdent && string(tokens[i+2].Bytes) == oldID {
+					tokens[i+2].Bytes = []byte(newID)
+				} else if tokens[i+1].Type == hclsyntax.TokenOBrack && tokens[i+2].Type == hclsyntax.TokenOQuote &&
+					tokens[i+3].Type == hclsyntax.TokenQuotedLit && string(tokens[i+3].Bytes) == oldID {
+					tokens[i+3].Bytes = []byte(newID)
+				}
+			case "stepId":
+				// stepId = "old" in goto actions.
+				if (tokens[i+1].Type == hclsyntax.TokenEqual || tokens[i+1].Type == hclsyntax.TokenColon) &&
+					tokens[i+2].Type == hclsyntax.TokenOQuote && tokens[i+3].Type == hclsyntax.TokenQuotedLit && string(tokens[i+3].Bytes) == oldID {
+					tokens[i+3].Bytes = []byte(newID)
+				}
+			}
+		}
+	}
+	return e.reparse()
+}
+
+// SetStepAttribute sets a string attribute of a step, such as description or
+// operationId. Runtime expressions are written as native references.
+func (e *HCLEditor) SetStepAttribute(workflowID, stepID, name, value string) error {
+	wf, step, err := e.step(workflowID, stepID)
+	if err != nil {
+		return err
+	}
+	text, ok := nativeString(value, e.scope(wf))
+	if !ok {
+		text = quoteHCL(value)
+	}
+	tokens, err := expressionTokens(text, 0)
+	if err != nil {
+		return err
+	}
+	setAttribute(step, name, tokens)
+	return e.reparse()
+}
+
+// SetParameter adds a parameter to a step, or replaces the one with the same
+// name and location. The other parameters keep their comments and layout.
+func (e *HCLEditor) SetParameter(workflowID, stepID string, param *arazzo1.Parameter) error {
+	wf, step, err := e.step(workflowID, stepID)
+	if err != nil {
+		return err
+	}
+	scope := e.scope(wf)
+	attr := step.Body().GetAttribute("parameters")
+	if attr == nil {
+		tokens, err := renderParameter(param, blockIndent(step)+2, scope)
+		if err != nil {
+			return err
+		}
+		tuple := hclwrite.Tokens{{Type: hclsyntax.TokenOBrack, Bytes: []byte("[")}, newlineToken()}
+		tuple = append(tuple, tokens...)
+		tuple = append(tuple, newlineToken(), &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte("]"), SpacesBefore: blockIndent(step) + 2})
+		setAttribute(step, "parameters", tuple)
+		return e.reparse()
+	}
+
+	expr := attr.Expr().BuildTokens(nil)
+	elems, err := tupleElements(expr)
+	if err != nil {
+		return fmt.Errorf("step %q: parameters: %w", stepID, err)
+	}
+	k := findParameter(elems, expr, param.Name, string(param.In))
+	indent := blockIndent(step) + 4
+	if k >= 0 {
+		indent = elems[k].indent(expr)
+	} else if len(elems) > 0 {
+		indent = elems[len(elems)-1].indent(expr)
+	}
+	tokens, err := renderParameter(param, indent, scope)
+	if err != nil {
+		return err
+	}
+
+	var spliced hclwrite.Tokens
+	switch {
+	case k >= 0:
+		el := elems[k]
+		tokens[0].SpacesBefore = expr[el.coreStart].SpacesBefore
+		spliced = append(spliced, expr[:el.coreStart]...)
+		spliced = append(spliced, tokens...)
+		spliced = append(spliced, expr[el.coreEnd:]...)
+	case len(elems) == 0:
+		spliced = append(spliced, expr[0], newlineToken())
+		spliced = append(spliced, tokens...)
+		spliced = append(spliced, newlineToken())
+		spliced = append(spliced, expr[len(expr)-1])
+		spliced[len(spliced)-1].SpacesBefore = blockIndent(step) + 2
+	default:
+		last := elems[len(elems)-1]
+		if last.separator >= 0 {
+			// A trailing comma: the parameter goes after it.
+			spliced = append(spliced, expr[:last.separator+1]...)
+			spliced = append(spliced, newlineToken())
+			spliced = append(spliced, tokens...)
+			spliced = append(spliced, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+			spliced = append(spliced, expr[last.separator+1:]...)
+		} else {
+			spliced = append(spliced, expr[:last.coreEnd]...)
+			spliced = append(spliced, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")}, newlineToken())
+			spliced = append(spliced, tokens...)
+			spliced = append(spliced, expr[last.coreEnd:]...)
+		}
+	}
+	step.Body().SetAttributeRaw("parameters", spliced)
+	return e.reparse()
+}
+
+// RemoveParameter removes the parameter of a step with the given name and
+// location; an empty location matches any.
+func (e *HCLEditor) RemoveParameter(workflowID, stepID, name, in string) error {
+	_, step, err := e.step(workflowID, stepID)
+	if err != nil {
+		return err
+	}
+	attr := step.Body().GetAttribute("parameters")
+	if attr == nil {
+		return fmt.Errorf("step %q has no parameter %q", stepID, name)
+	}
+	expr := attr.Expr().BuildTokens(nil)
+	elems, err := tupleElements(expr)
+	if err != nil {
+		return fmt.Errorf("step %q: parameters: %w", stepID, err)
+	}
+	k := -1
+	for i, el := range elems {
+		n, l := parameterKey(expr[el.coreStart:el.coreEnd])
+		if n == name && (in == "" || l == in) {
+			k = i
+			break
+		}
+	}
+	if k < 0 {
+		return fmt.Errorf("step %q has no parameter %q", stepID, name)
+	}
+	if len(elems) == 1 {
+		step.Body().RemoveAttribute("parameters")
+		return e.reparse()
+	}
+
+	el := elems[k]
+	var spliced hclwrite.Tokens
+	if el.separator >= 0 {
+		spliced = append(spliced, expr[:el.start]...)
+		spliced = append(spliced, expr[el.separator+1:]...)
+	} else {
+		// The last parameter, without trailing comma: drop the comma before it.
+		prev := elems[k-1]
+		spliced = append(spliced, expr[:prev.separator]...)
+		spliced = append(spliced, expr[el.coreEnd:]...)
+	}
+	step.Body().SetAttributeRaw("parameters", spliced)
+	return e.reparse()
+}
+
+// setAttribute sets an attribute of block to the expression tokens. A new
+// attribute goes after the last one, with the same indentation; the caller
+// reparses.
+func setAttribute(block *hclwrite.Block, name string, expr hclwrite.Tokens) {
+	expr[0].SpacesBefore = 1
+	body := block.Body()
+	if body.GetAttribute(name) != nil {
+		body.SetAttributeRaw(name, expr)
+		return
+	}
+	tokens := body.BuildTokens(nil)
+	at, indent := 0, blockIndent(block)+2
+	if len(tokens) > 0 && tokens[0].Type == hclsyntax.TokenNewline {
+		at = 1
+	}
+	for _, attr := range body.Attributes() {
+		attrTokens := attr.BuildTokens(nil)
+		if i := tokenIndex(tokens, attrTokens[len(attrTokens)-1]) + 1; i > at {
+			at = i
+			indent = attrTokens[0].SpacesBefore
+		}
+	}
+	line := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(name), SpacesBefore: indent},
+		{Type: hclsyntax.TokenEqual, Bytes: []byte("="), SpacesBefore: 1},
+	}
+	line = append(line, expr...)
+	line = append(line, newlineToken())
+	spliced := append(hclwrite.Tokens{}, tokens[:at]...)
+	spliced = append(spliced, line...)
+	spliced = append(spliced, tokens[at:]...)
+	body.Clear()
+	body.AppendUnstructuredTokens(spliced)
+}
+
+// workflow returns the block of a workflow.
+func (e *HCLEditor) workflow(workflowID string) (*hclwrite.Block, error) {
+	for _, block := range e.file.Body().Blocks() {
+		if block.Type() == "workflow" && len(block.Labels()) > 0 && block.Labels()[0] == workflowID {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("workflow %q not found", workflowID)
+}
+
+// step returns the blocks of a workflow and one of its steps.
+func (e *HCLEditor) step(workflowID, stepID string) (*hclwrite.Block, *hclwrite.Block, error) {
+	wf, err := e.workflow(workflowID)
+	if err != nil {
+		return nil, nil, err
+	}
+	step := findBlock(stepBlocks(wf), stepID)
+	if step == nil {
+		return nil, nil, fmt.Errorf("step %q not found in workflow %q", stepID, workflowID)
+	}
+	return wf, step, nil
+}
+
+// scope returns the names the references of a workflow may use in the
+// file: its steps, with the new ones in steps, and the workflows and source
+// descriptions of the file. References to names defined in the other files
+// of a module stay quoted.
+func (e *HCLEditor) scope(wf *hclwrite.Block, steps ...string) *referenceScope {
+	scope := &referenceScope{steps: map[string]bool{}, workflows: map[string]bool{}, sourceDescriptions: map[string]bool{}}
+	for _, block := range e.file.Body().Blocks() {
+		if len(block.Labels()) == 0 {
+			continue
+		}
+		switch block.Type() {
+		case "workflow":
+			scope.workflows[block.Labels()[0]] = true
+		case "sourceDescription":
+			scope.sourceDescriptions[block.Labels()[0]] = true
+		}
+	}
+	for _, block := range stepBlocks(wf) {
+		scope.steps[block.Labels()[0]] = true
+	}
+	for _, id := range steps {
+		scope.steps[id] = true
+	}
+	return scope
+}
+
+// reparse rebuilds the syntax tree from the edited tokens, so that spliced
+// tokens become blocks and attributes again.
+func (e *HCLEditor) reparse() error {
+	file, diags := hclwrite.ParseConfig(e.Bytes(), e.filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("edit produced invalid HCL: %w", diags)
+	}
+	e.file = file
+	return nil
+}
+
+func stepBlocks(wf *hclwrite.Block) []*hclwrite.Block {
+	var steps []*hclwrite.Block
+	for _, block := range wf.Body().Blocks() {
+		if block.Type() == "step" && len(block.Labels()) > 0 {
+			steps = append(steps, block)
+		}
+	}
+	return steps
+}
+
+func findBlock(blocks []*hclwrite.Block, label string) *hclwrite.Block {
+	for _, block := range blocks {
+		if block.Labels()[0] == label {
+			return block
+		}
+	}
+	return nil
+}
+
+// blockIndent returns the indentation of a block's first line.
+func blockIndent(block *hclwrite.Block) int {
+	tokens := block.BuildTokens(nil)
+	for _, t := range tokens {
+		if t.Type != hclsyntax.TokenNewline {
+			return t.SpacesBefore
+		}
+	}
+	return 0
+}
+
+func tokenIndex(tokens hclwrite.Tokens, t *hclwrite.Token) int {
+	for i, candidate := range tokens {
+		if candidate == t {
+			return i
+		}
+	}
+	return len(tokens) - 1
+}
+
+func newlineToken() *hclwrite.Token {
+	return &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")}
+}
+
+// renderStep returns the tokens of a step block, indented by indent, with
+// the references scope defines written natively.
+func renderStep(step *arazzo1.Step, indent int, scope *referenceScope) (hclwrite.Tokens, error) {
+	// MarshalHCL transforms the document in place; it gets a copy.
+	copied := *step
+	copied.Parameters = append([]any(nil), step.Parameters...)
+	if step.RequestBody != nil {
+		rb := *step.RequestBody
+		copied.RequestBody = &rb
+	}
+	doc := &arazzo1.Arazzo{Workflows: []*arazzo1.Workflow{{WorkflowId: "workflow", Steps: []*arazzo1.Step{&copied}}}}
+	src, err := marshalHCL(doc, scope)
+	if err != nil {
+		return nil, err
+	}
+	// Formatted, the step is indented by 2 within its workflow.
+	src = reindent(hclwrite.Format(src), indent-2, false)
+	file, diags := hclwrite.ParseConfig(src, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	wf := file.Body().FirstMatchingBlock("workflow", []string{"workflow"})
+	if wf == nil || len(wf.Body().Blocks()) == 0 {
+		return nil, fmt.Errorf("rendering step %q failed", step.StepId)
+	}
+	return wf.Body().Blocks()[0].BuildTokens(nil), nil
+}
+
+// renderParameter returns the tokens of a parameter object whose first line
+// is indented by indent. Its keys are name, in, value, reference and then
+// the extensions. References scope defines are written natively.
+func renderParameter(param *arazzo1.Parameter, indent int, scope *referenceScope) (hclwrite.Tokens, error) {
+	data, err := json.Marshal(param)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	order := []string{"name", "in", "value", "reference"}
+	var rest []string
+	for k := range fields {
+		if k != "name" && k != "in" && k != "value" && k != "reference" {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, k := range append(order, rest...) {
+		raw, ok := fields[k]
+		if !ok {
+			continue
+		}
+		text, err := valueText(raw, scope)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %s: %w", param.Name, k, err)
+		}
+		key := k
+		if !hclsyntax.ValidIdentifier(k) {
+			key = quoteHCL(k)
+		}
+		fmt.Fprintf(&b, "%s = %s\n", key, text)
+	}
+	b.WriteString("}")
+	return expressionTokens(b.String(), indent)
+}
+
+// valueText returns the HCL of a JSON value, with the runtime expressions
+// scope defines as native references.
+func valueText(raw json.RawMessage, scope *referenceScope) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if text, ok := nativeString(s, scope); ok {
+			return text, nil
+		}
+		return quoteHCL(s), nil
+	}
+	ty, err := ctyjson.ImpliedType(raw)
+	if err != nil {
+		return "", err
+	}
+	val, err := ctyjson.Unmarshal(raw, ty)
+	if err != nil {
+		return "", err
+	}
+	src, err := nativeReferences([]byte("x = "+string(hclwrite.TokensForValue(val).Bytes())), scope)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(string(src), "x = "), nil
+}
+
+// expressionTokens parses and formats the HCL expression text, indenting its
+// lines after the first by indent. The first token is indented by indent too.
+func expressionTokens(text string, indent int) (hclwrite.Tokens, error) {
+	src := reindent(hclwrite.Format([]byte("x = "+text+"\n")), indent, true)
+	file, diags := hclwrite.ParseConfig(src, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	attr := file.Body().GetAttribute("x")
+	if attr == nil {
+		return nil, fmt.Errorf("invalid expression %q", text)
+	}
+	tokens := attr.Expr().BuildTokens(nil)
+	tokens[0].SpacesBefore = indent
+	return tokens, nil
+}
+
+// reindent shifts the lines of src by delta spaces, skipping the first line
+// if skipFirst is set.
+func reindent(src []byte, delta int, skipFirst bool) []byte {
+	lines := strings.Split(string(src), "\n")
+	for i, line := range lines {
+		if (i == 0 && skipFirst) || line == "" {
+			continue
+		}
+		if delta >= 0 {
+			lines[i] = strings.Repeat(" ", delta) + line
+		} else {
+			cut := len(line) - len(strings.TrimLeft(line, " "))
+			lines[i] = line[min(cut, -delta):]
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// tupleElement locates an element of a tuple expression by token indexes:
+// it spans [start, separator), with its expression at [coreStart, coreEnd)
+// and separator the index of the comma after it, or -1.
+type tupleElement struct {
+	start, coreStart, coreEnd, separator int
+}
+
+// indent returns the indentation of the element's first line.
+func (el tupleElement) indent(tokens hclwrite.Tokens) int {
+	return tokens[el.coreStart].SpacesBefore
+}
+
+// tupleElements splits the tokens of a tuple expression into its elements.
+func tupleElements(tokens hclwrite.Tokens) ([]tupleElement, error) {
+	if len(tokens) < 2 || tokens[0].Type != hclsyntax.TokenOBrack || tokens[len(tokens)-1].Type != hclsyntax.TokenCBrack {
+		return nil, fmt.Errorf("not a list")
+	}
+	var elems []tupleElement
+	depth := 0
+	start := 1
+	closeElement := func(end, separator int) {
+		el := tupleElement{start: start, coreStart: -1, separator: separator}
+		for i := start; i < end; i++ {
+			if tokens[i].Type != hclsyntax.TokenNewline && tokens[i].Type != hclsyntax.TokenComment {
+				if el.coreStart < 0 {
+					el.coreStart = i
+				}
+				el.coreEnd = i + 1
+			}
+		}
+		if el.coreStart >= 0 {
+			elems = append(elems, el)
+		}
+		start = end + 1
+	}
+	for i := 1; i < len(tokens)-1; i++ {
+		switch tokens[i].Type {
+		case hclsyntax.TokenOBrack, hclsyntax.TokenOBrace, hclsyntax.TokenOParen, hclsyntax.TokenTemplateInterp, hclsyntax.TokenTemplateControl:
+			depth++
+		case hclsyntax.TokenCBrack, hclsyntax.TokenCBrace, hclsyntax.TokenCParen, hclsyntax.TokenTemplateSeqEnd:
+			depth--
+		case hclsyntax.TokenComma:
+			if depth == 0 {
+				closeElement(i, i)
+			}
+		}
+	}
+	closeElement(len(tokens)-1, -1)
+	return elems, nil
+}
+
+// findParameter returns the index of the element with the given name and
+// location, or -1.
+func findParameter(elems []tupleElement, tokens hclwrite.Tokens, name, in string) int {
+	for i, el := range elems {
+		n, l := parameterKey(tokens[el.coreStart:el.coreEnd])
+		if n == name && l == in {
+			return i
+		}
+	}
+	return -1
+}
+
+// parameterKey returns the literal name and in of a parameter object.
+func parameterKey(tokens hclwrite.Tokens) (name, in string) {
+	expr, diags := hclsyntax.ParseExpression(tokens.Bytes(), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return "", ""
+	}
+	obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return "", ""
+	}
+	for _, item := range obj.Items {
+		key := hcl.ExprAsKeyword(item.KeyExpr)
+		if key != "name" && key != "in" {
+			continue
+		}
+		val, diags := item.ValueExpr.Value(nil)
+		if diags.HasErrors() || val.Type() != cty.String {
+			continue
+		}
+		if key == "name" {
+			name = val.AsString()
+		} else {
+			in = val.AsString()
+		}
+	}
+	return name, in
+}
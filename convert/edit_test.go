@@ -0,0 +1,362 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+)
+
+const editHCL = `arazzo = "1.0.0"
+
+# Document metadata.
+info {
+  title   = "Editing"
+  version = "1.0.0"
+}
+
+sourceDescription "petstore" {
+  url  = "./petstore.yaml"
+  type = "openapi"
+}
+
+workflow "adopt" {
+  # Log in first.
+  step "login" {
+    description = <<EOT
+Logs in the user.
+Keeps the token.
+EOT
+    operationId = "loginUser"
+    parameters = [
+      # The account name.
+      {
+        name  = "username"
+        in    = "query"
+        value = inputs.username
+      },
+      {
+        name  = "password"
+        in    = "query"
+        value = inputs.password
+      }
+    ]
+    outputs = {
+      token = response.body.token
+    }
+    onSuccess {
+      successAction "retry" {
+        type   = "goto"
+        stepId = "login"
+      }
+    }
+  }
+
+  step "list" {
+    operationId    = "findPets" # aligned by hand
+    successCriterion {
+      condition = "$statusCode == 200"
+    }
+    parameters = [
+      {
+        name  = "Authorization"
+        in    = "header"
+        value = "Bearer ${steps.login.outputs.token}"
+      },
+    ]
+  }
+}
+`
+
+func newTestEditor(t *testing.T) *HCLEditor {
+	t.Helper()
+	e, err := NewHCLEditor([]byte(editHCL), "edit.hcl")
+	if err != nil {
+		t.Fatalf("NewHCLEditor failed: %v", err)
+	}
+	return e
+}
+
+// decodeEdited checks that the edited document is still valid and that the
+// untouched comments and layout survived.
+func decodeEdited(t *testing.T, e *HCLEditor) *arazzo1.Arazzo {
+	t.Helper()
+	src := string(e.Bytes())
+	for _, s := range []string{
+		"# Document metadata.",
+		"# Log in first.",
+		"# The account name.",
+		"<<EOT\nLogs in the user.\nKeeps the token.\nEOT\n",
+		`operationId    = "findPets" # aligned by hand`,
+	} {
+		if !strings.Contains(src, s) {
+			t.Errorf("edited document lost %q:\n%s", s, src)
+		}
+	}
+	var doc arazzo1.Arazzo
+	if err := UnmarshalHCL(e.Bytes(), &doc); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v\n%s", err, src)
+	}
+	return &doc
+}
+
+func stepIDs(wf *arazzo1.Workflow) string {
+	var ids []string
+	for _, s := range wf.Steps {
+		ids = append(ids, s.StepId)
+	}
+	return strings.Join(ids, ",")
+}
+
+func parameter(step *arazzo1.Step, name string) map[string]any {
+	for _, p := range step.Parameters {
+		if m, ok := p.(map[string]any); ok && m["name"] == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func TestHCLEditorAddStep(t *testing.T) {
+	e := newTestEditor(t)
+	step := &arazzo1.Step{
+		StepId:      "adopt",
+		OperationId: "adoptPet",
+		Parameters: []any{map[string]any{
+			"name": "token", "in": "header", "value": "$steps.login.outputs.token",
+		}},
+	}
+	if err := e.AddStep("adopt", step, ""); err != nil {
+		t.Fatalf("AddStep failed: %v", err)
+	}
+	if err := e.AddStep("adopt", &arazzo1.Step{StepId: "check", OperationId: "getUser"}, "login"); err != nil {
+		t.Fatalf("AddStep after login failed: %v", err)
+	}
+	src := string(e.Bytes())
+	if !strings.Contains(src, "\n  step \"adopt\" {\n    operationId = \"adoptPet\"\n") {
+		t.Errorf("new step is not indented like its siblings:\n%s", src)
+	}
+	if !strings.Contains(src, "steps.login.outputs.token") {
+		t.Errorf("new step does not use native references:\n%s", src)
+	}
+
+	doc := decodeEdited(t, e)
+	wf := doc.Workflows[0]
+	if got := stepIDs(wf); got != "login,check,list,adopt" {
+		t.Errorf("steps = %s", got)
+	}
+	if p := parameter(wf.Steps[3], "token"); p == nil || p["value"] != "$steps.login.outputs.token" {
+		t.Errorf("new step parameter = %v", p)
+	}
+
+	for _, tt := range []struct {
+		workflow string
+		step     *arazzo1.Step
+		after    string
+	}{
+		{"missing", &arazzo1.Step{StepId: "x"}, ""},
+		{"adopt", &arazzo1.Step{StepId: "login"}, ""},
+		{"adopt", &arazzo1.Step{StepId: "x"}, "missing"},
+		{"adopt", &arazzo1.Step{}, ""},
+	} {
+		if err := e.AddStep(tt.workflow, tt.step, tt.after); err == nil {
+			t.Errorf("AddStep(%q, %q, %q) succeeded", tt.workflow, tt.step.StepId, tt.after)
+		}
+	}
+}
+
+func TestHCLEditorAddStepTypedParameters(t *testing.T) {
+	e := newTestEditor(t)
+	step := &arazzo1.Step{
+		StepId:      "adopt",
+		OperationId: "adoptPet",
+		Parameters: []any{
+			&arazzo1.Parameter{Name: "X-Trace", In: arazzo1.ParameterInHeader, Value: "$steps.login.outputs.token"},
+			arazzo1.ParameterOrReusable{Reusable: &arazzo1.ReusableObject{Reference: "$components.parameters.page"}},
+		},
+	}
+	if err := e.AddStep("adopt", step, ""); err != nil {
+		t.Fatalf("AddStep failed: %v", err)
+	}
+	if _, ok := step.Parameters[0].(*arazzo1.Parameter); !ok {
+		t.Errorf("AddStep changed the parameters of the step: %#v", step.Parameters)
+	}
+	doc := decodeEdited(t, e)
+	added := doc.Workflows[0].Steps[2]
+	if p := parameter(added, "X-Trace"); p == nil || p["in"] != "header" || p["value"] != "$steps.login.outputs.token" {
+		t.Errorf("typed parameter = %v", p)
+	}
+	if len(added.Parameters) != 2 {
+		t.Fatalf("%d parameters, want 2", len(added.Parameters))
+	}
+	if p, ok := added.Parameters[1].(map[string]any); !ok || p["reference"] != "$components.parameters.page" {
+		t.Errorf("reusable parameter = %v", added.Parameters[1])
+	}
+}
+
+func TestHCLEditorRemoveStep(t *testing.T) {
+	e := newTestEditor(t)
+	if err := e.RemoveStep("adopt", "list"); err != nil {
+		t.Fatalf("RemoveStep failed: %v", err)
+	}
+	src := string(e.Bytes())
+	if strings.Contains(src, `step "list"`) || !strings.Contains(src, "# Log in first.") {
+		t.Errorf("unexpected document:\n%s", src)
+	}
+	if err := e.RemoveStep("adopt", "list"); err == nil {
+		t.Error("removing a missing step succeeded")
+	}
+}
+
+func TestHCLEditorSetParameter(t *testing.T) {
+	e := newTestEditor(t)
+	for _, p := range []*arazzo1.Parameter{
+		// Replaces the password parameter in place.
+		{Name: "password", In: "query", Value: "$inputs.secret"},
+		// Appends after an element without a trailing comma.
+		{Name: "remember", In: "query", Value: true},
+	} {
+		if err := e.SetParameter("adopt", "login", p); err != nil {
+			t.Fatalf("SetParameter(%s) failed: %v", p.Name, err)
+		}
+	}
+	// Appends after an element with a trailing comma.
+	if err := e.SetParameter("adopt", "list", &arazzo1.Parameter{Name: "limit", In: "query", Value: 10}); err != nil {
+		t.Fatalf("SetParameter(limit) failed: %v", err)
+	}
+	src := string(e.Bytes())
+	if !strings.Contains(src, "value = inputs.secret") {
+		t.Errorf("password is not a native reference:\n%s", src)
+	}
+
+	doc := decodeEdited(t, e)
+	login, list := doc.Workflows[0].Steps[0], doc.Workflows[0].Steps[1]
+	if len(login.Parameters) != 3 || len(list.Parameters) != 2 {
+		t.Fatalf("parameters = %v, %v", login.Parameters, list.Parameters)
+	}
+	for _, tt := range []struct {
+		step *arazzo1.Step
+		name string
+		want any
+	}{
+		{login, "username", "$inputs.username"},
+		{login, "password", "$inputs.secret"},
+		{login, "remember", true},
+		{list, "Authorization", "Bearer {$steps.login.outputs.token}"},
+		{list, "limit", 10},
+	} {
+		if p := parameter(tt.step, tt.name); p == nil || fmt.Sprint(p["value"]) != fmt.Sprint(tt.want) {
+			t.Errorf("parameter %s = %v, want %v", tt.name, p, tt.want)
+		}
+	}
+	if name, _ := login.Parameters[1].(map[string]any)["name"].(string); name != "password" {
+		t.Errorf("password moved to %v", login.Parameters)
+	}
+}
+
+func TestHCLEditorSetParameterNewAttribute(t *testing.T) {
+	e := newTestEditor(t)
+	if err := e.AddStep("adopt", &arazzo1.Step{StepId: "adopt", OperationId: "adoptPet"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetParameter("adopt", "adopt", &arazzo1.Parameter{Name: "petId", In: "path", Value: "$inputs.petId"}); err != nil {
+		t.Fatalf("SetParameter failed: %v", err)
+	}
+	if !strings.Contains(string(e.Bytes()), "    parameters = [\n      {\n") {
+		t.Errorf("parameters attribute is not indented:\n%s", e.Bytes())
+	}
+	doc := decodeEdited(t, e)
+	if p := parameter(doc.Workflows[0].Steps[2], "petId"); p == nil || p["value"] != "$inputs.petId" {
+		t.Errorf("petId = %v", p)
+	}
+}
+
+func TestHCLEditorRemoveParameter(t *testing.T) {
+	e := newTestEditor(t)
+	if err := e.RemoveParameter("adopt", "login", "password", "query"); err != nil {
+		t.Fatalf("RemoveParameter failed: %v", err)
+	}
+	if err := e.RemoveParameter("adopt", "list", "Authorization", "header"); err != nil {
+		t.Fatalf("RemoveParameter failed: %v", err)
+	}
+	if err := e.RemoveParameter("adopt", "list", "Authorization", "header"); err == nil {
+		t.Error("removing a missing parameter succeeded")
+	}
+	doc := decodeEdited(t, e)
+	login, list := doc.Workflows[0].Steps[0], doc.Workflows[0].Steps[1]
+	if len(login.Parameters) != 1 || parameter(login, "username") == nil {
+		t.Errorf("login parameters = %v", login.Parameters)
+	}
+	if len(list.Parameters) != 0 {
+		t.Errorf("list parameters = %v", list.Parameters)
+	}
+}
+
+func TestHCLEditorRenameStep(t *testing.T) {
+	e := newTestEditor(t)
+	if err := e.RenameStep("adopt", "login", "signIn"); err != nil {
+		t.Fatalf("RenameStep failed: %v", err)
+	}
+	if err := e.RenameStep("adopt", "list", "signIn"); err == nil {
+		t.Error("renaming onto an existing step succeeded")
+	}
+	src := string(e.Bytes())
+	if strings.Contains(src, `"login"`) || strings.Contains(src, ".login.") {
+		t.Errorf("document still refers to login:\n%s", src)
+	}
+	doc := decodeEdited(t, e)
+	wf := doc.Workflows[0]
+	if got := stepIDs(wf); got != "signIn,list" {
+		t.Errorf("steps = %s", got)
+	}
+	if got := wf.Steps[0].OnSuccess[0].SuccessAction.StepId; got != "signIn" {
+		t.Errorf("goto stepId = %q", got)
+	}
+	if p := parameter(wf.Steps[1], "Authorization"); p == nil || p["value"] != "Bearer {$steps.signIn.outputs.token}" {
+		t.Errorf("Authorization = %v", p)
+	}
+}
+
+func TestHCLEditorSetStepAttribute(t *testing.T) {
+	e := newTestEditor(t)
+	if err := e.SetStepAttribute("adopt", "list", "operationId", "$sourceDescriptions.petstore.listPets"); err != nil {
+		t.Fatalf("SetStepAttribute failed: %v", err)
+	}
+	if err := e.SetStepAttribute("adopt", "list", "description", "Lists the pets."); err != nil {
+		t.Fatalf("SetStepAttribute failed: %v", err)
+	}
+	if err := e.SetStepAttribute("adopt", "missing", "description", "x"); err == nil {
+		t.Error("editing a missing step succeeded")
+	}
+	src := string(e.Bytes())
+	if !strings.Contains(src, "operationId    = sourceDescriptions.petstore.listPets # aligned by hand") {
+		t.Errorf("operationId lost its layout:\n%s", src)
+	}
+	var doc arazzo1.Arazzo
+	if err := UnmarshalHCL(e.Bytes(), &doc); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v\n%s", err, src)
+	}
+	list := doc.Workflows[0].Steps[1]
+	if list.OperationId != "$sourceDescriptions.petstore.listPets" || list.Description != "Lists the pets." {
+		t.Errorf("list = %q, %q", list.OperationId, list.Description)
+	}
+}
+
+func TestHCLEditorSetStepAttributeDanglingReference(t *testing.T) {
+	e := newTestEditor(t)
+	if err := e.SetStepAttribute("adopt", "list", "operationId", "$sourceDescriptions.unknown.listPets"); err != nil {
+		t.Fatalf("SetStepAttribute failed: %v", err)
+	}
+	src := string(e.Bytes())
+	if !strings.Contains(src, `"$sourceDescriptions.unknown.listPets"`) {
+		t.Errorf("dangling reference is not quoted:\n%s", src)
+	}
+	var doc arazzo1.Arazzo
+	if err := UnmarshalHCL(e.Bytes(), &doc); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v\n%s", err, src)
+	}
+	if got := doc.Workflows[0].Steps[1].OperationId; got != "$sourceDescriptions.unknown.listPets" {
+		t.Errorf("operationId = %q", got)
+	}
+}
@@ -0,0 +1,319 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"gopkg.in/yaml.v3"
+)
+
+// Format returns an Arazzo document in its canonical layout. The extension of
+// filename selects the format: .json, .hcl, or else YAML; filename also names
+// the document in diagnostics.
+//
+// JSON and YAML documents are decoded and written again with the fields in
+// specification order (arazzo, info, sourceDescriptions, workflows,
+// components, and so on for every object), extensions last and sorted by
+// name, map keys such as outputs and components sorted, and two-space
+// indentation. Fields that are neither in the specification nor extensions
+// are dropped; UnknownFields lists them. YAML documents keep their comments,
+// and the quoting and block style of the strings they keep.
+//
+// HCL documents keep their blocks, comments, locals and variables, which a
+// decode would lose, and are laid out the way terraform fmt lays them out:
+// two-space indentation and aligned equals signs. Only whitespace changes:
+// blocks and attributes are not reordered. Files of a module are formatted
+// on their own.
+func Format(filename string, data []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".hcl":
+		if _, diags := hclsyntax.ParseConfig(data, filename, hcl.InitialPos); diags.HasErrors() {
+			return nil, diags
+		}
+		return hclwrite.Format(data), nil
+	case ".json":
+		var doc arazzo1.Arazzo
+		if err := UnmarshalJSON(data, &doc); err != nil {
+			return nil, err
+		}
+		if err := canonicalParameters(&doc); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(&doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		var doc arazzo1.Arazzo
+		if err := UnmarshalYAML(data, &doc); err != nil {
+			return nil, err
+		}
+		if err := canonicalParameters(&doc); err != nil {
+			return nil, err
+		}
+		return formatYAML(data, &doc)
+	}
+}
+
+// formatYAML returns doc, decoded from data, in the layout of MarshalYAML,
+// with the comments of data and the styles of its scalars.
+func formatYAML(data []byte, doc *arazzo1.Arazzo) ([]byte, error) {
+	out, err := MarshalYAML(doc)
+	if err != nil {
+		return nil, err
+	}
+	var formatted, original yaml.Node
+	if err := yaml.Unmarshal(out, &formatted); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &original); err != nil {
+		return nil, err
+	}
+	// The comment above the first field is that of the document, whatever
+	// field comes first once formatted.
+	head := firstKey(&original)
+	var comment string
+	if head != nil {
+		comment, head.HeadComment = head.HeadComment, ""
+	}
+	keepStyle(&formatted, &original)
+	if head := firstKey(&formatted); head != nil && comment != "" {
+		head.HeadComment = strings.TrimSuffix(comment+"\n"+head.HeadComment, "\n")
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&formatted); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// firstKey returns the first key of a document holding a mapping, or nil.
+func firstKey(doc *yaml.Node) *yaml.Node {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode || len(doc.Content[0].Content) == 0 {
+		return nil
+	}
+	return doc.Content[0].Content[0]
+}
+
+// keepStyle copies to node, formatted, the comments of original, the node
+// at the same place in the document as written, and its quoting or block
+// style when both are the same scalar. Mapping keys match as the decoder
+// matches field names, so comments move with the fields they annotate.
+func keepStyle(node, original *yaml.Node) {
+	for original.Kind == yaml.AliasNode && original.Alias != nil {
+		original = original.Alias
+	}
+	node.HeadComment, node.LineComment, node.FootComment = original.HeadComment, original.LineComment, original.FootComment
+	switch {
+	case node.Kind != original.Kind:
+	case node.Kind == yaml.ScalarNode:
+		if node.Value == original.Value && node.ShortTag() == original.ShortTag() {
+			node.Style = original.Style &^ yaml.FlowStyle
+		}
+	case node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			j := mappingKey(original, node.Content[i].Value)
+			if j >= 0 {
+				keepStyle(node.Content[i], original.Content[j])
+				keepStyle(node.Content[i+1], original.Content[j+1])
+			}
+		}
+	default:
+		for i := 0; i < len(node.Content) && i < len(original.Content); i++ {
+			keepStyle(node.Content[i], original.Content[i])
+		}
+	}
+}
+
+// mappingKey returns the index of key in the content of a mapping, or of a
+// key equal to it but for case, or -1.
+func mappingKey(mapping *yaml.Node, key string) int {
+	found := -1
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		switch k := mapping.Content[i].Value; {
+		case k == key:
+			return i
+		case found < 0 && strings.EqualFold(k, key):
+			found = i
+		}
+	}
+	return found
+}
+
+// UnknownFields returns the fields of a JSON or YAML document that Format
+// drops because they are neither in the specification nor extensions, as
+// JSON Pointers such as /workflows/0/retries. Null and empty values, which
+// carry nothing, are not listed. HCL documents, which Format does not
+// decode, have none.
+func UnknownFields(filename string, data []byte) ([]string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".hcl" {
+		return nil, nil
+	}
+	out, err := Format(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	unmarshal := yaml.Unmarshal
+	if ext == ".json" {
+		unmarshal = json.Unmarshal
+	}
+	var before, after any
+	if err := unmarshal(data, &before); err != nil {
+		return nil, err
+	}
+	if err := unmarshal(out, &after); err != nil {
+		return nil, err
+	}
+	var fields []string
+	missingFields(before, after, "", &fields)
+	return fields, nil
+}
+
+// missingFields appends the paths of the members of before, a decoded
+// document, that are missing from after, the same document formatted. Keys
+// match case-insensitively, as the decoder matches field names.
+func missingFields(before, after any, path string, fields *[]string) {
+	switch b := before.(type) {
+	case map[string]any:
+		a, ok := after.(map[string]any)
+		if !ok {
+			return
+		}
+		keys := make([]string, 0, len(b))
+		for k := range b {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			p := path + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+			v, ok := a[key]
+			for k, av := range a {
+				if !ok && strings.EqualFold(k, key) {
+					v, ok = av, true
+				}
+			}
+			switch {
+			case ok:
+				missingFields(b[key], v, p, fields)
+			case !emptyValue(b[key]):
+				*fields = append(*fields, p)
+			}
+		}
+	case []any:
+		a, _ := after.([]any)
+		for i := 0; i < len(b) && i < len(a); i++ {
+			missingFields(b[i], a[i], path+"/"+strconv.Itoa(i), fields)
+		}
+	}
+}
+
+// emptyValue reports whether v is null, or an empty string, array or
+// object, which formatting omits.
+func emptyValue(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// canonicalParameters gives step parameters, which are decoded as maps, the
+// field order of parameters and reusable objects.
+func canonicalParameters(doc *arazzo1.Arazzo) error {
+	for _, wf := range doc.Workflows {
+		if wf == nil {
+			continue
+		}
+		for _, step := range wf.Steps {
+			if step == nil {
+				continue
+			}
+			for i, p := range step.Parameters {
+				if _, ok := p.(map[string]any); !ok {
+					continue
+				}
+				data, err := json.Marshal(p)
+				if err != nil {
+					return err
+				}
+				var param arazzo1.ParameterOrReusable
+				if err := json.Unmarshal(data, &param); err != nil {
+					return err
+				}
+				step.Parameters[i] = param
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML unmarshals YAML data into an Arazzo document.
+func UnmarshalYAML(yamlData []byte, doc *arazzo1.Arazzo) error {
+	var raw any
+	if err := yaml.Unmarshal(yamlData, &raw); err != nil {
+		return err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return UnmarshalJSON(data, doc)
+}
+
+// MarshalYAML marshals an Arazzo document to YAML format, with the fields in
+// the order MarshalJSON writes them and two-space indentation.
+func MarshalYAML(doc *arazzo1.Arazzo) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, and decoding it into a node keeps the key order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle drops the flow style and quoting of nodes decoded from JSON,
+// leaving the encoder to choose the plain YAML layout.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
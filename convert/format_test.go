@@ -0,0 +1,242 @@
+package convert
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestFormatExamples(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("examples", "1.0.0", "*.arazzo.*"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no examples: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Format(file, data)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if comment := strings.TrimSpace(line); strings.HasPrefix(comment, "#") && !strings.Contains(string(out), comment) {
+				t.Errorf("%s: formatting dropped the comment %q", file, comment)
+			}
+		}
+		again, err := Format(file, out)
+		if err != nil {
+			t.Errorf("%s: formatting the output: %v", file, err)
+			continue
+		}
+		if string(again) != string(out) {
+			t.Errorf("%s: formatting is not idempotent:\n%s\n---\n%s", file, out, again)
+		}
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	// The same document twice, with keys in a different order.
+	inputs := []string{`{
+  "x-team": "pets", "components": {"x-c": 1, "parameters": {"b": {"value": 2, "name": "b"}, "a": {"name": "a", "value": 1}}},
+  "workflows": [{"steps": [{"parameters": [{"value": "$inputs.id", "in": "path", "name": "id"}],
+    "outputs": {"z": "$statusCode", "a": "$url"}, "stepId": "get", "operationId": "getPet"}],
+    "workflowId": "w", "x-a": true}],
+  "sourceDescriptions": [{"url": "pets.yaml", "name": "pets"}],
+  "info": {"version": "1.0.0", "title": "Pets <&>"}, "arazzo": "1.0.0", "x-a": 1}`,
+		`{"arazzo": "1.0.0", "x-a": 1, "x-team": "pets", "info": {"title": "Pets <&>", "version": "1.0.0"},
+  "workflows": [{"workflowId": "w", "x-a": true, "steps": [{"stepId": "get", "operationId": "getPet",
+    "parameters": [{"name": "id", "in": "path", "value": "$inputs.id"}], "outputs": {"a": "$url", "z": "$statusCode"}}]}],
+  "sourceDescriptions": [{"name": "pets", "url": "pets.yaml"}],
+  "components": {"parameters": {"a": {"name": "a", "value": 1}, "b": {"name": "b", "value": 2}}, "x-c": 1}}`,
+	}
+	want := `{
+  "arazzo": "1.0.0",
+  "info": {
+    "title": "Pets <&>",
+    "version": "1.0.0"
+  },
+  "sourceDescriptions": [
+    {
+      "name": "pets",
+      "url": "pets.yaml"
+    }
+  ],
+  "workflows": [
+    {
+      "workflowId": "w",
+      "steps": [
+        {
+          "stepId": "get",
+          "operationId": "getPet",
+          "parameters": [
+            {
+              "name": "id",
+              "in": "path",
+              "value": "$inputs.id"
+            }
+          ],
+          "outputs": {
+            "a": "$url",
+            "z": "$statusCode"
+          }
+        }
+      ],
+      "x-a": true
+    }
+  ],
+  "components": {
+    "parameters": {
+      "a": {
+        "name": "a",
+        "value": 1
+      },
+      "b": {
+        "name": "b",
+        "value": 2
+      }
+    },
+    "x-c": 1
+  },
+  "x-a": 1,
+  "x-team": "pets"
+}
+`
+	for i, input := range inputs {
+		out, err := Format("doc.arazzo.json", []byte(input))
+		if err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+		if string(out) != want {
+			t.Errorf("input %d:\n%s", i, out)
+		}
+	}
+}
+
+func TestFormatYAML(t *testing.T) {
+	input := `# Pet workflows.
+info: {version: 1.0.0, title: Pets}
+arazzo: 1.0.0
+x-note: "multi\nline"
+workflows:
+    # The only workflow.
+    - steps: []
+      workflowId: w # its id
+      outputs: {id: "$inputs.id", flag: "true", name: 'plain'}
+sourceDescriptions: [{name: pets, url: pets.yaml}]
+`
+	want := `# Pet workflows.
+arazzo: 1.0.0
+info:
+  title: Pets
+  version: 1.0.0
+sourceDescriptions:
+  - name: pets
+    url: pets.yaml
+workflows:
+  # The only workflow.
+  - workflowId: w # its id
+    steps: []
+    outputs:
+      flag: "true"
+      id: "$inputs.id"
+      name: 'plain'
+x-note: "multi\nline"
+`
+	out, err := Format("doc.arazzo.yaml", []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Errorf("got:\n%s", out)
+	}
+}
+
+func TestFormatHCL(t *testing.T) {
+	input := `arazzo = "1.0.0"
+# Keep this comment.
+locals {
+  id = "petId"
+}
+workflow "w" {
+step "get" {
+operationId = "getPet"
+    description="Fetch a pet"
+  }
+}
+`
+	want := `arazzo = "1.0.0"
+# Keep this comment.
+locals {
+  id = "petId"
+}
+workflow "w" {
+  step "get" {
+    operationId = "getPet"
+    description = "Fetch a pet"
+  }
+}
+`
+	out, err := Format("doc.arazzo.hcl", []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Errorf("got:\n%s", out)
+	}
+
+	_, err = Format("bad.arazzo.hcl", []byte("workflow \"w\" {\n  step = \n}\n"))
+	var diags hcl.Diagnostics
+	if !errors.As(err, &diags) || diags[0].Subject == nil || diags[0].Subject.Filename != "bad.arazzo.hcl" {
+		t.Errorf("expected diagnostics for bad.arazzo.hcl, got %v", err)
+	}
+	if _, err := Format("bad.arazzo.json", []byte(`{"arazzo": 1}`)); err == nil || !strings.Contains(err.Error(), "arazzo") {
+		t.Errorf("expected a decoding error, got %v", err)
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	input := `{
+  "arazzo": "1.0.0",
+  "Info": {"title": "Pets", "version": "1", "owner": "ann"},
+  "workflows": [{"workflowId": "w", "retries": 3, "notes": null, "x-kept": 1,
+    "steps": [{"stepId": "get", "operationId": "getPet", "requestBody": {"payload": {"extra": true}},
+      "parameters": [{"name": "id", "in": "path", "value": 1, "style": "simple"}], "a/b": {}, "c~d": "e"}]}]
+}`
+	want := []string{
+		"/Info/owner",
+		"/workflows/0/retries",
+		"/workflows/0/steps/0/c~0d",
+		"/workflows/0/steps/0/parameters/0/style",
+	}
+	for _, file := range []string{"doc.arazzo.json", "doc.arazzo.yaml"} {
+		fields, err := UnknownFields(file, []byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(fields, want) {
+			t.Errorf("%s: unknown fields %q, want %q", file, fields, want)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join("examples", "1.0.0", "*.arazzo.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fields, err := UnknownFields(file, data); err != nil || len(fields) != 0 {
+			t.Errorf("%s: unknown fields %q, %v", file, fields, err)
+		}
+	}
+}
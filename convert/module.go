@@ -0,0 +1,443 @@
+package convert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/horizon/dethcl"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ModuleSuffix is the file name suffix of the files of an HCL Arazzo module.
+const ModuleSuffix = ".arazzo.hcl"
+
+// moduleFile is a parsed file of an HCL Arazzo module.
+type moduleFile struct {
+	name string
+	src  []byte
+	body *hclsyntax.Body
+}
+
+// LoadHCLDir loads an HCL Arazzo module: the *.arazzo.hcl files of dir,
+// merged in file name order into one document, e.g. info.arazzo.hcl,
+// sources.arazzo.hcl and a file per workflow. See UnmarshalHCLFiles.
+func LoadHCLDir(dir string, doc *arazzo1.Arazzo) error {
+	names, err := filepath.Glob(filepath.Join(dir, "*"+ModuleSuffix))
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no %s files in %s", ModuleSuffix, dir)
+	}
+	files := make(map[string][]byte, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		files[name] = data
+	}
+	return UnmarshalHCLFiles(files, doc)
+}
+
+// UnmarshalHCLFiles unmarshals the files of an HCL Arazzo module, keyed by
+// file name, into one document. Workflows, source descriptions and
+// components may be defined in any file, and references between files are
+// resolved across the module. Top-level locals and variable blocks define
+// shared values for all files:
+//
+//	variable "api_version" {
+//	  default = "2024-01"
+//	}
+//	locals {
+//	  base_headers = [
+//	    { name = "X-Api-Version", in = "header", value = var.api_version },
+//	  ]
+//	}
+//
+// which are read as local.base_headers and var.api_version. A variable takes
+// its default value. Duplicate definitions are reported as hcl.Diagnostics
+// with the positions of both.
+func UnmarshalHCLFiles(files map[string][]byte, doc *arazzo1.Arazzo) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	module := make([]*moduleFile, 0, len(names))
+	var diags hcl.Diagnostics
+	for _, name := range names {
+		file, d := hclsyntax.ParseConfig(files[name], name, hcl.Pos{Line: 1, Column: 1})
+		diags = append(diags, d...)
+		if body, ok := file.Body.(*hclsyntax.Body); ok && !d.HasErrors() {
+			module = append(module, &moduleFile{name: name, src: files[name], body: body})
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+
+	scope, diags := moduleScope(module)
+	if diags.HasErrors() {
+		return diags
+	}
+	for _, f := range module {
+		src, edits, d := expandReferences(f.src, f.body, scope)
+		if diags = append(diags, d...); d.HasErrors() {
+			continue
+		}
+		target := doc
+		if len(module) > 1 {
+			target = new(arazzo1.Arazzo)
+		}
+		if err := decodeModuleFile(f, src, sourceMap{f.src, edits}, target); err != nil {
+			if d, ok := err.(hcl.Diagnostics); ok {
+				diags = append(diags, d...)
+				continue
+			}
+			return err
+		}
+		if target != doc {
+			mergeArazzo(doc, target)
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+	return nil
+}
+
+// decodeModuleFile decodes a module file whose references have been
+// expanded into src. Workflows and components are decoded from the syntax
+// tree, so that their diagnostics point into the original file through m,
+// and so are extensions; dethcl decodes the rest.
+func decodeModuleFile(f *moduleFile, src []byte, m sourceMap, doc *arazzo1.Arazzo) error {
+	file, diags := hclsyntax.ParseConfig(src, f.name, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return m.relocate(diags)
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return fmt.Errorf("unexpected HCL body type: %T", file.Body)
+	}
+
+	var workflows []*arazzo1.Workflow
+	var components *arazzo1.Components
+	var decoded []edit
+	extensions, d := decodeExtensions(body, &decoded)
+	diags = append(diags, d...)
+	var infoExtensions map[string]any
+	sourceExtensions := map[string]map[string]any{}
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "info":
+			infoExtensions, d = decodeExtensions(block.Body, &decoded)
+			diags = append(diags, d...)
+			continue
+		case "sourceDescription":
+			ext, d := decodeExtensions(block.Body, &decoded)
+			if diags = append(diags, d...); ext != nil && len(block.Labels) > 0 {
+				sourceExtensions[block.Labels[0]] = ext
+			}
+			continue
+		case "workflow":
+			wf := new(arazzo1.Workflow)
+			diags = append(diags, wf.DecodeHCL(block.Body, block.Labels...)...)
+			workflows = append(workflows, wf)
+		case "components":
+			if components == nil {
+				components = new(arazzo1.Components)
+			}
+			diags = append(diags, components.DecodeHCL(block.Body)...)
+		default:
+			continue
+		}
+		decoded = append(decoded, edit{block.Range().Start.Byte, block.Range().End.Byte, ""})
+	}
+	if diags.HasErrors() {
+		return m.relocate(diags)
+	}
+
+	if err := dethcl.Unmarshal(applyEdits(src, decoded), doc); err != nil {
+		if f.name != "" {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		return err
+	}
+	doc.Workflows = workflows
+	if components != nil {
+		doc.Components = components
+	}
+	doc.Extensions = extensions
+	if doc.Info != nil {
+		doc.Info.Extensions = infoExtensions
+	}
+	for _, sd := range doc.SourceDescriptions {
+		if sd != nil && sourceExtensions[sd.Name] != nil {
+			sd.Extensions = sourceExtensions[sd.Name]
+		}
+	}
+	transformArazzoFromHCL(doc)
+	return nil
+}
+
+// moduleScope returns the names and values shared by the files of a module,
+// reporting duplicate definitions.
+func moduleScope(module []*moduleFile) (*referenceScope, hcl.Diagnostics) {
+	scope := &referenceScope{
+		workflows:          map[string]bool{},
+		sourceDescriptions: map[string]bool{},
+		values:             map[string]cty.Value{"local": cty.EmptyObjectVal, "var": cty.EmptyObjectVal},
+	}
+	var diags hcl.Diagnostics
+	defined := map[string]hcl.Range{}
+	define := func(kind, name string, rng hcl.Range) bool {
+		key := kind + "\x00" + name
+		if first, ok := defined[key]; ok {
+			what := kind
+			if name != "" {
+				what = fmt.Sprintf("%s %q", kind, name)
+			}
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate " + kind,
+				Detail:   fmt.Sprintf("The %s was already defined at %s.", what, first),
+				Subject:  rng.Ptr(),
+			})
+			return false
+		}
+		defined[key] = rng
+		return true
+	}
+
+	var variables []*hclsyntax.Block
+	var locals []*hclsyntax.Attribute
+	for _, f := range module {
+		for _, attr := range sortedAttributes(f.body) {
+			define("attribute", attr.Name, attr.NameRange)
+		}
+		for _, block := range f.body.Blocks {
+			label := ""
+			if len(block.Labels) > 0 {
+				label = block.Labels[0]
+			}
+			switch block.Type {
+			case "info":
+				define("info block", "", block.DefRange())
+			case "components":
+				for _, attr := range sortedAttributes(block.Body) {
+					if obj, ok := attr.Expr.(*hclsyntax.ObjectConsExpr); ok && attr.Name == "inputs" {
+						for _, item := range obj.Items {
+							if key, ok := objectKey(item.KeyExpr); ok {
+								define("component input", key, item.KeyExpr.Range())
+							}
+						}
+						continue
+					}
+					define("components attribute", attr.Name, attr.NameRange)
+				}
+				for _, b := range block.Body.Blocks {
+					if len(b.Labels) > 0 {
+						define("component "+b.Type, b.Labels[0], b.DefRange())
+					}
+				}
+			case "workflow":
+				define("workflow", label, block.DefRange())
+				scope.workflows[label] = true
+			case "sourceDescription":
+				define("source description", label, block.DefRange())
+				scope.sourceDescriptions[label] = true
+			case "variable":
+				if len(block.Labels) != 1 {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid variable block",
+						Detail:   `A variable block needs one label, its name, e.g. variable "api_version".`,
+						Subject:  block.DefRange().Ptr(),
+					})
+				} else if define("variable", label, block.DefRange()) {
+					variables = append(variables, block)
+				}
+			case "locals":
+				for _, attr := range sortedAttributes(block.Body) {
+					if define("local value", attr.Name, attr.NameRange) {
+						locals = append(locals, attr)
+					}
+				}
+			}
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	diags = append(diags, evalModuleValues(module, variables, locals, scope)...)
+	return scope, diags
+}
+
+// evalModuleValues evaluates the variables and then the locals of a module
+// into scope.values. Locals may refer to variables and to each other, in any
+// order.
+func evalModuleValues(module []*moduleFile, variables []*hclsyntax.Block, locals []*hclsyntax.Attribute, scope *referenceScope) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	vars := map[string]cty.Value{}
+	for _, block := range variables {
+		attr, ok := block.Body.Attributes["default"]
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing variable default",
+				Detail:   fmt.Sprintf("The variable %q needs a default value.", block.Labels[0]),
+				Subject:  block.DefRange().Ptr(),
+			})
+			continue
+		}
+		v, d := evalModuleExpression(module, attr.Expr, scope)
+		diags = append(diags, d...)
+		vars[block.Labels[0]] = v
+	}
+	if len(vars) > 0 {
+		scope.values["var"] = cty.ObjectVal(vars)
+	}
+
+	names := map[string]bool{}
+	for _, attr := range locals {
+		names[attr.Name] = true
+	}
+	values := map[string]cty.Value{}
+	for len(locals) > 0 {
+		var pending []*hclsyntax.Attribute
+		for _, attr := range locals {
+			if !localsReady(attr.Expr, names, values) {
+				pending = append(pending, attr)
+				continue
+			}
+			v, d := evalModuleExpression(module, attr.Expr, scope)
+			diags = append(diags, d...)
+			values[attr.Name] = v
+			scope.values["local"] = cty.ObjectVal(values)
+		}
+		if len(pending) == len(locals) {
+			for _, attr := range pending {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Circular local value",
+					Detail:   fmt.Sprintf("The local value %q depends on itself.", attr.Name),
+					Subject:  attr.NameRange.Ptr(),
+				})
+			}
+			break
+		}
+		locals = pending
+	}
+	return diags
+}
+
+// localsReady reports whether the locals that expr refers to have been
+// evaluated. References to undefined locals are left for the evaluation to
+// report.
+func localsReady(expr hclsyntax.Expression, names map[string]bool, values map[string]cty.Value) bool {
+	for _, t := range expr.Variables() {
+		if t.RootName() != "local" || len(t) < 2 {
+			continue
+		}
+		if attr, ok := t[1].(hcl.TraverseAttr); ok && names[attr.Name] {
+			if _, done := values[attr.Name]; !done {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// evalModuleExpression evaluates the expression of a module value. Runtime
+// expressions in it are written as quoted strings first, so a local value
+// may hold references such as "Bearer ${inputs.token}".
+func evalModuleExpression(module []*moduleFile, expr hclsyntax.Expression, scope *referenceScope) (cty.Value, hcl.Diagnostics) {
+	rng := expr.Range()
+	var src []byte
+	for _, f := range module {
+		if f.name == rng.Filename {
+			src = f.src
+		}
+	}
+	edits, diags := expandExpression(expr, scope)
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+	for i := range edits {
+		edits[i].start -= rng.Start.Byte
+		edits[i].end -= rng.Start.Byte
+	}
+	text := applyEdits(src[rng.Start.Byte:rng.End.Byte], edits)
+	expanded, diags := hclsyntax.ParseExpression(text, rng.Filename, rng.Start)
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+	return expanded.Value(nil)
+}
+
+// sortedAttributes returns the attributes of body in source order.
+func sortedAttributes(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].NameRange.Start.Byte < attrs[j].NameRange.Start.Byte })
+	return attrs
+}
+
+// objectKey returns the name of an object key written as an identifier or a
+// string.
+func objectKey(expr hclsyntax.Expression) (string, bool) {
+	if name := hcl.ExprAsKeyword(expr); name != "" {
+		return name, true
+	}
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || v.Type() != cty.String || v.IsNull() {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+// mergeArazzo merges a decoded module file into doc. Duplicates have been
+// reported before decoding, so nothing is overwritten.
+func mergeArazzo(doc, part *arazzo1.Arazzo) {
+	if part.Arazzo != "" {
+		doc.Arazzo = part.Arazzo
+	}
+	if part.Info != nil {
+		doc.Info = part.Info
+	}
+	doc.SourceDescriptions = append(doc.SourceDescriptions, part.SourceDescriptions...)
+	doc.Workflows = append(doc.Workflows, part.Workflows...)
+	doc.Extensions = mergeMap(doc.Extensions, part.Extensions)
+	if c := part.Components; c != nil {
+		if doc.Components == nil {
+			doc.Components = new(arazzo1.Components)
+		}
+		doc.Components.Inputs = mergeMap(doc.Components.Inputs, c.Inputs)
+		doc.Components.Parameters = mergeMap(doc.Components.Parameters, c.Parameters)
+		doc.Components.SuccessActions = mergeMap(doc.Components.SuccessActions, c.SuccessActions)
+		doc.Components.FailureActions = mergeMap(doc.Components.FailureActions, c.FailureActions)
+		doc.Components.Extensions = mergeMap(doc.Components.Extensions, c.Extensions)
+	}
+}
+
+func mergeMap[V any](dst, src map[string]V) map[string]V {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]V, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
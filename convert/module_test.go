@@ -0,0 +1,247 @@
+package convert
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/hashicorp/hcl/v2"
+)
+
+var moduleFiles = map[string]string{
+	"info.arazzo.hcl": `
+arazzo = "1.0.0"
+info {
+  title   = "Pet store module"
+  version = var.api_version
+}
+
+variable "api_version" {
+  default = "2024-01"
+}
+
+locals {
+  auth = {
+    name  = "Authorization"
+    in    = "header"
+    value = "Bearer ${workflows.login.outputs.token}"
+  }
+  base_headers = [
+    local.auth,
+    { name = "X-Api-Version", in = "header", value = var.api_version },
+  ]
+}
+`,
+	"sources.arazzo.hcl": `
+sourceDescription "petstore" {
+  url  = "./petstore.yaml"
+  type = "openapi"
+}
+components {
+  parameter "limit" {
+    in    = "query"
+    value = 10
+  }
+}
+`,
+	"login.arazzo.hcl": `
+workflow "login" {
+  outputs = {
+    token = steps.login.outputs.token
+  }
+  step "login" {
+    operationId = sourceDescriptions.petstore.loginUser
+    outputs = {
+      token = response.body.token
+    }
+  }
+}
+`,
+	"pets.arazzo.hcl": `
+workflow "pets" {
+  description = "Lists pets with API ${var.api_version}."
+  step "list" {
+    operationId = "findPets"
+    parameters  = local.base_headers
+  }
+}
+`,
+	// Not part of the module.
+	"notes.hcl": `not = valid = hcl`,
+}
+
+func TestLoadHCLDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range moduleFiles {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var doc arazzo1.Arazzo
+	if err := LoadHCLDir(dir, &doc); err != nil {
+		t.Fatalf("LoadHCLDir failed: %v", err)
+	}
+
+	if doc.Arazzo != "1.0.0" || doc.Info == nil || doc.Info.Version != "2024-01" {
+		t.Errorf("arazzo = %q, info = %+v", doc.Arazzo, doc.Info)
+	}
+	if len(doc.SourceDescriptions) != 1 || doc.SourceDescriptions[0].Name != "petstore" {
+		t.Errorf("source descriptions = %v", doc.SourceDescriptions)
+	}
+	if doc.Components == nil || doc.Components.Parameters["limit"] == nil {
+		t.Errorf("components = %+v", doc.Components)
+	}
+	// Files are merged in name order.
+	if len(doc.Workflows) != 2 || doc.Workflows[0].WorkflowId != "login" || doc.Workflows[1].WorkflowId != "pets" {
+		t.Fatalf("workflows = %v", doc.Workflows)
+	}
+	login, pets := doc.Workflows[0], doc.Workflows[1]
+	if got := login.Steps[0].OperationId; got != "$sourceDescriptions.petstore.loginUser" {
+		t.Errorf("operationId = %q", got)
+	}
+	if pets.Description != "Lists pets with API 2024-01." {
+		t.Errorf("description = %q", pets.Description)
+	}
+	params := pets.Steps[0].Parameters
+	if len(params) != 2 {
+		t.Fatalf("parameters = %v", params)
+	}
+	for i, want := range []string{"Bearer {$workflows.login.outputs.token}", "2024-01"} {
+		if got := params[i].(map[string]any)["value"]; got != want {
+			t.Errorf("parameter %d = %v, want %q", i, got, want)
+		}
+	}
+
+	if err := LoadHCLDir(t.TempDir(), &doc); err == nil {
+		t.Error("loading an empty directory succeeded")
+	}
+}
+
+func TestUnmarshalHCLLocals(t *testing.T) {
+	src := `
+arazzo = "1.0.0"
+locals {
+  header = "X-${local.name}"
+  name   = "Trace"
+}
+workflow "wf" {
+  step "s" {
+    operationId = "op"
+    parameters = [
+      { name = local.header, in = "header", value = inputs.trace },
+    ]
+  }
+}
+`
+	var doc arazzo1.Arazzo
+	if err := UnmarshalHCL([]byte(src), &doc); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v", err)
+	}
+	p := doc.Workflows[0].Steps[0].Parameters[0].(map[string]any)
+	if p["name"] != "X-Trace" || p["value"] != "$inputs.trace" {
+		t.Errorf("parameter = %v", p)
+	}
+}
+
+func TestUnmarshalHCLFilesErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		files  map[string]string
+		detail string
+		file   string
+	}{
+		{
+			name: "duplicate workflow",
+			files: map[string]string{
+				"a.arazzo.hcl": "workflow \"wf\" {\n}\n",
+				"b.arazzo.hcl": "\nworkflow \"wf\" {\n}\n",
+			},
+			detail: `The workflow "wf" was already defined at a.arazzo.hcl:1,1-14.`,
+			file:   "b.arazzo.hcl",
+		},
+		{
+			name: "duplicate component",
+			files: map[string]string{
+				"a.arazzo.hcl": "components {\n  parameter \"p\" {\n    value = 1\n  }\n}\n",
+				"b.arazzo.hcl": "components {\n  parameter \"p\" {\n    value = 2\n  }\n}\n",
+			},
+			detail: `The component parameter "p" was already defined at a.arazzo.hcl:2,3-16.`,
+			file:   "b.arazzo.hcl",
+		},
+		{
+			name: "duplicate component input",
+			files: map[string]string{
+				"a.arazzo.hcl": "components {\n  inputs = { creds = {} }\n}\n",
+				"b.arazzo.hcl": "components {\n  inputs = { creds = {} }\n}\n",
+			},
+			detail: `The component input "creds" was already defined at a.arazzo.hcl`,
+			file:   "b.arazzo.hcl",
+		},
+		{
+			name: "duplicate info",
+			files: map[string]string{
+				"a.arazzo.hcl": "info {\n  title = \"a\"\n}\n",
+				"b.arazzo.hcl": "info {\n  title = \"b\"\n}\n",
+			},
+			detail: "The info block was already defined at a.arazzo.hcl",
+			file:   "b.arazzo.hcl",
+		},
+		{
+			name: "duplicate local",
+			files: map[string]string{
+				"a.arazzo.hcl": "locals {\n  x = 1\n}\n",
+				"b.arazzo.hcl": "locals {\n  x = 2\n}\n",
+			},
+			detail: `The local value "x" was already defined at a.arazzo.hcl`,
+			file:   "b.arazzo.hcl",
+		},
+		{
+			name:   "circular locals",
+			files:  map[string]string{"a.arazzo.hcl": "locals {\n  x = local.y\n  y = local.x\n}\n"},
+			detail: `The local value "x" depends on itself.`,
+			file:   "a.arazzo.hcl",
+		},
+		{
+			name:   "undefined local",
+			files:  map[string]string{"a.arazzo.hcl": "workflow \"wf\" {\n  summary = local.missing\n}\n"},
+			detail: `This object does not have an attribute named "missing".`,
+			file:   "a.arazzo.hcl",
+		},
+		{
+			name:   "missing default",
+			files:  map[string]string{"a.arazzo.hcl": "variable \"v\" {\n}\n"},
+			detail: `The variable "v" needs a default value.`,
+			file:   "a.arazzo.hcl",
+		},
+		{
+			name: "undefined cross-file reference",
+			files: map[string]string{
+				"a.arazzo.hcl": "sourceDescription \"api\" {\n  url = \"api.yaml\"\n}\n",
+				"b.arazzo.hcl": "workflow \"wf\" {\n  step \"s\" {\n    operationId = sourceDescriptions.other.op\n  }\n}\n",
+			},
+			detail: `There is no source description "other".`,
+			file:   "b.arazzo.hcl",
+		},
+	} {
+		files := map[string][]byte{}
+		for name, src := range tt.files {
+			files[name] = []byte(src)
+		}
+		var doc arazzo1.Arazzo
+		err := UnmarshalHCLFiles(files, &doc)
+		var diags hcl.Diagnostics
+		if !errors.As(err, &diags) || len(diags) == 0 {
+			t.Errorf("%s: expected hcl.Diagnostics, got %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(diags[0].Detail, tt.detail) {
+			t.Errorf("%s: detail = %q, want %q", tt.name, diags[0].Detail, tt.detail)
+		}
+		if diags[0].Subject == nil || diags[0].Subject.Filename != tt.file {
+			t.Errorf("%s: subject = %v, want %s", tt.name, diags[0].Subject, tt.file)
+		}
+	}
+}
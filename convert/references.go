@@ -0,0 +1,603 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyconvert "github.com/zclconf/go-cty/cty/convert"
+)
+
+// runtimeRoots are the roots of Arazzo runtime expressions. In HCL they are
+// written as traversals, e.g. steps.login.outputs.token for
+// $steps.login.outputs.token.
+var runtimeRoots = map[string]bool{
+	"url":                true,
+	"method":             true,
+	"statusCode":         true,
+	"self":               true,
+	"request":            true,
+	"response":           true,
+	"message":            true,
+	"inputs":             true,
+	"outputs":            true,
+	"steps":              true,
+	"workflows":          true,
+	"sourceDescriptions": true,
+	"components":         true,
+}
+
+// bareRoots are the runtime expressions without any segment.
+var bareRoots = map[string]bool{"url": true, "method": true, "statusCode": true, "self": true}
+
+// edit replaces the bytes [start, end) of a source with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// applyEdits applies non-overlapping edits to src.
+func applyEdits(src []byte, edits []edit) []byte {
+	if len(edits) == 0 {
+		return src
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		b.Write(src[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.Write(src[last:])
+	return []byte(b.String())
+}
+
+// moduleRoots are the roots of the module values, local.name and var.name,
+// which are replaced by their values when a module is loaded.
+var moduleRoots = map[string]bool{"local": true, "var": true}
+
+// referenceScope holds the names that references of a workflow may use.
+type referenceScope struct {
+	steps              map[string]bool // nil outside a workflow
+	workflows          map[string]bool
+	sourceDescriptions map[string]bool
+	values             map[string]cty.Value // local and var objects of the module
+}
+
+// sourceMap maps positions in a source rewritten by edits back to the
+// original source, so that diagnostics point at what the user wrote.
+type sourceMap struct {
+	src   []byte
+	edits []edit // sorted by start
+}
+
+// offset returns the original offset of an offset in the rewritten source.
+// Offsets inside a replacement map to its start, or to its end for the end
+// of a range.
+func (m sourceMap) offset(off int, end bool) int {
+	delta := 0
+	for _, e := range m.edits {
+		start := e.start + delta
+		if off < start {
+			break
+		}
+		if off < start+len(e.text) {
+			if end {
+				return e.end
+			}
+			return e.start
+		}
+		delta += len(e.text) - (e.end - e.start)
+	}
+	return off - delta
+}
+
+// pos returns the position of an original offset.
+func (m sourceMap) pos(off int) hcl.Pos {
+	if off > len(m.src) {
+		off = len(m.src)
+	}
+	line := 1 + bytes.Count(m.src[:off], []byte("\n"))
+	lineStart := bytes.LastIndexByte(m.src[:off], '\n') + 1
+	return hcl.Pos{Line: line, Column: 1 + utf8.RuneCount(m.src[lineStart:off]), Byte: off}
+}
+
+func (m sourceMap) rng(r *hcl.Range) *hcl.Range {
+	if r == nil {
+		return nil
+	}
+	return &hcl.Range{
+		Filename: r.Filename,
+		Start:    m.pos(m.offset(r.Start.Byte, false)),
+		End:      m.pos(m.offset(r.End.Byte, true)),
+	}
+}
+
+// relocate returns diags with their ranges in the original source.
+func (m sourceMap) relocate(diags hcl.Diagnostics) hcl.Diagnostics {
+	out := make(hcl.Diagnostics, len(diags))
+	for i, d := range diags {
+		moved := *d
+		moved.Subject = m.rng(d.Subject)
+		moved.Context = m.rng(d.Context)
+		out[i] = &moved
+	}
+	return out
+}
+
+// expandReferences rewrites the native references of an HCL Arazzo file as
+// quoted runtime expressions, so that it decodes like the quoted form: a
+// traversal such as steps.login.outputs.token becomes
+// "$steps.login.outputs.token", and a template such as
+// "Bearer ${steps.login.outputs.token}" becomes
+// "Bearer {$steps.login.outputs.token}". Module values are replaced by their
+// values, and the locals and variable blocks defining them are removed.
+// References to unknown roots, steps, workflows or source descriptions are
+// reported as diagnostics. The edits made are returned for mapping positions
+// back.
+func expandReferences(src []byte, body *hclsyntax.Body, scope *referenceScope) ([]byte, []edit, hcl.Diagnostics) {
+	var edits []edit
+	var diags hcl.Diagnostics
+	var walk func(body *hclsyntax.Body, scope *referenceScope, top bool)
+	walk = func(body *hclsyntax.Body, scope *referenceScope, top bool) {
+		for _, attr := range body.Attributes {
+			e, d := expandExpression(attr.Expr, scope)
+			edits = append(edits, e...)
+			diags = append(diags, d...)
+		}
+		for _, block := range body.Blocks {
+			inner := scope
+			switch block.Type {
+			case "locals", "variable":
+				if top {
+					edits = append(edits, edit{block.Range().Start.Byte, block.Range().End.Byte, ""})
+					continue
+				}
+			case "workflow":
+				inner = &referenceScope{steps: map[string]bool{}, workflows: scope.workflows, sourceDescriptions: scope.sourceDescriptions, values: scope.values}
+				for _, b := range block.Body.Blocks {
+					if b.Type == "step" && len(b.Labels) > 0 {
+						inner.steps[b.Labels[0]] = true
+					}
+				}
+			}
+			walk(block.Body, inner, false)
+		}
+	}
+	walk(body, scope, true)
+
+	if diags.HasErrors() {
+		return nil, nil, diags
+	}
+	return applyEdits(src, edits), edits, diags
+}
+
+// expandExpression returns the edits rewriting the references of expr.
+func expandExpression(expr hclsyntax.Expression, scope *referenceScope) ([]edit, hcl.Diagnostics) {
+	var edits []edit
+	var diags hcl.Diagnostics
+	// Object keys are names, not references; and the parts of a rewritten
+	// template are not rewritten again.
+	var skip []hcl.Range
+	skipped := func(r hcl.Range) bool {
+		for _, s := range skip {
+			if r.Start.Byte >= s.Start.Byte && r.End.Byte <= s.End.Byte {
+				return true
+			}
+		}
+		return false
+	}
+
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		if skipped(node.Range()) {
+			return nil
+		}
+		switch n := node.(type) {
+		case *hclsyntax.ObjectConsKeyExpr:
+			skip = append(skip, n.Range())
+		case *hclsyntax.ScopeTraversalExpr:
+			if moduleRoots[n.Traversal.RootName()] {
+				v, d := n.Traversal.TraverseAbs(scope.context())
+				diags = append(diags, d...)
+				if !d.HasErrors() {
+					edits = append(edits, edit{n.SrcRange.Start.Byte, n.SrcRange.End.Byte, valueHCL(v)})
+				}
+				skip = append(skip, n.Range())
+				return nil
+			}
+			s, d := referenceExpression(n.Traversal, scope)
+			diags = append(diags, d...)
+			if !d.HasErrors() {
+				edits = append(edits, edit{n.SrcRange.Start.Byte, n.SrcRange.End.Byte, quoteHCL(s)})
+			}
+			skip = append(skip, n.Range())
+		case *hclsyntax.TemplateWrapExpr:
+			if t, ok := n.Wrapped.(*hclsyntax.ScopeTraversalExpr); ok && moduleRoots[t.Traversal.RootName()] {
+				// "${local.name}" is the value itself.
+				v, d := t.Traversal.TraverseAbs(scope.context())
+				diags = append(diags, d...)
+				if !d.HasErrors() {
+					edits = append(edits, edit{n.SrcRange.Start.Byte, n.SrcRange.End.Byte, valueHCL(v)})
+				}
+				skip = append(skip, n.Range())
+			} else if ok {
+				s, d := referenceExpression(t.Traversal, scope)
+				diags = append(diags, d...)
+				if !d.HasErrors() {
+					edits = append(edits, edit{n.SrcRange.Start.Byte, n.SrcRange.End.Byte, quoteHCL("{" + s + "}")})
+				}
+				skip = append(skip, n.Range())
+			}
+		case *hclsyntax.TemplateExpr:
+			if n.IsStringLiteral() {
+				return nil
+			}
+			var b strings.Builder
+			ok := true
+			for _, part := range n.Parts {
+				switch p := part.(type) {
+				case *hclsyntax.LiteralValueExpr:
+					b.WriteString(p.Val.AsString())
+				case *hclsyntax.ScopeTraversalExpr:
+					if moduleRoots[p.Traversal.RootName()] {
+						s, d := moduleString(p.Traversal, scope)
+						diags = append(diags, d...)
+						ok = ok && !d.HasErrors()
+						b.WriteString(s)
+						continue
+					}
+					s, d := referenceExpression(p.Traversal, scope)
+					diags = append(diags, d...)
+					ok = ok && !d.HasErrors()
+					b.WriteString("{" + s + "}")
+				default:
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Unsupported template",
+						Detail:   "Only references to runtime values can be interpolated in Arazzo strings.",
+						Subject:  part.Range().Ptr(),
+					})
+					ok = false
+				}
+			}
+			if ok {
+				edits = append(edits, edit{n.SrcRange.Start.Byte, n.SrcRange.End.Byte, quoteHCL(b.String())})
+			}
+			skip = append(skip, n.Range())
+		}
+		return nil
+	})
+	return edits, diags
+}
+
+// context returns the evaluation context of the module values.
+func (s *referenceScope) context() *hcl.EvalContext {
+	return &hcl.EvalContext{Variables: s.values}
+}
+
+// valueHCL returns the HCL expression of a module value.
+func valueHCL(v cty.Value) string {
+	return string(hclwrite.TokensForValue(v).Bytes())
+}
+
+// moduleString returns the value of a module reference interpolated in a
+// string.
+func moduleString(t hcl.Traversal, scope *referenceScope) (string, hcl.Diagnostics) {
+	v, diags := t.TraverseAbs(scope.context())
+	if diags.HasErrors() {
+		return "", diags
+	}
+	str, err := ctyconvert.Convert(v, cty.String)
+	if err != nil || str.IsNull() {
+		return "", hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid template interpolation value",
+			Detail:   fmt.Sprintf("Only strings, numbers and bools can be interpolated, not %s.", v.Type().FriendlyName()),
+			Subject:  t.SourceRange().Ptr(),
+		}}
+	}
+	return str.AsString(), nil
+}
+
+// referenceExpression returns the runtime expression of a reference. The
+// segments after request.body or response.body form a JSON pointer, e.g.
+// response.body.items[0].id is $response.body#/items/0/id.
+func referenceExpression(t hcl.Traversal, scope *referenceScope) (string, hcl.Diagnostics) {
+	root := t.RootName()
+	rng := t.SourceRange()
+	if !runtimeRoots[root] {
+		return "", hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Undefined reference",
+			Detail:   fmt.Sprintf("%q is not a runtime expression; references start with inputs, outputs, steps, workflows, sourceDescriptions, components, request, response, url, method or statusCode.", root),
+			Subject:  &rng,
+		}}
+	}
+
+	segments := make([]string, 0, len(t)-1)
+	for _, step := range t[1:] {
+		switch s := step.(type) {
+		case hcl.TraverseAttr:
+			segments = append(segments, s.Name)
+		case hcl.TraverseIndex:
+			key := s.Key
+			switch {
+			case key.Type() == cty.String:
+				segments = append(segments, key.AsString())
+			case key.Type() == cty.Number:
+				segments = append(segments, key.AsBigFloat().Text('f', -1))
+			default:
+				return "", hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid reference",
+					Detail:   "Reference keys must be strings or numbers.",
+					Subject:  s.SrcRange.Ptr(),
+				}}
+			}
+		default:
+			return "", hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid reference",
+				Detail:   "Splat and other traversals are not runtime expressions.",
+				Subject:  step.SourceRange().Ptr(),
+			}}
+		}
+	}
+
+	if bareRoots[root] != (len(segments) == 0) {
+		detail := fmt.Sprintf("%s takes no attributes.", root)
+		if !bareRoots[root] {
+			detail = fmt.Sprintf("%s needs at least one attribute, e.g. %s.name.", root, root)
+		}
+		return "", hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Invalid reference", Detail: detail, Subject: &rng}}
+	}
+	if len(segments) > 0 {
+		var names map[string]bool
+		var kind string
+		switch root {
+		case "steps":
+			names, kind = scope.steps, "step"
+		case "workflows":
+			names, kind = scope.workflows, "workflow"
+		case "sourceDescriptions":
+			names, kind = scope.sourceDescriptions, "source description"
+		}
+		if names != nil && !names[segments[0]] {
+			return "", hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Undefined reference",
+				Detail:   fmt.Sprintf("There is no %s %q.", kind, segments[0]),
+				Subject:  &rng,
+			}}
+		}
+	}
+
+	s := "$" + root
+	if (root == "request" || root == "response") && len(segments) > 1 && segments[0] == "body" {
+		pointer := make([]string, len(segments)-1)
+		for i, seg := range segments[1:] {
+			pointer[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(seg)
+		}
+		return s + ".body#/" + strings.Join(pointer, "/"), nil
+	}
+	for _, seg := range segments {
+		s += "." + seg
+	}
+	return s, nil
+}
+
+// nativeReferences rewrites the quoted runtime expressions of an HCL Arazzo
+// document in the native syntax that expandReferences reads back: a value
+// "$steps.login.outputs.token" becomes steps.login.outputs.token, and
+// "Bearer {$steps.login.outputs.token}" becomes
+// "Bearer ${steps.login.outputs.token}". Other strings are left as written,
+// and so are references expandReferences would reject: to a step that is not
+// in the workflow, or to an unknown workflow or source description.
+//
+// outer, which may be nil, holds the names src is written among, as when it
+// is a step or parameter added to a workflow: its workflows and source
+// descriptions then replace those of src, and its steps are added to those
+// of each workflow.
+func nativeReferences(src []byte, outer *referenceScope) ([]byte, error) {
+	file, diags := hclsyntax.ParseConfig(src, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return src, nil
+	}
+
+	top := &referenceScope{workflows: map[string]bool{}, sourceDescriptions: map[string]bool{}}
+	if outer != nil {
+		maps.Copy(top.workflows, outer.workflows)
+		maps.Copy(top.sourceDescriptions, outer.sourceDescriptions)
+		top.steps = outer.steps
+	} else {
+		for _, block := range body.Blocks {
+			if len(block.Labels) == 0 {
+				continue
+			}
+			switch block.Type {
+			case "workflow":
+				top.workflows[block.Labels[0]] = true
+			case "sourceDescription":
+				top.sourceDescriptions[block.Labels[0]] = true
+			}
+		}
+	}
+
+	var edits []edit
+	var walk func(body *hclsyntax.Body, scope *referenceScope)
+	walk = func(body *hclsyntax.Body, scope *referenceScope) {
+		for _, attr := range body.Attributes {
+			var keys []hcl.Range
+			hclsyntax.VisitAll(attr.Expr, func(node hclsyntax.Node) hcl.Diagnostics {
+				switch n := node.(type) {
+				case *hclsyntax.ObjectConsKeyExpr:
+					keys = append(keys, n.Range())
+				case *hclsyntax.TemplateExpr:
+					for _, k := range keys {
+						if n.SrcRange.Start.Byte >= k.Start.Byte && n.SrcRange.End.Byte <= k.End.Byte {
+							return nil
+						}
+					}
+					if !n.IsStringLiteral() || len(n.Parts) != 1 {
+						return nil
+					}
+					lit, ok := n.Parts[0].(*hclsyntax.LiteralValueExpr)
+					if !ok {
+						return nil
+					}
+					if text, ok := nativeString(lit.Val.AsString(), scope); ok {
+						edits = append(edits, edit{n.SrcRange.Start.Byte, n.SrcRange.End.Byte, text})
+					}
+				}
+				return nil
+			})
+		}
+		for _, block := range body.Blocks {
+			inner := scope
+			if block.Type == "workflow" && scope == top {
+				inner = &referenceScope{steps: map[string]bool{}, workflows: top.workflows, sourceDescriptions: top.sourceDescriptions}
+				maps.Copy(inner.steps, top.steps)
+				for _, b := range block.Body.Blocks {
+					if b.Type == "step" && len(b.Labels) > 0 {
+						inner.steps[b.Labels[0]] = true
+					}
+				}
+			}
+			walk(block.Body, inner)
+		}
+	}
+	walk(body, top)
+	return applyEdits(src, edits), nil
+}
+
+// nativeString returns the native HCL for a string: a traversal if it is a
+// runtime expression, or a template if it embeds runtime expressions in
+// braces. It reports false if the string has no such form, or refers to a
+// name scope does not define; a nil scope defines every name.
+func nativeString(s string, scope *referenceScope) (string, bool) {
+	if scope == nil {
+		scope = &referenceScope{}
+	}
+	if t, ok := nativeTraversal(s, scope); ok {
+		return t, true
+	}
+	if !strings.Contains(s, "{$") {
+		return "", false
+	}
+	var b strings.Builder
+	b.WriteString(`"`)
+	rest := s
+	for {
+		i := strings.Index(rest, "{$")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(rest[i:], "}")
+		if j < 0 {
+			return "", false
+		}
+		t, ok := nativeTraversal(rest[i+1:i+j], scope)
+		if !ok {
+			return "", false
+		}
+		b.WriteString(escapeTemplate(rest[:i]))
+		b.WriteString("${" + t + "}")
+		rest = rest[i+j+1:]
+	}
+	b.WriteString(escapeTemplate(rest))
+	b.WriteString(`"`)
+	return b.String(), true
+}
+
+// nativeTraversal returns the traversal of a runtime expression. It reports
+// false unless the traversal reads back in scope as exactly the same
+// expression.
+func nativeTraversal(s string, scope *referenceScope) (string, bool) {
+	if !strings.HasPrefix(s, "$") {
+		return "", false
+	}
+	expr, pointer, hasPointer := strings.Cut(s[1:], "#")
+	segments := strings.Split(expr, ".")
+	if !runtimeRoots[segments[0]] {
+		return "", false
+	}
+	if hasPointer {
+		if len(segments) != 2 || segments[1] != "body" || !strings.HasPrefix(pointer, "/") {
+			return "", false
+		}
+		for _, token := range strings.Split(pointer[1:], "/") {
+			segments = append(segments, strings.NewReplacer("~1", "/", "~0", "~").Replace(token))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(segments[0])
+	for _, seg := range segments[1:] {
+		if hclsyntax.ValidIdentifier(seg) {
+			b.WriteString("." + seg)
+		} else if _, err := strconv.ParseUint(seg, 10, 32); err == nil && (seg == "0" || seg[0] != '0') {
+			b.WriteString("[" + seg + "]")
+		} else {
+			b.WriteString("[" + quoteHCL(seg) + "]")
+		}
+	}
+
+	// Check that the traversal reads back unchanged, e.g. that the pointer
+	// had no invalid escapes.
+	t, diags := hclsyntax.ParseTraversalAbs([]byte(b.String()), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return "", false
+	}
+	back, diags := referenceExpression(t, scope)
+	if diags.HasErrors() || back != s {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// escapeTemplate escapes s as the literal part of a quoted HCL template.
+func escapeTemplate(s string) string {
+	q := quoteHCL(s)
+	return q[1 : len(q)-1]
+}
+
+// quoteHCL quotes s as an HCL string literal, escaping template sequences.
+func quoteHCL(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteString(`\` + string(r))
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		case (r == '{') && i > 0 && (s[i-1] == '$' || s[i-1] == '%'):
+			// Double the marker written before, so ${ and %{ stay literal.
+			b.WriteByte(s[i-1])
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
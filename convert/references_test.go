@@ -0,0 +1,274 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/hashicorp/hcl/v2"
+)
+
+const nativeHCL = `
+arazzo = "1.0.0"
+info {
+  title   = "Native references"
+  version = "1.0.0"
+}
+sourceDescription "petstore" {
+  url  = "./petstore.yaml"
+  type = "openapi"
+}
+workflow "login-and-list" {
+  outputs = {
+    token = steps.login.outputs.token
+  }
+  step "login" {
+    operationId = sourceDescriptions.petstore.loginUser
+    parameters = [
+      {
+        name  = "username"
+        in    = "query"
+        value = inputs.username
+      }
+    ]
+    outputs = {
+      token    = response.body.token
+      first    = response.body.items[0]["a/b"]
+      location = response.header.Location
+      status   = statusCode
+    }
+  }
+  step "list" {
+    operationId = "findPets"
+    parameters = [
+      {
+        name  = "Authorization"
+        in    = "header"
+        value = "Bearer ${steps.login.outputs.token}"
+      },
+      {
+        name  = "X-Trace"
+        in    = "header"
+        value = "${inputs.trace}"
+      }
+    ]
+  }
+}
+`
+
+func TestUnmarshalHCLNativeReferences(t *testing.T) {
+	var doc arazzo1.Arazzo
+	if err := UnmarshalHCL([]byte(nativeHCL), &doc); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v", err)
+	}
+	wf := doc.Workflows[0]
+	if got := wf.Outputs["token"]; got != "$steps.login.outputs.token" {
+		t.Errorf("workflow output = %q", got)
+	}
+	login := wf.Steps[0]
+	if login.OperationId != "$sourceDescriptions.petstore.loginUser" {
+		t.Errorf("operationId = %q", login.OperationId)
+	}
+	for name, want := range map[string]string{
+		"token":    "$response.body#/token",
+		"first":    "$response.body#/items/0/a~1b",
+		"location": "$response.header.Location",
+		"status":   "$statusCode",
+	} {
+		if got := login.Outputs[name]; got != want {
+			t.Errorf("output %s = %q, want %q", name, got, want)
+		}
+	}
+	for i, want := range []string{"$inputs.username"} {
+		if got := login.Parameters[i].(map[string]any)["value"]; got != want {
+			t.Errorf("login parameter %d = %v, want %q", i, got, want)
+		}
+	}
+	for i, want := range []string{"Bearer {$steps.login.outputs.token}", "{$inputs.trace}"} {
+		if got := wf.Steps[1].Parameters[i].(map[string]any)["value"]; got != want {
+			t.Errorf("list parameter %d = %v, want %q", i, got, want)
+		}
+	}
+}
+
+func TestUnmarshalHCLUndefinedReferences(t *testing.T) {
+	for _, tt := range []struct {
+		value  string
+		detail string
+	}{
+		{"foo.bar", `"foo" is not a runtime expression`},
+		{"steps.missing.outputs.id", `There is no step "missing"`},
+		{"workflows.other.outputs.id", `There is no workflow "other"`},
+		{"sourceDescriptions.ledger.getEntry", `There is no source description "ledger"`},
+		{`"Bearer ${upper(inputs.token)}"`, "Only references to runtime values"},
+		{"inputs", "inputs needs at least one attribute"},
+	} {
+		src := `
+workflow "wf" {
+  step "s" {
+    operationId = "op"
+    outputs = {
+      value = ` + tt.value + `
+    }
+  }
+}
+`
+		var doc arazzo1.Arazzo
+		err := UnmarshalHCL([]byte(src), &doc)
+		var diags hcl.Diagnostics
+		if !errors.As(err, &diags) || len(diags) == 0 {
+			t.Errorf("%s: expected hcl.Diagnostics, got %v", tt.value, err)
+			continue
+		}
+		if !strings.Contains(diags[0].Detail, tt.detail) {
+			t.Errorf("%s: detail = %q, want %q", tt.value, diags[0].Detail, tt.detail)
+		}
+		if diags[0].Subject == nil || diags[0].Subject.Start.Line != 6 {
+			t.Errorf("%s: subject = %v, want line 6", tt.value, diags[0].Subject)
+		}
+	}
+}
+
+func TestNativeString(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+	}{
+		{"$inputs.username", "inputs.username"},
+		{"$steps.do-the-refresh.outputs.token", "steps.do-the-refresh.outputs.token"},
+		{"$response.body#/items/0/a~1b", `response.body.items[0]["a/b"]`},
+		{"$response.body#/items/01", `response.body.items["01"]`},
+		{"$request.header.X-Trace-Id", "request.header.X-Trace-Id"},
+		{"$statusCode", "statusCode"},
+		{"$inputs.first.name", "inputs.first.name"},
+		{"Bearer {$steps.login.outputs.token}", `"Bearer ${steps.login.outputs.token}"`},
+		{"{$inputs.a}-${b}-{$inputs.c}", `"${inputs.a}-$${b}-${inputs.c}"`},
+		{"$steps.login", "steps.login"},
+		{"$inputs.", `inputs[""]`},
+		// Strings without a native form stay quoted.
+		{"$statusCode == 200", ""},
+		{"$response.body#/a~2b", ""},
+		{"$unknown.value", ""},
+		{"{$inputs.a", ""},
+		{"plain", ""},
+	} {
+		got, ok := nativeString(tt.in, nil)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("nativeString(%q) = %q, %v; want %q", tt.in, got, ok, tt.want)
+		}
+	}
+}
+
+func TestMarshalHCLNativeReferences(t *testing.T) {
+	doc := &arazzo1.Arazzo{
+		Arazzo:             "1.0.0",
+		Info:               &arazzo1.Info{Title: "Native", Version: "1.0.0"},
+		SourceDescriptions: []*arazzo1.SourceDescription{{Name: "api", URL: "api.yaml", Type: "openapi"}},
+		Workflows: []*arazzo1.Workflow{{
+			WorkflowId: "wf",
+			Outputs:    map[string]string{"id": "$steps.create.outputs.id"},
+			Steps: []*arazzo1.Step{{
+				StepId:      "create",
+				OperationId: "$sourceDescriptions.api.createPet",
+				Parameters: []any{map[string]any{
+					"name": "Authorization", "in": "header", "value": "Bearer {$inputs.token}",
+				}},
+				SuccessCriteria: []*arazzo1.Criterion{{Condition: "$statusCode == 201"}},
+				Outputs:         map[string]string{"id": "$response.body#/id"},
+			}},
+		}},
+	}
+	want, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hclData, err := MarshalHCL(doc)
+	if err != nil {
+		t.Fatalf("MarshalHCL failed: %v", err)
+	}
+	src := string(hclData)
+	for _, s := range []string{
+		"steps.create.outputs.id",
+		"operationId = sourceDescriptions.api.createPet",
+		`"Bearer ${inputs.token}"`,
+		`"$statusCode == 201"`,
+		"response.body.id",
+	} {
+		if !strings.Contains(src, s) {
+			t.Errorf("HCL missing %s:\n%s", s, src)
+		}
+	}
+
+	var back arazzo1.Arazzo
+	if err := UnmarshalHCL(hclData, &back); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v", err)
+	}
+	got, err := json.Marshal(&back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("round trip changed the document:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestMarshalHCLDanglingReferences(t *testing.T) {
+	doc := &arazzo1.Arazzo{
+		Arazzo:             "1.0.0",
+		Info:               &arazzo1.Info{Title: "Dangling", Version: "1.0.0"},
+		SourceDescriptions: []*arazzo1.SourceDescription{{Name: "api", URL: "api.yaml", Type: "openapi"}},
+		Workflows: []*arazzo1.Workflow{{
+			WorkflowId: "wf",
+			Outputs: map[string]string{
+				"id":      "$steps.create.outputs.id",
+				"missing": "$steps.missing.outputs.x",
+				"other":   "$workflows.nope.outputs.y",
+				"self":    "$workflows.wf.outputs.id",
+			},
+			Steps: []*arazzo1.Step{{
+				StepId:      "create",
+				OperationId: "$sourceDescriptions.unknown.createPet",
+				Parameters: []any{map[string]any{
+					"name": "Authorization", "in": "header", "value": "Bearer {$steps.missing.outputs.token}",
+				}},
+			}},
+		}},
+	}
+	want, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hclData, err := MarshalHCL(doc)
+	if err != nil {
+		t.Fatalf("MarshalHCL failed: %v", err)
+	}
+	src := string(hclData)
+	for _, s := range []string{
+		"= steps.create.outputs.id",
+		"= workflows.wf.outputs.id",
+		`"$steps.missing.outputs.x"`,
+		`"$workflows.nope.outputs.y"`,
+		`"$sourceDescriptions.unknown.createPet"`,
+		`"Bearer {$steps.missing.outputs.token}"`,
+	} {
+		if !strings.Contains(src, s) {
+			t.Errorf("HCL missing %s:\n%s", s, src)
+		}
+	}
+
+	var back arazzo1.Arazzo
+	if err := UnmarshalHCL(hclData, &back); err != nil {
+		t.Fatalf("UnmarshalHCL failed: %v\n%s", err, src)
+	}
+	got, err := json.Marshal(&back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("round trip changed the document:\n got %s\nwant %s", got, want)
+	}
+}
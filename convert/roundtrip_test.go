@@ -2,12 +2,15 @@ package convert
 
 import (
 	"encoding/json"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/genelet/arazzo/arazzo1"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,8 +23,8 @@ func TestRoundTripExamples(t *testing.T) {
 		// Empty - all previously known issues have been fixed
 	}
 
-	// Find all arazzo YAML files
-	files, err := filepath.Glob(filepath.Join(examplesDir, "*.arazzo.yaml"))
+	// Find all arazzo YAML files, bnpl-arazzo.yaml included
+	files, err := filepath.Glob(filepath.Join(examplesDir, "*arazzo.yaml"))
 	if err != nil {
 		t.Fatalf("Failed to glob examples: %v", err)
 	}
@@ -417,3 +420,173 @@ func TestRefTransformation(t *testing.T) {
 		}
 	}
 }
+
+// TestHCLLegacyEscapes checks that HCL written by earlier versions, with
+// newlines and quotes escaped as \\n and \\", still decodes to them.
+func TestHCLLegacyEscapes(t *testing.T) {
+	data, err := os.ReadFile("./examples/1.0.0/LoginAndRetrievePets.arazzo.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc arazzo1.Arazzo
+	if err := UnmarshalHCL(data, &doc); err != nil {
+		t.Fatalf("UnmarshalHCL: %v", err)
+	}
+	if got := doc.Info.Description; !strings.HasSuffix(got, "an available pet.\n") {
+		t.Errorf("description = %q, want a trailing newline", got)
+	}
+
+	legacy := `arazzo = "1.0.0"
+info {
+  title       = "t"
+  version     = "1"
+  description = "one\\ntwo, a \\\\n kept"
+}
+sourceDescription "api" {
+  url  = "api.yaml"
+  type = "openapi"
+}
+workflow "w" {
+  inputs = {
+    note = "say \\\"hi\\\"\\nbye"
+  }
+  step "s" {
+    operationId = "op"
+  }
+}
+`
+	if err := UnmarshalHCL([]byte(legacy), &doc); err != nil {
+		t.Fatalf("UnmarshalHCL: %v", err)
+	}
+	if got, want := doc.Info.Description, "one\ntwo, a \\n kept"; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+	if got, want := doc.Workflows[0].Inputs.(map[string]any)["note"], "say \"hi\"\nbye"; got != want {
+		t.Errorf("input = %q, want %q", got, want)
+	}
+}
+
+// hclSafetyDocument returns a document with s as every string, and key as a
+// key of every dynamic value and extension, that the HCL writer can get
+// wrong.
+func hclSafetyDocument(s, key string) string {
+	// Maps in lists hold maps, as the object schemas of a oneOf do.
+	nested := map[string]any{"type": "object", "properties": map[string]any{key: map[string]any{key: s, "list": []any{map[string]any{key: map[string]any{key: s}}}}}}
+	dynamic := map[string]any{key: s, "list": []any{s, map[string]any{key: s}, nested}}
+	criterion := map[string]any{
+		"context":   "$response.body",
+		"condition": s,
+		"type":      "jsonpath",
+		"version":   "draft-goessner-dictionary-expressions-01",
+		"x-e":       dynamic,
+	}
+	doc := map[string]any{
+		"arazzo": "1.0.0",
+		"info":   map[string]any{"title": s, "summary": s, "description": s, "version": "1", "x-e": dynamic},
+		"sourceDescriptions": []any{
+			map[string]any{"name": "api", "url": s, "type": "openapi", "x-e": dynamic},
+		},
+		"workflows": []any{map[string]any{
+			"workflowId":  "w",
+			"summary":     s,
+			"description": s,
+			"inputs":      dynamic,
+			"parameters": []any{
+				map[string]any{"name": "p", "in": "query", "value": s, "x-e": dynamic},
+				map[string]any{"reference": "$components.parameters.c", "value": s},
+			},
+			"steps": []any{map[string]any{
+				"stepId":      "s",
+				"description": s,
+				"operationId": "op",
+				"parameters":  []any{map[string]any{"name": "q", "in": "header", "value": dynamic}},
+				"requestBody": map[string]any{
+					"contentType":  "application/json",
+					"payload":      dynamic,
+					"replacements": []any{map[string]any{"target": "/a", "value": s, "x-e": dynamic}},
+					"x-e":          dynamic,
+				},
+				"successCriteria": []any{criterion},
+				"onSuccess": []any{
+					map[string]any{"name": "done", "type": "end", "criteria": []any{criterion}, "x-e": dynamic},
+				},
+				"onFailure": []any{
+					map[string]any{"name": "retry", "type": "retry", "retryAfter": 1.5, "retryLimit": 2, "x-e": dynamic},
+					map[string]any{"reference": "$components.failureActions.f", "value": s},
+				},
+				"outputs": map[string]any{"o": s},
+				"x-e":     dynamic,
+			}},
+			"successActions": []any{map[string]any{"name": "ok", "type": "end", "x-e": dynamic}},
+			"outputs":        map[string]any{"o": s},
+			"x-e":            dynamic,
+		}},
+		"components": map[string]any{
+			"inputs":         map[string]any{"in": dynamic},
+			"parameters":     map[string]any{"c": map[string]any{"name": "c", "in": "query", "value": dynamic, "x-e": dynamic}},
+			"successActions": map[string]any{"ok": map[string]any{"name": "ok", "type": "end", "x-e": dynamic}},
+			"failureActions": map[string]any{"f": map[string]any{"name": "f", "type": "end", "criteria": []any{criterion}}},
+			"x-e":            dynamic,
+		},
+		"x-e": dynamic,
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// hclSafetyPieces are the pieces of the strings hclSafetyDocument is given.
+var hclSafetyPieces = []string{
+	`"`, `\`, `\n`, `\\n`, "\n", "\r", "\t", "\x01", "${", "%{", "}", "$$", "%%", "$ ", "{", "a", "é", "😀", " ",
+}
+
+// FuzzHCLRoundTrip checks that a document survives JSON -> HCL -> JSON
+// whatever the strings and $-prefixed keys in it. The seeds are random
+// strings of hclSafetyPieces.
+func FuzzHCLRoundTrip(f *testing.F) {
+	r := rand.New(rand.NewPCG(1, 2))
+	keys := []string{"$ref", "$schema", "$id", "$custom", "$custom-dash", "plain", "x-dash"}
+	for range 50 {
+		var b strings.Builder
+		for range r.IntN(12) + 1 {
+			b.WriteString(hclSafetyPieces[r.IntN(len(hclSafetyPieces))])
+		}
+		f.Add(b.String(), keys[r.IntN(len(keys))])
+	}
+	f.Fuzz(func(t *testing.T, s, key string) {
+		if s == "" || !utf8.ValidString(s) || !utf8.ValidString(key) || strings.HasPrefix(s, "$") ||
+			!hclsyntax.ValidIdentifier(toHCLKey(key)) || strings.HasPrefix(key, "_") {
+			// dethcl drops empty strings, invalid UTF-8 is not kept by
+			// JSON, strings starting with $ are runtime expressions,
+			// maps may be written as blocks, whose keys must be
+			// identifiers, and keys starting with _ are read back as $
+			// keys.
+			t.Skip()
+		}
+		var doc arazzo1.Arazzo
+		if err := json.Unmarshal([]byte(hclSafetyDocument(s, key)), &doc); err != nil {
+			t.Fatal(err)
+		}
+		want, err := json.Marshal(&doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hclData, err := MarshalHCL(&doc)
+		if err != nil {
+			t.Fatalf("MarshalHCL: %v", err)
+		}
+		var back arazzo1.Arazzo
+		if err := UnmarshalHCL(hclData, &back); err != nil {
+			t.Fatalf("UnmarshalHCL: %v\n%s", err, hclData)
+		}
+		got, err := json.Marshal(&back)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("round trip of %q, %q changed the document:\nwant %s\ngot  %s\nHCL:\n%s", s, key, want, got, hclData)
+		}
+	})
+}
@@ -0,0 +1,505 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// dethcl escapes the strings of typed fields, but writes the values of
+// dynamic fields, those of type any and the maps and slices of them, as they
+// are, and skips extensions. The transforms below walk the whole model by
+// reflection, so that no field is missed:
+//
+//   - keys of dynamic values and extensions starting with $ are made HCL
+//     identifiers, $ref as _ref and $name as __dollar__name;
+//   - strings of dynamic values are escaped as HCL string literals;
+//   - extensions are written after dethcl as x- attributes of the block of
+//     their object.
+//
+// Strings of typed fields, decoded either way, are exact, except for the
+// legacy escapes below.
+
+// transformArazzoForHCL prepares the dynamic values and extensions of doc
+// for dethcl.
+func transformArazzoForHCL(doc *arazzo1.Arazzo) {
+	legacyStrings(doc, escapeLegacy)
+	walkModel(reflect.ValueOf(doc), hclValue, hclKeys)
+}
+
+// hclParameters replaces the step parameters of doc that are not maps, such
+// as *arazzo1.Parameter or arazzo1.ParameterOrReusable, with the maps their
+// JSON decodes to: dethcl writes the parameters of a step as a list of
+// objects, and a typed parameter as a block HCL cannot read back.
+func hclParameters(doc *arazzo1.Arazzo) error {
+	for _, wf := range doc.Workflows {
+		if wf == nil {
+			continue
+		}
+		for _, step := range wf.Steps {
+			if step == nil {
+				continue
+			}
+			for i, param := range step.Parameters {
+				if _, ok := param.(map[string]any); ok || param == nil {
+					continue
+				}
+				data, err := json.Marshal(param)
+				if err != nil {
+					return fmt.Errorf("step %s: parameter %d: %w", step.StepId, i, err)
+				}
+				var m map[string]any
+				if err := json.Unmarshal(data, &m); err != nil {
+					return fmt.Errorf("step %s: parameter %d: %w", step.StepId, i, err)
+				}
+				step.Parameters[i] = m
+			}
+		}
+	}
+	return nil
+}
+
+// transformArazzoFromHCL restores the keys of the dynamic values and
+// extensions of doc decoded from HCL.
+func transformArazzoFromHCL(doc *arazzo1.Arazzo) {
+	walkModel(reflect.ValueOf(doc), jsonKeys, jsonKeys)
+	legacyStrings(doc, unescapeLegacy)
+}
+
+// Earlier versions wrote newlines in descriptions and summaries, and
+// newlines and quotes in the strings of inputs, step parameters and
+// payloads, as \\n and \\" in the HCL source, and decoded them back. Those
+// strings are still decoded that way, so such files read unchanged: a run
+// of backslashes before n, or before " in dynamic values, loses one
+// backslash, except that a lone \n is a newline. They are written with a
+// backslash added before each run of backslashes followed by n or ", which
+// the decoding removes, so that any string reads back exactly.
+
+// legacyStrings replaces the strings the earlier versions escaped with
+// f(s, quotes), quotes being set for the strings of dynamic values.
+func legacyStrings(doc *arazzo1.Arazzo, f func(s string, quotes bool) string) {
+	if doc.Info != nil {
+		doc.Info.Description = f(doc.Info.Description, false)
+		doc.Info.Summary = f(doc.Info.Summary, false)
+	}
+	for _, wf := range doc.Workflows {
+		if wf == nil {
+			continue
+		}
+		wf.Description = f(wf.Description, false)
+		wf.Summary = f(wf.Summary, false)
+		if wf.Inputs != nil {
+			wf.Inputs = legacyValue(wf.Inputs, f)
+		}
+		for _, step := range wf.Steps {
+			if step == nil {
+				continue
+			}
+			step.Description = f(step.Description, false)
+			for i, param := range step.Parameters {
+				step.Parameters[i] = legacyValue(param, f)
+			}
+			if step.RequestBody != nil && step.RequestBody.Payload != nil {
+				step.RequestBody.Payload = legacyValue(step.RequestBody.Payload, f)
+			}
+		}
+	}
+	if doc.Components != nil {
+		for k, v := range doc.Components.Inputs {
+			doc.Components.Inputs[k] = legacyValue(v, f)
+		}
+	}
+}
+
+// legacyValue applies f to the strings of a dynamic value.
+func legacyValue(v any, f func(s string, quotes bool) string) any {
+	switch v := v.(type) {
+	case string:
+		return f(v, true)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = legacyValue(e, f)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = legacyValue(e, f)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// escapeLegacy adds a backslash before each run of backslashes followed by
+// n, or by " when quotes is set.
+func escapeLegacy(s string, quotes bool) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		j := i
+		for j < len(s) && s[j] == '\\' {
+			j++
+		}
+		if j < len(s) && (s[j] == 'n' || quotes && s[j] == '"') {
+			b.WriteByte('\\')
+		}
+		b.WriteString(s[i:j])
+		i = j - 1
+	}
+	return b.String()
+}
+
+// unescapeLegacy decodes the escapes of escapeLegacy and of earlier
+// versions: a run of backslashes followed by n, or by " when quotes is set,
+// loses a backslash, and a single backslash before n is a newline.
+func unescapeLegacy(s string, quotes bool) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		j := i
+		for j < len(s) && s[j] == '\\' {
+			j++
+		}
+		switch {
+		case j == len(s) || s[j] != 'n' && !(quotes && s[j] == '"'):
+			b.WriteString(s[i:j])
+		case j-i > 1:
+			b.WriteString(s[i+1 : j])
+		case s[j] == 'n':
+			b.WriteByte('\n')
+			j++
+		default:
+			// A lone \" is a quote: the backslash is dropped.
+		}
+		i = j - 1
+	}
+	return b.String()
+}
+
+// walkModel replaces every dynamic value of v, a model value, with
+// dynamic(value), and every extension with extension(value).
+func walkModel(v reflect.Value, dynamic, extension func(any) any) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			walkModel(v.Elem(), dynamic, extension)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			field, f := t.Field(i), v.Field(i)
+			switch {
+			case !field.IsExported():
+			case field.Name == "Extensions":
+				for name, value := range f.Interface().(map[string]any) {
+					f.SetMapIndex(reflect.ValueOf(name), reflect.ValueOf(extension(value)))
+				}
+			case isDynamic(field.Type):
+				if !f.IsNil() {
+					f.Set(reflect.ValueOf(dynamic(f.Interface())).Convert(field.Type))
+				}
+			default:
+				walkModel(f, dynamic, extension)
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			walkModel(v.Index(i), dynamic, extension)
+		}
+	case reflect.Map:
+		// Elements of maps are pointers, so they are changed in place.
+		for it := v.MapRange(); it.Next(); {
+			walkModel(it.Value(), dynamic, extension)
+		}
+	}
+}
+
+// isDynamic reports whether values of t are dynamic: any, or a map or slice
+// of any.
+func isDynamic(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Map, reflect.Slice:
+		return t.Elem().Kind() == reflect.Interface
+	}
+	return false
+}
+
+// hclValue returns a dynamic value the way dethcl must be given it: keys as
+// HCL identifiers and strings escaped.
+func hclValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return escapeTemplate(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[toHCLKey(k)] = hclValue(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			m, ok := e.(map[string]any)
+			if !ok {
+				out[i] = hclValue(e)
+				continue
+			}
+			// dethcl writes the maps in a list as objects, but the
+			// maps in those as blocks, which an object cannot hold.
+			obj := make(map[string]any, len(m))
+			for k, e := range m {
+				if m, ok := e.(map[string]any); ok {
+					o := hclObject(hclKeys(m).(map[string]any))
+					obj[toHCLKey(k)] = &o
+				} else {
+					obj[toHCLKey(k)] = hclValue(e)
+				}
+			}
+			out[i] = obj
+		}
+		return out
+	default:
+		// Model values, such as a *ParameterOrReusable among the
+		// parameters of a step.
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer {
+			walkModel(rv, hclValue, hclKeys)
+		}
+		return v
+	}
+}
+
+// hclObject is a map in a map in a list of a dynamic value, written as an
+// object expression whatever it holds. Its keys are HCL identifiers
+// already, and its strings are not escaped.
+type hclObject map[string]any
+
+// MarshalHCL implements dethcl.Marshaler: it returns the attributes of the
+// object, which dethcl encloses in braces.
+func (o *hclObject) MarshalHCL() ([]byte, error) {
+	keys := make([]string, 0, len(*o))
+	for k := range *o {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		raw, err := json.Marshal((*o)[k])
+		if err != nil {
+			return nil, err
+		}
+		ty, err := ctyjson.ImpliedType(raw)
+		if err != nil {
+			return nil, err
+		}
+		val, err := ctyjson.Unmarshal(raw, ty)
+		if err != nil {
+			return nil, err
+		}
+		name := k
+		if !hclsyntax.ValidIdentifier(k) {
+			name = quoteHCL(k)
+		}
+		fmt.Fprintf(&b, "%s = %s\n", name, hclwrite.TokensForValue(val).Bytes())
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// hclKeys returns v with the keys of its maps as HCL identifiers.
+func hclKeys(v any) any {
+	return mapKeys(v, toHCLKey)
+}
+
+// jsonKeys returns v with the keys of its maps restored from HCL
+// identifiers.
+func jsonKeys(v any) any {
+	return mapKeys(v, fromHCLKey)
+}
+
+// mapKeys returns v with key applied to the keys of its maps.
+func mapKeys(v any, key func(string) string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[key(k)] = mapKeys(e, key)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = mapKeys(e, key)
+		}
+		return out
+	default:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer {
+			keys := func(v any) any { return mapKeys(v, key) }
+			walkModel(rv, keys, keys)
+		}
+		return v
+	}
+}
+
+// writeExtensions adds the extensions of v, a model value, as x- attributes
+// to body, the HCL dethcl wrote for v. Blocks are matched to the fields of
+// v by their hcl tags: by position for slices and by label for maps.
+func writeExtensions(body *hclwrite.Body, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := range t.NumField() {
+		field, f := t.Field(i), v.Field(i)
+		if field.Name == "Extensions" {
+			if err := setExtensions(body, f.Interface().(map[string]any)); err != nil {
+				return err
+			}
+			continue
+		}
+		name, kind, _ := strings.Cut(field.Tag.Get("hcl"), ",")
+		if kind != "block" {
+			continue
+		}
+		var blocks []*hclwrite.Block
+		for _, block := range body.Blocks() {
+			if block.Type() == name {
+				blocks = append(blocks, block)
+			}
+		}
+		switch f.Kind() {
+		case reflect.Pointer:
+			if !f.IsNil() && len(blocks) > 0 {
+				if err := writeExtensions(blocks[0].Body(), f); err != nil {
+					return err
+				}
+			}
+		case reflect.Slice:
+			n := 0
+			for j := range f.Len() {
+				if f.Index(j).IsNil() {
+					continue
+				}
+				if n < len(blocks) {
+					if err := writeExtensions(blocks[n].Body(), f.Index(j)); err != nil {
+						return err
+					}
+				}
+				n++
+			}
+		case reflect.Map:
+			for _, block := range blocks {
+				if labels := block.Labels(); len(labels) > 0 {
+					if e := f.MapIndex(reflect.ValueOf(labels[0])); e.IsValid() {
+						if err := writeExtensions(block.Body(), e); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setExtensions sets the extensions as attributes of body, in name order.
+func setExtensions(body *hclwrite.Body, extensions map[string]any) error {
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if !hclsyntax.ValidIdentifier(name) {
+			return fmt.Errorf("extension %q is not a valid HCL attribute name", name)
+		}
+		raw, err := json.Marshal(extensions[name])
+		if err != nil {
+			return err
+		}
+		ty, err := ctyjson.ImpliedType(raw)
+		if err != nil {
+			return err
+		}
+		val, err := ctyjson.Unmarshal(raw, ty)
+		if err != nil {
+			return err
+		}
+		body.SetAttributeValue(name, val)
+	}
+	return nil
+}
+
+// decodeExtensions decodes the x- attributes of body, which dethcl does not
+// know, and adds edits that remove them.
+func decodeExtensions(body *hclsyntax.Body, edits *[]edit) (map[string]any, hcl.Diagnostics) {
+	var extensions map[string]any
+	var diags hcl.Diagnostics
+	for name, attr := range body.Attributes {
+		if !strings.HasPrefix(name, "x-") {
+			continue
+		}
+		*edits = append(*edits, edit{attr.SrcRange.Start.Byte, attr.SrcRange.End.Byte, ""})
+		val, d := attr.Expr.Value(nil)
+		if diags = append(diags, d...); d.HasErrors() {
+			continue
+		}
+		v, err := goValue(val)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid extension value",
+				Detail:   err.Error(),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+			continue
+		}
+		if extensions == nil {
+			extensions = make(map[string]any)
+		}
+		extensions[name] = v
+	}
+	return extensions, diags
+}
+
+// goValue returns val as the Go value JSON decodes to.
+func goValue(val cty.Value) (any, error) {
+	raw, err := ctyjson.Marshal(val, val.Type())
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	err = json.Unmarshal(raw, &v)
+	return v, err
+}
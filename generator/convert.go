@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/convert"
+	"github.com/genelet/arazzo/internal/oasutil"
 	"github.com/genelet/horizon/dethcl"
 	"github.com/genelet/oas/openapi31"
 	"gopkg.in/yaml.v3"
@@ -40,6 +44,8 @@ func parseOpenAPI(content []byte) (*openapi31.OpenAPI, error) {
 }
 
 // NewArazzoFromFiles creates an Arazzo document from OpenAPI and Generator files.
+// Diagnostics are only reported as an error in Strict mode; use ToArazzo to
+// inspect them.
 func NewArazzoFromFiles(openapiFile, generatorFile string, format ...string) (*arazzo1.Arazzo, error) {
 	// Parse Generator
 	genBytes, err := os.ReadFile(generatorFile)
@@ -78,61 +84,88 @@ func NewArazzoFromFiles(openapiFile, generatorFile string, format ...string) (*a
 		return nil, fmt.Errorf("parsing openapi file: %w", err)
 	}
 	gen.openapiDoc = doc
+	if err := gen.loadSources(filepath.Dir(generatorFile)); err != nil {
+		return nil, err
+	}
 
-	return gen.ToArazzo(openapiFile)
+	arazzo, _, err := gen.ToArazzo(openapiFile)
+	return arazzo, err
 }
 
-// NewGeneratorFromArazzo creates a Generator config from Arazzo and OpenAPI files.
+// NewGeneratorFromArazzo creates a Generator config from Arazzo and OpenAPI
+// files. The Arazzo file may be JSON, YAML or HCL, chosen by its extension.
+// The OpenAPI file, which is optional, supplies the provider's server URL.
+// The config is Verbatim and keeps every field of the document, so that
+// ToArazzo returns it unchanged.
 func NewGeneratorFromArazzo(arazzoFile, openapiFile string) (*Generator, error) {
-	// Parse Arazzo
-	azBytes, err := os.ReadFile(arazzoFile)
+	az, err := readArazzo(arazzoFile)
 	if err != nil {
-		return nil, fmt.Errorf("reading arazzo file: %w", err)
+		return nil, err
 	}
-	var az arazzo1.Arazzo
-	// Arazzo can be JSON or YAML
-	if err := json.Unmarshal(azBytes, &az); err != nil {
-		if err := yaml.Unmarshal(azBytes, &az); err != nil {
-			return nil, fmt.Errorf("parsing arazzo file: %w", err)
+
+	var doc *openapi31.OpenAPI
+	if openapiFile != "" {
+		oaBytes, err := os.ReadFile(openapiFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading openapi file: %w", err)
+		}
+		if doc, err = parseOpenAPI(oaBytes); err != nil {
+			return nil, fmt.Errorf("parsing openapi file: %w", err)
 		}
 	}
+	return newGeneratorFromArazzo(az, doc), nil
+}
 
-	// Parse OpenAPI
-	oaBytes, err := os.ReadFile(openapiFile)
+// readArazzo reads an Arazzo file in JSON, YAML or HCL format.
+func readArazzo(filename string) (*arazzo1.Arazzo, error) {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("reading openapi file: %w", err)
+		return nil, fmt.Errorf("reading arazzo file: %w", err)
+	}
+	az := new(arazzo1.Arazzo)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".hcl":
+		err = convert.UnmarshalHCL(data, az)
+	case ".json":
+		err = convert.UnmarshalJSON(data, az)
+	default:
+		// YAML goes through JSON, which the arazzo1 types unmarshal with
+		// their extensions.
+		var obj interface{}
+		if err = yaml.Unmarshal(data, &obj); err == nil {
+			if data, err = json.Marshal(obj); err == nil {
+				err = convert.UnmarshalJSON(data, az)
+			}
+		}
 	}
-	doc, err := parseOpenAPI(oaBytes)
 	if err != nil {
-		return nil, fmt.Errorf("parsing openapi file: %w", err)
+		return nil, fmt.Errorf("parsing arazzo file: %w", err)
 	}
+	return az, nil
+}
 
+// newGeneratorFromArazzo returns the Verbatim config of az. The first source
+// description is the provider, the others are Sources.
+func newGeneratorFromArazzo(az *arazzo1.Arazzo, doc *openapi31.OpenAPI) *Generator {
 	gen := &Generator{
 		openapiDoc: doc,
-		Provider: &Provider{
-			Name:      "my-source", // Default
-			ServerURL: "",          // Default empty
-		},
+		Arazzo:     az.Arazzo,
+		Info:       az.Info,
+		Provider:   &Provider{Name: "my-source"}, // Default
 		Components: az.Components,
 		Extensions: az.Extensions,
+		Verbatim:   true,
 	}
-
-	if len(doc.Servers) > 0 {
-		gen.Provider.ServerURL = doc.Servers[0].URL
-	}
-
-	// Try to get source name from Arazzo if possible
-	if len(az.SourceDescriptions) > 0 {
-		gen.Provider.Name = az.SourceDescriptions[0].Name
+	for i, sd := range az.SourceDescriptions {
+		p := &Provider{Name: sd.Name, URL: sd.URL, Type: sd.Type, Extensions: sd.Extensions}
+		if i == 0 {
+			gen.Provider = p
+		} else {
+			gen.Sources = append(gen.Sources, p)
+		}
 	}
-
-	// Reserve Info fields in Appendices
-	if az.Info != nil {
-		gen.Provider.Appendices = make(map[string]interface{})
-		gen.Provider.Appendices["info_title"] = az.Info.Title
-		gen.Provider.Appendices["info_version"] = az.Info.Version
-		gen.Provider.Appendices["info_summary"] = az.Info.Summary
-		gen.Provider.Appendices["info_description"] = az.Info.Description
+	if doc != nil && len(doc.Servers) > 0 {
+		gen.Provider.ServerURL = doc.Servers[0].URL
 	}
 
 	// Iterate workflows
@@ -144,17 +177,13 @@ func NewGeneratorFromArazzo(arazzoFile, openapiFile string) (*Generator, error)
 			Inputs:         wf.Inputs,
 			Outputs:        wf.Outputs,
 			DependsOn:      wf.DependsOn,
+			Parameters:     wf.Parameters,
 			SuccessActions: wf.SuccessActions,
 			FailureActions: wf.FailureActions,
 			Extensions:     wf.Extensions,
 		}
 
 		for _, step := range wf.Steps {
-			opID := step.OperationId
-			if idx := strings.LastIndex(opID, "."); idx != -1 {
-				opID = opID[idx+1:]
-			}
-
 			// Infer name
 			name := step.StepId
 			if name == "" {
@@ -180,11 +209,15 @@ func NewGeneratorFromArazzo(arazzoFile, openapiFile string) (*Generator, error)
 				copy(op.Parameters, step.Parameters)
 			}
 
-			// Copy RequestBody
+			// Copy RequestBody. The payload key marks it as a full RequestBody,
+			// even when it has no payload.
 			if step.RequestBody != nil {
 				b, _ := json.Marshal(step.RequestBody)
 				var rbMap map[string]interface{}
 				_ = json.Unmarshal(b, &rbMap)
+				if _, ok := rbMap["payload"]; !ok {
+					rbMap["payload"] = nil
+				}
 				op.RequestBody = rbMap
 			}
 
@@ -193,116 +226,127 @@ func NewGeneratorFromArazzo(arazzoFile, openapiFile string) (*Generator, error)
 		gen.Workflows = append(gen.Workflows, spec)
 	}
 
-	return gen, nil
+	return gen
 }
 
-// ToArazzo converts the generator configuration and OpenAPI document to an Arazzo object.
-func (g *Generator) ToArazzo(openapiFilename string) (*arazzo1.Arazzo, error) {
-	if g.openapiDoc == nil {
-		return nil, fmt.Errorf("openapi document not set")
+// ToArazzo converts the generator configuration and OpenAPI document to an
+// Arazzo object. The diagnostics report the problems found on the way, such
+// as operations missing from OpenAPI, by their path in the config. In Strict
+// mode, diagnostics of error severity fail the conversion.
+func (g *Generator) ToArazzo(openapiFilename string) (*arazzo1.Arazzo, Diagnostics, error) {
+	if g.openapiDoc == nil && !g.Verbatim {
+		return nil, nil, fmt.Errorf("openapi document not set")
 	}
 
 	// Create Arazzo root
 	arazzo := &arazzo1.Arazzo{
-		Arazzo: "1.0.0",
-		Info: &arazzo1.Info{
-			Title:   "Generated Arazzo from " + g.openapiDoc.Info.Title,
-			Version: "1.0.0",
-			Summary: "Generated from " + openapiFilename,
-		},
-		SourceDescriptions: []*arazzo1.SourceDescription{
-			{
-				Name:       g.Provider.Name,
-				URL:        openapiFilename,
-				Type:       arazzo1.SourceDescriptionTypeOpenAPI,
-				Extensions: g.Provider.Extensions,
-			},
-		},
+		Arazzo:     g.Arazzo,
+		Info:       g.info(openapiFilename),
 		Components: g.Components,
 		Extensions: g.Extensions,
 	}
-
-	// Restore Info from Appendices if available
-	if g.Provider.Appendices != nil {
-		if v, ok := g.Provider.Appendices["info_title"].(string); ok && v != "" {
-			arazzo.Info.Title = v
-		}
-		if v, ok := g.Provider.Appendices["info_version"].(string); ok && v != "" {
-			arazzo.Info.Version = v
-		}
-		if v, ok := g.Provider.Appendices["info_summary"].(string); ok && v != "" {
-			arazzo.Info.Summary = v
-		}
-		if v, ok := g.Provider.Appendices["info_description"].(string); ok && v != "" {
-			arazzo.Info.Description = v
+	if arazzo.Arazzo == "" {
+		arazzo.Arazzo = "1.0.0"
+	}
+	url := g.Provider.URL
+	if url == "" {
+		url = openapiFilename
+	}
+	arazzo.SourceDescriptions = append(arazzo.SourceDescriptions, g.Provider.sourceDescription(url, g.Verbatim))
+	for _, p := range g.Sources {
+		if p != nil {
+			arazzo.SourceDescriptions = append(arazzo.SourceDescriptions, p.sourceDescription(p.OpenAPI, g.Verbatim))
 		}
 	}
 
 	if len(g.Workflows) == 0 {
-		return nil, fmt.Errorf("no workflows found in generator config")
+		return nil, nil, fmt.Errorf("no workflows found in generator config")
+	}
+	if g.Verbatim {
+		for _, wfSpec := range g.Workflows {
+			wf := wfSpec.workflow()
+			for _, op := range wfSpec.Steps {
+				step := op.step()
+				step.OperationId, step.OperationPath, step.WorkflowId = op.OperationId, op.OperationPath, op.WorkflowId
+				step.Parameters = op.Parameters
+				rb, err := op.requestBody()
+				if err != nil {
+					return nil, nil, fmt.Errorf("step %q: request body: %w", op.Name, err)
+				}
+				step.RequestBody = rb
+				wf.Steps = append(wf.Steps, step)
+			}
+			arazzo.Workflows = append(arazzo.Workflows, wf)
+		}
+		return arazzo, nil, nil
+	}
+	sources, err := g.sources()
+	if err != nil {
+		return nil, nil, err
 	}
 
+	workflowIDs := make(map[string]bool, len(g.Workflows))
 	for _, wfSpec := range g.Workflows {
-		wf := &arazzo1.Workflow{
-			WorkflowId:     wfSpec.WorkflowId,
-			Summary:        wfSpec.Summary,
-			Description:    wfSpec.Description,
-			Inputs:         wfSpec.Inputs,
-			DependsOn:      wfSpec.DependsOn,
-			Outputs:        wfSpec.Outputs,
-			SuccessActions: wfSpec.SuccessActions,
-			FailureActions: wfSpec.FailureActions,
-			Extensions:     wfSpec.Extensions,
-			Steps:          []*arazzo1.Step{},
+		workflowIDs[wfSpec.WorkflowId] = true
+	}
+	var diags Diagnostics
+
+	for i, wfSpec := range g.Workflows {
+		wf := wfSpec.workflow()
+		wc := newSourcesContext(sources)
+		for _, op := range wfSpec.Steps {
+			wc.stepIDs[op.Name] = true
 		}
 
 		// Create steps
-		for _, op := range wfSpec.Steps {
-			step := &arazzo1.Step{
-				StepId:          op.Name,
-				Description:     op.Description,
-				SuccessCriteria: op.SuccessCriteria,
-				OnSuccess:       op.OnSuccess,
-				OnFailure:       op.OnFailure,
-				Extensions:      op.Extensions,
-				Outputs:         op.Outputs,
-			}
+		for j, op := range wfSpec.Steps {
+			wc.path = fmt.Sprintf("workflows[%d].steps[%d]", i, j)
+			step := op.step()
 
-			// Handle RequestBody (now map[string]interface{} for HCL compat)
-			if len(op.RequestBody) > 0 {
-				// Check for "payload" key
-				if _, hasPayload := op.RequestBody["payload"]; hasPayload {
-					// Convert map to struct via JSON (simplest way to handle conversions)
-					b, _ := json.Marshal(op.RequestBody)
-					var rb arazzo1.RequestBody
-					if err := json.Unmarshal(b, &rb); err == nil {
-						step.RequestBody = &rb
-					}
-				} else {
-					// Treat entirely as Payload
-					step.RequestBody = &arazzo1.RequestBody{
-						Payload: op.RequestBody,
-					}
-				}
+			if rb, err := op.requestBody(); err != nil {
+				wc.report(SeverityError, ".request_body", "dropped: %v", err)
+			} else {
+				step.RequestBody = rb
 			}
 
 			// Handle Target (Operation vs Workflow)
 			if op.WorkflowId != "" {
 				step.WorkflowId = op.WorkflowId
+				if !strings.HasPrefix(op.WorkflowId, "$") && !workflowIDs[op.WorkflowId] {
+					wc.report(SeverityError, ".workflow_id", "workflow %q not found", op.WorkflowId)
+				}
 			} else if op.OperationId != "" {
 				step.OperationId = op.OperationId
 			} else if op.OperationPath != "" {
 				step.OperationPath = op.OperationPath
 			} else {
-				// Default fallback
+				// Default fallback: a guess, which Strict mode rejects.
 				step.OperationId = "$source." + op.Name
+				wc.report(SeverityError, "", "no operation_id, operation_path or workflow_id; using %s", step.OperationId)
 			}
 
 			// Copy Parameters
 			step.Parameters = op.Parameters
 
+			src := sources[0]
+			if step.WorkflowId == "" {
+				if s, err := stepSource(sources, op); err != nil {
+					suffix := ""
+					if op.Source != "" {
+						suffix = ".source"
+					}
+					wc.report(SeverityError, suffix, "%v", err)
+				} else {
+					src = s
+				}
+				if len(sources) > 1 {
+					src.qualify(step)
+				}
+			}
+			wc.use(src)
+
 			// Enrichment: This might modify Parameters, RequestBody, SuccessCriteria
-			enrichStepFromOpenAPI(step, g.openapiDoc)
+			op := enrichStep(step, src.doc, wc)
 
 			// Add default success criteria if still missing (fallback)
 			if len(step.SuccessCriteria) == 0 {
@@ -314,48 +358,226 @@ func (g *Generator) ToArazzo(openapiFilename string) (*arazzo1.Arazzo, error) {
 			}
 
 			wf.Steps = append(wf.Steps, step)
+			wc.addOutputs(step, src.doc, op)
 		}
+		// Token steps run before the steps that use their access token.
+		wf.Steps = append(wc.tokens(), wf.Steps...)
+		wf.Inputs = wc.inferInputs(wf)
+		diags = append(diags, wc.diagnostics...)
 		arazzo.Workflows = append(arazzo.Workflows, wf)
 	}
 
-	return arazzo, nil
+	if g.Strict && diags.HasErrors() {
+		return nil, diags, fmt.Errorf("strict generation failed:\n%s", diags.Errors())
+	}
+	return arazzo, diags, nil
 }
 
-// enrichStepFromOpenAPI looks up the operation in the OpenAPI doc and enriches the step parameters.
-func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
-	if step.WorkflowId != "" {
-		return // Cannot enrich workflow steps from OpenAPI
+// info returns the info of the generated document: Info, or else one derived
+// from the OpenAPI document and the Appendices of the provider.
+func (g *Generator) info(openapiFilename string) *arazzo1.Info {
+	if g.Info != nil {
+		return g.Info
+	}
+	info := &arazzo1.Info{
+		Title:   "Generated Arazzo",
+		Version: "1.0.0",
+		Summary: "Generated from " + openapiFilename,
+	}
+	if g.openapiDoc != nil && g.openapiDoc.Info != nil {
+		info.Title += " from " + g.openapiDoc.Info.Title
 	}
 
-	// Find operation
-	var op *openapi31.Operation
-	// Simple lookup by OperationId
-	opID := step.OperationId
-	// Remove source prefix if present (e.g., "$source.petId")
-	if idx := strings.LastIndex(opID, "."); idx != -1 {
-		opID = opID[idx+1:]
+	// Restore Info from Appendices if available
+	if g.Provider.Appendices != nil {
+		if v, ok := g.Provider.Appendices["info_title"].(string); ok && v != "" {
+			info.Title = v
+		}
+		if v, ok := g.Provider.Appendices["info_version"].(string); ok && v != "" {
+			info.Version = v
+		}
+		if v, ok := g.Provider.Appendices["info_summary"].(string); ok && v != "" {
+			info.Summary = v
+		}
+		if v, ok := g.Provider.Appendices["info_description"].(string); ok && v != "" {
+			info.Description = v
+		}
 	}
+	return info
+}
 
-	if opID != "" && doc.Paths != nil {
-		for _, pathItem := range doc.Paths.Paths {
-			ops := []*openapi31.Operation{pathItem.Get, pathItem.Put, pathItem.Post, pathItem.Delete, pathItem.Options, pathItem.Head, pathItem.Patch, pathItem.Trace}
-			for _, o := range ops {
-				if o != nil && o.OperationID == opID {
-					op = o
-					break
-				}
-			}
-			if op != nil {
-				break
+// sourceDescription returns the source description of p, located at url
+// unless p has a URL. Its type defaults to openapi, except when verbatim.
+func (p *Provider) sourceDescription(url string, verbatim bool) *arazzo1.SourceDescription {
+	sd := &arazzo1.SourceDescription{Name: p.Name, URL: p.URL, Type: p.Type, Extensions: p.Extensions}
+	if sd.URL == "" {
+		sd.URL = url
+	}
+	if sd.Type == "" && !verbatim {
+		sd.Type = arazzo1.SourceDescriptionTypeOpenAPI
+	}
+	return sd
+}
+
+// workflow returns the workflow of spec without its steps.
+func (spec *WorkflowSpec) workflow() *arazzo1.Workflow {
+	return &arazzo1.Workflow{
+		WorkflowId:     spec.WorkflowId,
+		Summary:        spec.Summary,
+		Description:    spec.Description,
+		Inputs:         spec.Inputs,
+		DependsOn:      spec.DependsOn,
+		Outputs:        spec.Outputs,
+		Parameters:     spec.Parameters,
+		SuccessActions: spec.SuccessActions,
+		FailureActions: spec.FailureActions,
+		Extensions:     spec.Extensions,
+		Steps:          []*arazzo1.Step{},
+	}
+}
+
+// step returns the step of op without its target, parameters and request body.
+func (op *OperationSpec) step() *arazzo1.Step {
+	return &arazzo1.Step{
+		StepId:          op.Name,
+		Description:     op.Description,
+		SuccessCriteria: op.SuccessCriteria,
+		OnSuccess:       op.OnSuccess,
+		OnFailure:       op.OnFailure,
+		Extensions:      op.Extensions,
+		Outputs:         op.Outputs,
+	}
+}
+
+// requestBody returns the RequestBody of op. A map with a "payload" key is a
+// full RequestBody; any other map is the payload.
+func (op *OperationSpec) requestBody() (*arazzo1.RequestBody, error) {
+	if len(op.RequestBody) == 0 {
+		return nil, nil
+	}
+	if _, hasPayload := op.RequestBody["payload"]; !hasPayload {
+		return &arazzo1.RequestBody{Payload: op.RequestBody}, nil
+	}
+	// Convert map to struct via JSON (simplest way to handle conversions)
+	b, err := json.Marshal(op.RequestBody)
+	if err != nil {
+		return nil, err
+	}
+	var rb arazzo1.RequestBody
+	if err := json.Unmarshal(b, &rb); err != nil {
+		return nil, err
+	}
+	return &rb, nil
+}
+
+// workflowContext carries the state shared by the steps of one workflow while
+// they are enriched.
+type workflowContext struct {
+	security    *securityBuilder // of the current step's source
+	payload     *Payload         // of the current step's source
+	sources     []*source
+	builders    []*securityBuilder    // by source, in order of first use
+	stepIDs     map[string]bool       // stepIds already used in the workflow
+	produced    []*stepOutput         // outputs of the steps enriched so far
+	inputs      map[string]*inputHint // by input name
+	path        string                // config path of the current step
+	diagnostics Diagnostics
+}
+
+func newWorkflowContext(doc *openapi31.OpenAPI, provider *Provider) *workflowContext {
+	return newSourcesContext([]*source{{provider: provider, doc: doc}})
+}
+
+// newSourcesContext returns a workflowContext over sources, starting with the first.
+func newSourcesContext(sources []*source) *workflowContext {
+	wc := &workflowContext{sources: sources, stepIDs: make(map[string]bool)}
+	wc.use(sources[0])
+	return wc
+}
+
+// report records a diagnostic for the current step. suffix extends the
+// step's config path, e.g. ".operation_id".
+func (wc *workflowContext) report(severity Severity, suffix, format string, args ...any) {
+	wc.diagnostics = append(wc.diagnostics, Diagnostic{Severity: severity, Path: wc.path + suffix, Message: fmt.Sprintf(format, args...)})
+}
+
+// use makes src the source of the steps enriched next.
+func (wc *workflowContext) use(src *source) {
+	wc.payload = nil
+	if src.provider != nil {
+		wc.payload = src.provider.Payload
+	}
+	for _, b := range wc.builders {
+		if b.src == src {
+			wc.security = b
+			return
+		}
+	}
+	var sec *Security
+	if src.provider != nil {
+		sec = src.provider.Security
+	}
+	b := newSecurityBuilder(src.doc, sec)
+	b.src, b.sources, b.stepIDs = src, wc.sources, wc.stepIDs
+	b.hint = func(name, description string) {
+		wc.hintInput(name, nil, &openapi31.Schema{Type: &openapi31.StringOrStringArray{String: "string"}}, description, true)
+	}
+	wc.builders = append(wc.builders, b)
+	wc.security = b
+}
+
+// tokens returns the token steps of all sources. With several sources, their
+// operations are qualified by the source they belong to.
+func (wc *workflowContext) tokens() []*arazzo1.Step {
+	var steps []*arazzo1.Step
+	for _, b := range wc.builders {
+		for _, step := range b.tokens {
+			if len(wc.sources) > 1 {
+				b.src.qualify(step)
 			}
+			steps = append(steps, step)
 		}
-	} else if step.OperationPath != "" {
-		// Attempt resolution by path
-		op = resolveOperationByPath(doc, step.OperationPath)
 	}
+	return steps
+}
 
-	if op == nil {
-		return
+// enrichStepFromOpenAPI looks up the operation in the OpenAPI doc and enriches the step parameters.
+func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
+	enrichStep(step, doc, newWorkflowContext(doc, nil))
+}
+
+// enrichStep is enrichStepFromOpenAPI within the context of the step's workflow.
+// Values that earlier steps of the workflow produce are wired to their outputs.
+// It returns the step's operation, or nil if it was not found.
+func enrichStep(step *arazzo1.Step, doc *openapi31.OpenAPI, wc *workflowContext) *openapi31.Operation {
+	if step.WorkflowId != "" {
+		return nil // Cannot enrich workflow steps from OpenAPI
+	}
+
+	found := findOperation(doc, step)
+	if found == nil {
+		if step.OperationId != "" {
+			wc.report(SeverityError, ".operation_id", "operation %q not found in OpenAPI", step.OperationId)
+		} else if step.OperationPath != "" {
+			wc.report(SeverityError, ".operation_path", "operation %q not found in OpenAPI", step.OperationPath)
+		}
+		return nil
+	}
+	op := found.Operation
+	// Path-level parameters and $refs count as well.
+	params := found.Parameters()
+
+	// inputValue wires a parameter to an earlier step output, or else to an
+	// input. p is nil for parameters the operation does not declare.
+	inputValue := func(name string, p *openapi31.Parameter) string {
+		if p == nil {
+			return "$inputs." + name
+		}
+		if expr, ok := wc.wire(name, oasutil.SchemaType(oasutil.ResolveSchema(doc, p.Schema)), ".parameters"); ok {
+			return expr
+		}
+		wc.hintInput(name, doc, p.Schema, p.Description, p.Required)
+		return "$inputs." + name
 	}
 
 	// Enrichment Logic 1: Auto-fill 'in' for parameters and Auto-include required parameters
@@ -363,17 +585,17 @@ func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
 	existingParams := make(map[string]bool)
 	var newParams []interface{}
 
-	for _, pFunc := range step.Parameters {
+	for k, pFunc := range step.Parameters {
 		// Handle string requests (e.g. "X-Trace-Id")
 		if name, ok := pFunc.(string); ok {
 			// Find this param in OpenAPI
 			found := false
-			for _, oasP := range op.Parameters {
+			for _, oasP := range params {
 				if oasP.Name == name {
 					param := &arazzo1.Parameter{
 						Name:  oasP.Name,
 						In:    arazzo1.ParameterIn(oasP.In),
-						Value: "$inputs." + oasP.Name, // Default value
+						Value: inputValue(oasP.Name, oasP), // Default value
 					}
 					newParams = append(newParams, param)
 					existingParams[name] = true
@@ -389,10 +611,11 @@ func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
 				// For now, let's convert it to a basic Parameter to avoid type issues later
 				param := &arazzo1.Parameter{
 					Name:  name,
-					Value: "$inputs." + name,
+					Value: inputValue(name, nil),
 				}
 				newParams = append(newParams, param)
 				existingParams[name] = true
+				wc.report(SeverityWarning, fmt.Sprintf(".parameters[%d]", k), "parameter %q is not declared by the operation; kept without location", name)
 			}
 			continue
 		}
@@ -403,7 +626,7 @@ func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
 			name, _ = pMap["name"].(string)
 			inVal, _ := pMap["in"].(string)
 			if name != "" && inVal == "" {
-				for _, oasP := range op.Parameters {
+				for _, oasP := range params {
 					if oasP.Name == name {
 						pMap["in"] = oasP.In
 						break
@@ -413,7 +636,7 @@ func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
 			newParams = append(newParams, pMap)
 		} else if pStruct, ok := pFunc.(*arazzo1.Parameter); ok {
 			name = pStruct.Name
-			enrichParameterStruct(pStruct, op)
+			enrichParameterStruct(pStruct, params)
 			newParams = append(newParams, pStruct)
 		} else {
 			// Unknown type, keep it
@@ -426,7 +649,7 @@ func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
 	}
 
 	// Second, Auto-include Mandatory Parameters from OpenAPI
-	for _, oasP := range op.Parameters {
+	for _, oasP := range params {
 		if _, exists := existingParams[oasP.Name]; exists {
 			continue
 		}
@@ -436,7 +659,7 @@ func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
 			param := &arazzo1.Parameter{
 				Name:  oasP.Name,
 				In:    arazzo1.ParameterIn(oasP.In),
-				Value: "$inputs." + oasP.Name,
+				Value: inputValue(oasP.Name, oasP),
 			}
 			newParams = append(newParams, param)
 		}
@@ -445,46 +668,16 @@ func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
 	step.Parameters = newParams
 
 	// Enrichment Logic 2: Security Parameters
-
-	// Enrichment Logic 2: Security Parameters
-	if len(op.Security) > 0 && doc.Components != nil && doc.Components.SecuritySchemes != nil {
-		// Just take the first requirement set for now
-		req := op.Security[0]
-		for name := range req {
-			if schemeRef, ok := doc.Components.SecuritySchemes[name]; ok {
-				// schemeRef might be a reference or value. Assuming value usage simplified for now as generator is mostly reader
-				// Actually openapi31.SecuritySchemes is map[string]*SecurityScheme|Reference
-				// We need to resolve it. But typically it's direct in components.
-				// In genelet/oas/openapi31, SecurityScheme is struct.
-				if schemeRef.Type == "apiKey" {
-					// Add parameter
-					param := arazzo1.Parameter{
-						Name:  schemeRef.Name,
-						In:    arazzo1.ParameterIn(schemeRef.In),
-						Value: "$inputs." + name, // Heuristic default
-					}
-					// Only add if not present
-					if !parameterExists(step.Parameters, param.Name) {
-						step.Parameters = append(step.Parameters, &param)
-					}
-				} else if schemeRef.Type == "http" {
-					headerName := "Authorization"
-					if !parameterExists(step.Parameters, headerName) {
-						param := arazzo1.Parameter{
-							Name:  headerName,
-							In:    arazzo1.ParameterInHeader, // Authorization is always header
-							Value: "$inputs." + name,
-						}
-						step.Parameters = append(step.Parameters, &param)
-					}
-				}
-			}
-		}
-	}
+	wc.security.apply(step, op)
 
 	// Enrichment Logic 3: Dynamic Success Criteria
 	if len(step.SuccessCriteria) == 0 && op.Responses != nil && len(op.Responses.StatusCode) > 0 {
+		codes := make([]string, 0, len(op.Responses.StatusCode))
 		for code := range op.Responses.StatusCode {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
 			// Check for 2xx codes strings
 			if strings.HasPrefix(code, "2") {
 				step.SuccessCriteria = append(step.SuccessCriteria, &arazzo1.Criterion{
@@ -495,37 +688,53 @@ func enrichStepFromOpenAPI(step *arazzo1.Step, doc *openapi31.OpenAPI) {
 	}
 
 	// Enrichment Logic 4: Request Body Content-Type and Payload
-	if op.RequestBody != nil && len(op.RequestBody.Content) > 0 {
+	if rb := found.RequestBody(); rb != nil && len(rb.Content) > 0 {
 		if step.RequestBody == nil {
 			step.RequestBody = &arazzo1.RequestBody{}
 		}
 
+		var prefer []string
+		if wc.payload != nil {
+			prefer = wc.payload.ContentTypes
+		}
 		if step.RequestBody.ContentType == "" {
-			// Pick first content type
-			for ct, mediaType := range op.RequestBody.Content {
-				step.RequestBody.ContentType = ct
-
-				// Payload Scaffolding
-				if step.RequestBody.Payload == nil {
-					if mediaType.Example != nil {
-						step.RequestBody.Payload = mediaType.Example
-					} else if len(mediaType.Examples) > 0 {
-						// Pick first example
-						for _, ex := range mediaType.Examples {
-							step.RequestBody.Payload = ex.Value
-							break
-						}
-					}
+			step.RequestBody.ContentType = oasutil.PreferredMediaType(rb.Content, prefer)
+		}
+
+		// Payload Scaffolding: examples first, then the schema
+		if mediaType := rb.Content[step.RequestBody.ContentType]; mediaType != nil && step.RequestBody.Payload == nil {
+			if mediaType.Example != nil {
+				step.RequestBody.Payload = mediaType.Example
+			} else if len(mediaType.Examples) > 0 {
+				// Pick the first example by name
+				names := make([]string, 0, len(mediaType.Examples))
+				for name := range mediaType.Examples {
+					names = append(names, name)
 				}
-				break
+				sort.Strings(names)
+				if ex := mediaType.Examples[names[0]]; ex != nil {
+					step.RequestBody.Payload = ex.Value
+				}
+			} else if mediaType.Schema != nil {
+				wire := func(name, typ, pointer string) (string, bool) {
+					return wc.wire(name, typ, ".request_body"+strings.ReplaceAll(pointer, "/", "."))
+				}
+				input := func(name string, s *openapi31.Schema, required bool) {
+					wc.hintInput(name, doc, s, "", required)
+				}
+				payload, replacements := scaffoldPayload(doc, mediaType.Schema, wc.payload, wire, input)
+				step.RequestBody.Payload = payload
+				step.RequestBody.Replacements = append(step.RequestBody.Replacements, replacements...)
 			}
 		}
 	}
+
+	return op
 }
 
-func enrichParameterStruct(p *arazzo1.Parameter, op *openapi31.Operation) {
+func enrichParameterStruct(p *arazzo1.Parameter, params []*openapi31.Parameter) {
 	if p.Name != "" && p.In == "" {
-		for _, oasP := range op.Parameters {
+		for _, oasP := range params {
 			if oasP.Name == p.Name {
 				p.In = arazzo1.ParameterIn(oasP.In)
 				break
@@ -550,55 +759,56 @@ func parameterExists(params []any, name string) bool {
 	return false
 }
 
-// resolveOperationByPath resolves a JSON Pointer-like operation path (e.g. #/paths/~1users/get)
-func resolveOperationByPath(doc *openapi31.OpenAPI, path string) *openapi31.Operation {
-	// Strip source prefix if present (e.g., "$source#/paths...")
-	if idx := strings.LastIndex(path, "#"); idx != -1 {
-		path = path[idx+1:]
+// findOperation locates the operation of step in doc by its operationId,
+// ignoring any source prefix, or else by its operationPath.
+func findOperation(doc *openapi31.OpenAPI, step *arazzo1.Step) *oasutil.Operation {
+	opID := step.OperationId
+	// Remove source prefix if present (e.g., "$source.petId")
+	if idx := strings.LastIndex(opID, "."); idx != -1 {
+		opID = opID[idx+1:]
 	}
-
-	// Expecting /paths/{path_to_item}/{method}
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 || parts[1] != "paths" {
+	if opID != "" && doc.Paths != nil {
+		for _, path := range oasutil.SortedPaths(doc) {
+			item := doc.Paths.Paths[path]
+			for _, method := range oasutil.Methods {
+				if op := oasutil.OperationOf(item, method); op != nil && op.OperationID == opID {
+					return &oasutil.Operation{Method: method, Path: path, PathItem: item, Operation: op, Doc: doc}
+				}
+			}
+		}
 		return nil
 	}
+	if step.OperationPath != "" {
+		return operationAtPath(doc, step.OperationPath)
+	}
+	return nil
+}
 
-	// Unescape JSON pointer tokens: ~1 -> /, ~0 -> ~
-	unescape := func(s string) string {
-		s = strings.ReplaceAll(s, "~1", "/")
-		s = strings.ReplaceAll(s, "~0", "~")
-		return s
+// resolveOperationByPath resolves a JSON Pointer-like operation path (e.g. #/paths/~1users/get)
+func resolveOperationByPath(doc *openapi31.OpenAPI, path string) *openapi31.Operation {
+	if o := operationAtPath(doc, path); o != nil {
+		return o.Operation
 	}
+	return nil
+}
 
-	pathKey := unescape(parts[2]) // The path key, e.g. /users
-	method := parts[3]            // The method, e.g. get
+// operationAtPath is resolveOperationByPath, keeping the operation's path item.
+func operationAtPath(doc *openapi31.OpenAPI, path string) *oasutil.Operation {
+	// Strip source prefix if present (e.g., "$source#/paths...")
+	_, pointer := oasutil.SplitOperationPath(path)
 
-	if doc.Paths == nil {
+	// Expecting /paths/{path_to_item}/{method}
+	parts := strings.Split(pointer, "/")
+	if len(parts) < 4 || parts[1] != "paths" || doc.Paths == nil {
 		return nil
 	}
+	pathKey := oasutil.UnescapePointer(parts[2]) // The path key, e.g. /users
+	method := strings.ToLower(parts[3])          // The method, e.g. get
 
-	for key, pathItem := range doc.Paths.Paths {
-		if key == pathKey {
-			switch strings.ToLower(method) {
-			case "get":
-				return pathItem.Get
-			case "put":
-				return pathItem.Put
-			case "post":
-				return pathItem.Post
-			case "delete":
-				return pathItem.Delete
-			case "options":
-				return pathItem.Options
-			case "head":
-				return pathItem.Head
-			case "patch":
-				return pathItem.Patch
-			case "trace":
-				return pathItem.Trace
-			}
-		}
+	item := doc.Paths.Paths[pathKey]
+	op := oasutil.OperationOf(item, method)
+	if op == nil {
+		return nil
 	}
-
-	return nil
+	return &oasutil.Operation{Method: method, Path: pathKey, PathItem: item, Operation: op, Doc: doc}
 }
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity classifies a Diagnostic.
+type Severity string
+
+const (
+	// SeverityWarning marks a problem the generator worked around, such as a
+	// value that could be wired to several outputs.
+	SeverityWarning Severity = "warning"
+	// SeverityError marks an unresolved reference or dropped configuration.
+	// Strict generation fails on errors.
+	SeverityError Severity = "error"
+)
+
+// Diagnostic is a problem found while generating an Arazzo document, located
+// by its path in the generator config, e.g. workflows[0].steps[1].operation_id.
+type Diagnostic struct {
+	Severity Severity
+	Path     string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Path == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Severity, d.Path, d.Message)
+}
+
+// Diagnostics lists the problems found by ToArazzo.
+type Diagnostics []Diagnostic
+
+// HasErrors reports whether any diagnostic is an error.
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns the diagnostics of error severity.
+func (d Diagnostics) Errors() Diagnostics {
+	var errs Diagnostics
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			errs = append(errs, diag)
+		}
+	}
+	return errs
+}
+
+func (d Diagnostics) String() string {
+	lines := make([]string, len(d))
+	for i, diag := range d {
+		lines[i] = diag.String()
+	}
+	return strings.Join(lines, "\n")
+}
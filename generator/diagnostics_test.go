@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostics(t *testing.T) {
+	gen := &Generator{
+		openapiDoc: wiringDoc(),
+		Provider:   &Provider{Name: "pets"},
+		Workflows: []*WorkflowSpec{{WorkflowId: "wf", Steps: []*OperationSpec{
+			{Name: "missing", OperationId: "deletePet"},
+			{Name: "untargeted"},
+			{Name: "get", OperationId: "getPet", Parameters: []any{"verbose"}},
+			{Name: "adopt", OperationId: "adopt", RequestBody: map[string]any{"payload": "{}", "contentType": 1}},
+			{Name: "nested", WorkflowId: "other"},
+			{Name: "byPath", OperationPath: "#/paths/~1pets/put"},
+		}}},
+	}
+
+	az, diags, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+	require.NotNil(t, az)
+
+	type entry struct {
+		severity Severity
+		path     string
+	}
+	var got []entry
+	for _, d := range diags {
+		got = append(got, entry{d.Severity, d.Path})
+	}
+	assert.Equal(t, []entry{
+		{SeverityError, "workflows[0].steps[0].operation_id"},
+		{SeverityError, "workflows[0].steps[1]"},
+		{SeverityError, "workflows[0].steps[1].operation_id"},
+		{SeverityWarning, "workflows[0].steps[2].parameters[0]"},
+		{SeverityError, "workflows[0].steps[3].request_body"},
+		{SeverityError, "workflows[0].steps[4].workflow_id"},
+		{SeverityError, "workflows[0].steps[5].operation_path"},
+	}, got)
+	assert.Contains(t, diags[0].Message, `"deletePet" not found`)
+	assert.Contains(t, diags[1].String(), "error: workflows[0].steps[1]: no operation_id")
+	assert.Len(t, diags.Errors(), 6)
+
+	gen.Strict = true
+	az, diags, err = gen.ToArazzo("openapi.yaml")
+	assert.Nil(t, az)
+	assert.Len(t, diags, 7)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "workflows[0].steps[0].operation_id")
+	assert.NotContains(t, err.Error(), "warning")
+}
+
+func TestDiagnosticsStrictUntargetedStep(t *testing.T) {
+	// The step has no target, but its name is an operation of the OpenAPI
+	// document, so the fallback resolves.
+	gen := &Generator{
+		openapiDoc: wiringDoc(),
+		Provider:   &Provider{Name: "pets"},
+		Workflows:  []*WorkflowSpec{{WorkflowId: "wf", Steps: []*OperationSpec{{Name: "getPet"}}}},
+	}
+
+	az, diags, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "$source.getPet", az.Workflows[0].Steps[0].OperationId)
+	require.Len(t, diags, 1)
+	assert.Equal(t, SeverityError, diags[0].Severity)
+
+	gen.Strict = true
+	az, _, err = gen.ToArazzo("openapi.yaml")
+	assert.Nil(t, az)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "workflows[0].steps[0]: no operation_id, operation_path or workflow_id")
+}
@@ -118,3 +118,47 @@ func TestEnrichment_SkipDeprecatedDefault(t *testing.T) {
 	enrichStepFromOpenAPI(step, doc)
 	assert.Empty(t, step.Parameters, "Deprecated required params should be skipped by default")
 }
+
+func TestEnrichment_PathLevelAndRefParameters(t *testing.T) {
+	doc := &openapi31.OpenAPI{
+		Paths: &openapi31.Paths{
+			Paths: map[string]*openapi31.PathItem{
+				"/stores/{storeId}/pets": {
+					Parameters: []*openapi31.Parameter{
+						{Ref: "#/components/parameters/StoreId"},
+						{Name: "trace", In: "header", Required: true},
+					},
+					Post: &openapi31.Operation{
+						OperationID: "addPet",
+						Parameters: []*openapi31.Parameter{
+							// Overrides the path-level header.
+							{Name: "trace", In: "header", Required: false},
+						},
+						RequestBody: &openapi31.RequestBody{Ref: "#/components/requestBodies/Pet"},
+					},
+				},
+			},
+		},
+		Components: &openapi31.Components{
+			Parameters: map[string]*openapi31.Parameter{
+				"StoreId": {Name: "storeId", In: "path", Required: true},
+			},
+			RequestBodies: map[string]*openapi31.RequestBody{
+				"Pet": {Content: map[string]*openapi31.MediaType{
+					"application/json": {Example: map[string]any{"name": "Rex"}},
+				}},
+			},
+		},
+	}
+
+	step := &arazzo1.Step{OperationId: "addPet"}
+	enrichStepFromOpenAPI(step, doc)
+
+	assert.Equal(t, []any{
+		&arazzo1.Parameter{Name: "storeId", In: arazzo1.ParameterInPath, Value: "$inputs.storeId"},
+	}, step.Parameters)
+	if assert.NotNil(t, step.RequestBody) {
+		assert.Equal(t, "application/json", step.RequestBody.ContentType)
+		assert.Equal(t, map[string]any{"name": "Rex"}, step.RequestBody.Payload)
+	}
+}
@@ -1,9 +1,13 @@
 package generator
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewGeneratorFromArazzo(t *testing.T) {
@@ -90,3 +94,78 @@ paths:
 		t.Errorf("expected operation name 'getMyItem', got '%s'", op.Name)
 	}
 }
+
+func TestNewGeneratorFromArazzo_RoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "convert", "examples", "1.0.0", "*arazzo.*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			orig, err := readArazzo(file)
+			require.NoError(t, err)
+			want, err := json.Marshal(orig)
+			require.NoError(t, err)
+
+			gen, err := NewGeneratorFromArazzo(file, "")
+			require.NoError(t, err)
+			az, _, err := gen.ToArazzo("")
+			require.NoError(t, err)
+			got, err := json.Marshal(az)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+
+			// The config itself survives JSON.
+			data, err := json.Marshal(gen)
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), `"appendices"`, "empty optional fields are omitted")
+			var decoded Generator
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			az, _, err = decoded.ToArazzo("")
+			require.NoError(t, err)
+			got, err = json.Marshal(az)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
+
+func TestNewGeneratorFromArazzo_Lossless(t *testing.T) {
+	arazzoJSON := `{
+  "arazzo": "1.0.1",
+  "info": {"title": "Lossless", "version": "2.0.0", "x-owner": "payments"},
+  "sourceDescriptions": [
+    {"name": "bank", "url": "https://example.com/bank.yaml", "type": "openapi", "x-team": "core"},
+    {"name": "flows", "url": "flows.arazzo.yaml", "type": "arazzo"},
+    {"name": "auth", "url": "auth.yaml"}
+  ],
+  "workflows": [{
+    "workflowId": "pay",
+    "parameters": [{"name": "X-Request-Id", "in": "header", "value": "$inputs.requestId"}],
+    "steps": [
+      {
+        "stepId": "token",
+        "operationId": "$sourceDescriptions.auth.getToken",
+        "requestBody": {"contentType": "application/json", "replacements": [{"target": "/scope", "value": "pay"}]}
+      },
+      {"stepId": "login", "workflowId": "$sourceDescriptions.flows.login"}
+    ]
+  }]
+}`
+	arazzoFile := filepath.Join(t.TempDir(), "lossless.arazzo.json")
+	require.NoError(t, os.WriteFile(arazzoFile, []byte(arazzoJSON), 0644))
+
+	gen, err := NewGeneratorFromArazzo(arazzoFile, "")
+	require.NoError(t, err)
+	assert.True(t, gen.Verbatim)
+	assert.Equal(t, "payments", gen.Info.Extensions["x-owner"])
+	assert.Equal(t, "https://example.com/bank.yaml", gen.Provider.URL)
+	require.Len(t, gen.Sources, 2)
+	assert.Equal(t, "$sourceDescriptions.auth.getToken", gen.Workflows[0].Steps[0].OperationId)
+
+	az, _, err := gen.ToArazzo("")
+	require.NoError(t, err)
+	got, err := json.Marshal(az)
+	require.NoError(t, err)
+	assert.JSONEq(t, arazzoJSON, string(got))
+}
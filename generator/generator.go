@@ -7,21 +7,51 @@ import (
 
 // Generator represents a generator config.
 type Generator struct {
-	Provider   *Provider           `yaml:"provider" json:"provider" hcl:"provider,block"`
+	// Arazzo is the version of the generated document, 1.0.0 by default.
+	Arazzo string `yaml:"arazzo,omitempty" json:"arazzo,omitempty" hcl:"arazzo,optional"`
+	// Info is the info of the generated document. By default, it is derived
+	// from the OpenAPI document.
+	Info     *arazzo1.Info `yaml:"info,omitempty" json:"info,omitempty" hcl:"info,block"`
+	Provider *Provider     `yaml:"provider" json:"provider" hcl:"provider,block"`
+	// Sources declares further providers, for workflows spanning several APIs,
+	// such as an authorization server and a resource server. Each needs its
+	// OpenAPI file.
+	Sources    []*Provider         `yaml:"sources,omitempty" json:"sources,omitempty" hcl:"source,block"`
 	Workflows  []*WorkflowSpec     `yaml:"workflows" json:"workflows" hcl:"workflow,block"`
 	Components *arazzo1.Components `yaml:"components,omitempty" json:"components,omitempty" hcl:"components,block"`
 	Extensions map[string]any      `yaml:"extensions,omitempty" json:"extensions,omitempty" hcl:"extensions,optional"`
+	// Strict makes ToArazzo fail on diagnostics of error severity, such as
+	// operations missing from OpenAPI, so that CI can gate on configs.
+	Strict bool `yaml:"strict,omitempty" json:"strict,omitempty" hcl:"strict,optional"`
+	// Verbatim makes ToArazzo emit the workflows as written, without OpenAPI
+	// enrichment, wiring, token steps or inferred inputs. NewGeneratorFromArazzo
+	// sets it, so that existing Arazzo documents regenerate unchanged.
+	Verbatim bool `yaml:"verbatim,omitempty" json:"verbatim,omitempty" hcl:"verbatim,optional"`
 
 	// Internal
 	openapiDoc *openapi31.OpenAPI
+	sourceDocs map[string]*openapi31.OpenAPI // documents of Sources by name
 }
 
 // Provider represents the provider configuration.
 type Provider struct {
 	Name       string                 `yaml:"name" json:"name" hcl:"name"`
 	ServerURL  string                 `yaml:"server_url" json:"server_url" hcl:"server_url"`
-	Appendices map[string]interface{} `yaml:"appendices" json:"appendices" hcl:"appendices,optional"` // Reserves Info details
-	Extensions map[string]any         `yaml:"extensions,omitempty" json:"extensions,omitempty" hcl:"extensions,optional"`
+	Appendices map[string]interface{} `yaml:"appendices,omitempty" json:"appendices,omitempty" hcl:"appendices,optional"` // Reserves Info details
+	// URL is the url of the provider's source description. It defaults to
+	// the OpenAPI file.
+	URL string `yaml:"url,omitempty" json:"url,omitempty" hcl:"url,optional"`
+	// Type is the type of the provider's source description, openapi by
+	// default.
+	Type arazzo1.SourceDescriptionType `yaml:"type,omitempty" json:"type,omitempty" hcl:"type,optional"`
+	// OpenAPI is the OpenAPI file of a provider of Sources, relative to the
+	// generator file. It is also the URL of its source description.
+	OpenAPI string `yaml:"openapi,omitempty" json:"openapi,omitempty" hcl:"openapi,optional"`
+	// Security controls how OpenAPI security requirements are applied to steps.
+	Security *Security `yaml:"security,omitempty" json:"security,omitempty" hcl:"security,block"`
+	// Payload controls how request bodies are scaffolded from OpenAPI.
+	Payload    *Payload       `yaml:"payload,omitempty" json:"payload,omitempty" hcl:"payload,block"`
+	Extensions map[string]any `yaml:"extensions,omitempty" json:"extensions,omitempty" hcl:"extensions,optional"`
 }
 
 // WorkflowSpec defines a workflow in the generator.
@@ -36,8 +66,8 @@ type WorkflowSpec struct {
 	Outputs map[string]string `yaml:"outputs" json:"outputs" hcl:"outputs,block"`
 	// DependsOn specifies a list of other workflows (by their workflowId) that must successfully complete before this workflow can start.
 	DependsOn []string `yaml:"depends_on" json:"dependsOn" hcl:"depends_on,optional"`
-	// Parameters are not included in WorkflowSpec because the generator focuses on server-defined parameters (OpenAPI).
-	// Workflow-level parameters are not strictly defined by the server and should be handled via inputs or manually added if needed.
+	// Parameters apply to every operation step of the workflow. They are copied as written.
+	Parameters []*arazzo1.ParameterOrReusable `yaml:"parameters,omitempty" json:"parameters,omitempty" hcl:"parameter,block"`
 	// SuccessActions defines actions to take when the workflow completes successfully (e.g. emit an event, loop).
 	SuccessActions []*arazzo1.SuccessActionOrReusable `yaml:"success_actions" json:"successActions" hcl:"success_action,block"`
 	// FailureActions defines actions to take when the workflow fails (e.g. emit an event, retry).
@@ -63,7 +93,7 @@ type OperationSpec struct {
 	//    Example: { "username": "foo", "pwd": "bar" }
 	// 2. A map/object with a "payload" key: Treated as a full RequestBody configuration (allows setting contentType, replacements).
 	//    Example: { "contentType": "application/json", "payload": "{...}" }
-	// 3. Nil/Empty: Auto-generated from OpenAPI examples.
+	// 3. Nil/Empty: Auto-generated from OpenAPI examples, or synthesized from the request body schema.
 	// Note: For HCL compatibility, this must be a map. Raw strings are not supported in HCL generator input.
 	RequestBody map[string]interface{} `yaml:"request_body" json:"requestBody" hcl:"request_body,optional"`
 	// SuccessCriteria defines conditions for step success.
@@ -79,5 +109,9 @@ type OperationSpec struct {
 	OperationPath string            `yaml:"operation_path" json:"operationPath" hcl:"operation_path,optional"`
 	OperationId   string            `yaml:"operation_id" json:"operationId" hcl:"operation_id,optional"`
 	WorkflowId    string            `yaml:"workflow_id" json:"workflowId" hcl:"workflow_id,optional"`
-	Extensions    map[string]any    `yaml:"extensions,omitempty" json:"extensions,omitempty" hcl:"extensions,optional"`
+	// Source names the provider of the operation. When empty, it is the
+	// provider whose OpenAPI document defines the operation, or else the
+	// primary provider.
+	Source     string         `yaml:"source,omitempty" json:"source,omitempty" hcl:"source,optional"`
+	Extensions map[string]any `yaml:"extensions,omitempty" json:"extensions,omitempty" hcl:"extensions,optional"`
 }
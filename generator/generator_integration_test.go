@@ -66,7 +66,7 @@ func TestGenerator_ToArazzo_ParametersIntegration(t *testing.T) {
 	}
 
 	// 3. Execute ToArazzo
-	az, err := gen.ToArazzo("test.yaml")
+	az, _, err := gen.ToArazzo("test.yaml")
 	assert.NoError(t, err)
 	assert.NotNil(t, az)
 	assert.Len(t, az.Workflows, 1)
@@ -0,0 +1,341 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+	"gopkg.in/yaml.v3"
+)
+
+// InitOptions configures InitFromOpenAPI.
+type InitOptions struct {
+	// Name is the provider name. It defaults to "openapi".
+	Name string
+	// Tags selects the operations with at least one of the tags.
+	Tags []string
+	// Paths selects the operations whose path starts with one of the prefixes.
+	Paths []string
+	// Format is "yaml", the default, or "hcl".
+	Format string
+}
+
+// initStep is a step of a scaffolded generator config.
+type initStep struct {
+	name     string
+	op       *oasutil.Operation
+	required []*openapi31.Parameter
+}
+
+// initWorkflow is a workflow of a scaffolded generator config.
+type initWorkflow struct {
+	id      string
+	summary string
+	comment string
+	steps   []*initStep
+}
+
+// InitFileFromOpenAPI reads an OpenAPI file and scaffolds a generator config
+// for it. See InitFromOpenAPI.
+func InitFileFromOpenAPI(openapiFile string, opts *InitOptions) ([]byte, error) {
+	oaBytes, err := os.ReadFile(openapiFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading openapi file: %w", err)
+	}
+	doc, err := parseOpenAPI(oaBytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing openapi file: %w", err)
+	}
+	return InitFromOpenAPI(doc, opts)
+}
+
+// InitFromOpenAPI scaffolds a commented generator config, in YAML or HCL, for
+// the operations of doc selected by the tag and path filters. It proposes a
+// CRUD lifecycle workflow for each resource found in the paths: a collection
+// path accepting POST, followed by the GET, PUT or PATCH, and DELETE
+// operations of the collection path with one more path parameter, such as
+// /pets and /pets/{petId}; without PUT or PATCH on the item, those on the
+// collection update it. A last workflow lists every selected operation as
+// a candidate step. Steps list the operation's required parameters.
+func InitFromOpenAPI(doc *openapi31.OpenAPI, opts *InitOptions) ([]byte, error) {
+	var o InitOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Name == "" {
+		o.Name = "openapi"
+	}
+	if o.Format == "" {
+		o.Format = "yaml"
+	}
+	if o.Format != "yaml" && o.Format != "hcl" {
+		return nil, fmt.Errorf("unsupported format %q", o.Format)
+	}
+
+	var steps []*initStep
+	names := make(map[string]bool)
+	for _, p := range oasutil.SortedPaths(doc) {
+		item := doc.Paths.Paths[p]
+		for _, method := range oasutil.Methods {
+			op := oasutil.OperationOf(item, method)
+			if op == nil || !initSelected(p, op, &o) {
+				continue
+			}
+			name := operationStepId(method, p, op)
+			for base, i := name, 2; names[name]; i++ {
+				name = base + "-" + strconv.Itoa(i)
+			}
+			names[name] = true
+			step := &initStep{name: name, op: &oasutil.Operation{Method: method, Path: p, PathItem: item, Operation: op, Doc: doc}}
+			for _, param := range step.op.Parameters() {
+				if param.Required && !param.Deprecated {
+					step.required = append(step.required, param)
+				}
+			}
+			steps = append(steps, step)
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no operations match the filters")
+	}
+
+	workflows := crudWorkflows(steps)
+	workflows = append(workflows, &initWorkflow{
+		id:      "operations",
+		summary: "Candidate steps for the selected operations",
+		comment: "Every selected operation as a step. Move steps into workflows of their own, then remove this one.",
+		steps:   steps,
+	})
+
+	provider := &Provider{Name: o.Name}
+	if len(doc.Servers) > 0 {
+		provider.ServerURL = doc.Servers[0].URL
+	}
+	header := []string{"Generator config scaffolded from the OpenAPI document"}
+	if doc.Info != nil && doc.Info.Title != "" {
+		header[0] = fmt.Sprintf("Generator config scaffolded from %s %s", doc.Info.Title, doc.Info.Version)
+	}
+	header = append(header,
+		"Steps name their operation by operation_id, or by operation_path when it has none.",
+		"Required parameters are listed for reference: the generator includes them anyway,",
+		"wired to the output of an earlier step when one matches, or else to a workflow input.",
+	)
+
+	var buf bytes.Buffer
+	if o.Format == "hcl" {
+		writeInitHCL(&buf, header, provider, workflows)
+	} else {
+		writeInitYAML(&buf, header, provider, workflows)
+	}
+	return buf.Bytes(), nil
+}
+
+// initSelected reports whether the operation at path passes the filters.
+func initSelected(path string, op *openapi31.Operation, o *InitOptions) bool {
+	if len(o.Tags) > 0 {
+		found := false
+		for _, tag := range op.Tags {
+			for _, want := range o.Tags {
+				found = found || tag == want
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(o.Paths) > 0 {
+		for _, prefix := range o.Paths {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// crudWorkflows proposes a lifecycle workflow for each resource of steps.
+func crudWorkflows(steps []*initStep) []*initWorkflow {
+	byPath := make(map[string]map[string]*initStep)
+	var paths []string
+	for _, s := range steps {
+		if byPath[s.op.Path] == nil {
+			byPath[s.op.Path] = make(map[string]*initStep)
+			paths = append(paths, s.op.Path)
+		}
+		byPath[s.op.Path][s.op.Method] = s
+	}
+
+	var workflows []*initWorkflow
+	ids := make(map[string]bool)
+	for _, collection := range paths {
+		create := byPath[collection]["post"]
+		if create == nil {
+			continue
+		}
+		resource := resourceName(collection)
+		if resource == "" {
+			continue
+		}
+		for _, item := range paths {
+			param, ok := strings.CutPrefix(item, strings.TrimSuffix(collection, "/")+"/")
+			if !ok || !strings.HasPrefix(param, "{") || !strings.HasSuffix(param, "}") || strings.Contains(param, "/") {
+				continue
+			}
+			ops := byPath[item]
+			// Some APIs, like the pet store, update through the collection.
+			var update *initStep
+			for _, candidate := range []*initStep{ops["put"], ops["patch"], byPath[collection]["put"], byPath[collection]["patch"]} {
+				if update == nil {
+					update = candidate
+				}
+			}
+			wf := &initWorkflow{comment: fmt.Sprintf("Lifecycle of %s %s through %s and %s.", article(resource), resource, collection, item)}
+			verbs := []string{"Creates"}
+			wf.steps = append(wf.steps, create)
+			for _, s := range []struct {
+				step *initStep
+				verb string
+			}{{ops["get"], "reads"}, {update, "updates"}, {ops["delete"], "deletes"}} {
+				if s.step != nil {
+					wf.steps = append(wf.steps, s.step)
+					verbs = append(verbs, s.verb)
+				}
+			}
+			if len(wf.steps) == 1 {
+				continue
+			}
+			wf.summary = strings.Join(verbs[:len(verbs)-1], ", ") + " and " + verbs[len(verbs)-1] + " " + article(resource) + " " + resource
+			wf.id = stepIdFromName(resource) + "-lifecycle"
+			for base, i := wf.id, 2; ids[wf.id]; i++ {
+				wf.id = base + "-" + strconv.Itoa(i)
+			}
+			ids[wf.id] = true
+			workflows = append(workflows, wf)
+			break
+		}
+	}
+	return workflows
+}
+
+// resourceName returns the singular of the last literal segment of a path,
+// e.g. pet for /stores/{storeId}/pets.
+func resourceName(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		s := segments[i]
+		if s == "" || strings.HasPrefix(s, "{") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(s, "ies"):
+			return strings.TrimSuffix(s, "ies") + "y"
+		case strings.HasSuffix(s, "ss"):
+			return s
+		case strings.HasSuffix(s, "s"):
+			return strings.TrimSuffix(s, "s")
+		}
+		return s
+	}
+	return ""
+}
+
+// article returns the indefinite article for word.
+func article(word string) string {
+	if word != "" && strings.ContainsRune("aeiouAEIOU", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// comment returns the comment describing a step's operation.
+func (s *initStep) comment() string {
+	c := strings.ToUpper(s.op.Method) + " " + s.op.Path
+	if summary := strings.Join(strings.Fields(s.op.Operation.Summary), " "); summary != "" {
+		c += ": " + summary
+	}
+	if s.op.Operation.Deprecated {
+		c += " (deprecated)"
+	}
+	return c
+}
+
+// operationPath returns the operation_path of a step without operationId.
+func (s *initStep) operationPath() string {
+	return "#/paths/" + escapePointer(s.op.Path) + "/" + s.op.Method
+}
+
+func yamlString(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return strconv.Quote(s)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+func writeInitYAML(buf *bytes.Buffer, header []string, provider *Provider, workflows []*initWorkflow) {
+	for _, line := range header {
+		fmt.Fprintf(buf, "# %s\n", line)
+	}
+	fmt.Fprintf(buf, "provider:\n  name: %s\n  server_url: %s\n", yamlString(provider.Name), yamlString(provider.ServerURL))
+	buf.WriteString("\nworkflows:\n")
+	for i, wf := range workflows {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "  # %s\n", wf.comment)
+		fmt.Fprintf(buf, "  - workflow_id: %s\n    summary: %s\n    steps:\n", yamlString(wf.id), yamlString(wf.summary))
+		for _, s := range wf.steps {
+			fmt.Fprintf(buf, "      # %s\n", s.comment())
+			fmt.Fprintf(buf, "      - name: %s\n", yamlString(s.name))
+			if s.op.Operation.OperationID != "" {
+				fmt.Fprintf(buf, "        operation_id: %s\n", yamlString(s.op.Operation.OperationID))
+			} else {
+				fmt.Fprintf(buf, "        operation_path: %s\n", yamlString(s.operationPath()))
+			}
+			if len(s.required) > 0 {
+				buf.WriteString("        parameters:\n")
+				for _, p := range s.required {
+					fmt.Fprintf(buf, "          - %s # %s\n", yamlString(p.Name), p.In)
+				}
+			}
+		}
+	}
+}
+
+// hclString quotes s as an HCL string, escaping template sequences.
+func hclString(s string) string {
+	s = strings.NewReplacer("${", "$${", "%{", "%%{").Replace(s)
+	return strconv.Quote(s)
+}
+
+func writeInitHCL(buf *bytes.Buffer, header []string, provider *Provider, workflows []*initWorkflow) {
+	for _, line := range header {
+		fmt.Fprintf(buf, "# %s\n", line)
+	}
+	fmt.Fprintf(buf, "provider {\n  name       = %s\n  server_url = %s\n}\n", hclString(provider.Name), hclString(provider.ServerURL))
+	for _, wf := range workflows {
+		fmt.Fprintf(buf, "\n# %s\n", wf.comment)
+		fmt.Fprintf(buf, "workflow %s {\n  summary = %s\n", hclString(wf.id), hclString(wf.summary))
+		for _, s := range wf.steps {
+			fmt.Fprintf(buf, "\n  # %s\n", s.comment())
+			fmt.Fprintf(buf, "  step %s {\n", hclString(s.name))
+			if s.op.Operation.OperationID != "" {
+				fmt.Fprintf(buf, "    operation_id = %s\n", hclString(s.op.Operation.OperationID))
+			} else {
+				fmt.Fprintf(buf, "    operation_path = %s\n", hclString(s.operationPath()))
+			}
+			// Parameter blocks are listed commented out, as in the samples:
+			// the generator includes required parameters anyway.
+			for _, p := range s.required {
+				fmt.Fprintf(buf, "    # parameter {\n    #   name = %s\n    #   in   = %s\n    # }\n", hclString(p.Name), hclString(p.In))
+			}
+			buf.WriteString("  }\n")
+		}
+		buf.WriteString("}\n")
+	}
+}
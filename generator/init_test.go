@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/horizon/dethcl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestInitFromOpenAPI(t *testing.T) {
+	openapiFile := filepath.Join("testdata", "petstore.openapi.yaml")
+	doc, err := oasutil.ParseFile(openapiFile)
+	require.NoError(t, err)
+
+	for _, format := range []string{"yaml", "hcl"} {
+		t.Run(format, func(t *testing.T) {
+			out, err := InitFileFromOpenAPI(openapiFile, &InitOptions{Name: "petstore", Format: format})
+			require.NoError(t, err)
+			assert.Contains(t, string(out), "# GET /pet/{petId}: Find pet by ID.")
+
+			var gen Generator
+			if format == "hcl" {
+				require.NoError(t, dethcl.Unmarshal(out, &gen))
+			} else {
+				require.NoError(t, yaml.Unmarshal(out, &gen))
+			}
+			assert.Equal(t, "petstore", gen.Provider.Name)
+			assert.Equal(t, "https://petstore3.swagger.io/api/v3", gen.Provider.ServerURL)
+
+			var ids []string
+			for _, wf := range gen.Workflows {
+				ids = append(ids, wf.WorkflowId)
+			}
+			assert.Equal(t, []string{"pet-lifecycle", "order-lifecycle", "user-lifecycle", "operations"}, ids)
+
+			// The scaffold generates without errors.
+			gen.openapiDoc = doc
+			gen.Strict = true
+			az, _, err := gen.ToArazzo(openapiFile)
+			require.NoError(t, err)
+
+			pet := az.Workflows[0]
+			var steps []string
+			for _, s := range pet.Steps {
+				steps = append(steps, s.StepId)
+			}
+			assert.Equal(t, []string{"addPet", "getPetById", "updatePet", "deletePet"}, steps)
+			assert.Equal(t, "$steps.addPet.outputs.id", paramValue(t, pet.Steps[1], "petId"))
+		})
+	}
+}
+
+func TestInitFromOpenAPI_Filters(t *testing.T) {
+	doc, err := oasutil.ParseFile(filepath.Join("testdata", "petstore.openapi.yaml"))
+	require.NoError(t, err)
+
+	out, err := InitFromOpenAPI(doc, &InitOptions{Tags: []string{"user"}})
+	require.NoError(t, err)
+	var gen Generator
+	require.NoError(t, yaml.Unmarshal(out, &gen))
+	require.Len(t, gen.Workflows, 2)
+	assert.Equal(t, "user-lifecycle", gen.Workflows[0].WorkflowId)
+	for _, step := range gen.Workflows[1].Steps {
+		assert.Contains(t, step.OperationId, "User", step.Name)
+	}
+	assert.Equal(t, "openapi", gen.Provider.Name)
+
+	out, err = InitFromOpenAPI(doc, &InitOptions{Paths: []string{"/store"}})
+	require.NoError(t, err)
+	gen = Generator{}
+	require.NoError(t, yaml.Unmarshal(out, &gen))
+	assert.Equal(t, "order-lifecycle", gen.Workflows[0].WorkflowId)
+	assert.Len(t, gen.Workflows[1].Steps, 4)
+
+	_, err = InitFromOpenAPI(doc, &InitOptions{Tags: []string{"none"}})
+	assert.Error(t, err)
+}
+
+func TestResourceName(t *testing.T) {
+	for path, want := range map[string]string{
+		"/pets":                  "pet",
+		"/stores/{storeId}/pets": "pet",
+		"/categories":            "category",
+		"/address":               "address",
+		"/{id}":                  "",
+	} {
+		assert.Equal(t, want, resourceName(path), path)
+	}
+}
@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+// inputsPattern matches the $inputs references of a workflow.
+var inputsPattern = regexp.MustCompile(`\$inputs\.([A-Za-z0-9_\-]+)`)
+
+// maxInputDepth bounds the nesting of inferred input schemas.
+const maxInputDepth = 8
+
+// inputHint describes a $inputs value emitted by the generator.
+type inputHint struct {
+	doc         *openapi31.OpenAPI
+	schema      *openapi31.Schema
+	description string
+	required    bool
+}
+
+// hintInput records what is known about the named input. The first schema
+// and description win; the input is required if any use requires it.
+func (wc *workflowContext) hintInput(name string, doc *openapi31.OpenAPI, schema *openapi31.Schema, description string, required bool) {
+	if wc.inputs == nil {
+		wc.inputs = make(map[string]*inputHint)
+	}
+	h := wc.inputs[name]
+	if h == nil {
+		h = &inputHint{}
+		wc.inputs[name] = h
+	}
+	if h.schema == nil && schema != nil {
+		h.doc, h.schema = doc, schema
+	}
+	if h.description == "" {
+		h.description = description
+	}
+	h.required = h.required || required
+}
+
+// inferInputs returns the inputs JSON Schema of wf, with a property for each
+// $inputs name the workflow uses but does not declare. Properties take their
+// type, format, enum and description from the OpenAPI parameter or schema
+// they were generated for, and generated required values are listed as
+// required. Declared properties are kept as written; inputs given as a $ref
+// or in any other form than an object schema are returned unchanged.
+func (wc *workflowContext) inferInputs(wf *arazzo1.Workflow) any {
+	copied := *wf
+	copied.Inputs = nil
+	data, err := json.Marshal(&copied)
+	if err != nil {
+		return wf.Inputs
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range inputsPattern.FindAllStringSubmatch(string(data), -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	if len(names) == 0 {
+		return wf.Inputs
+	}
+
+	var schema map[string]any
+	switch v := wf.Inputs.(type) {
+	case nil:
+		schema = map[string]any{"type": "object"}
+	case map[string]any:
+		if _, ok := v["$ref"]; ok {
+			return wf.Inputs
+		}
+		schema = make(map[string]any, len(v)+2)
+		for k, item := range v {
+			schema[k] = item
+		}
+	default:
+		return wf.Inputs
+	}
+
+	properties := make(map[string]any)
+	if declared, ok := schema["properties"].(map[string]any); ok {
+		for k, item := range declared {
+			properties[k] = item
+		}
+	} else if schema["properties"] != nil {
+		return wf.Inputs
+	}
+	var required []string
+	isRequired := make(map[string]bool)
+	switch v := schema["required"].(type) {
+	case []string:
+		required = append(required, v...)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				required = append(required, s)
+			}
+		}
+	}
+	for _, name := range required {
+		isRequired[name] = true
+	}
+
+	added := false
+	for _, name := range names {
+		if _, ok := properties[name]; ok {
+			continue
+		}
+		added = true
+		h := wc.inputs[name]
+		if h == nil {
+			properties[name] = map[string]any{}
+			continue
+		}
+		properties[name] = inputProperty(h.doc, h.schema, h.description, 0)
+		if h.required && !isRequired[name] {
+			isRequired[name] = true
+			required = append(required, name)
+		}
+	}
+	if !added {
+		return wf.Inputs
+	}
+	schema["properties"] = properties
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// inputProperty converts an OpenAPI schema to the JSON Schema of an input,
+// keeping its type, format, enum and description.
+func inputProperty(doc *openapi31.OpenAPI, s *openapi31.Schema, description string, depth int) map[string]any {
+	prop := make(map[string]any)
+	s = oasutil.ResolveSchema(doc, s)
+	if s != nil && depth <= maxInputDepth {
+		var f flatSchema
+		flattenSchema(doc, s, &f, 0)
+		if f.typ != "" {
+			prop["type"] = f.typ
+		}
+		if s.Format != "" {
+			prop["format"] = s.Format
+		}
+		if len(f.enum) > 0 {
+			prop["enum"] = f.enum
+		}
+		if description == "" {
+			description = s.Description
+		}
+		switch f.typ {
+		case "array":
+			if s.Items != nil {
+				prop["items"] = inputProperty(doc, s.Items, "", depth+1)
+			}
+		case "object":
+			if len(f.properties) > 0 {
+				props := make(map[string]any, len(f.properties))
+				for name, p := range f.properties {
+					props[name] = inputProperty(doc, p, "", depth+1)
+				}
+				prop["properties"] = props
+			}
+			if len(f.required) > 0 {
+				prop["required"] = f.required
+			}
+		}
+	}
+	if description != "" {
+		prop["description"] = description
+	}
+	return prop
+}
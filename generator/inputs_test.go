@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/genelet/oas/openapi31"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferInputs(t *testing.T) {
+	doc := wiringDoc()
+	getPet := doc.Paths.Paths["/pets/{petId}"].Get
+	getPet.Parameters[0].Description = "The pet to fetch."
+	getPet.Parameters = append(getPet.Parameters, &openapi31.Parameter{
+		Name: "view", In: "query",
+		Schema: &openapi31.Schema{Type: &openapi31.StringOrStringArray{String: "string"}, Enum: []any{"short", "full"}},
+	})
+	gen := &Generator{
+		openapiDoc: doc,
+		Provider:   &Provider{Name: "pets"},
+		Workflows: []*WorkflowSpec{{
+			WorkflowId: "wf",
+			Inputs: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"note": map[string]any{"type": "string", "maxLength": 80}},
+				"required":   []any{"note"},
+			},
+			Steps: []*OperationSpec{
+				{Name: "get", OperationId: "getPet", Parameters: []any{"view"}},
+				{Name: "adopt", OperationId: "adopt"},
+				{Name: "custom", OperationId: "getThing", Parameters: []any{
+					map[string]any{"name": "id", "in": "path", "value": "$inputs.thing"},
+				}},
+			},
+		}},
+	}
+	az, _, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"petId":  map[string]any{"type": "integer", "description": "The pet to fetch."},
+			"view":   map[string]any{"type": "string", "enum": []any{"short", "full"}},
+			"note":   map[string]any{"type": "string", "maxLength": 80},
+			"pet_id": map[string]any{"type": "integer"},
+			"thing":  map[string]any{},
+		},
+		"required": []string{"note", "petId", "pet_id"},
+	}, az.Workflows[0].Inputs)
+	// The config is not modified.
+	assert.Len(t, gen.Workflows[0].Inputs.(map[string]any)["properties"], 1)
+}
+
+func TestInferInputs_Security(t *testing.T) {
+	gen := &Generator{
+		openapiDoc: securityDoc(),
+		Provider:   &Provider{Name: "secured", Security: &Security{Prefer: []string{"apiKey"}}},
+		Workflows:  []*WorkflowSpec{{WorkflowId: "wf", Steps: []*OperationSpec{{Name: "create", OperationId: "createItem"}}}},
+	}
+	az, _, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"apiKey": map[string]any{"type": "string", "description": "Credential for the apiKey security scheme."},
+		},
+		"required": []string{"apiKey"},
+	}, az.Workflows[0].Inputs)
+}
@@ -54,7 +54,7 @@ func TestGenerator_ToArazzo_InputsOutputsIntegration(t *testing.T) {
 	}
 
 	// 3. Execute ToArazzo
-	az, err := gen.ToArazzo("test.yaml")
+	az, _, err := gen.ToArazzo("test.yaml")
 	assert.NoError(t, err)
 
 	// 4. Verify Inputs/Outputs in Arazzo
@@ -145,7 +145,7 @@ func TestGenerator_ToArazzo_DataAssignments(t *testing.T) {
 	}
 
 	// 3. Execute ToArazzo
-	az, err := gen.ToArazzo("test.yaml")
+	az, _, err := gen.ToArazzo("test.yaml")
 	assert.NoError(t, err)
 
 	step := az.Workflows[0].Steps[0]
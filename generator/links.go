@@ -0,0 +1,305 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+// LinkOptions configures the derivation of workflows from OpenAPI links.
+type LinkOptions struct {
+	// Name is the source description name. It defaults to "openapi".
+	Name string
+	// MaxSteps bounds the number of steps of a workflow. It defaults to 10.
+	MaxSteps int
+}
+
+// linkNode is an operation of the links graph.
+type linkNode struct {
+	id    string // stepId
+	op    *oasutil.Operation
+	edges []*linkEdge
+	in    int // number of incoming edges
+}
+
+// linkEdge is a link from the response of one operation to another.
+type linkEdge struct {
+	name   string
+	status string
+	link   *openapi31.Link
+	to     *linkNode
+}
+
+// NewArazzoFromLinks generates an Arazzo document from the links of an
+// OpenAPI file, without a generator config. See NewGeneratorFromLinks.
+func NewArazzoFromLinks(openapiFile string, opts *LinkOptions) (*arazzo1.Arazzo, error) {
+	oaBytes, err := os.ReadFile(openapiFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading openapi file: %w", err)
+	}
+	doc, err := parseOpenAPI(oaBytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing openapi file: %w", err)
+	}
+	gen, err := NewGeneratorFromLinks(doc, opts)
+	if err != nil {
+		return nil, err
+	}
+	arazzo, _, err := gen.ToArazzo(openapiFile)
+	return arazzo, err
+}
+
+// NewGeneratorFromLinks derives a Generator config from the links declared on
+// the responses of an OpenAPI document. Each path through the links graph,
+// from an operation no link leads to until an operation without links,
+// becomes a candidate workflow, with one step per operation. A link's
+// parameters become parameters of the linked step, referencing outputs added
+// to the linking step, and the linking step succeeds on the link's status code.
+// Links to operations that cannot be resolved, such as operationRefs into
+// other documents, are skipped.
+func NewGeneratorFromLinks(doc *openapi31.OpenAPI, opts *LinkOptions) (*Generator, error) {
+	name, maxSteps := "openapi", 10
+	if opts != nil {
+		if opts.Name != "" {
+			name = opts.Name
+		}
+		if opts.MaxSteps > 0 {
+			maxSteps = opts.MaxSteps
+		}
+	}
+
+	nodes := linkGraph(doc)
+	var roots []*linkNode
+	for _, n := range nodes {
+		if len(n.edges) > 0 && n.in == 0 {
+			roots = append(roots, n)
+		}
+	}
+
+	gen := &Generator{openapiDoc: doc, Provider: &Provider{Name: name}}
+	if len(doc.Servers) > 0 {
+		gen.Provider.ServerURL = doc.Servers[0].URL
+	}
+	ids := make(map[string]bool)
+	reached := make(map[*linkNode]bool)
+	var walk func(path []*linkNode, edges []*linkEdge)
+	walk = func(path []*linkNode, edges []*linkEdge) {
+		last := path[len(path)-1]
+		reached[last] = true
+		extended := false
+		if len(path) < maxSteps {
+			for _, e := range last.edges {
+				if containsNode(path, e.to) {
+					continue
+				}
+				extended = true
+				walk(append(path[:len(path):len(path)], e.to), append(edges[:len(edges):len(edges)], e))
+			}
+		}
+		if !extended && len(path) > 1 {
+			gen.Workflows = append(gen.Workflows, linkWorkflow(path, edges, ids))
+		}
+	}
+	for _, root := range roots {
+		walk([]*linkNode{root}, nil)
+	}
+	// Operations only reachable through cycles start from the first unreached one.
+	for _, n := range nodes {
+		if len(n.edges) > 0 && !reached[n] {
+			walk([]*linkNode{n}, nil)
+		}
+	}
+
+	if len(gen.Workflows) == 0 {
+		return nil, fmt.Errorf("no links between operations found in openapi document")
+	}
+	return gen, nil
+}
+
+// linkGraph returns the operations of doc in path and method order, with
+// the links of their responses resolved to other operations.
+func linkGraph(doc *openapi31.OpenAPI) []*linkNode {
+	var nodes []*linkNode
+	byOp := make(map[*openapi31.Operation]*linkNode)
+	byID := make(map[string]*linkNode)
+	ids := make(map[string]bool)
+	for _, p := range oasutil.SortedPaths(doc) {
+		item := doc.Paths.Paths[p]
+		for _, method := range oasutil.Methods {
+			op := oasutil.OperationOf(item, method)
+			if op == nil {
+				continue
+			}
+			id := operationStepId(method, p, op)
+			for base, i := id, 2; ids[id]; i++ {
+				id = base + "-" + strconv.Itoa(i)
+			}
+			ids[id] = true
+			n := &linkNode{id: id, op: &oasutil.Operation{Method: method, Path: p, PathItem: item, Operation: op, Doc: doc}}
+			nodes = append(nodes, n)
+			byOp[op] = n
+			if op.OperationID != "" {
+				byID[op.OperationID] = n
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		if n.op.Operation.Responses == nil {
+			continue
+		}
+		codes := make([]string, 0, len(n.op.Operation.Responses.StatusCode))
+		for code := range n.op.Operation.Responses.StatusCode {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			resp := oasutil.ResolveResponse(doc, n.op.Operation.Responses.StatusCode[code])
+			if resp == nil {
+				continue
+			}
+			names := make([]string, 0, len(resp.Links))
+			for name := range resp.Links {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				link := resolveLink(doc, resp.Links[name])
+				if link == nil {
+					continue
+				}
+				var to *linkNode
+				if link.OperationId != "" {
+					to = byID[link.OperationId]
+				} else if strings.HasPrefix(link.OperationRef, "#") {
+					to = byOp[resolveOperationByPath(doc, link.OperationRef)]
+				}
+				if to == nil || to == n {
+					continue
+				}
+				n.edges = append(n.edges, &linkEdge{name: name, status: code, link: link, to: to})
+				to.in++
+			}
+		}
+	}
+	return nodes
+}
+
+// operationStepId returns a stepId for an operation: its operationId, or else
+// a slug of its method and path, e.g. get-users-username-repos for
+// GET /users/{username}/repos.
+func operationStepId(method, path string, op *openapi31.Operation) string {
+	if id := stepIdFromName(op.OperationID); id != "" {
+		return id
+	}
+	return strings.Join(strings.FieldsFunc(stepIdFromName(method+path), func(r rune) bool { return r == '-' }), "-")
+}
+
+// resolveLink follows a local "#/components/links/<name>" reference.
+func resolveLink(doc *openapi31.OpenAPI, l *openapi31.Link) *openapi31.Link {
+	for i := 0; l != nil && l.Ref != "" && i < 32; i++ {
+		name, ok := strings.CutPrefix(l.Ref, "#/components/links/")
+		if !ok || doc.Components == nil {
+			return nil
+		}
+		l = doc.Components.Links[oasutil.UnescapePointer(name)]
+	}
+	return l
+}
+
+func containsNode(path []*linkNode, n *linkNode) bool {
+	for _, p := range path {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+// linkWorkflow builds the workflow following edges through path.
+func linkWorkflow(path []*linkNode, edges []*linkEdge, ids map[string]bool) *WorkflowSpec {
+	stepIDs := make([]string, len(path))
+	for i, n := range path {
+		stepIDs[i] = n.id
+	}
+	id := strings.Join(stepIDs, "-")
+	for base, i := id, 2; ids[id]; i++ {
+		id = base + "-" + strconv.Itoa(i)
+	}
+	ids[id] = true
+
+	wf := &WorkflowSpec{
+		WorkflowId: id,
+		Summary:    fmt.Sprintf("Follows the links from %s to %s", path[0].id, path[len(path)-1].id),
+	}
+	steps := make([]*OperationSpec, len(path))
+	for i, n := range path {
+		steps[i] = &OperationSpec{Name: n.id, Description: n.op.Operation.Summary}
+		if n.op.Operation.OperationID != "" {
+			steps[i].OperationId = n.op.Operation.OperationID
+		} else {
+			steps[i].OperationPath = "#/paths/" + escapePointer(n.op.Path) + "/" + n.op.Method
+		}
+	}
+
+	for i, e := range edges {
+		from, to := steps[i], steps[i+1]
+		if _, err := strconv.Atoi(e.status); err == nil {
+			from.SuccessCriteria = []*arazzo1.Criterion{{Condition: "$statusCode == " + e.status}}
+		}
+		if e.link.Description != "" {
+			to.Description = e.link.Description
+		}
+
+		names := make([]string, 0, len(e.link.Parameters))
+		for name := range e.link.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			param := &arazzo1.Parameter{Name: name}
+			// Parameter names may be qualified by location, e.g. path.id.
+			if in, rest, ok := strings.Cut(name, "."); ok {
+				switch in {
+				case "path", "query", "header", "cookie":
+					param.In, param.Name = arazzo1.ParameterIn(in), rest
+				}
+			}
+			param.Value = linkValue(from, param.Name, e.link.Parameters[name])
+			to.Parameters = append(to.Parameters, param)
+		}
+		if e.link.RequestBody != nil {
+			payload := e.link.RequestBody
+			if s, ok := payload.(string); ok {
+				payload = linkValue(from, "requestBody", s)
+			}
+			to.RequestBody = map[string]any{"payload": payload}
+		}
+	}
+	wf.Steps = steps
+	return wf
+}
+
+// linkValue returns the value of a link parameter for the linked step. A
+// runtime expression is evaluated by the linking step as an output named
+// after the parameter, and referenced from there; constants are used as is.
+func linkValue(from *OperationSpec, name, value string) any {
+	if !strings.HasPrefix(value, "$") {
+		return value
+	}
+	if from.Outputs == nil {
+		from.Outputs = make(map[string]string)
+	}
+	output := stepIdFromName(name)
+	for base, i := output, 2; from.Outputs[output] != "" && from.Outputs[output] != value; i++ {
+		output = base + strconv.Itoa(i)
+	}
+	from.Outputs[output] = value
+	return "$steps." + from.Name + ".outputs." + output
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/oas/openapi31"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// linksDoc follows the links example of the OpenAPI specification: a created
+// user links to its details, which link to the user's repositories.
+func linksDoc() *openapi31.OpenAPI {
+	typ := func(s string) *openapi31.StringOrStringArray { return &openapi31.StringOrStringArray{String: s} }
+	pathParam := func(name string) *openapi31.Parameter {
+		return &openapi31.Parameter{Name: name, In: "path", Required: true, Schema: &openapi31.Schema{Type: typ("string")}}
+	}
+	return &openapi31.OpenAPI{
+		Info:    &openapi31.Info{Title: "Users"},
+		Servers: []*openapi31.Server{{URL: "https://api.example.com"}},
+		Paths: &openapi31.Paths{
+			Paths: map[string]*openapi31.PathItem{
+				"/users": {Post: &openapi31.Operation{
+					OperationID: "createUser",
+					Responses: &openapi31.Responses{StatusCode: map[string]*openapi31.Response{
+						"201": {Links: map[string]*openapi31.Link{
+							"GetUserByUserId": {
+								OperationId: "getUser",
+								Parameters:  map[string]string{"path.userId": "$response.body#/id"},
+							},
+						}},
+					}},
+				}},
+				"/users/{userId}": {Get: &openapi31.Operation{
+					OperationID: "getUser",
+					Parameters:  []*openapi31.Parameter{pathParam("userId")},
+					Responses: &openapi31.Responses{StatusCode: map[string]*openapi31.Response{
+						"200": {Links: map[string]*openapi31.Link{
+							"UserRepositories": {Ref: "#/components/links/UserRepositories"},
+							"Self": {
+								OperationRef: "#/paths/~1users~1{userId}/get",
+								Parameters:   map[string]string{"userId": "$request.path.userId"},
+							},
+						}},
+					}},
+				}},
+				"/users/{username}/repos": {Get: &openapi31.Operation{
+					Parameters: []*openapi31.Parameter{pathParam("username")},
+				}},
+				"/status": {Get: &openapi31.Operation{OperationID: "status"}},
+			},
+		},
+		Components: &openapi31.Components{
+			Links: map[string]*openapi31.Link{
+				"UserRepositories": {
+					OperationRef: "#/paths/~1users~1{username}~1repos/get",
+					Parameters:   map[string]string{"username": "$response.body#/username", "sort": "updated"},
+					Description:  "Lists the repositories of the user.",
+				},
+			},
+		},
+	}
+}
+
+func TestNewGeneratorFromLinks(t *testing.T) {
+	gen, err := NewGeneratorFromLinks(linksDoc(), &LinkOptions{Name: "users"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com", gen.Provider.ServerURL)
+
+	require.Len(t, gen.Workflows, 1)
+	wf := gen.Workflows[0]
+	assert.Equal(t, "createUser-getUser-get-users-username-repos", wf.WorkflowId)
+	require.Len(t, wf.Steps, 3)
+
+	create, get, repos := wf.Steps[0], wf.Steps[1], wf.Steps[2]
+	assert.Equal(t, "createUser", create.OperationId)
+	assert.Equal(t, map[string]string{"userId": "$response.body#/id"}, create.Outputs)
+	assert.Equal(t, "$statusCode == 201", create.SuccessCriteria[0].Condition)
+
+	assert.Equal(t, []any{&arazzo1.Parameter{Name: "userId", In: arazzo1.ParameterInPath, Value: "$steps.createUser.outputs.userId"}}, get.Parameters)
+	assert.Equal(t, map[string]string{"username": "$response.body#/username"}, get.Outputs)
+
+	assert.Equal(t, "#/paths/~1users~1{username}~1repos/get", repos.OperationPath)
+	assert.Equal(t, "Lists the repositories of the user.", repos.Description)
+	assert.Equal(t, []any{
+		&arazzo1.Parameter{Name: "sort", Value: "updated"},
+		&arazzo1.Parameter{Name: "username", Value: "$steps.getUser.outputs.username"},
+	}, repos.Parameters)
+
+	az, _, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "users", az.SourceDescriptions[0].Name)
+	assert.Len(t, az.Workflows[0].Steps, 3)
+}
+
+func TestNewGeneratorFromLinksCycle(t *testing.T) {
+	doc := linksDoc()
+	// Link the user details back to user creation: no operation is a root.
+	doc.Paths.Paths["/users/{userId}"].Get.Responses.StatusCode["200"].Links["Again"] = &openapi31.Link{OperationId: "createUser"}
+
+	gen, err := NewGeneratorFromLinks(doc, &LinkOptions{MaxSteps: 2})
+	require.NoError(t, err)
+	for _, wf := range gen.Workflows {
+		assert.LessOrEqual(t, len(wf.Steps), 2, wf.WorkflowId)
+	}
+	assert.Equal(t, "createUser-getUser", gen.Workflows[0].WorkflowId)
+}
+
+func TestNewArazzoFromLinks(t *testing.T) {
+	az, err := NewArazzoFromLinks(filepath.Join("..", "convert", "examples", "1.0.0", "FAPI-PAR.openapi.yaml"), nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, az.Workflows)
+
+	wf := az.Workflows[0]
+	require.GreaterOrEqual(t, len(wf.Steps), 2)
+	assert.Equal(t, "$response.body#/request_uri", wf.Steps[0].Outputs["request_uri"])
+	assert.Equal(t, "$steps."+wf.Steps[0].StepId+".outputs.request_uri", paramValue(t, wf.Steps[1], "request_uri"))
+
+	_, err = NewGeneratorFromLinks(&openapi31.OpenAPI{Paths: &openapi31.Paths{Paths: map[string]*openapi31.PathItem{
+		"/status": {Get: &openapi31.Operation{OperationID: "status"}},
+	}}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,204 @@
+package generator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+// Payload configures how request bodies are scaffolded from OpenAPI.
+type Payload struct {
+	// ContentTypes lists media types in order of preference, either exact or as
+	// a "type/*" range. Without a match, application/json is used, then any
+	// +json type, then the first declared type in lexical order.
+	ContentTypes []string `yaml:"content_types,omitempty" json:"contentTypes,omitempty" hcl:"content_types,optional"`
+	// Defaults fills scalar and array properties with typed zero values instead
+	// of $inputs.<field> placeholders.
+	Defaults bool `yaml:"defaults,omitempty" json:"defaults,omitempty" hcl:"defaults,optional"`
+}
+
+// maxPayloadDepth bounds the nesting of synthesized payloads.
+const maxPayloadDepth = 16
+
+// payloadBuilder synthesizes a request payload from a request body schema.
+type payloadBuilder struct {
+	doc          *openapi31.OpenAPI
+	defaults     bool
+	wire         func(name, typ, pointer string) (string, bool)
+	input        func(name string, s *openapi31.Schema, required bool)
+	replacements []*arazzo1.PayloadReplacement
+	visiting     map[*openapi31.Schema]bool
+}
+
+// scaffoldPayload builds a payload for schema. Object schemas yield their
+// required properties, or all properties at the top level when none are
+// required. Properties use the schema's const or default when declared;
+// properties that wire resolves to an output of an earlier step get a typed
+// zero value and a replacement with that output; any other property becomes a
+// $inputs.<field> placeholder, or a typed zero value with Payload.Defaults.
+// input, when not nil, is told the schema of each placeholder and whether the
+// field is required.
+func scaffoldPayload(doc *openapi31.OpenAPI, schema *openapi31.Schema, cfg *Payload, wire func(name, typ, pointer string) (string, bool), input func(name string, s *openapi31.Schema, required bool)) (any, []*arazzo1.PayloadReplacement) {
+	b := &payloadBuilder{
+		doc:      doc,
+		defaults: cfg != nil && cfg.Defaults,
+		wire:     wire,
+		input:    input,
+		visiting: make(map[*openapi31.Schema]bool),
+	}
+	return b.value(schema, "", "", 0, true), b.replacements
+}
+
+// flatSchema is a schema with allOf members merged and the first oneOf or
+// anyOf alternative chosen.
+type flatSchema struct {
+	typ        string
+	properties map[string]*openapi31.Schema
+	required   []string
+	enum       []any
+	constant   any
+	defaultVal any
+}
+
+// flattenSchema merges s into f.
+func flattenSchema(doc *openapi31.OpenAPI, s *openapi31.Schema, f *flatSchema, depth int) {
+	s = oasutil.ResolveSchema(doc, s)
+	if s == nil || depth > maxPayloadDepth {
+		return
+	}
+	if f.typ == "" {
+		f.typ = oasutil.SchemaType(s)
+	}
+	if f.typ == "" && len(s.Properties) > 0 {
+		f.typ = "object"
+	}
+	for name, p := range s.Properties {
+		if f.properties == nil {
+			f.properties = make(map[string]*openapi31.Schema)
+		}
+		if _, ok := f.properties[name]; !ok {
+			f.properties[name] = p
+		}
+	}
+	f.required = append(f.required, s.Required...)
+	if f.enum == nil {
+		f.enum = s.Enum
+	}
+	if f.constant == nil {
+		f.constant = s.Const
+	}
+	if f.defaultVal == nil {
+		f.defaultVal = s.Default
+	}
+	for _, member := range s.AllOf {
+		flattenSchema(doc, member, f, depth+1)
+	}
+	// Alternatives are exclusive; the first one is scaffolded.
+	if len(s.OneOf) > 0 {
+		flattenSchema(doc, s.OneOf[0], f, depth+1)
+	} else if len(s.AnyOf) > 0 {
+		flattenSchema(doc, s.AnyOf[0], f, depth+1)
+	}
+}
+
+// value returns the payload value for the schema of the named field at pointer.
+func (b *payloadBuilder) value(s *openapi31.Schema, name, pointer string, depth int, required bool) any {
+	s = oasutil.ResolveSchema(b.doc, s)
+	if s == nil {
+		// A required property without a schema.
+		if name == "" || b.defaults {
+			return nil
+		}
+		return b.placeholder(name, nil, required)
+	}
+	if b.visiting[s] || depth > maxPayloadDepth {
+		return nil
+	}
+	b.visiting[s] = true
+	defer delete(b.visiting, s)
+
+	var f flatSchema
+	flattenSchema(b.doc, s, &f, 0)
+	switch {
+	case f.constant != nil:
+		return f.constant
+	case f.defaultVal != nil:
+		return f.defaultVal
+	}
+	if name != "" && b.wire != nil {
+		if expr, ok := b.wire(name, f.typ, pointer); ok {
+			b.replacements = append(b.replacements, &arazzo1.PayloadReplacement{Target: pointer, Value: expr})
+			return zeroValue(f.typ)
+		}
+	}
+
+	if f.typ == "object" {
+		names := f.required
+		if depth == 0 && len(names) == 0 {
+			for prop := range f.properties {
+				names = append(names, prop)
+			}
+		}
+		isRequired := make(map[string]bool, len(f.required))
+		for _, prop := range f.required {
+			isRequired[prop] = true
+		}
+		sort.Strings(names)
+		out := make(map[string]any)
+		for _, prop := range names {
+			if _, done := out[prop]; done {
+				continue
+			}
+			p := oasutil.ResolveSchema(b.doc, f.properties[prop])
+			if p != nil && p.ReadOnly {
+				continue
+			}
+			out[prop] = b.value(p, prop, pointer+"/"+escapePointer(prop), depth+1, isRequired[prop])
+		}
+		return out
+	}
+
+	if b.defaults {
+		if len(f.enum) > 0 {
+			return f.enum[0]
+		}
+		return zeroValue(f.typ)
+	}
+	if name == "" {
+		name = "body"
+	}
+	return b.placeholder(name, s, required)
+}
+
+// placeholder returns the $inputs placeholder of the named field.
+func (b *payloadBuilder) placeholder(name string, s *openapi31.Schema, required bool) string {
+	if b.input != nil {
+		b.input(name, s, required)
+	}
+	return "$inputs." + name
+}
+
+// zeroValue returns the zero value of a JSON Schema type.
+func zeroValue(typ string) any {
+	switch typ {
+	case "object":
+		return map[string]any{}
+	case "array":
+		return []any{}
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "string":
+		return ""
+	}
+	return nil
+}
+
+// escapePointer escapes a JSON Pointer reference token.
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
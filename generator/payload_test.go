@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/oas/openapi31"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// payloadDoc returns a document whose createOrder body is described only by
+// a schema built from $ref, allOf and oneOf.
+func payloadDoc() *openapi31.OpenAPI {
+	typ := func(s string) *openapi31.StringOrStringArray { return &openapi31.StringOrStringArray{String: s} }
+	return &openapi31.OpenAPI{
+		Info: &openapi31.Info{Title: "Orders"},
+		Paths: &openapi31.Paths{
+			Paths: map[string]*openapi31.PathItem{
+				"/pets": {
+					Post: &openapi31.Operation{OperationID: "createPet"},
+				},
+				"/orders": {
+					Post: &openapi31.Operation{
+						OperationID: "createOrder",
+						RequestBody: &openapi31.RequestBody{
+							Content: map[string]*openapi31.MediaType{
+								"application/xml": {Schema: &openapi31.Schema{Type: typ("string")}},
+								"application/json": {Schema: &openapi31.Schema{AllOf: []*openapi31.Schema{
+									{Ref: "#/components/schemas/Base"},
+									{Ref: "#/components/schemas/Order"},
+								}}},
+							},
+						},
+					},
+				},
+			},
+		},
+		Components: &openapi31.Components{
+			Schemas: map[string]*openapi31.Schema{
+				"Base": {
+					Type:     typ("object"),
+					Required: []string{"id"},
+					Properties: map[string]*openapi31.Schema{
+						"id": {Type: typ("integer"), ReadOnly: true},
+					},
+				},
+				"Order": {
+					Type:     typ("object"),
+					Required: []string{"petId", "quantity", "status", "shipping", "notes"},
+					Properties: map[string]*openapi31.Schema{
+						"petId":    {Type: typ("integer")},
+						"quantity": {Type: typ("integer"), Default: 1},
+						"status":   {Type: typ("string"), Enum: []any{"placed", "approved"}},
+						"tags":     {Type: typ("array"), Items: &openapi31.Schema{Type: typ("string")}},
+						"notes":    {Type: typ("array"), Items: &openapi31.Schema{Type: typ("string")}},
+						"shipping": {OneOf: []*openapi31.Schema{
+							{Ref: "#/components/schemas/Address"},
+							{Type: typ("string")},
+						}},
+					},
+				},
+				"Address": {
+					Type:     typ("object"),
+					Required: []string{"street", "country"},
+					Properties: map[string]*openapi31.Schema{
+						"street":  {Type: typ("string")},
+						"country": {Const: "US"},
+						"parent":  {Ref: "#/components/schemas/Address"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPayload_FromSchema(t *testing.T) {
+	doc := payloadDoc()
+	step := &arazzo1.Step{OperationId: "createOrder"}
+	enrichStepFromOpenAPI(step, doc)
+
+	require.NotNil(t, step.RequestBody)
+	assert.Equal(t, "application/json", step.RequestBody.ContentType)
+	assert.Equal(t, map[string]any{
+		"petId":    "$inputs.petId",
+		"quantity": 1,
+		"status":   "$inputs.status",
+		"notes":    "$inputs.notes",
+		"shipping": map[string]any{
+			"street":  "$inputs.street",
+			"country": "US",
+		},
+	}, step.RequestBody.Payload)
+	assert.Empty(t, step.RequestBody.Replacements)
+}
+
+func TestPayload_Defaults(t *testing.T) {
+	doc := payloadDoc()
+	step := &arazzo1.Step{OperationId: "createOrder"}
+	enrichStep(step, doc, newWorkflowContext(doc, &Provider{Payload: &Payload{Defaults: true}}))
+
+	assert.Equal(t, map[string]any{
+		"petId":    0,
+		"quantity": 1,
+		"status":   "placed",
+		"notes":    []any{},
+		"shipping": map[string]any{
+			"street":  "",
+			"country": "US",
+		},
+	}, step.RequestBody.Payload)
+}
+
+func TestPayload_ContentTypePreference(t *testing.T) {
+	doc := payloadDoc()
+	step := &arazzo1.Step{OperationId: "createOrder"}
+	enrichStep(step, doc, newWorkflowContext(doc, &Provider{Payload: &Payload{ContentTypes: []string{"application/xml"}}}))
+
+	assert.Equal(t, "application/xml", step.RequestBody.ContentType)
+	assert.Equal(t, "$inputs.body", step.RequestBody.Payload)
+}
+
+func TestPayload_Replacements(t *testing.T) {
+	gen := &Generator{
+		openapiDoc: payloadDoc(),
+		Provider:   &Provider{Name: "orders"},
+		Workflows: []*WorkflowSpec{{
+			WorkflowId: "order-pet",
+			Steps: []*OperationSpec{
+				{Name: "pet", OperationId: "createPet", Outputs: map[string]string{"petId": "$response.body#/id"}},
+				{Name: "order", OperationId: "createOrder"},
+			},
+		}},
+	}
+	az, _, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+
+	rb := az.Workflows[0].Steps[1].RequestBody
+	require.NotNil(t, rb)
+	assert.Equal(t, 0, rb.Payload.(map[string]any)["petId"])
+	assert.Equal(t, []*arazzo1.PayloadReplacement{
+		{Target: "/petId", Value: "$steps.pet.outputs.petId"},
+	}, rb.Replacements)
+}
@@ -39,7 +39,8 @@ workflow "full-demo-workflow" {
   step "fetchUser" {
     operation_id = "getUser"
     
-    # Mode 1: Auto-include mandatory 'id' override
+    # Mode 1: 'id' is required; without this override it defaults to $inputs.id,
+    # since no earlier step produces an id.
     # parameter {
     #   name  = "id"
     #   in    = "path"
@@ -65,12 +66,8 @@ workflow "full-demo-workflow" {
 
   step "updateProfile" {
     operation_id = "updateProfile"
-    
-    # parameter {
-    #   name  = "id"
-    #   in    = "path"
-    #   value = 999
-    # }
+
+    # 'id' is auto-included and wired to fetchUser's response: $steps.fetchUser.outputs.id
 
     # Explicit Simple RequestBody
     # Since RequestBody is interface{}, HCL decoding might be tricky if we want a simple map.
@@ -5,16 +5,17 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/genelet/arazzo/arazzo1"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 )
 
 func TestSampleGeneration(t *testing.T) {
-	wd, _ := os.Getwd()
-	sampleDir := filepath.Join(wd, "samples")
+	// Relative paths keep the source URL of the sample deterministic.
+	sampleDir := "samples"
 	openapiFile := filepath.Join(sampleDir, "sample.openapi.yaml")
 	generatorFile := filepath.Join(sampleDir, "sample.generator.yaml")
-	outputFile := filepath.Join(sampleDir, "sample.arazzo.yaml")
+	sampleFile := filepath.Join(sampleDir, "sample.arazzo.yaml")
 
 	// Ensure sample dir exists (it should, but just in case)
 	if _, err := os.Stat(sampleDir); os.IsNotExist(err) {
@@ -62,13 +63,22 @@ func TestSampleGeneration(t *testing.T) {
 	payloadMapC, ok := stepC.RequestBody.Payload.(map[string]interface{})
 	assert.True(t, ok)
 	assert.Equal(t, "Updated bio from generator", payloadMapC["bio"])
+	// id is wired to the earlier fetchUser response
+	if assert.Len(t, stepC.Parameters, 1) {
+		p := stepC.Parameters[0].(*arazzo1.Parameter)
+		assert.Equal(t, "id", p.Name)
+		assert.Equal(t, "$steps.fetchUser.outputs.id", p.Value)
+	}
+	assert.Equal(t, "$response.body#/id", stepB.Outputs["id"])
 
-	// Serialize to YAML for visual inspection by user
+	// The checked-in sample is the generated document. It is compared, not
+	// rewritten, so test runs leave the tree clean.
 	bytes, err := yaml.Marshal(az)
 	assert.NoError(t, err)
 
-	err = os.WriteFile(outputFile, bytes, 0644)
+	want, err := os.ReadFile(sampleFile)
 	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(bytes), "%s is out of date", sampleFile)
 
 	// Sub-test: HCL Generation
 	t.Run("HCL Input", func(t *testing.T) {
@@ -0,0 +1,346 @@
+package generator
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/oas/openapi31"
+)
+
+// Security configures how OpenAPI security requirements become step parameters.
+type Security struct {
+	// Prefer lists security scheme names in order of preference. When an operation
+	// offers alternative requirement sets, the set whose schemes rank best in this
+	// list is used. Without a match, the first requirement set is used.
+	Prefer []string `yaml:"prefer,omitempty" json:"prefer,omitempty" hcl:"prefer,optional"`
+	// TokenSteps adds a step acquiring an OAuth2 access token at the start of each
+	// workflow that uses an oauth2 or openIdConnect scheme. Secured steps then send
+	// "Authorization: Bearer" with the token from that step's outputs, instead of
+	// expecting the token as a workflow input.
+	TokenSteps bool `yaml:"token_steps,omitempty" json:"tokenSteps,omitempty" hcl:"token_steps,optional"`
+	// Flow is the OAuth2 grant used by token steps: clientCredentials,
+	// authorizationCode (with PKCE) or password. By default the first of these
+	// defined by the scheme is used.
+	Flow string `yaml:"flow,omitempty" json:"flow,omitempty" hcl:"flow,optional"`
+	// TokenOperationId is the operation implementing the token endpoint. It is
+	// required for openIdConnect schemes, and for oauth2 flows whose tokenUrl does
+	// not match a path of the OpenAPI document.
+	TokenOperationId string `yaml:"token_operation_id,omitempty" json:"tokenOperationId,omitempty" hcl:"token_operation_id,optional"`
+}
+
+// OAuth2 flow names, as used by the OpenAPI OAuth Flows Object.
+const (
+	FlowClientCredentials = "clientCredentials"
+	FlowAuthorizationCode = "authorizationCode"
+	FlowPassword          = "password"
+	FlowImplicit          = "implicit"
+)
+
+// securityBuilder adds security parameters to the steps of one workflow and
+// collects the token steps they depend on.
+type securityBuilder struct {
+	src      *source   // the source of doc
+	sources  []*source // all sources, searched for token endpoints
+	doc      *openapi31.OpenAPI
+	cfg      *Security
+	tokens   []*arazzo1.Step
+	tokenIDs map[string]string              // scheme name -> token stepId, "" when none could be built
+	stepIDs  map[string]bool                // stepIds already used in the workflow
+	hint     func(name, description string) // describes a credential input
+}
+
+func newSecurityBuilder(doc *openapi31.OpenAPI, cfg *Security) *securityBuilder {
+	return &securityBuilder{doc: doc, cfg: cfg, tokenIDs: make(map[string]string), stepIDs: make(map[string]bool)}
+}
+
+// securityRequirements returns the requirement sets that apply to op: its own
+// security when declared, including an empty list that opts out of security,
+// or else the document's global security.
+func securityRequirements(doc *openapi31.OpenAPI, op *openapi31.Operation) []openapi31.SecurityRequirement {
+	if op.Security != nil {
+		return op.Security
+	}
+	return doc.Security
+}
+
+// selectRequirement picks one of the alternative requirement sets. With a
+// preference list, a set ranks by its least preferred scheme and sets using
+// unlisted schemes are skipped. An empty set means anonymous access.
+func selectRequirement(reqs []openapi31.SecurityRequirement, cfg *Security) openapi31.SecurityRequirement {
+	if len(reqs) == 0 {
+		return nil
+	}
+	if cfg != nil && len(cfg.Prefer) > 0 {
+		rank := make(map[string]int, len(cfg.Prefer))
+		for i, name := range cfg.Prefer {
+			if _, ok := rank[name]; !ok {
+				rank[name] = i
+			}
+		}
+		best, bestRank := -1, 0
+		for i, req := range reqs {
+			if len(req) == 0 {
+				continue
+			}
+			worst, ok := 0, true
+			for name := range req {
+				r, listed := rank[name]
+				if !listed {
+					ok = false
+					break
+				}
+				worst = max(worst, r)
+			}
+			if ok && (best < 0 || worst < bestRank) {
+				best, bestRank = i, worst
+			}
+		}
+		if best >= 0 {
+			return reqs[best]
+		}
+	}
+	return reqs[0]
+}
+
+// securityScheme looks up a scheme in the components, following a local $ref.
+func (b *securityBuilder) securityScheme(name string) *openapi31.SecurityScheme {
+	if b.doc.Components == nil {
+		return nil
+	}
+	scheme := b.doc.Components.SecuritySchemes[name]
+	for i := 0; scheme != nil && scheme.Ref != "" && i < 32; i++ {
+		target, ok := strings.CutPrefix(scheme.Ref, "#/components/securitySchemes/")
+		if !ok {
+			return nil
+		}
+		scheme = b.doc.Components.SecuritySchemes[target]
+	}
+	return scheme
+}
+
+// apply adds the parameters required by the security of op to step. Parameters
+// already present on the step are left untouched.
+func (b *securityBuilder) apply(step *arazzo1.Step, op *openapi31.Operation) {
+	req := selectRequirement(securityRequirements(b.doc, op), b.cfg)
+	names := make([]string, 0, len(req))
+	for name := range req {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		scheme := b.securityScheme(name)
+		if scheme == nil {
+			continue
+		}
+		description := scheme.Description
+		if description == "" {
+			description = "Credential for the " + name + " security scheme."
+		}
+		var param *arazzo1.Parameter
+		switch scheme.Type {
+		case "apiKey":
+			param = &arazzo1.Parameter{
+				Name:  scheme.Name,
+				In:    arazzo1.ParameterIn(scheme.In),
+				Value: b.input(name, description),
+			}
+		case "http":
+			value := b.input(name, description)
+			switch strings.ToLower(scheme.Scheme) {
+			case "bearer":
+				value = "Bearer {" + value + "}"
+			case "basic":
+				value = "Basic {" + value + "}"
+			}
+			param = &arazzo1.Parameter{Name: "Authorization", In: arazzo1.ParameterInHeader, Value: value}
+		case "oauth2", "openIdConnect":
+			var value string
+			if id := b.tokenStep(name, scheme, req[name]); id != "" {
+				value = "Bearer {$steps." + id + ".outputs.access_token}"
+			} else {
+				value = "Bearer {" + b.input(name, description) + "}"
+			}
+			param = &arazzo1.Parameter{Name: "Authorization", In: arazzo1.ParameterInHeader, Value: value}
+		}
+		// mutualTLS is handled by the transport and needs no parameter.
+		if param != nil && !parameterExists(step.Parameters, param.Name) {
+			step.Parameters = append(step.Parameters, param)
+		}
+	}
+}
+
+// input returns the $inputs expression of a credential, describing it first.
+func (b *securityBuilder) input(name, description string) string {
+	if b.hint != nil {
+		b.hint(name, description)
+	}
+	return "$inputs." + name
+}
+
+// tokenFlow returns the OAuth2 flow used to acquire a token for scheme. The
+// flow object is nil for openIdConnect schemes, whose endpoints are discovered
+// at runtime.
+func (b *securityBuilder) tokenFlow(scheme *openapi31.SecurityScheme) (string, *openapi31.OAuthFlow) {
+	if scheme.Type == "openIdConnect" {
+		if b.cfg.Flow != "" {
+			return b.cfg.Flow, nil
+		}
+		return FlowClientCredentials, nil
+	}
+	if scheme.Flows == nil {
+		return "", nil
+	}
+	flows := map[string]*openapi31.OAuthFlow{
+		FlowClientCredentials: scheme.Flows.ClientCredentials,
+		FlowAuthorizationCode: scheme.Flows.AuthorizationCode,
+		FlowPassword:          scheme.Flows.Password,
+	}
+	if b.cfg.Flow != "" {
+		if f := flows[b.cfg.Flow]; f != nil {
+			return b.cfg.Flow, f
+		}
+		return "", nil
+	}
+	for _, name := range []string{FlowClientCredentials, FlowAuthorizationCode, FlowPassword} {
+		if f := flows[name]; f != nil {
+			return name, f
+		}
+	}
+	// Implicit flows deliver the token to the browser; it must be an input.
+	return "", nil
+}
+
+// tokenStep returns the stepId of the step acquiring a token for the named
+// scheme, creating the step on first use. It returns "" when token steps are
+// disabled or no token endpoint can be found.
+func (b *securityBuilder) tokenStep(name string, scheme *openapi31.SecurityScheme, scopes []string) string {
+	if b.cfg == nil || !b.cfg.TokenSteps {
+		return ""
+	}
+	if id, ok := b.tokenIDs[name]; ok {
+		return id
+	}
+	b.tokenIDs[name] = ""
+
+	flowName, flow := b.tokenFlow(scheme)
+	if flowName == "" {
+		return ""
+	}
+	step := &arazzo1.Step{
+		StepId:      b.uniqueStepId(stepIdFromName(name) + "-token"),
+		Description: "Acquires an access token for the " + name + " security scheme using the " + flowName + " grant.",
+	}
+	switch {
+	case b.cfg.TokenOperationId != "":
+		step.OperationId = b.cfg.TokenOperationId
+	case flow != nil:
+		step.OperationId, step.OperationPath = operationForURL(b.doc, flow.TokenUrl, "post")
+		if b.src != nil {
+			b.src.qualifyPath(step)
+		}
+		// The token endpoint may belong to another source, such as an
+		// authorization server.
+		for _, src := range b.sources {
+			if step.OperationId != "" || step.OperationPath != "" {
+				break
+			}
+			if src.doc != b.doc {
+				step.OperationId, step.OperationPath = operationForURL(src.doc, flow.TokenUrl, "post")
+				src.qualify(step)
+			}
+		}
+	}
+	if step.OperationId == "" && step.OperationPath == "" {
+		return ""
+	}
+
+	payload := map[string]any{"client_id": b.input("client_id", "OAuth2 client identifier.")}
+	switch flowName {
+	case FlowClientCredentials:
+		payload["grant_type"] = "client_credentials"
+		payload["client_secret"] = b.input("client_secret", "OAuth2 client secret.")
+	case FlowAuthorizationCode:
+		// The authorization request runs in a browser, which returns the code to
+		// the redirect URI. PKCE binds it to the code_verifier sent here.
+		payload["grant_type"] = "authorization_code"
+		payload["code"] = b.input("authorization_code", "Authorization code returned to the redirect URI.")
+		payload["redirect_uri"] = b.input("redirect_uri", "Redirect URI of the authorization request.")
+		payload["code_verifier"] = b.input("code_verifier", "PKCE code verifier of the authorization request.")
+	case FlowPassword:
+		payload["grant_type"] = "password"
+		payload["username"] = b.input("username", "Resource owner username.")
+		payload["password"] = b.input("password", "Resource owner password.")
+	default:
+		return ""
+	}
+	if len(scopes) > 0 && flowName != FlowAuthorizationCode {
+		payload["scope"] = strings.Join(scopes, " ")
+	}
+	step.RequestBody = &arazzo1.RequestBody{
+		ContentType: "application/x-www-form-urlencoded",
+		Payload:     payload,
+	}
+	step.SuccessCriteria = []*arazzo1.Criterion{{Condition: "$statusCode == 200"}}
+	step.Outputs = map[string]string{"access_token": "$response.body#/access_token"}
+
+	b.tokens = append(b.tokens, step)
+	b.tokenIDs[name] = step.StepId
+	return step.StepId
+}
+
+// uniqueStepId returns id, suffixed with a number if a step already uses it.
+func (b *securityBuilder) uniqueStepId(id string) string {
+	candidate := id
+	for i := 2; b.stepIDs[candidate]; i++ {
+		candidate = id + "-" + strconv.Itoa(i)
+	}
+	b.stepIDs[candidate] = true
+	return candidate
+}
+
+// stepIdFromName replaces characters not allowed in a stepId with '-'.
+func stepIdFromName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// operationForURL finds the operation of the given method whose path is a
+// suffix of the path of rawURL. It returns the operationId, or an operation
+// path when the operation has no operationId.
+func operationForURL(doc *openapi31.OpenAPI, rawURL, method string) (string, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path == "" || doc.Paths == nil {
+		return "", ""
+	}
+	target := strings.TrimSuffix(u.Path, "/")
+	keys := make([]string, 0, len(doc.Paths.Paths))
+	for key := range doc.Paths.Paths {
+		keys = append(keys, key)
+	}
+	// Prefer the longest matching path.
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, key := range keys {
+		path := strings.TrimSuffix(key, "/")
+		if path == "" || strings.Contains(path, "{") || !strings.HasSuffix(target, path) {
+			continue
+		}
+		pointer := "#/paths/" + escapePointer(key) + "/" + method
+		op := resolveOperationByPath(doc, pointer)
+		if op == nil {
+			continue
+		}
+		if op.OperationID != "" {
+			return op.OperationID, ""
+		}
+		return "", pointer
+	}
+	return "", ""
+}
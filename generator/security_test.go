@@ -0,0 +1,221 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/horizon/dethcl"
+	"github.com/genelet/oas/openapi31"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// securityDoc returns a document with a global oauth2 requirement and
+// operations that override it.
+func securityDoc() *openapi31.OpenAPI {
+	return &openapi31.OpenAPI{
+		Info:     &openapi31.Info{Title: "Secured"},
+		Security: []openapi31.SecurityRequirement{{"oauth": {"read", "write"}}},
+		Paths: &openapi31.Paths{
+			Paths: map[string]*openapi31.PathItem{
+				"/items": {
+					Get: &openapi31.Operation{OperationID: "listItems"},
+					Post: &openapi31.Operation{
+						OperationID: "createItem",
+						Security: []openapi31.SecurityRequirement{
+							{"oauth": {"write"}},
+							{"apiKey": {}},
+						},
+					},
+				},
+				"/health": {
+					Get: &openapi31.Operation{OperationID: "health", Security: []openapi31.SecurityRequirement{}},
+				},
+				"/me": {
+					Get: &openapi31.Operation{OperationID: "me", Security: []openapi31.SecurityRequirement{{"oidc": {"openid"}}}},
+				},
+				"/admin": {
+					Get: &openapi31.Operation{OperationID: "admin", Security: []openapi31.SecurityRequirement{{"basic": {}}}},
+				},
+				"/oauth/token": {
+					Post: &openapi31.Operation{OperationID: "getToken", Security: []openapi31.SecurityRequirement{}},
+				},
+			},
+		},
+		Components: &openapi31.Components{
+			SecuritySchemes: map[string]*openapi31.SecurityScheme{
+				"oauth": {
+					Type: "oauth2",
+					Flows: &openapi31.OAuthFlows{
+						AuthorizationCode: &openapi31.OAuthFlow{
+							AuthorizationUrl: "https://auth.example.com/oauth/authorize",
+							TokenUrl:         "https://auth.example.com/oauth/token",
+						},
+						ClientCredentials: &openapi31.OAuthFlow{TokenUrl: "https://auth.example.com/oauth/token"},
+					},
+				},
+				"apiKey": {Type: "apiKey", Name: "X-API-Key", In: "header"},
+				"oidc":   {Type: "openIdConnect", OpenIdConnectUrl: "https://auth.example.com/.well-known/openid-configuration"},
+				"basic":  {Type: "http", Scheme: "basic"},
+			},
+		},
+	}
+}
+
+func authorization(t *testing.T, step *arazzo1.Step) string {
+	t.Helper()
+	for _, p := range step.Parameters {
+		if param, ok := p.(*arazzo1.Parameter); ok && param.Name == "Authorization" {
+			return param.Value.(string)
+		}
+	}
+	return ""
+}
+
+func TestSecurity_Requirements(t *testing.T) {
+	doc := securityDoc()
+	tests := []struct {
+		operation string
+		want      string
+	}{
+		{"listItems", "Bearer {$inputs.oauth}"}, // global security
+		{"createItem", "Bearer {$inputs.oauth}"},
+		{"health", ""}, // security: [] opts out
+		{"me", "Bearer {$inputs.oidc}"},
+		{"admin", "Basic {$inputs.basic}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			step := &arazzo1.Step{OperationId: tt.operation}
+			enrichStepFromOpenAPI(step, doc)
+			assert.Equal(t, tt.want, authorization(t, step))
+		})
+	}
+}
+
+func TestSecurity_Prefer(t *testing.T) {
+	doc := securityDoc()
+	step := &arazzo1.Step{OperationId: "createItem"}
+	enrichStep(step, doc, newWorkflowContext(doc, &Provider{Security: &Security{Prefer: []string{"apiKey"}}}))
+
+	require.Len(t, step.Parameters, 1)
+	p := step.Parameters[0].(*arazzo1.Parameter)
+	assert.Equal(t, "X-API-Key", p.Name)
+	assert.Equal(t, arazzo1.ParameterInHeader, p.In)
+	assert.Equal(t, "$inputs.apiKey", p.Value)
+}
+
+func TestSecurity_TokenSteps(t *testing.T) {
+	doc := securityDoc()
+	gen := &Generator{
+		openapiDoc: doc,
+		Provider:   &Provider{Name: "api", Security: &Security{TokenSteps: true}},
+		Workflows: []*WorkflowSpec{{
+			WorkflowId: "items",
+			Steps: []*OperationSpec{
+				{Name: "list", OperationId: "listItems"},
+				{Name: "create", OperationId: "createItem"},
+				{Name: "health", OperationId: "health"},
+			},
+		}},
+	}
+	az, _, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+
+	steps := az.Workflows[0].Steps
+	require.Len(t, steps, 4)
+	token := steps[0]
+	assert.Equal(t, "oauth-token", token.StepId)
+	assert.Equal(t, "getToken", token.OperationId)
+	assert.Equal(t, "application/x-www-form-urlencoded", token.RequestBody.ContentType)
+	assert.Equal(t, map[string]any{
+		"grant_type":    "client_credentials",
+		"client_id":     "$inputs.client_id",
+		"client_secret": "$inputs.client_secret",
+		"scope":         "read write",
+	}, token.RequestBody.Payload)
+	assert.Equal(t, "$response.body#/access_token", token.Outputs["access_token"])
+
+	// One token step serves every step using the scheme.
+	assert.Equal(t, "Bearer {$steps.oauth-token.outputs.access_token}", authorization(t, steps[1]))
+	assert.Equal(t, "Bearer {$steps.oauth-token.outputs.access_token}", authorization(t, steps[2]))
+	assert.Equal(t, "", authorization(t, steps[3]))
+}
+
+func TestSecurity_TokenStepOperationPath(t *testing.T) {
+	doc := securityDoc()
+	doc.Paths.Paths["/oauth/token"].Post.OperationID = ""
+	gen := &Generator{
+		openapiDoc: doc,
+		Provider:   &Provider{Name: "api", Security: &Security{TokenSteps: true}},
+		Workflows: []*WorkflowSpec{{
+			WorkflowId: "items",
+			Steps:      []*OperationSpec{{Name: "list", OperationId: "listItems"}},
+		}},
+	}
+	az, _, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+
+	// A token operation without an operationId is referenced through the
+	// url of its source description.
+	token := az.Workflows[0].Steps[0]
+	assert.Equal(t, "oauth-token", token.StepId)
+	assert.Empty(t, token.OperationId)
+	assert.Equal(t, "{$sourceDescriptions.api.url}#/paths/~1oauth~1token/post", token.OperationPath)
+}
+
+func TestSecurity_AuthorizationCodePKCE(t *testing.T) {
+	doc := securityDoc()
+	wc := newWorkflowContext(doc, &Provider{Security: &Security{TokenSteps: true, Flow: FlowAuthorizationCode}})
+	step := &arazzo1.Step{OperationId: "listItems"}
+	enrichStep(step, doc, wc)
+
+	require.Len(t, wc.security.tokens, 1)
+	payload := wc.security.tokens[0].RequestBody.Payload.(map[string]any)
+	assert.Equal(t, "authorization_code", payload["grant_type"])
+	assert.Equal(t, "$inputs.authorization_code", payload["code"])
+	assert.Equal(t, "$inputs.code_verifier", payload["code_verifier"])
+	assert.Equal(t, "Bearer {$steps.oauth-token.outputs.access_token}", authorization(t, step))
+}
+
+func TestSecurity_OpenIdConnect(t *testing.T) {
+	doc := securityDoc()
+
+	// Without a token operation the discovery document cannot be followed.
+	wc := newWorkflowContext(doc, &Provider{Security: &Security{TokenSteps: true}})
+	step := &arazzo1.Step{OperationId: "me"}
+	enrichStep(step, doc, wc)
+	assert.Empty(t, wc.security.tokens)
+	assert.Equal(t, "Bearer {$inputs.oidc}", authorization(t, step))
+
+	wc = newWorkflowContext(doc, &Provider{Security: &Security{TokenSteps: true, TokenOperationId: "getToken"}})
+	step = &arazzo1.Step{OperationId: "me"}
+	enrichStep(step, doc, wc)
+	require.Len(t, wc.security.tokens, 1)
+	assert.Equal(t, "openid", wc.security.tokens[0].RequestBody.Payload.(map[string]any)["scope"])
+	assert.Equal(t, "Bearer {$steps.oidc-token.outputs.access_token}", authorization(t, step))
+}
+
+func TestSecurity_HCL(t *testing.T) {
+	src := `
+provider {
+  name       = "api"
+  server_url = "https://api.example.com"
+  security {
+    prefer             = ["oauth", "apiKey"]
+    token_steps        = true
+    flow               = "clientCredentials"
+    token_operation_id = "getToken"
+  }
+}
+`
+	var gen Generator
+	require.NoError(t, dethcl.Unmarshal([]byte(src), &gen))
+	require.NotNil(t, gen.Provider.Security)
+	assert.Equal(t, &Security{
+		Prefer:           []string{"oauth", "apiKey"},
+		TokenSteps:       true,
+		Flow:             FlowClientCredentials,
+		TokenOperationId: "getToken",
+	}, gen.Provider.Security)
+}
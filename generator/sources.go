@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+// source is a provider together with its OpenAPI document.
+type source struct {
+	provider *Provider
+	doc      *openapi31.OpenAPI
+}
+
+// sources returns the primary provider followed by the providers of Sources.
+func (g *Generator) sources() ([]*source, error) {
+	list := []*source{{provider: g.Provider, doc: g.openapiDoc}}
+	seen := map[string]bool{g.Provider.Name: true}
+	for _, p := range g.Sources {
+		if p == nil {
+			continue
+		}
+		if p.Name == "" {
+			return nil, fmt.Errorf("source without name")
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate source name %q", p.Name)
+		}
+		seen[p.Name] = true
+		doc := g.sourceDocs[p.Name]
+		if doc == nil {
+			return nil, fmt.Errorf("source %q: openapi document not set", p.Name)
+		}
+		list = append(list, &source{provider: p, doc: doc})
+	}
+	return list, nil
+}
+
+// loadSources parses the OpenAPI file of each provider of Sources. Relative
+// file names are relative to dir, the directory of the generator file.
+func (g *Generator) loadSources(dir string) error {
+	for _, p := range g.Sources {
+		if p == nil {
+			continue
+		}
+		if p.OpenAPI == "" {
+			return fmt.Errorf("source %q: openapi file required", p.Name)
+		}
+		filename := p.OpenAPI
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+		doc, err := oasutil.ParseFile(filename)
+		if err != nil {
+			return fmt.Errorf("source %q: %w", p.Name, err)
+		}
+		if g.sourceDocs == nil {
+			g.sourceDocs = make(map[string]*openapi31.OpenAPI)
+		}
+		g.sourceDocs[p.Name] = doc
+	}
+	return nil
+}
+
+// stepSource returns the source of an operation step: the one named by the
+// step's Source, or by the qualifier of its operationId or operationPath, or
+// else the only source defining the operation. It defaults to the primary
+// provider.
+func stepSource(sources []*source, spec *OperationSpec) (*source, error) {
+	name := spec.Source
+	if name == "" {
+		if spec.OperationId != "" {
+			name, _ = oasutil.SplitOperationId(spec.OperationId)
+		} else if spec.OperationPath != "" {
+			name, _ = oasutil.SplitOperationPath(spec.OperationPath)
+		}
+	}
+	if name == "" && len(sources) > 1 {
+		docs := make(map[string]*openapi31.OpenAPI, len(sources))
+		for _, s := range sources {
+			docs[s.provider.Name] = s.doc
+		}
+		r := oasutil.NewResolver(docs)
+		var op *oasutil.Operation
+		var err error
+		if spec.OperationId != "" {
+			op, err = r.ResolveOperationId(spec.OperationId)
+		} else if spec.OperationPath != "" {
+			op, err = r.ResolveOperationPath(spec.OperationPath)
+		}
+		if err == nil && op != nil {
+			name = op.Source
+		}
+	}
+	if name == "" {
+		return sources[0], nil
+	}
+	for _, s := range sources {
+		if s.provider.Name == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("step %q: unknown source %q", spec.Name, name)
+}
+
+// qualify prefixes the unqualified operationId or operationPath of step with
+// the source description of s.
+func (s *source) qualify(step *arazzo1.Step) {
+	if step.OperationId != "" && !strings.HasPrefix(step.OperationId, "$") {
+		step.OperationId = "$sourceDescriptions." + s.provider.Name + "." + step.OperationId
+	}
+	s.qualifyPath(step)
+}
+
+// qualifyPath prefixes an operationPath of step that points into the document,
+// such as #/paths/~1token/post, with the url of the source description of s.
+func (s *source) qualifyPath(step *arazzo1.Step) {
+	if strings.HasPrefix(step.OperationPath, "#") {
+		step.OperationPath = "{$sourceDescriptions." + s.provider.Name + ".url}" + step.OperationPath
+	}
+}
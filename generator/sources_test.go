@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/genelet/oas/openapi31"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authServerDoc and bankDoc split a FAPI-style API: the bank's operations are
+// secured by tokens of the authorization server.
+func authServerDoc() *openapi31.OpenAPI {
+	return &openapi31.OpenAPI{
+		Info: &openapi31.Info{Title: "Authorization Server"},
+		Paths: &openapi31.Paths{Paths: map[string]*openapi31.PathItem{
+			"/oauth/token": {Post: &openapi31.Operation{OperationID: "getToken"}},
+			"/status":      {Get: &openapi31.Operation{OperationID: "status"}},
+		}},
+	}
+}
+
+func bankDoc() *openapi31.OpenAPI {
+	return &openapi31.OpenAPI{
+		Info:     &openapi31.Info{Title: "Bank"},
+		Security: []openapi31.SecurityRequirement{{"oauth": {"accounts"}}},
+		Paths: &openapi31.Paths{Paths: map[string]*openapi31.PathItem{
+			"/accounts": {Get: &openapi31.Operation{OperationID: "listAccounts"}},
+			"/status":   {Get: &openapi31.Operation{OperationID: "status", Security: []openapi31.SecurityRequirement{}}},
+		}},
+		Components: &openapi31.Components{SecuritySchemes: map[string]*openapi31.SecurityScheme{
+			"oauth": {Type: "oauth2", Flows: &openapi31.OAuthFlows{
+				ClientCredentials: &openapi31.OAuthFlow{TokenUrl: "https://auth.example.com/oauth/token"},
+			}},
+		}},
+	}
+}
+
+func TestSources(t *testing.T) {
+	gen := &Generator{
+		openapiDoc: authServerDoc(),
+		Provider:   &Provider{Name: "auth"},
+		Sources:    []*Provider{{Name: "bank", OpenAPI: "bank.yaml", Security: &Security{TokenSteps: true}}},
+		sourceDocs: map[string]*openapi31.OpenAPI{"bank": bankDoc()},
+		Workflows: []*WorkflowSpec{{WorkflowId: "wf", Steps: []*OperationSpec{
+			{Name: "accounts", OperationId: "listAccounts"},
+			{Name: "bankStatus", OperationId: "status", Source: "bank"},
+			{Name: "authStatus", OperationId: "$sourceDescriptions.auth.status"},
+		}}},
+	}
+	az, _, err := gen.ToArazzo("auth.yaml")
+	require.NoError(t, err)
+
+	require.Len(t, az.SourceDescriptions, 2)
+	assert.Equal(t, "auth", az.SourceDescriptions[0].Name)
+	assert.Equal(t, "bank", az.SourceDescriptions[1].Name)
+	assert.Equal(t, "bank.yaml", az.SourceDescriptions[1].URL)
+
+	steps := az.Workflows[0].Steps
+	require.Len(t, steps, 4)
+	// The bank's token endpoint is found in the authorization server's document.
+	assert.Equal(t, "oauth-token", steps[0].StepId)
+	assert.Equal(t, "$sourceDescriptions.auth.getToken", steps[0].OperationId)
+	assert.Equal(t, "$sourceDescriptions.bank.listAccounts", steps[1].OperationId)
+	assert.Equal(t, "Bearer {$steps.oauth-token.outputs.access_token}", authorization(t, steps[1]))
+	assert.Equal(t, "$sourceDescriptions.bank.status", steps[2].OperationId)
+	assert.Equal(t, "$sourceDescriptions.auth.status", steps[3].OperationId)
+
+	gen.Workflows[0].Steps[0].Source = "ledger"
+	_, diags, err := gen.ToArazzo("auth.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, diags)
+	assert.Equal(t, "workflows[0].steps[0].source", diags[0].Path)
+	assert.Contains(t, diags[0].Message, `unknown source "ledger"`)
+}
+
+func TestSources_Files(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+		return filename
+	}
+	auth := write("auth.yaml", `openapi: 3.1.0
+info: {title: Auth, version: "1"}
+paths:
+  /par:
+    post: {operationId: pushAuthorization}
+`)
+	write("bank.yaml", `openapi: 3.1.0
+info: {title: Bank, version: "1"}
+paths:
+  /accounts/{accountId}:
+    get:
+      operationId: getAccount
+      parameters:
+        - {name: accountId, in: path, required: true, schema: {type: string}}
+`)
+	config := write("generator.hcl", `
+provider {
+  name       = "auth"
+  server_url = ""
+}
+
+source {
+  name       = "bank"
+  server_url = ""
+  openapi    = "bank.yaml"
+}
+
+workflow "fapi" {
+  step "par" {
+    operation_id = "pushAuthorization"
+  }
+  step "account" {
+    operation_id = "getAccount"
+  }
+}
+`)
+
+	az, err := NewArazzoFromFiles(auth, config, "hcl")
+	require.NoError(t, err)
+	require.Len(t, az.SourceDescriptions, 2)
+	steps := az.Workflows[0].Steps
+	assert.Equal(t, "$sourceDescriptions.auth.pushAuthorization", steps[0].OperationId)
+	assert.Equal(t, "$sourceDescriptions.bank.getAccount", steps[1].OperationId)
+	assert.Equal(t, "$inputs.accountId", paramValue(t, steps[1], "accountId"))
+}
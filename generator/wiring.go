@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+// stepOutput is a value produced by an earlier step of a workflow. Explicit
+// outputs come from the generator config; inferred outputs are the top-level
+// properties of the step's success response and are only added to the step's
+// Outputs once a later step uses them.
+type stepOutput struct {
+	step     *arazzo1.Step
+	name     string // output name
+	value    string // runtime expression, e.g. $response.body#/id
+	typ      string // JSON Schema type, "" when unknown
+	explicit bool
+}
+
+// expression returns the runtime expression referencing the output, adding
+// it to the producing step's outputs first if needed.
+func (o *stepOutput) expression() string {
+	if !o.explicit {
+		if o.step.Outputs == nil {
+			o.step.Outputs = make(map[string]string)
+		}
+		// The name may be taken by another value of the step.
+		base := o.name
+		for i := 2; o.step.Outputs[o.name] != "" && o.step.Outputs[o.name] != o.value; i++ {
+			o.name = base + strconv.Itoa(i)
+		}
+		o.step.Outputs[o.name] = o.value
+		o.explicit = true
+	}
+	return "$steps." + o.step.StepId + ".outputs." + o.name
+}
+
+// addOutputs makes the outputs of step available to the steps after it. op is
+// the step's operation, or nil when it is not an OpenAPI operation.
+func (wc *workflowContext) addOutputs(step *arazzo1.Step, doc *openapi31.OpenAPI, op *openapi31.Operation) {
+	var schema *openapi31.Schema
+	if op != nil {
+		if resp := (&oasutil.Operation{Doc: doc, Operation: op}).SuccessResponse(); resp != nil {
+			schema = oasutil.ContentSchema(resp.Content)
+		}
+	}
+
+	covered := make(map[string]bool)
+	names := make([]string, 0, len(step.Outputs))
+	for name := range step.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := step.Outputs[name]
+		out := &stepOutput{step: step, name: name, value: value, explicit: true}
+		if tokens, ok := bodyTokens(value); ok {
+			out.typ = oasutil.SchemaType(oasutil.SchemaAt(doc, schema, tokens))
+			if len(tokens) == 1 {
+				covered[tokens[0]] = true
+			}
+		}
+		wc.produced = append(wc.produced, out)
+	}
+
+	if schema == nil {
+		return
+	}
+	var f flatSchema
+	flattenSchema(doc, schema, &f, 0)
+	props := make([]string, 0, len(f.properties))
+	for prop := range f.properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+	for _, prop := range props {
+		if covered[prop] {
+			continue
+		}
+		wc.produced = append(wc.produced, &stepOutput{
+			step:  step,
+			name:  prop,
+			value: "$response.body#/" + escapePointer(prop),
+			typ:   oasutil.SchemaType(oasutil.ResolveSchema(doc, f.properties[prop])),
+		})
+	}
+}
+
+// bodyTokens returns the JSON Pointer reference tokens of a $response.body
+// expression, written either as $response.body#/a/b or $response.body.a.b.
+func bodyTokens(value string) ([]string, bool) {
+	expr, err := arazzo1.ParseExpression(value)
+	if err != nil || expr.Root != "response" || len(expr.Segments) == 0 || expr.Segments[0] != "body" {
+		return nil, false
+	}
+	if expr.Pointer != "" {
+		tokens := strings.Split(strings.TrimPrefix(expr.Pointer, "/"), "/")
+		for i, t := range tokens {
+			tokens[i] = oasutil.UnescapePointer(t)
+		}
+		return tokens, true
+	}
+	return expr.Segments[1:], true
+}
+
+// wire returns the expression of the earlier step output supplying the named
+// value of JSON Schema type typ. A value matches outputs of the same name,
+// ignoring case, '_' and '-'; a name like petId also matches an id output of
+// a step whose id or operation mentions pet. Outputs of incompatible types
+// are ignored, and explicit outputs win over inferred ones. When several
+// steps still match, a warning is reported at where, the config path of the
+// value relative to its step, and ok is false.
+func (wc *workflowContext) wire(name, typ, where string) (string, bool) {
+	key := normalizeName(name)
+	if key == "" {
+		return "", false
+	}
+	var candidates []*stepOutput
+	for _, o := range wc.produced {
+		if normalizeName(o.name) == key && typesCompatible(o.typ, typ) {
+			candidates = append(candidates, o)
+		}
+	}
+	if prefix, ok := strings.CutSuffix(key, "id"); ok && prefix != "" && len(candidates) == 0 {
+		for _, o := range wc.produced {
+			_, opID := oasutil.SplitOperationId(o.step.OperationId)
+			if normalizeName(o.name) == "id" && typesCompatible(o.typ, typ) &&
+				(strings.Contains(normalizeName(o.step.StepId), prefix) || strings.Contains(normalizeName(opID), prefix)) {
+				candidates = append(candidates, o)
+			}
+		}
+	}
+
+	var explicit []*stepOutput
+	for _, o := range candidates {
+		if o.explicit {
+			explicit = append(explicit, o)
+		}
+	}
+	if len(explicit) > 0 {
+		candidates = explicit
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", false
+	case 1:
+		return candidates[0].expression(), true
+	}
+	steps := make([]string, len(candidates))
+	for i, o := range candidates {
+		steps[i] = fmt.Sprintf("%s.%s", o.step.StepId, o.name)
+	}
+	wc.report(SeverityWarning, where, "%q matches outputs %s; not wired", name, strings.Join(steps, ", "))
+	return "", false
+}
+
+// normalizeName lowercases a name and removes '_' and '-', so that pet_id,
+// pet-id and petId compare equal.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// typesCompatible reports whether values of JSON Schema types a and b can be
+// exchanged. Unknown types are compatible with anything.
+func typesCompatible(a, b string) bool {
+	numeric := func(t string) bool { return t == "integer" || t == "number" }
+	return a == "" || b == "" || a == b || (numeric(a) && numeric(b))
+}
@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/oas/openapi31"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wiringDoc returns a pet store where creating a pet or an owner returns an id.
+func wiringDoc() *openapi31.OpenAPI {
+	typ := func(s string) *openapi31.StringOrStringArray { return &openapi31.StringOrStringArray{String: s} }
+	created := func(schema string) *openapi31.Responses {
+		return &openapi31.Responses{StatusCode: map[string]*openapi31.Response{
+			"201": {Content: map[string]*openapi31.MediaType{
+				"application/json": {Schema: &openapi31.Schema{Ref: "#/components/schemas/" + schema}},
+			}},
+		}}
+	}
+	pathParam := func(name, t string) *openapi31.Parameter {
+		return &openapi31.Parameter{Name: name, In: "path", Required: true, Schema: &openapi31.Schema{Type: typ(t)}}
+	}
+	return &openapi31.OpenAPI{
+		Info: &openapi31.Info{Title: "Pets"},
+		Paths: &openapi31.Paths{
+			Paths: map[string]*openapi31.PathItem{
+				"/pets":   {Post: &openapi31.Operation{OperationID: "createPet", Responses: created("Pet")}},
+				"/owners": {Post: &openapi31.Operation{OperationID: "createOwner", Responses: created("Owner")}},
+				"/pets/{petId}": {Get: &openapi31.Operation{
+					OperationID: "getPet",
+					Parameters:  []*openapi31.Parameter{pathParam("petId", "integer")},
+				}},
+				"/things/{id}": {Get: &openapi31.Operation{
+					OperationID: "getThing",
+					Parameters:  []*openapi31.Parameter{pathParam("id", "integer")},
+				}},
+				"/tags/{tag}": {Get: &openapi31.Operation{
+					OperationID: "getTag",
+					Parameters:  []*openapi31.Parameter{pathParam("tag", "integer")},
+				}},
+				"/adoptions": {Post: &openapi31.Operation{
+					OperationID: "adopt",
+					RequestBody: &openapi31.RequestBody{Content: map[string]*openapi31.MediaType{
+						"application/json": {Schema: &openapi31.Schema{
+							Type:     typ("object"),
+							Required: []string{"pet_id", "note"},
+							Properties: map[string]*openapi31.Schema{
+								"pet_id": {Type: typ("integer")},
+								"note":   {Type: typ("string")},
+							},
+						}},
+					}},
+				}},
+			},
+		},
+		Components: &openapi31.Components{
+			Schemas: map[string]*openapi31.Schema{
+				"Pet": {Type: typ("object"), Properties: map[string]*openapi31.Schema{
+					"id":  {Type: typ("integer")},
+					"tag": {Type: typ("string")},
+				}},
+				"Owner": {Type: typ("object"), Properties: map[string]*openapi31.Schema{
+					"id": {Type: typ("integer")},
+				}},
+			},
+		},
+	}
+}
+
+func generateWorkflow(t *testing.T, steps ...*OperationSpec) (*arazzo1.Workflow, Diagnostics) {
+	t.Helper()
+	gen := &Generator{
+		openapiDoc: wiringDoc(),
+		Provider:   &Provider{Name: "pets"},
+		Workflows:  []*WorkflowSpec{{WorkflowId: "wf", Steps: steps}},
+	}
+	az, diags, err := gen.ToArazzo("openapi.yaml")
+	require.NoError(t, err)
+	return az.Workflows[0], diags
+}
+
+func paramValue(t *testing.T, step *arazzo1.Step, name string) any {
+	t.Helper()
+	for _, p := range step.Parameters {
+		if param, ok := p.(*arazzo1.Parameter); ok && param.Name == name {
+			return param.Value
+		}
+	}
+	t.Fatalf("step %s has no parameter %s", step.StepId, name)
+	return nil
+}
+
+func TestWiring_Parameters(t *testing.T) {
+	wf, diags := generateWorkflow(t,
+		&OperationSpec{Name: "create", OperationId: "createPet"},
+		&OperationSpec{Name: "get", OperationId: "getPet"},
+		&OperationSpec{Name: "thing", OperationId: "getThing"},
+	)
+	assert.Empty(t, diags)
+
+	// petId matches the id of the step creating a pet; id matches by name.
+	assert.Equal(t, "$steps.create.outputs.id", paramValue(t, wf.Steps[1], "petId"))
+	assert.Equal(t, "$steps.create.outputs.id", paramValue(t, wf.Steps[2], "id"))
+	// The inferred output is added to the producing step, once.
+	assert.Equal(t, map[string]string{"id": "$response.body#/id"}, wf.Steps[0].Outputs)
+}
+
+func TestWiring_ExplicitOutputs(t *testing.T) {
+	wf, _ := generateWorkflow(t,
+		&OperationSpec{Name: "create", OperationId: "createPet", Outputs: map[string]string{"petId": "$response.body#/id"}},
+		&OperationSpec{Name: "get", OperationId: "getPet"},
+	)
+	assert.Equal(t, "$steps.create.outputs.petId", paramValue(t, wf.Steps[1], "petId"))
+	assert.Equal(t, map[string]string{"petId": "$response.body#/id"}, wf.Steps[0].Outputs)
+}
+
+func TestWiring_TypeMismatch(t *testing.T) {
+	// The created pet's tag is a string; the tag path parameter is an integer.
+	wf, _ := generateWorkflow(t,
+		&OperationSpec{Name: "create", OperationId: "createPet"},
+		&OperationSpec{Name: "tag", OperationId: "getTag"},
+	)
+	assert.Equal(t, "$inputs.tag", paramValue(t, wf.Steps[1], "tag"))
+	assert.Empty(t, wf.Steps[0].Outputs)
+}
+
+func TestWiring_Ambiguous(t *testing.T) {
+	wf, diags := generateWorkflow(t,
+		&OperationSpec{Name: "pet", OperationId: "createPet"},
+		&OperationSpec{Name: "owner", OperationId: "createOwner"},
+		&OperationSpec{Name: "thing", OperationId: "getThing"},
+	)
+	assert.Equal(t, "$inputs.id", paramValue(t, wf.Steps[2], "id"))
+	require.Len(t, diags, 1)
+	assert.Equal(t, SeverityWarning, diags[0].Severity)
+	assert.Equal(t, "workflows[0].steps[2].parameters", diags[0].Path)
+	assert.Contains(t, diags[0].Message, `"id" matches outputs pet.id, owner.id`)
+}
+
+func TestWiring_Payload(t *testing.T) {
+	wf, _ := generateWorkflow(t,
+		&OperationSpec{Name: "create", OperationId: "createPet"},
+		&OperationSpec{Name: "adopt", OperationId: "adopt"},
+	)
+	rb := wf.Steps[1].RequestBody
+	require.NotNil(t, rb)
+	assert.Equal(t, map[string]any{"pet_id": 0, "note": "$inputs.note"}, rb.Payload)
+	assert.Equal(t, []*arazzo1.PayloadReplacement{
+		{Target: "/pet_id", Value: "$steps.create.outputs.id"},
+	}, rb.Replacements)
+}
@@ -0,0 +1,277 @@
+// Package oasutil resolves Arazzo step targets against parsed OpenAPI documents.
+package oasutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/oas/openapi31"
+	"gopkg.in/yaml.v3"
+)
+
+// Methods lists the HTTP methods of a PathItem in a stable order.
+var Methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Operation is an OpenAPI operation located within its source document.
+type Operation struct {
+	// Source is the name of the source description the operation belongs to.
+	Source string
+	// Method is the lower-case HTTP method.
+	Method string
+	// Path is the path template, e.g. /pet/{petId}.
+	Path      string
+	PathItem  *openapi31.PathItem
+	Operation *openapi31.Operation
+	Doc       *openapi31.OpenAPI
+}
+
+// Parse parses an OpenAPI document, handling both JSON and YAML.
+// Since openapi31 relies on UnmarshalJSON for custom logic, YAML is converted to JSON first.
+func Parse(content []byte) (*openapi31.OpenAPI, error) {
+	var doc openapi31.OpenAPI
+	if err := json.Unmarshal(content, &doc); err == nil {
+		return &doc, nil
+	}
+
+	var obj any
+	if err := yaml.Unmarshal(content, &obj); err != nil {
+		return nil, fmt.Errorf("parsing yaml: %w", err)
+	}
+	jsonBytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("converting yaml to json: %w", err)
+	}
+	if err := json.Unmarshal(jsonBytes, &doc); err != nil {
+		return nil, fmt.Errorf("parsing converted json: %w", err)
+	}
+	return &doc, nil
+}
+
+// ParseFile reads and parses an OpenAPI document from disk.
+func ParseFile(filename string) (*openapi31.OpenAPI, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading openapi file: %w", err)
+	}
+	return Parse(content)
+}
+
+// OperationOf returns the operation for the given lower-case method.
+func OperationOf(item *openapi31.PathItem, method string) *openapi31.Operation {
+	if item == nil {
+		return nil
+	}
+	switch strings.ToLower(method) {
+	case "get":
+		return item.Get
+	case "put":
+		return item.Put
+	case "post":
+		return item.Post
+	case "delete":
+		return item.Delete
+	case "options":
+		return item.Options
+	case "head":
+		return item.Head
+	case "patch":
+		return item.Patch
+	case "trace":
+		return item.Trace
+	}
+	return nil
+}
+
+// SortedPaths returns the path templates of a document in lexical order.
+func SortedPaths(doc *openapi31.OpenAPI) []string {
+	if doc == nil || doc.Paths == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(doc.Paths.Paths))
+	for k := range doc.Paths.Paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Resolver finds operations across the OpenAPI documents named by an
+// Arazzo document's source descriptions.
+type Resolver struct {
+	sources map[string]*openapi31.OpenAPI
+	names   []string
+}
+
+// NewResolver creates a Resolver over OpenAPI documents keyed by source description name.
+func NewResolver(sources map[string]*openapi31.OpenAPI) *Resolver {
+	r := &Resolver{sources: sources}
+	for name := range sources {
+		r.names = append(r.names, name)
+	}
+	sort.Strings(r.names)
+	return r
+}
+
+// Source returns the document registered under name.
+func (r *Resolver) Source(name string) *openapi31.OpenAPI {
+	return r.sources[name]
+}
+
+// ResolveStep locates the operation targeted by an operation step.
+func (r *Resolver) ResolveStep(step *arazzo1.Step) (*Operation, error) {
+	switch {
+	case step.OperationId != "":
+		return r.ResolveOperationId(step.OperationId)
+	case step.OperationPath != "":
+		return r.ResolveOperationPath(step.OperationPath)
+	}
+	return nil, fmt.Errorf("step %q does not reference an operation", step.StepId)
+}
+
+// ResolveOperationId resolves a plain or $sourceDescriptions-qualified operationId.
+func (r *Resolver) ResolveOperationId(operationId string) (*Operation, error) {
+	source, opID := SplitOperationId(operationId)
+	names := r.names
+	if source != "" {
+		if _, ok := r.sources[source]; !ok {
+			return nil, fmt.Errorf("unknown source description %q in operationId %q", source, operationId)
+		}
+		names = []string{source}
+	}
+
+	var found *Operation
+	for _, name := range names {
+		doc := r.sources[name]
+		for _, path := range SortedPaths(doc) {
+			item := doc.Paths.Paths[path]
+			for _, method := range Methods {
+				op := OperationOf(item, method)
+				if op == nil || op.OperationID != opID {
+					continue
+				}
+				if found != nil {
+					return nil, fmt.Errorf("operationId %q is ambiguous between sources %q and %q", opID, found.Source, name)
+				}
+				found = &Operation{Source: name, Method: method, Path: path, PathItem: item, Operation: op, Doc: doc}
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("operationId %q not found", operationId)
+	}
+	return found, nil
+}
+
+// ResolveOperationPath resolves an operationPath such as
+// {$sourceDescriptions.petstore.url}#/paths/~1pet~1{petId}/get.
+// When the pointer stops at the path item and it has a single operation, that operation is used.
+func (r *Resolver) ResolveOperationPath(operationPath string) (*Operation, error) {
+	source, pointer := SplitOperationPath(operationPath)
+	parts := strings.Split(pointer, "/")
+	if len(parts) < 3 || parts[0] != "" || parts[1] != "paths" {
+		return nil, fmt.Errorf("operationPath %q must point into /paths", operationPath)
+	}
+	pathKey := UnescapePointer(parts[2])
+	method := ""
+	if len(parts) > 3 {
+		method = strings.ToLower(parts[3])
+	}
+
+	names := r.names
+	if source != "" {
+		if _, ok := r.sources[source]; !ok {
+			return nil, fmt.Errorf("unknown source description %q in operationPath %q", source, operationPath)
+		}
+		names = []string{source}
+	}
+	for _, name := range names {
+		doc := r.sources[name]
+		if doc.Paths == nil {
+			continue
+		}
+		item, ok := doc.Paths.Paths[pathKey]
+		if !ok {
+			continue
+		}
+		if method == "" {
+			for _, m := range Methods {
+				if OperationOf(item, m) == nil {
+					continue
+				}
+				if method != "" {
+					return nil, fmt.Errorf("operationPath %q is ambiguous: path has several operations", operationPath)
+				}
+				method = m
+			}
+		}
+		if op := OperationOf(item, method); op != nil {
+			return &Operation{Source: name, Method: method, Path: pathKey, PathItem: item, Operation: op, Doc: doc}, nil
+		}
+	}
+	return nil, fmt.Errorf("operationPath %q not found", operationPath)
+}
+
+// SplitOperationId separates the source description name from a qualified
+// operationId. "$sourceDescriptions.petstore.getPet" yields ("petstore", "getPet");
+// an unqualified id yields an empty source.
+func SplitOperationId(operationId string) (string, string) {
+	const prefix = "$sourceDescriptions."
+	if strings.HasPrefix(operationId, prefix) {
+		rest := operationId[len(prefix):]
+		if idx := strings.Index(rest, "."); idx != -1 {
+			return rest[:idx], rest[idx+1:]
+		}
+	}
+	return "", operationId
+}
+
+// SplitOperationPath separates the source description name from the JSON
+// Pointer of an operationPath. "{$sourceDescriptions.petstore.url}#/paths/~1pet/get"
+// yields ("petstore", "/paths/~1pet/get"); "#/paths/~1pet/get" yields an empty source.
+func SplitOperationPath(operationPath string) (string, string) {
+	idx := strings.LastIndex(operationPath, "#")
+	if idx == -1 {
+		return "", operationPath
+	}
+	prefix := strings.TrimSuffix(strings.TrimPrefix(operationPath[:idx], "{"), "}")
+	source := ""
+	if strings.HasPrefix(prefix, "$sourceDescriptions.") {
+		source = strings.TrimSuffix(strings.TrimPrefix(prefix, "$sourceDescriptions."), ".url")
+	}
+	return source, operationPath[idx+1:]
+}
+
+// UnescapePointer unescapes a JSON Pointer token: ~1 -> /, ~0 -> ~.
+func UnescapePointer(s string) string {
+	s = strings.ReplaceAll(s, "~1", "/")
+	return strings.ReplaceAll(s, "~0", "~")
+}
+
+// ServerURL returns the base URL for an operation, preferring operation-level
+// servers over path-level and document-level ones. Server variables are
+// replaced by their default values.
+func (o *Operation) ServerURL() string {
+	var servers []*openapi31.Server
+	switch {
+	case o.Operation != nil && len(o.Operation.Servers) > 0:
+		servers = o.Operation.Servers
+	case o.PathItem != nil && len(o.PathItem.Servers) > 0:
+		servers = o.PathItem.Servers
+	case o.Doc != nil:
+		servers = o.Doc.Servers
+	}
+	if len(servers) == 0 || servers[0] == nil {
+		return ""
+	}
+	url := servers[0].URL
+	for name, v := range servers[0].Variables {
+		if v != nil {
+			url = strings.ReplaceAll(url, "{"+name+"}", v.Default)
+		}
+	}
+	return strings.TrimSuffix(url, "/")
+}
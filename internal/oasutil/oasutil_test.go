@@ -0,0 +1,124 @@
+package oasutil
+
+import (
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/oas/openapi31"
+)
+
+func testSources() map[string]*openapi31.OpenAPI {
+	return map[string]*openapi31.OpenAPI{
+		"pets": {
+			Servers: []*openapi31.Server{{
+				URL:       "https://{region}.pets.example.com/v1/",
+				Variables: map[string]*openapi31.ServerVariable{"region": {Default: "eu"}},
+			}},
+			Paths: &openapi31.Paths{Paths: map[string]*openapi31.PathItem{
+				"/pets/{id}": {
+					Get:    &openapi31.Operation{OperationID: "getPet"},
+					Delete: &openapi31.Operation{OperationID: "deletePet"},
+				},
+				"/pets": {
+					Post: &openapi31.Operation{
+						OperationID: "createPet",
+						Servers:     []*openapi31.Server{{URL: "https://write.pets.example.com"}},
+					},
+				},
+			}},
+		},
+		"users": {
+			Paths: &openapi31.Paths{Paths: map[string]*openapi31.PathItem{
+				"/users/{id}": {Get: &openapi31.Operation{OperationID: "getUser"}},
+			}},
+		},
+	}
+}
+
+func TestResolveOperationId(t *testing.T) {
+	r := NewResolver(testSources())
+
+	op, err := r.ResolveOperationId("getPet")
+	if err != nil {
+		t.Fatalf("ResolveOperationId failed: %v", err)
+	}
+	if op.Source != "pets" || op.Method != "get" || op.Path != "/pets/{id}" {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+	if got := op.ServerURL(); got != "https://eu.pets.example.com/v1" {
+		t.Errorf("unexpected server url %q", got)
+	}
+
+	op, err = r.ResolveOperationId("$sourceDescriptions.users.getUser")
+	if err != nil {
+		t.Fatalf("ResolveOperationId failed: %v", err)
+	}
+	if op.Source != "users" {
+		t.Errorf("expected source users, got %q", op.Source)
+	}
+
+	op, err = r.ResolveOperationId("createPet")
+	if err != nil {
+		t.Fatalf("ResolveOperationId failed: %v", err)
+	}
+	if got := op.ServerURL(); got != "https://write.pets.example.com" {
+		t.Errorf("operation servers should win, got %q", got)
+	}
+
+	if _, err := r.ResolveOperationId("$sourceDescriptions.users.getPet"); err == nil {
+		t.Error("expected error when operation is not in the named source")
+	}
+	if _, err := r.ResolveOperationId("$sourceDescriptions.nope.getPet"); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestResolveOperationPath(t *testing.T) {
+	r := NewResolver(testSources())
+
+	op, err := r.ResolveOperationPath("{$sourceDescriptions.pets.url}#/paths/~1pets~1{id}/delete")
+	if err != nil {
+		t.Fatalf("ResolveOperationPath failed: %v", err)
+	}
+	if op.Operation.OperationID != "deletePet" {
+		t.Errorf("unexpected operation %q", op.Operation.OperationID)
+	}
+
+	op, err = r.ResolveOperationPath("#/paths/~1pets/post")
+	if err != nil {
+		t.Fatalf("ResolveOperationPath failed: %v", err)
+	}
+	if op.Operation.OperationID != "createPet" {
+		t.Errorf("unexpected operation %q", op.Operation.OperationID)
+	}
+
+	// A pointer to a path item with a single operation resolves to it.
+	op, err = r.ResolveOperationPath("{$sourceDescriptions.users.url}#/paths/~1users~1{id}")
+	if err != nil {
+		t.Fatalf("ResolveOperationPath failed: %v", err)
+	}
+	if op.Method != "get" {
+		t.Errorf("expected get, got %q", op.Method)
+	}
+
+	if _, err := r.ResolveOperationPath("#/paths/~1pets~1{id}"); err == nil {
+		t.Error("expected error for a path item with several operations")
+	}
+	if _, err := r.ResolveOperationPath("#/components/schemas/Pet"); err == nil {
+		t.Error("expected error for a pointer outside /paths")
+	}
+}
+
+func TestResolveStep(t *testing.T) {
+	r := NewResolver(testSources())
+	if _, err := r.ResolveStep(&arazzo1.Step{StepId: "s", WorkflowId: "other"}); err == nil {
+		t.Error("expected error for a workflow step")
+	}
+	op, err := r.ResolveStep(&arazzo1.Step{StepId: "s", OperationId: "getUser"})
+	if err != nil {
+		t.Fatalf("ResolveStep failed: %v", err)
+	}
+	if op.Path != "/users/{id}" {
+		t.Errorf("unexpected path %q", op.Path)
+	}
+}
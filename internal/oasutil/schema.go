@@ -0,0 +1,242 @@
+package oasutil
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/oas/openapi31"
+)
+
+// maxRefDepth bounds $ref chains so that cyclic references terminate.
+const maxRefDepth = 32
+
+// ResolveSchema follows local "#/components/schemas/<name>" references until it
+// reaches a schema without $ref. It returns s unchanged when the reference
+// cannot be resolved.
+func ResolveSchema(doc *openapi31.OpenAPI, s *openapi31.Schema) *openapi31.Schema {
+	for i := 0; s != nil && s.Ref != "" && i < maxRefDepth; i++ {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok || doc == nil || doc.Components == nil {
+			return s
+		}
+		target, ok := doc.Components.Schemas[UnescapePointer(name)]
+		if !ok || target == nil {
+			return s
+		}
+		s = target
+	}
+	return s
+}
+
+// ResolveResponse follows a local "#/components/responses/<name>" reference.
+func ResolveResponse(doc *openapi31.OpenAPI, r *openapi31.Response) *openapi31.Response {
+	for i := 0; r != nil && r.Ref != "" && i < maxRefDepth; i++ {
+		name, ok := strings.CutPrefix(r.Ref, "#/components/responses/")
+		if !ok || doc == nil || doc.Components == nil {
+			return r
+		}
+		target, ok := doc.Components.Responses[UnescapePointer(name)]
+		if !ok || target == nil {
+			return r
+		}
+		r = target
+	}
+	return r
+}
+
+// ResolveParameter follows a local "#/components/parameters/<name>" reference.
+func ResolveParameter(doc *openapi31.OpenAPI, p *openapi31.Parameter) *openapi31.Parameter {
+	for i := 0; p != nil && p.Ref != "" && i < maxRefDepth; i++ {
+		name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+		if !ok || doc == nil || doc.Components == nil {
+			return p
+		}
+		target, ok := doc.Components.Parameters[UnescapePointer(name)]
+		if !ok || target == nil {
+			return p
+		}
+		p = target
+	}
+	return p
+}
+
+// ResolveRequestBody follows a local "#/components/requestBodies/<name>" reference.
+func ResolveRequestBody(doc *openapi31.OpenAPI, rb *openapi31.RequestBody) *openapi31.RequestBody {
+	for i := 0; rb != nil && rb.Ref != "" && i < maxRefDepth; i++ {
+		name, ok := strings.CutPrefix(rb.Ref, "#/components/requestBodies/")
+		if !ok || doc == nil || doc.Components == nil {
+			return rb
+		}
+		target, ok := doc.Components.RequestBodies[UnescapePointer(name)]
+		if !ok || target == nil {
+			return rb
+		}
+		rb = target
+	}
+	return rb
+}
+
+// Parameters returns the effective parameters of the operation: those of its
+// path item that the operation does not override by name and location,
+// followed by the operation's own, with references resolved. Unresolvable
+// references are left out.
+func (o *Operation) Parameters() []*openapi31.Parameter {
+	if o.Operation == nil {
+		return nil
+	}
+	resolve := func(list []*openapi31.Parameter) []*openapi31.Parameter {
+		var params []*openapi31.Parameter
+		for _, p := range list {
+			if p = ResolveParameter(o.Doc, p); p != nil && p.Ref == "" {
+				params = append(params, p)
+			}
+		}
+		return params
+	}
+	own := resolve(o.Operation.Parameters)
+	overridden := make(map[string]bool, len(own))
+	for _, p := range own {
+		overridden[p.In+" "+p.Name] = true
+	}
+	var params []*openapi31.Parameter
+	if o.PathItem != nil {
+		for _, p := range resolve(o.PathItem.Parameters) {
+			if !overridden[p.In+" "+p.Name] {
+				params = append(params, p)
+			}
+		}
+	}
+	return append(params, own...)
+}
+
+// RequestBody returns the operation's request body with references resolved,
+// or nil when it has none or the reference cannot be resolved.
+func (o *Operation) RequestBody() *openapi31.RequestBody {
+	if o.Operation == nil {
+		return nil
+	}
+	if rb := ResolveRequestBody(o.Doc, o.Operation.RequestBody); rb != nil && rb.Ref == "" {
+		return rb
+	}
+	return nil
+}
+
+// SuccessResponse returns the operation's first 2xx response in status code
+// order, then a "2XX" range response, then the default response.
+func (o *Operation) SuccessResponse() *openapi31.Response {
+	if o.Operation == nil || o.Operation.Responses == nil {
+		return nil
+	}
+	responses := o.Operation.Responses
+	codes := make([]string, 0, len(responses.StatusCode))
+	for code := range responses.StatusCode {
+		if len(code) == 3 && code[0] == '2' {
+			if _, err := strconv.Atoi(code); err == nil {
+				codes = append(codes, code)
+			}
+		}
+	}
+	sort.Strings(codes)
+	switch {
+	case len(codes) > 0:
+		return ResolveResponse(o.Doc, responses.StatusCode[codes[0]])
+	case responses.StatusCode["2XX"] != nil:
+		return ResolveResponse(o.Doc, responses.StatusCode["2XX"])
+	}
+	return ResolveResponse(o.Doc, responses.Default)
+}
+
+// ContentSchema returns the schema of the preferred media type in content,
+// as chosen by PreferredMediaType without a preference list.
+func ContentSchema(content map[string]*openapi31.MediaType) *openapi31.Schema {
+	if mt := content[PreferredMediaType(content, nil)]; mt != nil {
+		return mt.Schema
+	}
+	return nil
+}
+
+// PreferredMediaType returns the key of content with the most preferred media
+// type. Entries of prefer are matched in order, either exactly or as a
+// "type/*" range, ignoring case and media type parameters. Without a match,
+// application/json is chosen, then any +json type, then the first type in
+// lexical order. It returns "" when content is empty.
+func PreferredMediaType(content map[string]*openapi31.MediaType, prefer []string) string {
+	if len(content) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, want := range prefer {
+		want = mediaType(want)
+		for _, k := range keys {
+			ct := mediaType(k)
+			if ct == want || (strings.HasSuffix(want, "/*") && strings.HasPrefix(ct, strings.TrimSuffix(want, "*"))) {
+				return k
+			}
+		}
+	}
+	pick := keys[0]
+	for _, k := range keys {
+		ct := mediaType(k)
+		if ct == "application/json" {
+			return k
+		}
+		if strings.HasSuffix(ct, "+json") && !strings.HasSuffix(mediaType(pick), "json") {
+			pick = k
+		}
+	}
+	return pick
+}
+
+// mediaType normalizes a media type for comparison.
+func mediaType(ct string) string {
+	ct, _, _ = strings.Cut(ct, ";")
+	return strings.ToLower(strings.TrimSpace(ct))
+}
+
+// SchemaAt walks a schema along JSON Pointer reference tokens: numeric tokens
+// select array items, other tokens select properties. Members of allOf, anyOf
+// and oneOf are searched for properties. It returns nil when the path leaves
+// the schema.
+func SchemaAt(doc *openapi31.OpenAPI, s *openapi31.Schema, tokens []string) *openapi31.Schema {
+	s = ResolveSchema(doc, s)
+	if len(tokens) == 0 || s == nil {
+		return s
+	}
+	token := tokens[0]
+	if _, err := strconv.Atoi(token); err == nil && s.Items != nil {
+		return SchemaAt(doc, s.Items, tokens[1:])
+	}
+	if p, ok := s.Properties[token]; ok {
+		return SchemaAt(doc, p, tokens[1:])
+	}
+	for _, group := range [][]*openapi31.Schema{s.AllOf, s.AnyOf, s.OneOf} {
+		for _, member := range group {
+			if found := SchemaAt(doc, member, tokens); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// SchemaType returns the primary JSON type of a schema, ignoring "null" in
+// type arrays. It returns "" when no type is declared.
+func SchemaType(s *openapi31.Schema) string {
+	if s == nil || s.Type == nil {
+		return ""
+	}
+	if s.Type.String != "" {
+		return s.Type.String
+	}
+	for _, t := range s.Type.Array {
+		if t != "null" {
+			return t
+		}
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package oasutil
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/genelet/oas/openapi31"
+)
+
+func TestSchemaAt(t *testing.T) {
+	typ := func(s string) *openapi31.StringOrStringArray { return &openapi31.StringOrStringArray{String: s} }
+	doc := &openapi31.OpenAPI{
+		Components: &openapi31.Components{
+			Schemas: map[string]*openapi31.Schema{
+				"Pet": {AllOf: []*openapi31.Schema{
+					{Ref: "#/components/schemas/Base"},
+					{Properties: map[string]*openapi31.Schema{"name": {Type: typ("string")}}},
+				}},
+				"Base": {Properties: map[string]*openapi31.Schema{"id": {Type: typ("integer"), Format: "int64"}}},
+			},
+			Responses: map[string]*openapi31.Response{
+				"Pets": {Content: map[string]*openapi31.MediaType{
+					"application/xml":  {Schema: &openapi31.Schema{Type: typ("string")}},
+					"application/json": {Schema: &openapi31.Schema{Type: typ("array"), Items: &openapi31.Schema{Ref: "#/components/schemas/Pet"}}},
+				}},
+			},
+		},
+	}
+	op := &Operation{Doc: doc, Operation: &openapi31.Operation{Responses: &openapi31.Responses{
+		StatusCode: map[string]*openapi31.Response{
+			"404": {Description: "not found"},
+			"200": {Ref: "#/components/responses/Pets"},
+		},
+	}}}
+
+	resp := op.SuccessResponse()
+	if resp == nil || resp.Content == nil {
+		t.Fatalf("expected the 200 response to resolve, got %+v", resp)
+	}
+	body := ContentSchema(resp.Content)
+	if SchemaType(body) != "array" {
+		t.Fatalf("expected the JSON schema to be preferred, got %+v", body)
+	}
+	if s := SchemaAt(doc, body, []string{"0", "id"}); SchemaType(s) != "integer" || s.Format != "int64" {
+		t.Errorf("expected /0/id to resolve through allOf and $ref, got %+v", s)
+	}
+	if s := SchemaAt(doc, body, []string{"0", "name"}); SchemaType(s) != "string" {
+		t.Errorf("expected /0/name to be a string, got %+v", s)
+	}
+	if s := SchemaAt(doc, body, []string{"0", "missing"}); s != nil {
+		t.Errorf("expected nil for an unknown property, got %+v", s)
+	}
+	if got := SchemaType(&openapi31.Schema{Type: &openapi31.StringOrStringArray{Array: []string{"null", "number"}}}); got != "number" {
+		t.Errorf("expected number, got %q", got)
+	}
+}
+
+func TestPreferredMediaType(t *testing.T) {
+	content := map[string]*openapi31.MediaType{
+		"text/plain":                        {},
+		"application/x-www-form-urlencoded": {},
+		"application/vnd.api+json":          {},
+		"multipart/form-data":               {},
+	}
+	tests := []struct {
+		prefer []string
+		want   string
+	}{
+		{nil, "application/vnd.api+json"},
+		{[]string{"multipart/*"}, "multipart/form-data"},
+		{[]string{"application/xml", "Application/X-WWW-Form-Urlencoded"}, "application/x-www-form-urlencoded"},
+		{[]string{"application/xml"}, "application/vnd.api+json"},
+	}
+	for _, tt := range tests {
+		if got := PreferredMediaType(content, tt.prefer); got != tt.want {
+			t.Errorf("PreferredMediaType(%v) = %q, want %q", tt.prefer, got, tt.want)
+		}
+	}
+	content["application/json; charset=utf-8"] = &openapi31.MediaType{}
+	if got := PreferredMediaType(content, nil); got != "application/json; charset=utf-8" {
+		t.Errorf("PreferredMediaType() = %q, want application/json", got)
+	}
+	if got := PreferredMediaType(nil, nil); got != "" {
+		t.Errorf("PreferredMediaType(nil) = %q, want empty", got)
+	}
+}
+
+func TestOperationParameters(t *testing.T) {
+	doc := &openapi31.OpenAPI{
+		Components: &openapi31.Components{
+			Parameters: map[string]*openapi31.Parameter{
+				"PetId": {Name: "petId", In: "path", Required: true},
+				"Limit": {Name: "limit", In: "query"},
+			},
+			RequestBodies: map[string]*openapi31.RequestBody{
+				"Pet": {Content: map[string]*openapi31.MediaType{"application/json": {}}},
+			},
+		},
+	}
+	item := &openapi31.PathItem{
+		Parameters: []*openapi31.Parameter{
+			{Ref: "#/components/parameters/PetId"},
+			{Name: "verbose", In: "query"},
+			{Name: "verbose", In: "header"},
+		},
+	}
+	op := &Operation{Doc: doc, PathItem: item, Operation: &openapi31.Operation{
+		Parameters: []*openapi31.Parameter{
+			{Name: "verbose", In: "query", Required: true},
+			{Ref: "#/components/parameters/Limit"},
+			{Ref: "#/components/parameters/Missing"},
+		},
+		RequestBody: &openapi31.RequestBody{Ref: "#/components/requestBodies/Pet"},
+	}}
+
+	var got []string
+	for _, p := range op.Parameters() {
+		got = append(got, fmt.Sprintf("%s %s %v", p.In, p.Name, p.Required))
+	}
+	want := []string{"path petId true", "header verbose false", "query verbose true", "query limit false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parameters() = %v, want %v", got, want)
+	}
+
+	if rb := op.RequestBody(); rb == nil || rb.Content["application/json"] == nil {
+		t.Errorf("RequestBody() = %+v, want the Pet request body", rb)
+	}
+	op.Operation.RequestBody = &openapi31.RequestBody{Ref: "#/components/requestBodies/Missing"}
+	if rb := op.RequestBody(); rb != nil {
+		t.Errorf("RequestBody() = %+v, want nil for an unresolved reference", rb)
+	}
+}
@@ -0,0 +1,269 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/genelet/arazzo/arazzo1"
+)
+
+// ErrDocumentChanged is returned by Resume for a checkpoint taken from a
+// different Arazzo document than the Runner's.
+var ErrDocumentChanged = errors.New("the document changed since the checkpoint was taken")
+
+// Checkpoint is the state of a run, saved as it progresses so that an
+// interrupted or failed run can be resumed with Runner.Resume. It encodes
+// as JSON.
+type Checkpoint struct {
+	// Document is the digest of the Arazzo document the run was started
+	// with.
+	Document string `json:"document"`
+	// WorkflowIds are the workflows the run was started with; all when
+	// empty.
+	WorkflowIds []string `json:"workflowIds,omitempty"`
+	// Inputs maps workflow ids to their inputs.
+	Inputs map[string]map[string]any `json:"inputs,omitempty"`
+	// Workflows are the workflows that started, in the order they started.
+	Workflows []*WorkflowCheckpoint `json:"workflows"`
+}
+
+// WorkflowCheckpoint is the state of a workflow run.
+type WorkflowCheckpoint struct {
+	WorkflowId string `json:"workflowId"`
+	// Status is set once the workflow completed.
+	Status  Status         `json:"status,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Outputs map[string]any `json:"outputs,omitempty"`
+	// Steps are the steps that completed, in execution order.
+	Steps []*StepCheckpoint `json:"steps,omitempty"`
+	// Next is the step the workflow continues at, and NextWorkflow the
+	// workflow a goto action runs in place of the rest of it. Both are
+	// empty when the workflow has not started, or has run all its steps.
+	Next         string `json:"next,omitempty"`
+	NextWorkflow string `json:"nextWorkflow,omitempty"`
+	// Retrying is the step Next while it is being retried.
+	Retrying *StepCheckpoint `json:"retrying,omitempty"`
+}
+
+// StepCheckpoint is the state of a step run.
+type StepCheckpoint struct {
+	StepId   string         `json:"stepId"`
+	Status   Status         `json:"status,omitempty"`
+	Attempts int            `json:"attempts"`
+	Outputs  map[string]any `json:"outputs,omitempty"`
+	// Retries counts the retries of a step being retried by the failure
+	// action that repeated it: onFailure/i for the i-th failure action of
+	// the step, and failureActions/i for the i-th of its workflow.
+	Retries map[string]int `json:"retries,omitempty"`
+}
+
+// CheckpointFile returns a function for Runner.SaveCheckpoint that writes
+// each checkpoint to path as JSON. The file is replaced atomically, so it
+// holds a complete checkpoint even when the run is killed.
+func CheckpointFile(path string) func(*Checkpoint) error {
+	return func(cp *Checkpoint) error {
+		data, err := json.MarshalIndent(cp, "", "  ")
+		if err != nil {
+			return err
+		}
+		f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return os.Rename(f.Name(), path)
+	}
+}
+
+// ReadCheckpoint reads a checkpoint written by CheckpointFile.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// Resume continues the run cp was saved from, with its workflows and
+// inputs. Workflows that passed are not run again; their results are
+// restored from cp, with Resumed set. The other workflows continue where
+// they stopped: their completed steps are restored, and the step that was
+// running, or that failed, runs again, keeping the retries it had used
+// when it was interrupted while being retried. A workflow step that had
+// not completed calls its workflow again from the start.
+//
+// Resume returns ErrDocumentChanged when the Runner's document is not the
+// one cp was taken from. Checkpoints continue to be saved to
+// SaveCheckpoint, starting from the state in cp, which is not modified.
+func (r *Runner) Resume(ctx context.Context, cp *Checkpoint) (*Result, error) {
+	sum, err := digest(r.doc)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Document != sum {
+		return nil, fmt.Errorf("%w: checkpoint of %s, document is %s", ErrDocumentChanged, cp.Document, sum)
+	}
+	return r.run(ctx, cp.Inputs, cp.WorkflowIds, cp)
+}
+
+// digest returns the SHA-256 digest of the JSON encoding of doc, which is
+// the same whatever format doc was read from.
+func digest(doc *arazzo1.Arazzo) (string, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("digest of document: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// checkpointer keeps the Checkpoint of an execution and the one it
+// resumes. Its methods do nothing on a nil checkpointer.
+type checkpointer struct {
+	save func(*Checkpoint) error
+	// saved holds the workflows of the checkpoint resumed, which are not
+	// modified.
+	saved map[string]*WorkflowCheckpoint
+
+	mu        sync.Mutex
+	cp        *Checkpoint
+	workflows map[string]*WorkflowCheckpoint
+}
+
+// newCheckpointer returns the checkpointer of a run, or nil when the run
+// neither saves nor resumes checkpoints.
+func newCheckpointer(r *Runner, inputs map[string]map[string]any, workflowIds []string, resume *Checkpoint) (*checkpointer, error) {
+	if r.SaveCheckpoint == nil && resume == nil {
+		return nil, nil
+	}
+	sum, err := digest(r.doc)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpointer{
+		save:      r.SaveCheckpoint,
+		saved:     make(map[string]*WorkflowCheckpoint),
+		cp:        &Checkpoint{Document: sum, WorkflowIds: workflowIds, Inputs: inputs, Workflows: []*WorkflowCheckpoint{}},
+		workflows: make(map[string]*WorkflowCheckpoint),
+	}
+	if resume == nil {
+		return c, nil
+	}
+	for _, w := range resume.Workflows {
+		c.saved[w.WorkflowId] = w
+	}
+	// The checkpoint continues from a copy of the one resumed.
+	data, err := json.Marshal(resume.Workflows)
+	if err != nil {
+		return nil, fmt.Errorf("copying checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.cp.Workflows); err != nil {
+		return nil, fmt.Errorf("copying checkpoint: %w", err)
+	}
+	for _, w := range c.cp.Workflows {
+		c.workflows[w.WorkflowId] = w
+	}
+	return c, nil
+}
+
+// resumed returns the saved state of a workflow, if any.
+func (c *checkpointer) resumed(workflowId string) *WorkflowCheckpoint {
+	if c == nil {
+		return nil
+	}
+	return c.saved[workflowId]
+}
+
+// update applies f to the state of a workflow and saves the checkpoint.
+func (c *checkpointer) update(workflowId string, f func(w *WorkflowCheckpoint)) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := c.workflows[workflowId]
+	if w == nil {
+		w = &WorkflowCheckpoint{WorkflowId: workflowId}
+		c.workflows[workflowId] = w
+		c.cp.Workflows = append(c.cp.Workflows, w)
+	}
+	f(w)
+	if c.save == nil {
+		return nil
+	}
+	return c.save(c.cp)
+}
+
+// stepCompleted records a step that completed, and where its workflow
+// continues.
+func (c *checkpointer) stepCompleted(workflowId string, sr *StepResult, next, nextWorkflow string) error {
+	return c.update(workflowId, func(w *WorkflowCheckpoint) {
+		w.Status, w.Error = "", ""
+		w.Steps = append(w.Steps, &StepCheckpoint{StepId: sr.StepId, Status: sr.Status, Attempts: sr.Attempts, Outputs: sr.Outputs})
+		w.Next, w.NextWorkflow, w.Retrying = next, nextWorkflow, nil
+	})
+}
+
+// retrying records a step about to be retried, with the retries it used.
+func (c *checkpointer) retrying(workflowId string, sr *StepResult, failure []failureAction, retries map[*arazzo1.FailureAction]int) error {
+	counts := make(map[string]int, len(retries))
+	for _, action := range failure {
+		if n := retries[action.FailureAction]; n > 0 {
+			counts[action.key] = n
+		}
+	}
+	return c.update(workflowId, func(w *WorkflowCheckpoint) {
+		w.Status, w.Error = "", ""
+		w.Next, w.NextWorkflow = sr.StepId, ""
+		w.Retrying = &StepCheckpoint{StepId: sr.StepId, Attempts: sr.Attempts, Retries: counts}
+	})
+}
+
+// completed records the outcome of a workflow. A step that failed is
+// retried afresh when the workflow is resumed, while one that was
+// interrupted keeps the retries it used.
+func (c *checkpointer) completed(res *WorkflowResult) error {
+	return c.update(res.WorkflowId, func(w *WorkflowCheckpoint) {
+		w.Status, w.Error, w.Outputs = res.Status, "", res.Outputs
+		if res.Err != nil {
+			w.Error = res.Err.Error()
+		}
+		if res.Status != StatusCancelled {
+			w.Retrying = nil
+		}
+	})
+}
+
+// restoreWorkflow returns the result of a workflow that passed before it
+// was resumed.
+func restoreWorkflow(w *WorkflowCheckpoint, inputs map[string]any) *WorkflowResult {
+	res := &WorkflowResult{WorkflowId: w.WorkflowId, Status: StatusPassed, Inputs: inputs, Outputs: w.Outputs, Resumed: true}
+	for _, s := range w.Steps {
+		res.Steps = append(res.Steps, restoreStep(s))
+	}
+	return res
+}
+
+// restoreStep returns the result of a step that completed before its
+// workflow was resumed.
+func restoreStep(s *StepCheckpoint) *StepResult {
+	return &StepResult{StepId: s.StepId, Status: s.Status, Attempts: s.Attempts, Outputs: s.Outputs, Resumed: true}
+}
@@ -0,0 +1,253 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"path/filepath"
+	"testing"
+)
+
+const checkpointArazzo = documentHeader + `
+workflows:
+  - workflowId: pet
+    steps:
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - {name: petId, in: path, value: 7}
+        outputs:
+          name: $response.body#/name
+  - workflowId: onboard
+    inputs:
+      type: object
+      properties:
+        user: {type: string}
+    steps:
+      - stepId: login
+        operationId: login
+        requestBody:
+          payload: {user: $inputs.user}
+        outputs:
+          token: $response.body#/token
+      - stepId: flaky
+        operationId: flaky
+        successCriteria:
+          - condition: $statusCode == 200
+        onFailure:
+          - {name: again, type: retry, retryLimit: 1}
+      - stepId: list
+        operationId: listPets
+        parameters:
+          - {name: Authorization, in: header, value: 'Bearer {$steps.login.outputs.token}'}
+        successCriteria:
+          - condition: $statusCode == 200
+    outputs:
+      token: $steps.login.outputs.token
+  - workflowId: after
+    dependsOn: [onboard]
+    steps:
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - {name: petId, in: path, value: 8}
+`
+
+func TestCheckpointResume(t *testing.T) {
+	srv := newPetServer(t)
+	srv.flakyAfter = 5
+	path := filepath.Join(t.TempDir(), "run.json")
+	r := newRunner(t, srv, checkpointArazzo)
+	r.SaveCheckpoint = CheckpointFile(path)
+
+	inputs := map[string]map[string]any{"onboard": {"user": "ann"}}
+	result, err := r.Run(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Workflow("onboard").Status != StatusFailed || result.Workflow("after").Status != StatusCancelled {
+		t.Fatalf("first run: onboard %s, after %s", result.Workflow("onboard").Status, result.Workflow("after").Status)
+	}
+
+	cp, err := ReadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("ReadCheckpoint failed: %v", err)
+	}
+	if len(cp.Workflows) != 2 || cp.Inputs["onboard"]["user"] != "ann" {
+		t.Fatalf("checkpoint = %+v", cp)
+	}
+	var onboard *WorkflowCheckpoint
+	for _, w := range cp.Workflows {
+		if w.WorkflowId == "onboard" {
+			onboard = w
+		}
+	}
+	if onboard == nil || onboard.Status != StatusFailed || onboard.Next != "flaky" || onboard.Retrying != nil ||
+		len(onboard.Steps) != 1 || onboard.Steps[0].Outputs["token"] != "token-ann" {
+		t.Fatalf("onboard checkpoint = %+v", onboard)
+	}
+
+	// The outage is over: the next call of /flaky succeeds.
+	srv.mu.Lock()
+	srv.flakyAfter = srv.flaky
+	srv.mu.Unlock()
+	r = newRunner(t, srv, checkpointArazzo)
+	r.SaveCheckpoint = CheckpointFile(path)
+	result, err = r.Resume(context.Background(), cp)
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if err := result.Err(); err != nil {
+		t.Fatalf("resumed run failed: %v", err)
+	}
+	if pet := result.Workflow("pet"); !pet.Resumed || !pet.Steps[0].Resumed || pet.Steps[0].Outputs["name"] != "rex" {
+		t.Errorf("pet was not restored: %+v", pet)
+	}
+	res := result.Workflow("onboard")
+	if res.Resumed || len(res.Steps) != 3 || !res.Steps[0].Resumed || res.Steps[1].Resumed || res.Steps[1].Attempts != 1 {
+		t.Errorf("onboard = %+v", res)
+	}
+	// The restored token authorizes the list step.
+	if res.Outputs["token"] != "token-ann" || res.Steps[2].Status != StatusPassed {
+		t.Errorf("onboard outputs = %v, list %s", res.Outputs, res.Steps[2].Status)
+	}
+	if after := result.Workflow("after"); after.Status != StatusPassed || after.Resumed {
+		t.Errorf("after = %+v", after)
+	}
+
+	cp, err = ReadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("ReadCheckpoint failed: %v", err)
+	}
+	if len(cp.Workflows) != 3 {
+		t.Fatalf("%d workflows in the checkpoint of the resumed run, want 3", len(cp.Workflows))
+	}
+	for _, w := range cp.Workflows {
+		if w.Status != StatusPassed || w.Next != "" {
+			t.Errorf("checkpoint of %s = %+v", w.WorkflowId, w)
+		}
+	}
+}
+
+func TestCheckpointRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		workflows string
+		retries   map[string]int
+		attempts  int
+	}{
+		{
+			name: "one action",
+			workflows: `
+workflows:
+  - workflowId: flaky
+    steps:
+      - stepId: call
+        operationId: flaky
+        successCriteria:
+          - condition: $statusCode == 200
+        onFailure:
+          - {name: again, type: retry, retryLimit: 2, retryAfter: 0.01}
+`,
+			retries:  map[string]int{"onFailure/0": 1},
+			attempts: 3,
+		},
+		{
+			name: "actions sharing a name",
+			workflows: `
+workflows:
+  - workflowId: flaky
+    failureActions:
+      - reference: $components.failureActions.retry
+    steps:
+      - stepId: call
+        operationId: flaky
+        successCriteria:
+          - condition: $statusCode == 200
+        onFailure:
+          - {name: retry, type: retry, retryLimit: 1, retryAfter: 0.01}
+components:
+  failureActions:
+    retry: {name: retry, type: retry, retryLimit: 1, retryAfter: 0.01}
+`,
+			retries:  map[string]int{"onFailure/0": 1},
+			attempts: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newPetServer(t)
+			srv.flakyAfter = 10
+			r := newRunner(t, srv, documentHeader+tt.workflows)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var last *Checkpoint
+			r.SaveCheckpoint = func(cp *Checkpoint) error {
+				last = cp
+				if w := cp.Workflows[0]; w.Retrying != nil {
+					// Interrupt the run while it waits to retry.
+					cancel()
+				}
+				return nil
+			}
+			result, err := r.Run(ctx, nil)
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if res := result.Workflow("flaky"); res.Status != StatusCancelled {
+				t.Fatalf("interrupted run = %+v", res)
+			}
+			retrying := last.Workflows[0].Retrying
+			if retrying == nil || retrying.StepId != "call" || retrying.Attempts != 1 || !maps.Equal(retrying.Retries, tt.retries) {
+				t.Fatalf("retrying = %+v", retrying)
+			}
+
+			// The resumed step uses the retries left.
+			r.SaveCheckpoint = nil
+			result, err = r.Resume(context.Background(), last)
+			if err != nil {
+				t.Fatalf("Resume failed: %v", err)
+			}
+			res := result.Workflow("flaky")
+			if res.Status != StatusFailed || res.Steps[0].Attempts != tt.attempts {
+				t.Errorf("resumed run = %s after %d attempts, want failed after %d", res.Status, res.Steps[0].Attempts, tt.attempts)
+			}
+			if srv.flaky != tt.attempts {
+				t.Errorf("/flaky called %d times, want %d", srv.flaky, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestCheckpointDocumentChanged(t *testing.T) {
+	var cp *Checkpoint
+	r := newRunner(t, newPetServer(t), checkpointArazzo)
+	r.SaveCheckpoint = func(c *Checkpoint) error {
+		cp = c
+		return nil
+	}
+	if _, err := r.Run(context.Background(), nil, "pet"); err != nil {
+		t.Fatal(err)
+	}
+	changed := newRunner(t, nil, checkpointArazzo+`
+  - workflowId: extra
+    steps:
+      - stepId: get
+        operationId: getPet
+`)
+	if _, err := changed.Resume(context.Background(), cp); !errors.Is(err, ErrDocumentChanged) {
+		t.Errorf("Resume of a changed document = %v, want ErrDocumentChanged", err)
+	}
+}
+
+func TestCheckpointSaveError(t *testing.T) {
+	r := newRunner(t, newPetServer(t), checkpointArazzo)
+	r.SaveCheckpoint = func(*Checkpoint) error { return errors.New("disk full") }
+	res, err := r.RunWorkflow(context.Background(), "pet", nil)
+	if err == nil || res.Status != StatusFailed {
+		t.Fatalf("RunWorkflow = %v, %v, want a checkpoint error", res.Status, err)
+	}
+	if want := "workflow pet: step get: checkpoint: disk full"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,541 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+)
+
+// scope holds what runtime expressions can refer to at a point in a
+// workflow run.
+type scope struct {
+	e      *execution
+	wf     *arazzo1.Workflow
+	inputs map[string]any
+	// steps holds the outputs of the steps run so far.
+	steps map[string]map[string]any
+	// outputs are the outputs of the workflow a workflow step called.
+	outputs map[string]any
+	// req, res and request are the exchange of an operation step; request
+	// maps "in:name" and "body" to the values sent.
+	req     *Request
+	res     *Response
+	request map[string]any
+}
+
+// with returns a copy of s for the criteria and outputs of a step.
+func (s *scope) with(request map[string]any, res *Response, outputs map[string]any) *scope {
+	c := *s
+	c.request, c.res, c.outputs = request, res, outputs
+	return &c
+}
+
+// values evaluates a map of runtime expressions, as step and workflow
+// outputs are.
+func (s *scope) values(m map[string]string) (map[string]any, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]any, len(m))
+	for _, k := range sortedKeys(m) {
+		v, err := s.value(m[k])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+// value evaluates a literal value, replacing the runtime expressions it
+// contains. A string that is a single expression takes the expression's
+// value; expressions embedded in braces are formatted into the string.
+func (s *scope) value(v any) (any, error) {
+	switch val := normalize(v).(type) {
+	case string:
+		parts, err := arazzo1.ParseTemplate(val)
+		if err != nil {
+			return val, nil
+		}
+		if len(parts) == 1 {
+			if parts[0].Expression != nil {
+				return s.evaluate(parts[0].Expression)
+			}
+			return val, nil
+		}
+		var b strings.Builder
+		for _, p := range parts {
+			if p.Expression == nil {
+				b.WriteString(p.Literal)
+				continue
+			}
+			x, err := s.evaluate(p.Expression)
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(text(x))
+		}
+		return b.String(), nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			x, err := s.value(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = x
+		}
+		return out, nil
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			x, err := s.value(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = x
+		}
+		return out, nil
+	default:
+		return val, nil
+	}
+}
+
+// evaluate returns the value of a runtime expression. When planning, values
+// that are not known before requests are sent, such as those of responses,
+// evaluate to the expression as a placeholder, "{$steps.login.outputs.token}".
+func (s *scope) evaluate(e *arazzo1.Expression) (any, error) {
+	v, err := s.resolve(e)
+	if s.e.plan && (err != nil || v == nil) {
+		return "{" + e.Raw + "}", nil
+	}
+	return v, err
+}
+
+// escape escapes a value for a URL. When planning, placeholders are kept as
+// they are, to be readable.
+func (s *scope) escape(v string, escape func(string) string) string {
+	if s.e.plan {
+		return keepPlaceholders(v, escape)
+	}
+	return escape(v)
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\$[^{}]*\}`)
+
+// keepPlaceholders escapes v except for the placeholders in it.
+func keepPlaceholders(v string, escape func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringIndex(v, -1) {
+		b.WriteString(escape(v[last:loc[0]]))
+		b.WriteString(v[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(escape(v[last:]))
+	return b.String()
+}
+
+func (s *scope) resolve(e *arazzo1.Expression) (any, error) {
+	needResponse := func() error {
+		if s.res == nil {
+			return fmt.Errorf("%s is only available after a request", e.Raw)
+		}
+		return nil
+	}
+	// deref applies the dot segments and the JSON Pointer that follow a
+	// value.
+	deref := func(v any, segments []string) any {
+		return lookup(v, append(append([]string{}, segments...), pointerTokens(e.Pointer)...))
+	}
+
+	switch e.Root {
+	case arazzo1.ExpressionStatusCode:
+		if err := needResponse(); err != nil {
+			return nil, err
+		}
+		return float64(s.res.StatusCode), nil
+	case arazzo1.ExpressionURL:
+		if s.req == nil {
+			return nil, fmt.Errorf("%s is only available after a request", e.Raw)
+		}
+		return s.req.URL, nil
+	case arazzo1.ExpressionMethod:
+		if s.req == nil {
+			return nil, fmt.Errorf("%s is only available after a request", e.Raw)
+		}
+		return s.req.Method, nil
+	case arazzo1.ExpressionResponse:
+		if err := needResponse(); err != nil {
+			return nil, err
+		}
+		switch e.Segments[0] {
+		case "header":
+			if values := s.res.Header.Values(e.Segments[1]); len(values) > 0 {
+				return values[0], nil
+			}
+			return nil, nil
+		case "body":
+			return deref(s.res.Data(), e.Segments[1:]), nil
+		}
+		return nil, fmt.Errorf("%s is not available in responses", e.Raw)
+	case arazzo1.ExpressionRequest:
+		if s.request == nil {
+			return nil, fmt.Errorf("%s is only available after a request", e.Raw)
+		}
+		if e.Segments[0] == "body" {
+			return deref(s.request["body"], e.Segments[1:]), nil
+		}
+		for key, v := range s.request {
+			in, name, _ := strings.Cut(key, ":")
+			if in == e.Segments[0] && (name == e.Segments[1] || in == "header" && strings.EqualFold(name, e.Segments[1])) {
+				return deref(v, nil), nil
+			}
+		}
+		return nil, nil
+	case arazzo1.ExpressionInputs:
+		return deref(s.inputs, e.Segments), nil
+	case arazzo1.ExpressionOutputs:
+		if s.outputs == nil {
+			return nil, fmt.Errorf("%s is only available in workflow step outputs", e.Raw)
+		}
+		return deref(s.outputs, e.Segments), nil
+	case arazzo1.ExpressionSteps:
+		outputs, ok := s.steps[e.Segments[0]]
+		if !ok {
+			return nil, fmt.Errorf("%s refers to a step that has not run", e.Raw)
+		}
+		rest := e.Segments[1:]
+		if len(rest) < 1 || rest[0] != "outputs" {
+			return nil, fmt.Errorf("%s must reference step outputs", e.Raw)
+		}
+		return deref(outputs, rest[1:]), nil
+	case arazzo1.ExpressionWorkflows:
+		res := s.e.workflow(e.Segments[0])
+		if res == nil || res.Status != StatusPassed {
+			return nil, fmt.Errorf("%s refers to a workflow that has not completed", e.Raw)
+		}
+		rest := e.Segments[1:]
+		switch {
+		case len(rest) > 0 && rest[0] == "outputs":
+			return deref(res.Outputs, rest[1:]), nil
+		case len(rest) > 0 && rest[0] == "inputs":
+			return deref(res.Inputs, rest[1:]), nil
+		}
+		return nil, fmt.Errorf("%s must reference workflow inputs or outputs", e.Raw)
+	case arazzo1.ExpressionSourceDescriptions:
+		for _, sd := range s.e.r.doc.SourceDescriptions {
+			if sd != nil && sd.Name == e.Segments[0] {
+				return deref(map[string]any{"name": sd.Name, "url": sd.URL, "type": string(sd.Type)}, e.Segments[1:]), nil
+			}
+		}
+		return nil, fmt.Errorf("%s refers to an unknown source description", e.Raw)
+	case arazzo1.ExpressionComponents:
+		c := s.e.r.doc.Components
+		if c != nil && len(e.Segments) >= 2 {
+			switch e.Segments[0] {
+			case "inputs":
+				if v, ok := c.Inputs[e.Segments[1]]; ok {
+					return deref(v, e.Segments[2:]), nil
+				}
+			case "parameters":
+				if p := c.Parameters[e.Segments[1]]; p != nil {
+					v, err := s.value(p.Value)
+					if err != nil {
+						return nil, err
+					}
+					return deref(v, e.Segments[2:]), nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%s does not resolve to a component input or parameter", e.Raw)
+	}
+	return nil, fmt.Errorf("%s is not supported", e.Raw)
+}
+
+// criteria evaluates criteria, all of which must pass for a step to
+// succeed.
+func (s *scope) criteria(criteria []*arazzo1.Criterion) []*CriterionResult {
+	results := make([]*CriterionResult, 0, len(criteria))
+	for _, c := range criteria {
+		if c == nil {
+			continue
+		}
+		passed, err := s.criterion(c)
+		results = append(results, &CriterionResult{Criterion: c, Passed: passed, Err: err})
+	}
+	return results
+}
+
+// met reports whether all criteria of an action pass. Criteria that cannot
+// be evaluated are not met, so the action is skipped.
+func (s *scope) met(criteria []*arazzo1.Criterion) bool {
+	for _, c := range s.criteria(criteria) {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// criterion evaluates a simple or regex criterion.
+func (s *scope) criterion(c *arazzo1.Criterion) (bool, error) {
+	switch criterionType(c) {
+	case arazzo1.CriterionTypeSimple:
+		node, err := arazzo1.ParseCondition(c.Condition)
+		if err != nil {
+			return false, err
+		}
+		v, err := s.condition(node)
+		return truthy(v), err
+	case arazzo1.CriterionTypeRegex:
+		v, err := s.value(c.Context)
+		if err != nil {
+			return false, err
+		}
+		re, err := regexp.Compile(c.Condition)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(text(v)), nil
+	}
+	return false, fmt.Errorf("%s criteria are not supported", criterionType(c))
+}
+
+// condition evaluates a node of a simple condition.
+func (s *scope) condition(node arazzo1.ConditionNode) (any, error) {
+	switch n := node.(type) {
+	case *arazzo1.ConditionLiteral:
+		return n.Value, nil
+	case *arazzo1.ConditionExpression:
+		return s.evaluate(n.Expression)
+	case *arazzo1.ConditionNot:
+		v, err := s.condition(n.Operand)
+		return !truthy(v), err
+	case *arazzo1.ConditionBinary:
+		left, err := s.condition(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "&&":
+			if !truthy(left) {
+				return false, nil
+			}
+			right, err := s.condition(n.Right)
+			return truthy(right), err
+		case "||":
+			if truthy(left) {
+				return true, nil
+			}
+			right, err := s.condition(n.Right)
+			return truthy(right), err
+		}
+		right, err := s.condition(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		return compare(left, n.Op, right), nil
+	}
+	return nil, fmt.Errorf("unexpected condition node %T", node)
+}
+
+func criterionType(c *arazzo1.Criterion) arazzo1.CriterionType {
+	if c.ExpressionType != nil {
+		return c.ExpressionType.Type
+	}
+	if c.Type == "" {
+		return arazzo1.CriterionTypeSimple
+	}
+	return c.Type
+}
+
+// normalize converts a typed value to the generic JSON model.
+func normalize(v any) any {
+	switch v.(type) {
+	case nil, string, bool, float64, map[string]any, []any:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// lookup returns the value at the path of tokens, or nil when it does not
+// exist.
+func lookup(v any, tokens []string) any {
+	node := normalize(v)
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case map[string]any:
+			node = n[token]
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil
+			}
+			node = n[i]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// setPointer sets the value at a JSON Pointer inside maps and slices.
+func setPointer(target any, pointer string, value any) {
+	tokens := pointerTokens(pointer)
+	node := target
+	for i, token := range tokens {
+		last := i == len(tokens)-1
+		switch n := node.(type) {
+		case map[string]any:
+			if last {
+				n[token] = value
+				return
+			}
+			if n[token] == nil {
+				n[token] = map[string]any{}
+			}
+			node = n[token]
+		case []any:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(n) {
+				return
+			}
+			if last {
+				n[idx] = value
+				return
+			}
+			node = n[idx]
+		default:
+			return
+		}
+	}
+}
+
+func pointerTokens(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, t := range tokens {
+		tokens[i] = oasutil.UnescapePointer(t)
+	}
+	return tokens
+}
+
+// compare evaluates a comparison of a simple criterion condition.
+func compare(a any, op string, b any) bool {
+	a, b = normalize(a), normalize(b)
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		x, xok := toNumber(a)
+		y, yok := toNumber(b)
+		if xok && yok {
+			switch op {
+			case "==":
+				return x == y
+			case "!=":
+				return x != y
+			case "<":
+				return x < y
+			case "<=":
+				return x <= y
+			case ">":
+				return x > y
+			case ">=":
+				return x >= y
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return reflect.DeepEqual(a, b)
+	case "!=":
+		return !reflect.DeepEqual(a, b)
+	}
+	x, xok := a.(string)
+	y, yok := b.(string)
+	if !xok || !yok {
+		return false
+	}
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return false
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// truthy reports whether a value counts as true in a condition.
+func truthy(v any) bool {
+	switch n := normalize(v).(type) {
+	case nil:
+		return false
+	case bool:
+		return n
+	case float64:
+		return n != 0
+	case string:
+		return n != ""
+	}
+	return true
+}
+
+// text formats a value for a string: strings as they are, numbers without
+// exponents, and objects and arrays as JSON.
+func text(v any) string {
+	switch n := normalize(v).(type) {
+	case nil:
+		return ""
+	case string:
+		return n
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(n)
+	default:
+		data, _ := json.Marshal(n)
+		return string(data)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
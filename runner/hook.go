@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"context"
+	"slices"
+
+	"github.com/genelet/arazzo/arazzo1"
+)
+
+// Hook is called around the workflows and steps a Runner runs, to adjust
+// requests, such as to add credentials or correlation ids, to veto steps, or
+// to record metrics and audit logs. The Before methods return the context
+// the workflow or attempt continues with, so hooks can pass values, such as
+// trace spans, to the calls that follow; they return ctx when they have
+// nothing to add.
+//
+// Hooks are called in the order of Runner.Hooks for Before methods and
+// OnRetry, and in reverse order for After methods. As workflows run
+// concurrently, hooks must be safe for concurrent use. Embed NopHook to
+// implement only some of the methods.
+type Hook interface {
+	// BeforeWorkflow is called before the first step of a workflow runs,
+	// including workflows called by workflow steps and goto actions. An
+	// error vetoes the workflow, which fails with it.
+	BeforeWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult) (context.Context, error)
+
+	// AfterWorkflow is called once a workflow BeforeWorkflow was called for
+	// has completed, with its status, outputs and error in res.
+	AfterWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult)
+
+	// BeforeStep is called before each attempt of a step: for an operation
+	// step before its request, sr.Request, is sent, which the hook may
+	// modify, with $request expressions seeing the changes, and for a
+	// workflow step before the workflow is called.
+	// sr.Attempts counts the attempts, this one included. An error vetoes
+	// the step, which fails with it, without its failure actions.
+	BeforeStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult) (context.Context, error)
+
+	// OnRetry is called when a retry failure action repeats a step, with
+	// the response and criteria of the attempt that failed in sr, before
+	// waiting retryAfter seconds. ctx is that of the failed attempt.
+	OnRetry(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult, action *arazzo1.FailureAction)
+
+	// AfterStep is called once a step has completed, with its status,
+	// outputs and error in sr. ctx is that of the last attempt.
+	AfterStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult)
+}
+
+// NopHook implements Hook with methods that do nothing.
+type NopHook struct{}
+
+func (NopHook) BeforeWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult) (context.Context, error) {
+	return ctx, nil
+}
+
+func (NopHook) AfterWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult) {}
+
+func (NopHook) BeforeStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult) (context.Context, error) {
+	return ctx, nil
+}
+
+func (NopHook) OnRetry(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult, action *arazzo1.FailureAction) {
+}
+
+func (NopHook) AfterStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult) {
+}
+
+func (r *Runner) beforeWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult) (context.Context, error) {
+	for _, h := range r.Hooks {
+		next, err := h.BeforeWorkflow(ctx, wf, res)
+		if err != nil {
+			return ctx, err
+		}
+		ctx = next
+	}
+	return ctx, nil
+}
+
+func (r *Runner) afterWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult) {
+	for _, h := range slices.Backward(r.Hooks) {
+		h.AfterWorkflow(ctx, wf, res)
+	}
+}
+
+func (r *Runner) beforeStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult) (context.Context, error) {
+	for _, h := range r.Hooks {
+		next, err := h.BeforeStep(ctx, wf, step, sr)
+		if err != nil {
+			return ctx, err
+		}
+		ctx = next
+	}
+	return ctx, nil
+}
+
+func (r *Runner) onRetry(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult, action *arazzo1.FailureAction) {
+	for _, h := range r.Hooks {
+		h.OnRetry(ctx, wf, step, sr, action)
+	}
+}
+
+func (r *Runner) afterStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult) {
+	for _, h := range slices.Backward(r.Hooks) {
+		h.AfterStep(ctx, wf, step, sr)
+	}
+}
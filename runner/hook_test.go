@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+)
+
+type ctxKey struct{}
+
+// recordingHook logs the calls it receives, adds an Authorization header to
+// requests, and vetoes the steps in veto.
+type recordingHook struct {
+	name string
+	veto map[string]bool
+
+	mu     *sync.Mutex
+	events *[]string
+}
+
+func (h recordingHook) log(format string, args ...any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.events = append(*h.events, h.name+" "+fmt.Sprintf(format, args...))
+}
+
+func (h recordingHook) BeforeWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult) (context.Context, error) {
+	h.log("before workflow %s", wf.WorkflowId)
+	return context.WithValue(ctx, ctxKey{}, wf.WorkflowId), nil
+}
+
+func (h recordingHook) AfterWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult) {
+	h.log("after workflow %s %s ctx=%v", wf.WorkflowId, res.Status, ctx.Value(ctxKey{}))
+}
+
+func (h recordingHook) BeforeStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult) (context.Context, error) {
+	h.log("before step %s attempt %d ctx=%v", step.StepId, sr.Attempts, ctx.Value(ctxKey{}))
+	if h.veto[step.StepId] {
+		return ctx, errors.New("vetoed")
+	}
+	if sr.Request != nil {
+		sr.Request.Header.Set("Authorization", "Bearer token-hook")
+	}
+	return ctx, nil
+}
+
+func (h recordingHook) OnRetry(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult, action *arazzo1.FailureAction) {
+	h.log("retry %s after %d with %s", step.StepId, sr.Response.StatusCode, action.Name)
+}
+
+func (h recordingHook) AfterStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult) {
+	h.log("after step %s %s attempts %d", step.StepId, sr.Status, sr.Attempts)
+}
+
+const hookDocument = documentHeader + `
+workflows:
+  - workflowId: pets
+    steps:
+      - stepId: flaky
+        operationId: flaky
+        successCriteria:
+          - condition: $statusCode == 200
+        onFailure:
+          - name: again
+            type: retry
+            retryLimit: 1
+      - stepId: list
+        operationId: listPets
+        successCriteria:
+          - condition: $statusCode == 200
+`
+
+func TestHooks(t *testing.T) {
+	srv := newPetServer(t)
+	srv.flakyAfter = 1
+	r := newRunner(t, srv, hookDocument)
+	var mu sync.Mutex
+	var events []string
+	r.Hooks = []Hook{
+		recordingHook{name: "a", mu: &mu, events: &events},
+		recordingHook{name: "b", mu: &mu, events: &events},
+	}
+	res, err := r.RunWorkflow(context.Background(), "pets", nil)
+	if err != nil {
+		t.Fatalf("RunWorkflow failed: %v", err)
+	}
+	if got := res.Steps[1].Request.Header.Get("Authorization"); got != "Bearer token-hook" {
+		t.Errorf("Authorization = %q, want the header set by the hook", got)
+	}
+	want := []string{
+		"a before workflow pets",
+		"b before workflow pets",
+		"a before step flaky attempt 1 ctx=pets",
+		"b before step flaky attempt 1 ctx=pets",
+		"a retry flaky after 503 with again",
+		"b retry flaky after 503 with again",
+		"a before step flaky attempt 2 ctx=pets",
+		"b before step flaky attempt 2 ctx=pets",
+		"b after step flaky passed attempts 2",
+		"a after step flaky passed attempts 2",
+		"a before step list attempt 1 ctx=pets",
+		"b before step list attempt 1 ctx=pets",
+		"b after step list passed attempts 1",
+		"a after step list passed attempts 1",
+		"b after workflow pets passed ctx=pets",
+		"a after workflow pets passed ctx=pets",
+	}
+	if strings.Join(events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("events:\n%s\nwant:\n%s", strings.Join(events, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHookVeto(t *testing.T) {
+	srv := newPetServer(t)
+	requests := 0
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	})
+	r := newRunner(t, srv, hookDocument)
+	var mu sync.Mutex
+	var events []string
+	r.Hooks = []Hook{recordingHook{name: "a", veto: map[string]bool{"flaky": true}, mu: &mu, events: &events}}
+
+	res, err := r.RunWorkflow(context.Background(), "pets", nil)
+	if err == nil || !strings.Contains(err.Error(), "step flaky: vetoed") {
+		t.Fatalf("err = %v, want the veto", err)
+	}
+	if res.Status != StatusFailed || len(res.Steps) != 1 || res.Steps[0].Attempts != 1 {
+		t.Errorf("result = %s with %d steps", res.Status, len(res.Steps))
+	}
+	if requests != 0 {
+		t.Errorf("sent %d requests for a vetoed step", requests)
+	}
+	if last := events[len(events)-1]; last != "a after workflow pets failed ctx=pets" {
+		t.Errorf("last event = %q", last)
+	}
+}
+
+// headerHook adds a traceparent to requests and replaces their X-Trace.
+type headerHook struct{ NopHook }
+
+func (headerHook) BeforeStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *StepResult) (context.Context, error) {
+	sr.Request.Header.Set("Traceparent", "00-abc-01")
+	sr.Request.Header.Set("X-Trace", "hook")
+	return ctx, nil
+}
+
+func TestHookRequestValues(t *testing.T) {
+	r := newRunner(t, newPetServer(t), documentHeader+`
+workflows:
+  - workflowId: pet
+    steps:
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - {name: petId, in: path, value: 7}
+          - {name: x-trace, in: header, value: step}
+          - {name: x-count, in: header, value: 2}
+        successCriteria:
+          - condition: $request.header.traceparent == '00-abc-01'
+        outputs:
+          traceparent: $request.header.Traceparent
+          trace: $request.header.x-trace
+          count: $request.header.x-count
+          petId: $request.path.petId
+`)
+	r.Hooks = []Hook{headerHook{}}
+	res, err := r.RunWorkflow(context.Background(), "pet", nil)
+	if err != nil {
+		t.Fatalf("RunWorkflow failed: %v", err)
+	}
+	// $request expressions see the request as the hooks left it, and the
+	// values hooks did not change keep their types.
+	want := map[string]any{"traceparent": "00-abc-01", "trace": "hook", "count": 2.0, "petId": 7.0}
+	for k, v := range want {
+		if got := res.Steps[0].Outputs[k]; fmt.Sprintf("%T %v", got, got) != fmt.Sprintf("%T %v", v, v) {
+			t.Errorf("output %s = %T %v, want %T %v", k, got, got, v, v)
+		}
+	}
+}
+
+// vetoWorkflow vetoes every workflow.
+type vetoWorkflow struct{ NopHook }
+
+func (vetoWorkflow) BeforeWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult) (context.Context, error) {
+	return ctx, errors.New("not today")
+}
+
+func TestHookVetoWorkflow(t *testing.T) {
+	r := newRunner(t, newPetServer(t), hookDocument)
+	r.Hooks = []Hook{vetoWorkflow{}}
+	res, err := r.RunWorkflow(context.Background(), "pets", nil)
+	if err == nil || err.Error() != "workflow pets: not today" || len(res.Steps) != 0 {
+		t.Errorf("err = %v, steps = %d", err, len(res.Steps))
+	}
+}
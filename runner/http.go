@@ -0,0 +1,324 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+)
+
+// Request is an HTTP request a step sent.
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Response is the HTTP response a step received.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	once sync.Once
+	data any
+}
+
+// Data returns the body decoded as JSON, or the body as a string when it is
+// not JSON.
+func (r *Response) Data() any {
+	r.once.Do(func() {
+		if len(r.Body) == 0 {
+			return
+		}
+		if err := json.Unmarshal(r.Body, &r.data); err != nil {
+			r.data = string(r.Body)
+		}
+	})
+	return r.data
+}
+
+// Param is a named value of a request: a query parameter, header or
+// cookie.
+type Param struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// call is the request of an operation step before it is encoded.
+type call struct {
+	method string
+	// url is the server URL and the path, with path parameters substituted.
+	url     string
+	query   []Param
+	header  []Param
+	cookies []Param
+	body    []byte
+	// values holds the values given to the parameters and the body, keyed
+	// by "in:name" and "body".
+	values map[string]any
+}
+
+// buildCall builds the request of an operation step.
+func (r *Runner) buildCall(sc *scope, op *oasutil.Operation, step *arazzo1.Step, params []*arazzo1.Parameter) (*call, error) {
+	base := r.BaseURLs[op.Source]
+	if base == "" {
+		base = op.ServerURL()
+	}
+	if base == "" {
+		return nil, fmt.Errorf("source %s declares no server and has no base URL", op.Source)
+	}
+
+	c := &call{method: strings.ToUpper(op.Method), values: make(map[string]any)}
+	path := op.Path
+	for _, p := range params {
+		v, err := sc.value(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", p.Name, err)
+		}
+		c.values[string(p.In)+":"+p.Name] = v
+		switch p.In {
+		case arazzo1.ParameterInPath:
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", sc.escape(text(v), url.PathEscape))
+		case arazzo1.ParameterInQuery:
+			if items, ok := normalize(v).([]any); ok {
+				for _, item := range items {
+					c.query = append(c.query, Param{p.Name, text(item)})
+				}
+			} else {
+				c.query = append(c.query, Param{p.Name, text(v)})
+			}
+		case arazzo1.ParameterInHeader:
+			c.setHeader(p.Name, text(v))
+		case arazzo1.ParameterInCookie:
+			c.cookies = append(c.cookies, Param{p.Name, text(v)})
+		default:
+			return nil, fmt.Errorf("parameter %q has no location", p.Name)
+		}
+	}
+	c.url = strings.TrimSuffix(base, "/") + path
+
+	if rb := step.RequestBody; rb != nil {
+		payload, err := sc.value(rb.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		}
+		for _, rep := range rb.Replacements {
+			if rep == nil {
+				continue
+			}
+			v, err := sc.value(rep.Value)
+			if err != nil {
+				return nil, fmt.Errorf("replacement %s: %w", rep.Target, err)
+			}
+			setPointer(payload, rep.Target, v)
+		}
+		var declared []string
+		if body := op.RequestBody(); body != nil {
+			for ct := range body.Content {
+				declared = append(declared, ct)
+			}
+		}
+		contentType := requestContentType(rb, declared)
+		data, err := encodeBody(contentType, payload)
+		if err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		}
+		c.setHeader("Content-Type", contentType)
+		c.body = data
+		c.values["body"] = payload
+	}
+	return c, nil
+}
+
+// setHeader sets a header, replacing any value it has.
+func (c *call) setHeader(name, value string) {
+	for i, h := range c.header {
+		if strings.EqualFold(h.Name, name) {
+			c.header[i].Value = value
+			return
+		}
+	}
+	c.header = append(c.header, Param{name, value})
+}
+
+// request encodes the call as the HTTP request to send.
+func (c *call) request() *Request {
+	req := &Request{Method: c.method, URL: c.url + encodeQuery(c.query, url.QueryEscape), Header: http.Header{}, Body: c.body}
+	for _, h := range c.header {
+		req.Header.Set(h.Name, h.Value)
+	}
+	if len(c.cookies) > 0 {
+		req.Header.Set("Cookie", cookieHeader(c.cookies))
+	}
+	return req
+}
+
+// sent returns the values of req, keyed like values, for the $request
+// expressions of a step whose request hooks may have changed. A value the
+// request still carries as the step encoded it keeps the type the step gave
+// it. Path parameters, which the URL does not delimit, are as the step gave
+// them.
+func (c *call) sent(req *Request) map[string]any {
+	values := make(map[string]any, len(c.values))
+	for key, v := range c.values {
+		if strings.HasPrefix(key, string(arazzo1.ParameterInPath)+":") {
+			values[key] = v
+		}
+	}
+	add := func(in arazzo1.ParameterIn, name string, sent []string) {
+		key := string(in) + ":" + name
+		for k, v := range c.values {
+			given, n, _ := strings.Cut(k, ":")
+			if given != string(in) || n != name && (in != arazzo1.ParameterInHeader || !strings.EqualFold(n, name)) {
+				continue
+			}
+			key = k
+			var texts []string
+			if items, ok := normalize(v).([]any); ok && in == arazzo1.ParameterInQuery {
+				for _, item := range items {
+					texts = append(texts, text(item))
+				}
+			} else {
+				texts = []string{text(v)}
+			}
+			if slices.Equal(texts, sent) {
+				values[key] = v
+				return
+			}
+		}
+		if len(sent) == 1 {
+			values[key] = sent[0]
+			return
+		}
+		items := make([]any, len(sent))
+		for i, v := range sent {
+			items[i] = v
+		}
+		values[key] = items
+	}
+	for name, vs := range req.Header {
+		if len(vs) > 0 && name != "Cookie" {
+			add(arazzo1.ParameterInHeader, name, vs)
+		}
+	}
+	if u, err := url.Parse(req.URL); err == nil {
+		for name, vs := range u.Query() {
+			add(arazzo1.ParameterInQuery, name, vs)
+		}
+	}
+	for _, line := range req.Header.Values("Cookie") {
+		cookies, _ := http.ParseCookie(line)
+		for _, cookie := range cookies {
+			add(arazzo1.ParameterInCookie, cookie.Name, []string{cookie.Value})
+		}
+	}
+	switch {
+	case bytes.Equal(req.Body, c.body):
+		if v, ok := c.values["body"]; ok {
+			values["body"] = v
+		}
+	case len(req.Body) > 0:
+		var v any
+		if err := json.Unmarshal(req.Body, &v); err != nil {
+			v = string(req.Body)
+		}
+		values["body"] = v
+	}
+	return values
+}
+
+// encodeQuery returns the query string, with its leading question mark, of
+// the parameters, in their order.
+func encodeQuery(query []Param, escape func(string) string) string {
+	if len(query) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(query))
+	for i, q := range query {
+		pairs[i] = escape(q.Name) + "=" + escape(q.Value)
+	}
+	return "?" + strings.Join(pairs, "&")
+}
+
+func cookieHeader(cookies []Param) string {
+	pairs := make([]string, len(cookies))
+	for i, c := range cookies {
+		pairs[i] = (&http.Cookie{Name: c.Name, Value: c.Value}).String()
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// send sends req and reads the response.
+func (r *Runner) send(ctx context.Context, req *Request) (*Response, error) {
+	hr, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	hr.Header = req.Header.Clone()
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(hr)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// requestContentType returns the content type for a step's request body,
+// falling back to the media types declared by the operation, JSON first.
+func requestContentType(rb *arazzo1.RequestBody, declared []string) string {
+	if rb.ContentType != "" {
+		return rb.ContentType
+	}
+	sort.Strings(declared)
+	for _, ct := range declared {
+		mt := strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+		if mt == "application/json" || strings.HasSuffix(mt, "+json") {
+			return ct
+		}
+	}
+	if len(declared) > 0 {
+		return declared[0]
+	}
+	return "application/json"
+}
+
+// encodeBody encodes a payload for the content type. A string payload is
+// sent as it is.
+func encodeBody(contentType string, payload any) ([]byte, error) {
+	if s, ok := payload.(string); ok {
+		return []byte(s), nil
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form := url.Values{}
+		if m, ok := payload.(map[string]any); ok {
+			for k, v := range m {
+				form.Set(k, text(v))
+			}
+		}
+		return []byte(form.Encode()), nil
+	}
+	if payload == nil {
+		return nil, nil
+	}
+	return json.Marshal(payload)
+}
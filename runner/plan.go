@@ -0,0 +1,228 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+)
+
+// Plan lists the requests the workflows of a document would send.
+type Plan struct {
+	Workflows []*WorkflowPlan `json:"workflows"`
+}
+
+// WorkflowPlan lists the requests of a workflow's steps.
+type WorkflowPlan struct {
+	WorkflowId string      `json:"workflowId"`
+	DependsOn  []string    `json:"dependsOn,omitempty"`
+	Steps      []*StepPlan `json:"steps"`
+}
+
+// StepPlan is the request of an operation step, or the plan of the
+// workflow a workflow step calls.
+type StepPlan struct {
+	StepId      string `json:"stepId"`
+	OperationId string `json:"operationId,omitempty"`
+	Method      string `json:"method,omitempty"`
+	// URL is the server URL and the path, with path parameters substituted.
+	URL     string  `json:"url,omitempty"`
+	Query   []Param `json:"query,omitempty"`
+	Header  []Param `json:"header,omitempty"`
+	Cookies []Param `json:"cookies,omitempty"`
+	// Body is the serialized request body.
+	Body     string        `json:"body,omitempty"`
+	Workflow *WorkflowPlan `json:"workflow,omitempty"`
+}
+
+// Plan resolves the steps of the workflows with the given ids, all workflows
+// when none are given, together with the workflows they depend on, and
+// renders their requests without sending them. inputs maps workflow ids to
+// their inputs.
+//
+// Values that are only known once requests have been sent, such as those
+// of responses and of step outputs, and inputs that are not given, are
+// rendered as placeholders holding their runtime expression, such as
+// "{$steps.login.outputs.token}". Steps are listed once each, in document
+// order, whatever actions they have. Hooks are not called.
+func (r *Runner) Plan(inputs map[string]map[string]any, workflowIds ...string) (*Plan, error) {
+	order, err := r.schedule(workflowIds)
+	if err != nil {
+		return nil, err
+	}
+	e := &execution{r: r, plan: true, workflows: make(map[string]*WorkflowResult)}
+	plan := &Plan{}
+	for _, wf := range order {
+		wp, err := e.planWorkflow(wf, inputs[wf.WorkflowId], nil)
+		if err != nil {
+			return nil, err
+		}
+		plan.Workflows = append(plan.Workflows, wp)
+	}
+	return plan, nil
+}
+
+func (e *execution) planWorkflow(wf *arazzo1.Workflow, inputs map[string]any, stack []string) (*WorkflowPlan, error) {
+	if slices.Contains(stack, wf.WorkflowId) {
+		return nil, fmt.Errorf("workflow %s calls itself", wf.WorkflowId)
+	}
+	stack = slices.Concat(stack, []string{wf.WorkflowId})
+	wp := &WorkflowPlan{WorkflowId: wf.WorkflowId, DependsOn: wf.DependsOn}
+	sc := &scope{e: e, wf: wf, inputs: inputs, steps: map[string]map[string]any{}}
+	for _, step := range wf.Steps {
+		sp, err := e.planStep(wf, step, sc, stack)
+		if err != nil {
+			return nil, fmt.Errorf("workflow %s: step %s: %w", wf.WorkflowId, step.StepId, err)
+		}
+		wp.Steps = append(wp.Steps, sp)
+	}
+	return wp, nil
+}
+
+func (e *execution) planStep(wf *arazzo1.Workflow, step *arazzo1.Step, sc *scope, stack []string) (*StepPlan, error) {
+	params, err := e.r.doc.StepParameters(wf, step)
+	if err != nil {
+		return nil, err
+	}
+	sp := &StepPlan{StepId: step.StepId}
+	if step.IsWorkflowStep() {
+		target := e.r.findWorkflow(step.WorkflowId)
+		if target == nil {
+			return nil, fmt.Errorf("unknown workflow %q", step.WorkflowId)
+		}
+		inputs := make(map[string]any, len(params))
+		for _, p := range params {
+			v, err := sc.value(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %q: %w", p.Name, err)
+			}
+			inputs[p.Name] = v
+		}
+		sp.Workflow, err = e.planWorkflow(target, inputs, stack)
+		return sp, err
+	}
+
+	op, err := e.r.resolver.ResolveStep(step)
+	if err != nil {
+		return nil, err
+	}
+	c, err := e.r.buildCall(sc, op, step, params)
+	if err != nil {
+		return nil, err
+	}
+	if op.Operation != nil {
+		sp.OperationId = op.Operation.OperationID
+	}
+	sp.Method, sp.URL = c.method, c.url
+	sp.Query, sp.Header, sp.Cookies = c.query, c.header, c.cookies
+	sp.Body = string(c.body)
+	return sp, nil
+}
+
+// WriteJSON writes the plan as indented JSON.
+func (p *Plan) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteText writes the plan as HTTP messages, grouped by workflow and step.
+func (p *Plan) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for i, wp := range p.Workflows {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writeWorkflowText(&b, wp, "")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeWorkflowText(b *strings.Builder, wp *WorkflowPlan, indent string) {
+	fmt.Fprintf(b, "%sworkflow %s\n", indent, wp.WorkflowId)
+	if len(wp.DependsOn) > 0 {
+		fmt.Fprintf(b, "%s  depends on %s\n", indent, strings.Join(wp.DependsOn, ", "))
+	}
+	for _, sp := range wp.Steps {
+		b.WriteString("\n")
+		if sp.Workflow != nil {
+			fmt.Fprintf(b, "%s  step %s\n", indent, sp.StepId)
+			writeWorkflowText(b, sp.Workflow, indent+"    ")
+			continue
+		}
+		if sp.OperationId != "" {
+			fmt.Fprintf(b, "%s  step %s (%s)\n", indent, sp.StepId, sp.OperationId)
+		} else {
+			fmt.Fprintf(b, "%s  step %s\n", indent, sp.StepId)
+		}
+		fmt.Fprintf(b, "%s    %s %s\n", indent, sp.Method, sp.fullURL())
+		for _, h := range sp.Header {
+			fmt.Fprintf(b, "%s    %s: %s\n", indent, h.Name, h.Value)
+		}
+		if len(sp.Cookies) > 0 {
+			fmt.Fprintf(b, "%s    Cookie: %s\n", indent, cookieHeader(sp.Cookies))
+		}
+		if sp.Body != "" {
+			b.WriteString("\n")
+			for _, line := range strings.Split(sp.Body, "\n") {
+				fmt.Fprintf(b, "%s    %s\n", indent, line)
+			}
+		}
+	}
+}
+
+// WriteCurl writes the plan as a shell script of curl commands, one per
+// operation step. Placeholders are left in the commands, so they must be
+// filled in before the script is run.
+func (p *Plan) WriteCurl(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	for _, wp := range p.Workflows {
+		writeWorkflowCurl(&b, wp)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeWorkflowCurl(b *strings.Builder, wp *WorkflowPlan) {
+	for _, sp := range wp.Steps {
+		if sp.Workflow != nil {
+			writeWorkflowCurl(b, sp.Workflow)
+			continue
+		}
+		fmt.Fprintf(b, "\n# workflow %s, step %s\n", wp.WorkflowId, sp.StepId)
+		// -g turns off the URL globbing that would expand the braces of
+		// placeholders.
+		fmt.Fprintf(b, "curl -g -X %s %s", sp.Method, shellQuote(sp.fullURL()))
+		for _, h := range sp.Header {
+			fmt.Fprintf(b, " \\\n  -H %s", shellQuote(h.Name+": "+h.Value))
+		}
+		if len(sp.Cookies) > 0 {
+			fmt.Fprintf(b, " \\\n  -b %s", shellQuote(cookieHeader(sp.Cookies)))
+		}
+		if sp.Body != "" {
+			fmt.Fprintf(b, " \\\n  --data-raw %s", shellQuote(sp.Body))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// fullURL returns the URL with the query string.
+func (sp *StepPlan) fullURL() string {
+	return sp.URL + encodeQuery(sp.Query, func(s string) string {
+		return keepPlaceholders(s, url.QueryEscape)
+	})
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
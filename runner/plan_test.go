@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const planDocument = documentHeader + `
+workflows:
+  - workflowId: login
+    steps:
+      - stepId: login
+        operationId: login
+        requestBody:
+          payload:
+            user: $inputs.user
+            password: $inputs.password
+        outputs:
+          token: $response.body#/token
+    outputs:
+      token: $steps.login.outputs.token
+  - workflowId: find-pet
+    dependsOn: [login]
+    steps:
+      - stepId: list
+        operationId: listPets
+        parameters:
+          - name: Authorization
+            in: header
+            value: Bearer {$workflows.login.outputs.token}
+          - name: status
+            in: query
+            value: [available, sold]
+          - name: session
+            in: cookie
+            value: $inputs.session
+        outputs:
+          petId: $response.body#/0/id
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - name: petId
+            in: path
+            value: $steps.list.outputs.petId
+`
+
+func planFor(t *testing.T) *Plan {
+	t.Helper()
+	r := newRunner(t, nil, planDocument)
+	r.BaseURLs = map[string]string{"pets": "https://api.example.com/v1"}
+	plan, err := r.Plan(map[string]map[string]any{
+		"login":    {"user": "ann"},
+		"find-pet": {"session": "s 1"},
+	}, "find-pet")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	return plan
+}
+
+func TestPlan(t *testing.T) {
+	plan := planFor(t)
+	if len(plan.Workflows) != 2 {
+		t.Fatalf("planned %d workflows, want login and find-pet", len(plan.Workflows))
+	}
+
+	login := plan.Workflows[0].Steps[0]
+	if login.Method != "POST" || login.URL != "https://api.example.com/v1/login" || login.OperationId != "login" {
+		t.Errorf("login = %s %s (%s)", login.Method, login.URL, login.OperationId)
+	}
+	if want := `{"password":"{$inputs.password}","user":"ann"}`; login.Body != want {
+		t.Errorf("login body = %s, want %s", login.Body, want)
+	}
+
+	list := plan.Workflows[1].Steps[0]
+	if got := list.fullURL(); got != "https://api.example.com/v1/pets?status=available&status=sold" {
+		t.Errorf("list URL = %s", got)
+	}
+	if len(list.Header) != 1 || list.Header[0].Value != "Bearer {$workflows.login.outputs.token}" {
+		t.Errorf("list header = %v", list.Header)
+	}
+	if len(list.Cookies) != 1 || list.Cookies[0] != (Param{"session", "s 1"}) {
+		t.Errorf("list cookies = %v", list.Cookies)
+	}
+
+	get := plan.Workflows[1].Steps[1]
+	if get.URL != "https://api.example.com/v1/pets/{$steps.list.outputs.petId}" {
+		t.Errorf("get URL = %s", get.URL)
+	}
+}
+
+func TestPlanFormats(t *testing.T) {
+	plan := planFor(t)
+
+	var text bytes.Buffer
+	if err := plan.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"workflow find-pet\n  depends on login\n",
+		"  step list (listPets)\n    GET https://api.example.com/v1/pets?status=available&status=sold\n    Authorization: Bearer {$workflows.login.outputs.token}\n    Cookie: session=\"s 1\"\n",
+		"    Content-Type: application/json\n\n    {\"password\":",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text plan missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var data bytes.Buffer
+	if err := plan.WriteJSON(&data); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Plan
+	if err := json.Unmarshal(data.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON plan does not decode: %v\n%s", err, data.String())
+	}
+	if got := decoded.Workflows[1].Steps[1].URL; got != "https://api.example.com/v1/pets/{$steps.list.outputs.petId}" {
+		t.Errorf("decoded URL = %s", got)
+	}
+
+	var curl bytes.Buffer
+	if err := plan.WriteCurl(&curl); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+# workflow find-pet, step list
+curl -g -X GET 'https://api.example.com/v1/pets?status=available&status=sold' \
+  -H 'Authorization: Bearer {$workflows.login.outputs.token}' \
+  -b 'session="s 1"'
+`
+	if !strings.Contains(curl.String(), want) {
+		t.Errorf("curl plan missing %q:\n%s", want, curl.String())
+	}
+}
+
+func TestPlanWorkflowStep(t *testing.T) {
+	r := newRunner(t, nil, documentHeader+`
+workflows:
+  - workflowId: get
+    steps:
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - name: petId
+            in: path
+            value: $inputs.id
+  - workflowId: main
+    steps:
+      - stepId: call
+        workflowId: get
+        parameters:
+          - name: id
+            value: a/b
+`)
+	plan, err := r.Plan(nil, "main")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	sub := plan.Workflows[0].Steps[0].Workflow
+	if sub == nil || sub.WorkflowId != "get" {
+		t.Fatalf("workflow step plan = %+v", plan.Workflows[0].Steps[0])
+	}
+	if got := sub.Steps[0].URL; got != "https://pets.example.com/pets/a%2Fb" {
+		t.Errorf("URL = %s, want the path parameter escaped", got)
+	}
+}
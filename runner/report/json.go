@@ -0,0 +1,288 @@
+package report
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/runner"
+)
+
+// Redacted replaces the values of redacted headers.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactHeaders are the headers redacted when Options.RedactHeaders
+// is nil.
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Options configures the JSON report.
+type Options struct {
+	// RedactHeaders lists the request and response headers, in any case,
+	// whose values are replaced with Redacted. DefaultRedactHeaders is used
+	// when nil; an empty list redacts nothing.
+	RedactHeaders []string
+}
+
+// Report is the JSON report of a run.
+type Report struct {
+	Passed    int               `json:"passed"`
+	Failed    int               `json:"failed"`
+	Cancelled int               `json:"cancelled"`
+	Workflows []*WorkflowReport `json:"workflows"`
+}
+
+// WorkflowReport is the report of a workflow run.
+type WorkflowReport struct {
+	WorkflowId string         `json:"workflowId"`
+	Status     runner.Status  `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	Start      time.Time      `json:"start"`
+	Duration   float64        `json:"duration"`
+	Inputs     map[string]any `json:"inputs,omitempty"`
+	Outputs    []*Value       `json:"outputs,omitempty"`
+	Steps      []*StepReport  `json:"steps"`
+	Resumed    bool           `json:"resumed,omitempty"`
+}
+
+// StepReport is the report of a step run.
+type StepReport struct {
+	StepId      string          `json:"stepId"`
+	OperationId string          `json:"operationId,omitempty"`
+	Status      runner.Status   `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Start       time.Time       `json:"start"`
+	Duration    float64         `json:"duration"`
+	Attempts    int             `json:"attempts"`
+	Exchanges   []*Exchange     `json:"exchanges,omitempty"`
+	Criteria    []*Criterion    `json:"criteria,omitempty"`
+	Outputs     []*Value        `json:"outputs,omitempty"`
+	Workflow    *WorkflowReport `json:"workflow,omitempty"`
+	Resumed     bool            `json:"resumed,omitempty"`
+}
+
+// Exchange is a request of a step attempt and its response.
+type Exchange struct {
+	Request  *Message     `json:"request"`
+	Response *Message     `json:"response,omitempty"`
+	Criteria []*Criterion `json:"criteria,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Start    time.Time    `json:"start"`
+	Duration float64      `json:"duration"`
+}
+
+// Message is a request or response. Bodies that are JSON are included as
+// JSON, others as strings.
+type Message struct {
+	Method     string              `json:"method,omitempty"`
+	URL        string              `json:"url,omitempty"`
+	StatusCode int                 `json:"statusCode,omitempty"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       any                 `json:"body,omitempty"`
+}
+
+// Criterion is an evaluated criterion.
+type Criterion struct {
+	Condition string `json:"condition"`
+	Context   string `json:"context,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Passed    bool   `json:"passed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Value is an output with the runtime expression it was evaluated from.
+type Value struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression,omitempty"`
+	Value      any    `json:"value"`
+}
+
+// JSON renders a result as an indented JSON Report. doc is the document the
+// result was run from, which gives the expressions of outputs; it may be
+// nil. opts may be nil.
+func JSON(doc *arazzo1.Arazzo, result *runner.Result, opts *Options) ([]byte, error) {
+	data, err := json.MarshalIndent(NewReport(doc, result, opts), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// NewReport returns the Report of a result. doc and opts may be nil.
+func NewReport(doc *arazzo1.Arazzo, result *runner.Result, opts *Options) *Report {
+	redact := DefaultRedactHeaders
+	if opts != nil && opts.RedactHeaders != nil {
+		redact = opts.RedactHeaders
+	}
+	b := &builder{doc: doc, redact: make(map[string]bool, len(redact))}
+	for _, h := range redact {
+		b.redact[http.CanonicalHeaderKey(h)] = true
+	}
+
+	report := &Report{Workflows: []*WorkflowReport{}}
+	for _, res := range result.Workflows {
+		switch res.Status {
+		case runner.StatusPassed:
+			report.Passed++
+		case runner.StatusFailed:
+			report.Failed++
+		case runner.StatusCancelled:
+			report.Cancelled++
+		}
+		report.Workflows = append(report.Workflows, b.workflow(res))
+	}
+	return report
+}
+
+type builder struct {
+	doc    *arazzo1.Arazzo
+	redact map[string]bool
+}
+
+func (b *builder) workflow(res *runner.WorkflowResult) *WorkflowReport {
+	var wf *arazzo1.Workflow
+	if b.doc != nil {
+		for _, w := range b.doc.Workflows {
+			if w != nil && w.WorkflowId == res.WorkflowId {
+				wf = w
+			}
+		}
+	}
+	wr := &WorkflowReport{
+		WorkflowId: res.WorkflowId,
+		Status:     res.Status,
+		Error:      message(res.Err),
+		Start:      res.Start,
+		Duration:   res.Duration.Seconds(),
+		Inputs:     res.Inputs,
+		Steps:      []*StepReport{},
+		Resumed:    res.Resumed,
+	}
+	if wf != nil {
+		wr.Outputs = values(wf.Outputs, res.Outputs)
+	} else {
+		wr.Outputs = values(nil, res.Outputs)
+	}
+	for _, sr := range res.Steps {
+		var step *arazzo1.Step
+		if wf != nil {
+			for _, s := range wf.Steps {
+				if s != nil && s.StepId == sr.StepId {
+					step = s
+				}
+			}
+		}
+		wr.Steps = append(wr.Steps, b.step(step, sr))
+	}
+	return wr
+}
+
+func (b *builder) step(step *arazzo1.Step, sr *runner.StepResult) *StepReport {
+	rep := &StepReport{
+		StepId:      sr.StepId,
+		OperationId: sr.OperationId,
+		Status:      sr.Status,
+		Error:       message(sr.Err),
+		Start:       sr.Start,
+		Duration:    sr.Duration.Seconds(),
+		Attempts:    sr.Attempts,
+		Criteria:    criteria(sr.Criteria),
+		Resumed:     sr.Resumed,
+	}
+	var expressions map[string]string
+	if step != nil {
+		expressions = step.Outputs
+	}
+	rep.Outputs = values(expressions, sr.Outputs)
+	for _, ex := range sr.Exchanges {
+		e := &Exchange{
+			Request:  b.request(ex.Request),
+			Criteria: criteria(ex.Criteria),
+			Error:    message(ex.Err),
+			Start:    ex.Start,
+			Duration: ex.Duration.Seconds(),
+		}
+		if ex.Response != nil {
+			e.Response = &Message{StatusCode: ex.Response.StatusCode, Header: b.header(ex.Response.Header), Body: body(ex.Response.Body)}
+		}
+		rep.Exchanges = append(rep.Exchanges, e)
+	}
+	if sr.Workflow != nil {
+		rep.Workflow = b.workflow(sr.Workflow)
+	}
+	return rep
+}
+
+func (b *builder) request(req *runner.Request) *Message {
+	return &Message{Method: req.Method, URL: req.URL, Header: b.header(req.Header), Body: body(req.Body)}
+}
+
+// header returns a copy of h with the redacted headers replaced.
+func (b *builder) header(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(h))
+	for name, values := range h {
+		if b.redact[http.CanonicalHeaderKey(name)] {
+			out[name] = []string{Redacted}
+			continue
+		}
+		out[name] = slices.Clone(values)
+	}
+	return out
+}
+
+// body returns a body as JSON when it is JSON, and as a string otherwise.
+func body(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	if json.Valid(data) {
+		return json.RawMessage(data)
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return data
+}
+
+func criteria(results []*runner.CriterionResult) []*Criterion {
+	var out []*Criterion
+	for _, c := range results {
+		cr := &Criterion{
+			Condition: c.Criterion.Condition,
+			Context:   c.Criterion.Context,
+			Passed:    c.Passed,
+			Error:     message(c.Err),
+		}
+		switch {
+		case c.Criterion.ExpressionType != nil:
+			cr.Type = string(c.Criterion.ExpressionType.Type)
+		default:
+			cr.Type = string(c.Criterion.Type)
+		}
+		out = append(out, cr)
+	}
+	return out
+}
+
+// values returns outputs in name order, with their expressions.
+func values(expressions map[string]string, outputs map[string]any) []*Value {
+	var out []*Value
+	for _, name := range sortedKeys(outputs) {
+		out = append(out, &Value{Name: name, Expression: expressions[name], Value: outputs[name]})
+	}
+	return out
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/genelet/arazzo/runner"
+)
+
+func TestJSON(t *testing.T) {
+	doc, result := run(t)
+	data, err := JSON(doc, result, nil)
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	for _, secret := range []string{"c2VjcmV0", "s3cr3t"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("report contains the secret %q:\n%s", secret, data)
+		}
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not valid JSON: %v\n%s", err, data)
+	}
+	if report.Passed != 2 || report.Failed != 1 || report.Cancelled != 1 || len(report.Workflows) != 4 {
+		t.Fatalf("report = %d passed, %d failed, %d cancelled, %d workflows",
+			report.Passed, report.Failed, report.Cancelled, len(report.Workflows))
+	}
+	byId := make(map[string]*WorkflowReport)
+	for _, wr := range report.Workflows {
+		byId[wr.WorkflowId] = wr
+	}
+
+	login := byId["login"]
+	if login.Inputs["user"] != "alice" || len(login.Outputs) != 1 ||
+		*login.Outputs[0] != (Value{Name: "token", Expression: "$steps.login.outputs.token", Value: "t0k3n"}) {
+		t.Errorf("login workflow = %+v", login)
+	}
+	step := login.Steps[0]
+	if step.OperationId != "login" || step.Status != runner.StatusPassed || len(step.Exchanges) != 1 ||
+		*step.Outputs[0] != (Value{Name: "token", Expression: "$response.body#/token", Value: "t0k3n"}) {
+		t.Fatalf("login step = %+v", step)
+	}
+	ex := step.Exchanges[0]
+	if ex.Request.Method != "POST" || ex.Request.Header["Authorization"][0] != Redacted ||
+		ex.Request.Header["X-Trace"][0] != "abc" {
+		t.Errorf("login request = %+v", ex.Request)
+	}
+	if body, _ := json.Marshal(ex.Request.Body); string(body) != `{"user":"alice"}` {
+		t.Errorf("login request body = %s", body)
+	}
+	if ex.Response.StatusCode != 200 || ex.Response.Header["Set-Cookie"][0] != Redacted {
+		t.Errorf("login response = %+v", ex.Response)
+	}
+	if len(ex.Criteria) != 1 || !ex.Criteria[0].Passed || ex.Criteria[0].Condition != "$statusCode == 200" {
+		t.Errorf("login criteria = %+v", ex.Criteria)
+	}
+
+	// Each attempt of a retried step is an exchange.
+	flaky := byId["flaky"].Steps[0]
+	if flaky.Attempts != 2 || len(flaky.Exchanges) != 2 ||
+		flaky.Exchanges[0].Response.StatusCode != 503 || flaky.Exchanges[0].Criteria[0].Passed ||
+		flaky.Exchanges[1].Response.StatusCode != 200 || !flaky.Exchanges[1].Criteria[0].Passed {
+		t.Errorf("flaky step = %+v", flaky)
+	}
+
+	fail := byId["broken"].Steps[0]
+	if fail.Status != runner.StatusFailed || fail.Error == "" || len(fail.Criteria) != 2 ||
+		fail.Criteria[1].Type != "jsonpath" || fail.Criteria[1].Context != "$response.body" || fail.Criteria[1].Passed {
+		t.Errorf("fail step = %+v", fail)
+	}
+	if after := byId["after"]; after.Status != runner.StatusCancelled || len(after.Steps) != 0 || after.Error == "" {
+		t.Errorf("after workflow = %+v", after)
+	}
+}
+
+func TestJSONRedactHeaders(t *testing.T) {
+	doc, result := run(t)
+	report := NewReport(doc, result, &Options{RedactHeaders: []string{"x-trace"}})
+	var login *WorkflowReport
+	for _, wr := range report.Workflows {
+		if wr.WorkflowId == "login" {
+			login = wr
+		}
+	}
+	header := login.Steps[0].Exchanges[0].Request.Header
+	if header["X-Trace"][0] != Redacted || header["Authorization"][0] != "Basic c2VjcmV0" {
+		t.Errorf("request header = %v", header)
+	}
+
+	report = NewReport(nil, result, &Options{RedactHeaders: []string{}})
+	for _, wr := range report.Workflows {
+		for _, v := range wr.Outputs {
+			if v.Expression != "" {
+				t.Errorf("output %s has an expression without a document", v.Name)
+			}
+		}
+	}
+}
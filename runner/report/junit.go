@@ -0,0 +1,188 @@
+// Package report renders the results of workflow runs for CI systems: as
+// JUnit XML, with workflows as test suites and steps as test cases, and as a
+// detailed JSON report of every request, response, criterion and output.
+package report
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/genelet/arazzo/runner"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr,omitempty"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitProblem `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit renders a result as JUnit XML. Each workflow is a test suite and
+// each step run a test case of it. A step whose success criteria were not
+// met is a failure, listing the criteria; a step that could not run, such
+// as when its request could not be sent, is an error. A workflow cancelled
+// because a dependency failed is a single skipped test case, and a workflow
+// that failed outside its steps has an additional test case with the error.
+func JUnit(result *runner.Result, name string) ([]byte, error) {
+	suites := junitSuites{Name: name}
+	var total time.Duration
+	for _, res := range result.Workflows {
+		suite := junitWorkflow(res)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+		total += res.Duration
+	}
+	suites.Time = seconds(total)
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+func junitWorkflow(res *runner.WorkflowResult) junitSuite {
+	suite := junitSuite{Name: res.WorkflowId, Time: seconds(res.Duration)}
+	if !res.Start.IsZero() {
+		suite.Timestamp = res.Start.UTC().Format("2006-01-02T15:04:05")
+	}
+	stepFailed := false
+	for _, sr := range res.Steps {
+		c := junitCase{Name: sr.StepId, Classname: res.WorkflowId, Time: seconds(sr.Duration)}
+		if sr.Status != runner.StatusPassed {
+			stepFailed = true
+			if failed := failedCriteria(sr); len(failed) > 0 || isStepError(sr.Err) {
+				c.Failure = &junitProblem{Message: failureMessage(sr, failed), Type: "criteria", Text: failureText(sr, failed)}
+			} else {
+				c.Error = &junitProblem{Message: message(sr.Err), Type: "error", Text: message(sr.Err)}
+			}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	switch {
+	case res.Status == runner.StatusCancelled && len(res.Steps) == 0:
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      res.WorkflowId,
+			Classname: res.WorkflowId,
+			Time:      seconds(0),
+			Skipped:   &junitProblem{Message: message(res.Err)},
+		})
+	case res.Err != nil && !stepFailed:
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      res.WorkflowId,
+			Classname: res.WorkflowId,
+			Time:      seconds(res.Duration),
+			Error:     &junitProblem{Message: message(res.Err), Type: "error", Text: message(res.Err)},
+		})
+	}
+
+	for _, c := range suite.Cases {
+		suite.Tests++
+		switch {
+		case c.Failure != nil:
+			suite.Failures++
+		case c.Error != nil:
+			suite.Errors++
+		case c.Skipped != nil:
+			suite.Skipped++
+		}
+	}
+	return suite
+}
+
+// failedCriteria returns the criteria of the last attempt of a step that
+// did not pass.
+func failedCriteria(sr *runner.StepResult) []*runner.CriterionResult {
+	var failed []*runner.CriterionResult
+	for _, c := range sr.Criteria {
+		if !c.Passed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+func failureMessage(sr *runner.StepResult, failed []*runner.CriterionResult) string {
+	if len(failed) == 0 {
+		return message(sr.Err)
+	}
+	conditions := make([]string, len(failed))
+	for i, c := range failed {
+		conditions[i] = c.Criterion.Condition
+	}
+	return "criteria not met: " + strings.Join(conditions, "; ")
+}
+
+func failureText(sr *runner.StepResult, failed []*runner.CriterionResult) string {
+	var b strings.Builder
+	if sr.Response != nil {
+		fmt.Fprintf(&b, "status code: %d\n", sr.Response.StatusCode)
+	}
+	fmt.Fprintf(&b, "attempts: %d\n", sr.Attempts)
+	for _, c := range failed {
+		fmt.Fprintf(&b, "criterion not met: %s", c.Criterion.Condition)
+		if c.Criterion.Context != "" {
+			fmt.Fprintf(&b, " (context %s)", c.Criterion.Context)
+		}
+		if c.Err != nil {
+			fmt.Fprintf(&b, ": %v", c.Err)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func isStepError(err error) bool {
+	var stepErr *runner.StepError
+	return errors.As(err, &stepErr)
+}
+
+func message(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// seconds formats a duration as JUnit times are: in seconds, with
+// milliseconds.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,211 @@
+package report
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/convert"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/arazzo/runner"
+	"github.com/genelet/oas/openapi31"
+)
+
+const testOpenAPI = `
+openapi: 3.1.0
+info: {title: Pets, version: 1.0.0}
+paths:
+  /login:
+    post:
+      operationId: login
+      requestBody:
+        content:
+          application/json: {}
+      responses: {"200": {description: ok}}
+  /flaky:
+    get:
+      operationId: flaky
+      responses: {"200": {description: ok}}
+  /fail:
+    get:
+      operationId: fail
+      responses: {"200": {description: ok}}
+  /pets:
+    get:
+      operationId: listPets
+      responses: {"200": {description: ok}}
+`
+
+const testArazzo = `
+arazzo: 1.0.0
+info: {title: Pets, version: 1.0.0}
+sourceDescriptions:
+  - {name: pets, url: pets.yaml, type: openapi}
+workflows:
+  - workflowId: login
+    steps:
+      - stepId: login
+        operationId: login
+        parameters:
+          - {name: Authorization, in: header, value: Basic c2VjcmV0}
+          - {name: X-Trace, in: header, value: abc}
+        requestBody:
+          payload: {user: $inputs.user}
+        successCriteria:
+          - condition: $statusCode == 200
+        outputs:
+          token: $response.body#/token
+    outputs:
+      token: $steps.login.outputs.token
+  - workflowId: flaky
+    steps:
+      - stepId: flaky
+        operationId: flaky
+        successCriteria:
+          - condition: $statusCode == 200
+        onFailure:
+          - {name: again, type: retry, retryLimit: 1}
+  - workflowId: broken
+    steps:
+      - stepId: fail
+        operationId: fail
+        successCriteria:
+          - condition: $statusCode == 200
+          - context: $response.body
+            condition: $.ok
+            type: jsonpath
+  - workflowId: after
+    dependsOn: [broken]
+    steps:
+      - stepId: list
+        operationId: listPets
+`
+
+// run runs the workflows of testArazzo against a test server.
+func run(t *testing.T) (*arazzo1.Arazzo, *runner.Result) {
+	t.Helper()
+	var mu sync.Mutex
+	var flaky int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+			w.Write([]byte(`{"token":"t0k3n"}`))
+		case "/flaky":
+			if flaky++; flaky == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error":"busy"}`))
+				return
+			}
+			w.Write([]byte(`{}`))
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"ok":false}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	var doc arazzo1.Arazzo
+	if err := convert.UnmarshalYAML([]byte(testArazzo), &doc); err != nil {
+		t.Fatal(err)
+	}
+	oa, err := oasutil.Parse([]byte(testOpenAPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := runner.New(&doc, map[string]*openapi31.OpenAPI{"pets": oa})
+	r.BaseURLs = map[string]string{"pets": srv.URL}
+	result, err := r.Run(context.Background(), map[string]map[string]any{"login": {"user": "alice"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return &doc, result
+}
+
+func TestJUnit(t *testing.T) {
+	_, result := run(t)
+	data, err := JUnit(result, "pets")
+	if err != nil {
+		t.Fatalf("JUnit failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header) {
+		t.Errorf("JUnit lacks the XML header:\n%s", data)
+	}
+
+	var suites junitSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("JUnit is not valid XML: %v\n%s", err, data)
+	}
+	if suites.Name != "pets" || suites.Tests != 4 || suites.Failures != 1 || suites.Errors != 0 || suites.Skipped != 1 {
+		t.Errorf("testsuites = %+v", suites)
+	}
+	bySuite := make(map[string]junitSuite)
+	for _, s := range suites.Suites {
+		bySuite[s.Name] = s
+	}
+	if len(bySuite) != 4 {
+		t.Fatalf("%d suites, want one for each workflow:\n%s", len(bySuite), data)
+	}
+
+	login := bySuite["login"]
+	if login.Tests != 1 || login.Failures != 0 || login.Timestamp == "" || login.Cases[0].Name != "login" ||
+		login.Cases[0].Classname != "login" || login.Cases[0].Failure != nil {
+		t.Errorf("login suite = %+v", login)
+	}
+	// A retried step that passed in the end is one passing case.
+	if flaky := bySuite["flaky"]; flaky.Tests != 1 || flaky.Failures != 0 {
+		t.Errorf("flaky suite = %+v", flaky)
+	}
+
+	broken := bySuite["broken"]
+	if broken.Tests != 1 || broken.Failures != 1 {
+		t.Fatalf("broken suite = %+v", broken)
+	}
+	failure := broken.Cases[0].Failure
+	if failure == nil {
+		t.Fatalf("fail case has no failure: %+v", broken.Cases[0])
+	}
+	if failure.Message != "criteria not met: $statusCode == 200; $.ok" || failure.Type != "criteria" {
+		t.Errorf("failure = %+v", failure)
+	}
+	for _, want := range []string{"status code: 500", "attempts: 1", "criterion not met: $.ok (context $response.body)"} {
+		if !strings.Contains(failure.Text, want) {
+			t.Errorf("failure text lacks %q:\n%s", want, failure.Text)
+		}
+	}
+
+	after := bySuite["after"]
+	if after.Tests != 1 || after.Skipped != 1 || after.Cases[0].Skipped == nil ||
+		!strings.Contains(after.Cases[0].Skipped.Message, "dependency broken failed") {
+		t.Errorf("after suite = %+v", after)
+	}
+}
+
+func TestJUnitWorkflowError(t *testing.T) {
+	result := &runner.Result{Workflows: []*runner.WorkflowResult{{
+		WorkflowId: "missing",
+		Status:     runner.StatusFailed,
+		Err:        &runner.StepError{Workflow: "missing", Step: "x"},
+	}}}
+	data, err := JUnit(result, "")
+	if err != nil {
+		t.Fatalf("JUnit failed: %v", err)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Tests != 1 || suites.Errors != 1 || suites.Suites[0].Cases[0].Error == nil {
+		t.Errorf("a workflow error without steps is not an error case:\n%s", data)
+	}
+}
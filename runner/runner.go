@@ -0,0 +1,583 @@
+// Package runner executes the workflows of an Arazzo document against live
+// APIs, resolving each step's operation against the OpenAPI source
+// descriptions.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+// Runner runs the workflows of an Arazzo document. It is safe for
+// concurrent use once configured.
+type Runner struct {
+	// HTTPClient sends the requests. http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+
+	// BaseURLs maps source description names to base URLs. Sources that
+	// are not listed use the first server of their OpenAPI document.
+	BaseURLs map[string]string
+
+	// Workers limits how many independent workflows Run executes at once.
+	// Zero means runtime.GOMAXPROCS(0).
+	Workers int
+
+	// Hooks are called around the workflows and steps run.
+	Hooks []Hook
+
+	// SaveCheckpoint, when set, is called with the state of Run after every
+	// step of the workflows it runs, before every retry, and when a
+	// workflow completes, so that the run can be resumed with Resume. The
+	// calls are serialized, and cp is modified after the call returns. An
+	// error fails the workflow. See CheckpointFile.
+	SaveCheckpoint func(cp *Checkpoint) error
+
+	doc      *arazzo1.Arazzo
+	resolver *oasutil.Resolver
+}
+
+// New returns a Runner for the workflows of doc. sources maps source
+// description names to their parsed OpenAPI documents.
+func New(doc *arazzo1.Arazzo, sources map[string]*openapi31.OpenAPI) *Runner {
+	return &Runner{doc: doc, resolver: oasutil.NewResolver(sources)}
+}
+
+// Status is the outcome of a workflow or step.
+type Status string
+
+const (
+	// StatusPassed means every step met its success criteria.
+	StatusPassed Status = "passed"
+	// StatusFailed means a step failed, or a request could not be sent.
+	StatusFailed Status = "failed"
+	// StatusCancelled means the workflow did not run, or was interrupted,
+	// because a dependency failed or the context was done.
+	StatusCancelled Status = "cancelled"
+)
+
+// WorkflowResult is the outcome of a workflow run.
+type WorkflowResult struct {
+	WorkflowId string
+	Status     Status
+	Inputs     map[string]any
+	Outputs    map[string]any
+	// Steps lists the step runs in execution order; a step appears again
+	// when a goto action returns to it.
+	Steps []*StepResult
+	// Err reports why the workflow failed or was cancelled.
+	Err      error
+	Start    time.Time
+	Duration time.Duration
+	// Resumed means the workflow passed before the run was resumed, and
+	// its result was restored from the checkpoint.
+	Resumed bool
+}
+
+// StepResult is the outcome of a step run, including its retries.
+type StepResult struct {
+	StepId string
+	// OperationId is the operationId of the operation of an operation step,
+	// also when the step refers to it by operationPath.
+	OperationId string
+	Status      Status
+	// Request and Response are the last HTTP exchange of an operation
+	// step. Response is nil when the request could not be sent.
+	Request  *Request
+	Response *Response
+	// Exchanges lists the HTTP exchanges of all attempts of an operation
+	// step, retries included.
+	Exchanges []*Exchange
+	// Workflow is the run of the workflow a workflow step calls.
+	Workflow *WorkflowResult
+	// Criteria are the success criteria of the last attempt.
+	Criteria []*CriterionResult
+	Outputs  map[string]any
+	// Attempts counts the requests sent, or workflow calls made,
+	// including retries.
+	Attempts int
+	Err      error
+	Start    time.Time
+	Duration time.Duration
+	// Resumed means the step completed before the run was resumed, and
+	// its result was restored from the checkpoint.
+	Resumed bool
+}
+
+// Exchange is a request an attempt of a step sent, and its response.
+type Exchange struct {
+	Request *Request
+	// Response is nil when the request could not be sent.
+	Response *Response
+	// Criteria are the success criteria evaluated against the response.
+	Criteria []*CriterionResult
+	// Err reports why the request could not be sent.
+	Err      error
+	Start    time.Time
+	Duration time.Duration
+}
+
+// CriterionResult is the evaluation of a criterion.
+type CriterionResult struct {
+	Criterion *arazzo1.Criterion
+	Passed    bool
+	// Err reports a criterion that could not be evaluated, which does not
+	// pass.
+	Err error
+}
+
+// StepError reports a step whose success criteria were not met and that
+// no failure action recovered.
+type StepError struct {
+	Workflow   string
+	Step       string
+	StatusCode int
+}
+
+func (e *StepError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("workflow %s: step %s failed", e.Workflow, e.Step)
+	}
+	return fmt.Sprintf("workflow %s: step %s failed with status %d", e.Workflow, e.Step, e.StatusCode)
+}
+
+// RunWorkflow runs a workflow with the given inputs, after the workflows it
+// depends on, which run without inputs. The error is that of the
+// workflow's result when it did not pass.
+func (r *Runner) RunWorkflow(ctx context.Context, workflowId string, inputs map[string]any) (*WorkflowResult, error) {
+	result, err := r.Run(ctx, map[string]map[string]any{workflowId: inputs}, workflowId)
+	if err != nil {
+		return nil, err
+	}
+	res := result.Workflow(workflowId)
+	return res, res.Err
+}
+
+// execution is the state of one call of Run, shared by the workflows it
+// runs.
+type execution struct {
+	r *Runner
+	// plan evaluates steps without sending requests, see Runner.Plan.
+	plan bool
+	// ckpt saves the checkpoints of the run, and holds the one it resumes.
+	ckpt *checkpointer
+
+	mu sync.Mutex
+	// workflows holds the completed workflows, which $workflows
+	// expressions refer to.
+	workflows map[string]*WorkflowResult
+}
+
+func (e *execution) workflow(id string) *WorkflowResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.workflows[id]
+}
+
+func (e *execution) completed(res *WorkflowResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.workflows[res.WorkflowId] = res
+}
+
+// transfer is where a step hands control to after it ran.
+type transfer struct {
+	// end ends the workflow.
+	end bool
+	// step continues the workflow at another step.
+	step string
+	// workflow runs another workflow in place of the rest of this one.
+	workflow string
+}
+
+// runWorkflow runs wf. stack lists the workflows being called, to reject
+// recursion through workflow steps; it is empty for the workflows Run
+// schedules, which are the ones checkpoints record.
+func (e *execution) runWorkflow(ctx context.Context, wf *arazzo1.Workflow, inputs map[string]any, stack []string) *WorkflowResult {
+	top := len(stack) == 0
+	if saved := e.ckpt.resumed(wf.WorkflowId); top && saved != nil && saved.Status == StatusPassed {
+		res := restoreWorkflow(saved, inputs)
+		e.completed(res)
+		return res
+	}
+	res := &WorkflowResult{WorkflowId: wf.WorkflowId, Inputs: inputs, Start: time.Now()}
+	ctx, err := e.r.beforeWorkflow(ctx, wf, res)
+	if err != nil {
+		err = fmt.Errorf("workflow %s: %w", wf.WorkflowId, err)
+	} else {
+		err = e.runSteps(ctx, wf, res, slices.Concat(stack, []string{wf.WorkflowId}))
+	}
+	res.Duration = time.Since(res.Start)
+	switch {
+	case err == nil:
+		res.Status = StatusPassed
+	case ctx.Err() != nil:
+		res.Status, res.Err = StatusCancelled, err
+	default:
+		res.Status, res.Err = StatusFailed, err
+	}
+	if top {
+		if err := e.ckpt.completed(res); err != nil && res.Status == StatusPassed {
+			res.Status, res.Err = StatusFailed, fmt.Errorf("workflow %s: checkpoint: %w", wf.WorkflowId, err)
+		}
+	}
+	e.completed(res)
+	e.r.afterWorkflow(ctx, wf, res)
+	return res
+}
+
+func (e *execution) runSteps(ctx context.Context, wf *arazzo1.Workflow, res *WorkflowResult, stack []string) error {
+	for _, id := range stack[:len(stack)-1] {
+		if id == wf.WorkflowId {
+			return fmt.Errorf("workflow %s calls itself", wf.WorkflowId)
+		}
+	}
+	index := make(map[string]int, len(wf.Steps))
+	for i, step := range wf.Steps {
+		index[step.StepId] = i
+	}
+	sc := &scope{e: e, wf: wf, inputs: res.Inputs, steps: map[string]map[string]any{}}
+	top := len(stack) == 1
+
+	i := 0
+	// gotoWorkflow is the workflow a goto action of the step from runs in
+	// place of the rest of this one.
+	var gotoWorkflow, from string
+	// retrying is the step being retried when the run was interrupted.
+	var retrying *StepCheckpoint
+	if saved := e.ckpt.resumed(wf.WorkflowId); top && saved != nil {
+		for _, s := range saved.Steps {
+			res.Steps = append(res.Steps, restoreStep(s))
+			sc.steps[s.StepId] = s.Outputs
+			from = s.StepId
+		}
+		switch {
+		case saved.NextWorkflow != "":
+			i, gotoWorkflow = len(wf.Steps), saved.NextWorkflow
+		case saved.Next != "":
+			j, ok := index[saved.Next]
+			if !ok {
+				return fmt.Errorf("workflow %s: checkpoint continues at unknown step %q", wf.WorkflowId, saved.Next)
+			}
+			i, retrying = j, saved.Retrying
+		case len(saved.Steps) > 0:
+			i = len(wf.Steps)
+		}
+	}
+
+	for i < len(wf.Steps) {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("workflow %s: %w", wf.WorkflowId, err)
+		}
+		step := wf.Steps[i]
+		var retried *StepCheckpoint
+		if retrying != nil && retrying.StepId == step.StepId {
+			retried = retrying
+		}
+		retrying = nil
+		sr, next, err := e.runStep(ctx, wf, step, sc, stack, retried)
+		res.Steps = append(res.Steps, sr)
+		if err != nil {
+			return err
+		}
+		sc.steps[step.StepId] = sr.Outputs
+		switch {
+		case next.end:
+			i = len(wf.Steps)
+		case next.step != "":
+			j, ok := index[next.step]
+			if !ok {
+				return fmt.Errorf("workflow %s: step %s: goto unknown step %q", wf.WorkflowId, step.StepId, next.step)
+			}
+			i = j
+		case next.workflow != "":
+			i, gotoWorkflow, from = len(wf.Steps), next.workflow, step.StepId
+		default:
+			i++
+		}
+		if top {
+			var nextStep string
+			if i < len(wf.Steps) {
+				nextStep = wf.Steps[i].StepId
+			}
+			if err := e.ckpt.stepCompleted(wf.WorkflowId, sr, nextStep, gotoWorkflow); err != nil {
+				return fmt.Errorf("workflow %s: step %s: checkpoint: %w", wf.WorkflowId, step.StepId, err)
+			}
+		}
+	}
+
+	if gotoWorkflow != "" {
+		target := e.r.findWorkflow(gotoWorkflow)
+		if target == nil {
+			return fmt.Errorf("workflow %s: step %s: goto unknown workflow %q", wf.WorkflowId, from, gotoWorkflow)
+		}
+		if sub := e.runWorkflow(ctx, target, nil, stack); sub.Err != nil {
+			return fmt.Errorf("workflow %s: step %s: %w", wf.WorkflowId, from, sub.Err)
+		}
+	}
+
+	outputs, err := sc.values(wf.Outputs)
+	if err != nil {
+		return fmt.Errorf("workflow %s: outputs: %w", wf.WorkflowId, err)
+	}
+	res.Outputs = outputs
+	return nil
+}
+
+// runStep runs a step until it succeeds or a failure action gives up, and
+// returns where the workflow continues. A step that fails yet hands control
+// to a goto action returns no error. retried, when not nil, holds the
+// attempts and retries of the step from the checkpoint resumed.
+func (e *execution) runStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sc *scope, stack []string, retried *StepCheckpoint) (*StepResult, transfer, error) {
+	sr := &StepResult{StepId: step.StepId, Start: time.Now()}
+	// actx is the context of the last attempt.
+	actx := ctx
+	defer func() {
+		sr.Duration = time.Since(sr.Start)
+		e.r.afterStep(actx, wf, step, sr)
+	}()
+	prefix := fmt.Sprintf("workflow %s: step %s", wf.WorkflowId, step.StepId)
+	fail := func(err error) (*StepResult, transfer, error) {
+		sr.Status, sr.Err = StatusFailed, err
+		return sr, transfer{}, err
+	}
+
+	success, failure, err := e.r.stepActions(wf, step)
+	if err != nil {
+		return fail(fmt.Errorf("%s: %w", prefix, err))
+	}
+	retries := make(map[*arazzo1.FailureAction]int)
+	if retried != nil {
+		sr.Attempts = retried.Attempts
+		for _, action := range failure {
+			if n, ok := retried.Retries[action.key]; ok {
+				retries[action.FailureAction] = n
+			}
+		}
+	}
+	for {
+		sr.Attempts++
+		var ssc *scope
+		actx, ssc, err = e.attempt(ctx, wf, step, sc, sr, stack)
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", prefix, err))
+		}
+
+		sr.Criteria = ssc.criteria(step.SuccessCriteria)
+		if n := len(sr.Exchanges); n > 0 && sr.Exchanges[n-1].Response == sr.Response {
+			sr.Exchanges[n-1].Criteria = sr.Criteria
+		}
+		passed := sr.Workflow == nil || sr.Workflow.Status == StatusPassed
+		for _, c := range sr.Criteria {
+			passed = passed && c.Passed
+		}
+		if len(step.SuccessCriteria) == 0 && sr.Response != nil {
+			passed = sr.Response.StatusCode >= 200 && sr.Response.StatusCode < 300
+		}
+
+		if passed {
+			outputs, err := ssc.values(step.Outputs)
+			if err != nil {
+				return fail(fmt.Errorf("%s: outputs: %w", prefix, err))
+			}
+			sr.Status, sr.Outputs = StatusPassed, outputs
+			for _, action := range success {
+				if !ssc.met(action.Criteria) {
+					continue
+				}
+				switch action.Type {
+				case arazzo1.SuccessActionTypeEnd:
+					return sr, transfer{end: true}, nil
+				case arazzo1.SuccessActionTypeGoto:
+					return sr, transfer{step: action.StepId, workflow: action.WorkflowId}, nil
+				}
+			}
+			return sr, transfer{}, nil
+		}
+
+		var failed error = &StepError{Workflow: wf.WorkflowId, Step: step.StepId}
+		switch {
+		case sr.Response != nil:
+			failed.(*StepError).StatusCode = sr.Response.StatusCode
+		case sr.Workflow != nil && sr.Workflow.Err != nil:
+			failed = fmt.Errorf("%s: %w", prefix, sr.Workflow.Err)
+		}
+		retry := false
+	actions:
+		for _, action := range failure {
+			if !ssc.met(action.Criteria) {
+				continue
+			}
+			switch action.Type {
+			case arazzo1.FailureActionTypeRetry:
+				limit := 1
+				if action.RetryLimit != nil {
+					limit = *action.RetryLimit
+				}
+				if retries[action.FailureAction] >= limit {
+					continue
+				}
+				retries[action.FailureAction]++
+				e.r.onRetry(actx, wf, step, sr, action.FailureAction)
+				if len(stack) == 1 {
+					if err := e.ckpt.retrying(wf.WorkflowId, sr, failure, retries); err != nil {
+						return fail(fmt.Errorf("%s: checkpoint: %w", prefix, err))
+					}
+				}
+				if action.RetryAfter != nil && *action.RetryAfter > 0 {
+					if err := sleep(ctx, *action.RetryAfter); err != nil {
+						return fail(fmt.Errorf("%s: %w", prefix, err))
+					}
+				}
+				retry = true
+				break actions
+			case arazzo1.FailureActionTypeGoto:
+				sr.Status, sr.Err = StatusFailed, failed
+				return sr, transfer{step: action.StepId, workflow: action.WorkflowId}, nil
+			case arazzo1.FailureActionTypeEnd:
+				return fail(failed)
+			}
+		}
+		if !retry {
+			return fail(failed)
+		}
+	}
+}
+
+// attempt sends the request of an operation step, or calls the workflow of
+// a workflow step, once, and returns the context of the attempt and the
+// scope its criteria and outputs are evaluated in.
+func (e *execution) attempt(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sc *scope, sr *StepResult, stack []string) (context.Context, *scope, error) {
+	params, err := e.r.doc.StepParameters(wf, step)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if step.IsWorkflowStep() {
+		target := e.r.findWorkflow(step.WorkflowId)
+		if target == nil {
+			return ctx, nil, fmt.Errorf("unknown workflow %q", step.WorkflowId)
+		}
+		inputs := make(map[string]any, len(params))
+		for _, p := range params {
+			v, err := sc.value(p.Value)
+			if err != nil {
+				return ctx, nil, fmt.Errorf("parameter %q: %w", p.Name, err)
+			}
+			inputs[p.Name] = v
+		}
+		sr.Workflow = nil
+		if ctx, err = e.r.beforeStep(ctx, wf, step, sr); err != nil {
+			return ctx, nil, err
+		}
+		sr.Workflow = e.runWorkflow(ctx, target, inputs, stack)
+		return ctx, sc.with(nil, nil, sr.Workflow.Outputs), nil
+	}
+
+	op, err := e.r.resolver.ResolveStep(step)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if op.Operation != nil {
+		sr.OperationId = op.Operation.OperationID
+	}
+	c, err := e.r.buildCall(sc, op, step, params)
+	if err != nil {
+		return ctx, nil, err
+	}
+	sr.Request, sr.Response = c.request(), nil
+	if ctx, err = e.r.beforeStep(ctx, wf, step, sr); err != nil {
+		return ctx, nil, err
+	}
+	ex := &Exchange{Request: sr.Request, Start: time.Now()}
+	sr.Exchanges = append(sr.Exchanges, ex)
+	res, err := e.r.send(ctx, sr.Request)
+	ex.Duration = time.Since(ex.Start)
+	if err != nil {
+		ex.Err = err
+		return ctx, nil, err
+	}
+	sr.Response, ex.Response = res, res
+	// $request expressions see the request as sent, with the changes of
+	// hooks such as an injected traceparent.
+	rsc := sc.with(c.sent(sr.Request), res, nil)
+	rsc.req = sr.Request
+	return ctx, rsc, nil
+}
+
+// failureAction is a resolved failure action, with key identifying where the
+// step or workflow lists it, such as onFailure/0 or failureActions/1.
+type failureAction struct {
+	*arazzo1.FailureAction
+	key string
+}
+
+// stepActions resolves the success and failure actions that apply to a step:
+// its own actions followed by the workflow-level ones.
+func (r *Runner) stepActions(wf *arazzo1.Workflow, step *arazzo1.Step) ([]*arazzo1.SuccessAction, []failureAction, error) {
+	var success []*arazzo1.SuccessAction
+	for _, a := range append(append([]*arazzo1.SuccessActionOrReusable{}, step.OnSuccess...), wf.SuccessActions...) {
+		action, err := r.doc.ResolveSuccessAction(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		if action != nil {
+			success = append(success, action)
+		}
+	}
+	var failure []failureAction
+	for i, a := range append(append([]*arazzo1.FailureActionOrReusable{}, step.OnFailure...), wf.FailureActions...) {
+		action, err := r.doc.ResolveFailureAction(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		if action == nil {
+			continue
+		}
+		key := fmt.Sprintf("onFailure/%d", i)
+		if i >= len(step.OnFailure) {
+			key = fmt.Sprintf("failureActions/%d", i-len(step.OnFailure))
+		}
+		failure = append(failure, failureAction{action, key})
+	}
+	return success, failure, nil
+}
+
+func (r *Runner) findWorkflow(id string) *arazzo1.Workflow {
+	for _, wf := range r.doc.Workflows {
+		if wf != nil && wf.WorkflowId == id {
+			return wf
+		}
+	}
+	return nil
+}
+
+// sleep waits for the given number of seconds or until ctx is done.
+func sleep(ctx context.Context, seconds float64) error {
+	timer := time.NewTimer(time.Duration(seconds * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// errorsOf joins the errors of the workflows that did not pass.
+func errorsOf(results []*WorkflowResult) error {
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,420 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/convert"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/oas/openapi31"
+)
+
+const petsOpenAPI = `
+openapi: 3.1.0
+info:
+  title: Pets
+  version: 1.0.0
+servers:
+  - url: https://pets.example.com
+paths:
+  /login:
+    post:
+      operationId: login
+      requestBody:
+        content:
+          application/json: {}
+      responses:
+        "200":
+          description: ok
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200":
+          description: ok
+  /pets/{petId}:
+    get:
+      operationId: getPet
+      responses:
+        "200":
+          description: ok
+  /flaky:
+    get:
+      operationId: flaky
+      responses:
+        "200":
+          description: ok
+  /fail:
+    get:
+      operationId: fail
+      responses:
+        "200":
+          description: ok
+  /slow:
+    get:
+      operationId: slow
+      responses:
+        "200":
+          description: ok
+`
+
+// petServer serves the pets API. /flaky fails until it has been called
+// flakyAfter times, and /slow records how many requests it serves at once.
+type petServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	flaky      int
+	flakyAfter int
+	active     int
+	maxActive  int
+	release    chan struct{}
+}
+
+func newPetServer(t *testing.T) *petServer {
+	t.Helper()
+	s := &petServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["user"] == nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token":"token-` + body["user"].(string) + `"}`))
+	})
+	mux.HandleFunc("GET /pets", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":7,"name":"rex","status":"` + r.URL.Query().Get("status") + `"}]`))
+	})
+	mux.HandleFunc("GET /pets/{petId}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":` + r.PathValue("petId") + `,"name":"rex"}`))
+	})
+	mux.HandleFunc("GET /flaky", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.flaky++
+		n := s.flaky
+		s.mu.Unlock()
+		if n <= s.flakyAfter {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.active++
+		s.maxActive = max(s.maxActive, s.active)
+		s.mu.Unlock()
+		if s.release != nil {
+			<-s.release
+		}
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newRunner parses an Arazzo document in YAML and returns a runner for it
+// against srv, or against the servers of the OpenAPI document when srv is
+// nil.
+func newRunner(t *testing.T, srv *petServer, arazzo string) *Runner {
+	t.Helper()
+	var doc arazzo1.Arazzo
+	if err := convert.UnmarshalYAML([]byte(arazzo), &doc); err != nil {
+		t.Fatalf("parsing arazzo document: %v", err)
+	}
+	oa, err := oasutil.Parse([]byte(petsOpenAPI))
+	if err != nil {
+		t.Fatalf("parsing openapi document: %v", err)
+	}
+	r := New(&doc, map[string]*openapi31.OpenAPI{"pets": oa})
+	if srv != nil {
+		r.BaseURLs = map[string]string{"pets": srv.URL}
+	}
+	return r
+}
+
+const documentHeader = `
+arazzo: 1.0.0
+info:
+  title: Pets
+  version: 1.0.0
+sourceDescriptions:
+  - name: pets
+    url: pets.yaml
+    type: openapi
+`
+
+func TestRunWorkflow(t *testing.T) {
+	srv := newPetServer(t)
+	r := newRunner(t, srv, documentHeader+`
+workflows:
+  - workflowId: find-pet
+    steps:
+      - stepId: login
+        operationId: login
+        requestBody:
+          payload:
+            user: placeholder
+          replacements:
+            - target: /user
+              value: $inputs.user
+        successCriteria:
+          - condition: $statusCode == 200
+        outputs:
+          token: $response.body#/token
+      - stepId: list
+        operationId: listPets
+        parameters:
+          - name: Authorization
+            in: header
+            value: Bearer {$steps.login.outputs.token}
+          - name: status
+            in: query
+            value: available
+        successCriteria:
+          - condition: $statusCode == 200
+          - context: $response.body#/0/name
+            condition: ^r
+            type: regex
+        outputs:
+          petId: $response.body#/0/id
+          status: $request.query.status
+      - stepId: get
+        operationPath: '{$sourceDescriptions.pets.url}#/paths/~1pets~1{petId}/get'
+        parameters:
+          - name: petId
+            in: path
+            value: $steps.list.outputs.petId
+        outputs:
+          name: $response.body#/name
+          url: $url
+    outputs:
+      name: $steps.get.outputs.name
+      status: $steps.list.outputs.status
+      url: $steps.get.outputs.url
+`)
+	res, err := r.RunWorkflow(context.Background(), "find-pet", map[string]any{"user": "ann"})
+	if err != nil {
+		t.Fatalf("RunWorkflow failed: %v", err)
+	}
+	if res.Status != StatusPassed {
+		t.Fatalf("status = %s, want passed", res.Status)
+	}
+	want := map[string]any{"name": "rex", "status": "available", "url": srv.URL + "/pets/7"}
+	for k, v := range want {
+		if res.Outputs[k] != v {
+			t.Errorf("output %s = %v, want %v", k, res.Outputs[k], v)
+		}
+	}
+	if len(res.Steps) != 3 {
+		t.Fatalf("ran %d steps, want 3", len(res.Steps))
+	}
+	login := res.Steps[0]
+	if got := string(login.Request.Body); got != `{"user":"ann"}` {
+		t.Errorf("login body = %s", got)
+	}
+	if got := login.Request.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("login content type = %q", got)
+	}
+	if got := res.Steps[1].Request.Header.Get("Authorization"); got != "Bearer token-ann" {
+		t.Errorf("Authorization = %q", got)
+	}
+	for _, c := range res.Steps[1].Criteria {
+		if !c.Passed {
+			t.Errorf("criterion %q did not pass: %v", c.Criterion.Condition, c.Err)
+		}
+	}
+}
+
+func TestRunFailureActions(t *testing.T) {
+	tests := []struct {
+		name       string
+		flakyAfter int
+		onFailure  string
+		status     Status
+		attempts   int
+		steps      []string
+	}{
+		{
+			name:       "retry until success",
+			flakyAfter: 2,
+			onFailure: `
+          - name: again
+            type: retry
+            retryLimit: 2`,
+			status:   StatusPassed,
+			attempts: 3,
+			steps:    []string{"call", "after"},
+		},
+		{
+			name:       "retry limit reached",
+			flakyAfter: 5,
+			onFailure: `
+          - name: again
+            type: retry
+            retryLimit: 2`,
+			status:   StatusFailed,
+			attempts: 3,
+			steps:    []string{"call"},
+		},
+		{
+			name:       "retry only when criteria are met",
+			flakyAfter: 1,
+			onFailure: `
+          - name: again
+            type: retry
+            criteria:
+              - condition: $statusCode == 429`,
+			status:   StatusFailed,
+			attempts: 1,
+			steps:    []string{"call"},
+		},
+		{
+			name:       "goto",
+			flakyAfter: 1,
+			onFailure: `
+          - name: recover
+            type: goto
+            stepId: after`,
+			status:   StatusPassed,
+			attempts: 1,
+			steps:    []string{"call", "after"},
+		},
+		{
+			name:       "end",
+			flakyAfter: 1,
+			onFailure: `
+          - name: stop
+            type: end`,
+			status:   StatusFailed,
+			attempts: 1,
+			steps:    []string{"call"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newPetServer(t)
+			srv.flakyAfter = tt.flakyAfter
+			r := newRunner(t, srv, documentHeader+`
+workflows:
+  - workflowId: flaky
+    steps:
+      - stepId: call
+        operationId: flaky
+        successCriteria:
+          - condition: $statusCode == 200
+        onFailure:`+tt.onFailure+`
+      - stepId: after
+        operationId: getPet
+        parameters:
+          - name: petId
+            in: path
+            value: 1
+`)
+			res, err := r.RunWorkflow(context.Background(), "flaky", nil)
+			if res.Status != tt.status {
+				t.Fatalf("status = %s, want %s (err %v)", res.Status, tt.status, err)
+			}
+			if (err != nil) != (tt.status != StatusPassed) {
+				t.Errorf("err = %v", err)
+			}
+			var stepErr *StepError
+			if err != nil && (!errors.As(err, &stepErr) || stepErr.StatusCode != http.StatusServiceUnavailable) {
+				t.Errorf("err = %v, want a StepError with status 503", err)
+			}
+			var steps []string
+			for _, sr := range res.Steps {
+				steps = append(steps, sr.StepId)
+			}
+			if strings.Join(steps, ",") != strings.Join(tt.steps, ",") {
+				t.Errorf("steps = %v, want %v", steps, tt.steps)
+			}
+			if got := res.Steps[0].Attempts; got != tt.attempts {
+				t.Errorf("attempts = %d, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRunWorkflowStep(t *testing.T) {
+	srv := newPetServer(t)
+	r := newRunner(t, srv, documentHeader+`
+workflows:
+  - workflowId: login
+    steps:
+      - stepId: login
+        operationId: login
+        requestBody:
+          payload:
+            user: $inputs.user
+        outputs:
+          token: $response.body#/token
+    outputs:
+      token: $steps.login.outputs.token
+  - workflowId: main
+    steps:
+      - stepId: auth
+        workflowId: login
+        parameters:
+          - name: user
+            value: bob
+        outputs:
+          token: $outputs.token
+    outputs:
+      token: $steps.auth.outputs.token
+`)
+	res, err := r.RunWorkflow(context.Background(), "main", nil)
+	if err != nil {
+		t.Fatalf("RunWorkflow failed: %v", err)
+	}
+	if got := res.Outputs["token"]; got != "token-bob" {
+		t.Errorf("token = %v, want token-bob", got)
+	}
+	if sub := res.Steps[0].Workflow; sub == nil || sub.Status != StatusPassed {
+		t.Errorf("workflow step result = %+v", sub)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a    any
+		op   string
+		b    any
+		want bool
+	}{
+		{float64(200), "==", float64(200), true},
+		{"200", "==", float64(200), true},
+		{float64(3), "<", float64(10), true},
+		{"3", "<", "10", false},
+		{"a", "!=", "b", true},
+		{nil, "==", nil, true},
+		{true, "==", true, true},
+		{map[string]any{"a": 1.0}, "==", map[string]any{"a": 1.0}, true},
+	}
+	for _, tt := range tests {
+		if got := compare(tt.a, tt.op, tt.b); got != tt.want {
+			t.Errorf("compare(%v %s %v) = %v, want %v", tt.a, tt.op, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/genelet/arazzo/arazzo1"
+)
+
+// Result is the outcome of a call of Run.
+type Result struct {
+	// Workflows lists the workflows run, or cancelled, in document order.
+	Workflows []*WorkflowResult
+}
+
+// Workflow returns the result of a workflow, or nil when it was not run.
+func (r *Result) Workflow(id string) *WorkflowResult {
+	for _, res := range r.Workflows {
+		if res.WorkflowId == id {
+			return res
+		}
+	}
+	return nil
+}
+
+// Err joins the errors of the workflows that did not pass.
+func (r *Result) Err() error {
+	return errorsOf(r.Workflows)
+}
+
+// DependencyError reports a workflow that was cancelled because a workflow
+// it depends on did not pass.
+type DependencyError struct {
+	WorkflowId string
+	Dependency string
+	// Err is the error of the dependency.
+	Err error
+}
+
+func (e *DependencyError) Error() string {
+	if _, ok := e.Err.(*DependencyError); ok {
+		return fmt.Sprintf("workflow %s cancelled: dependency %s was cancelled", e.WorkflowId, e.Dependency)
+	}
+	return fmt.Sprintf("workflow %s cancelled: dependency %s failed", e.WorkflowId, e.Dependency)
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+// Run runs the workflows with the given ids, all workflows when none are
+// given, together with the workflows they depend on. A workflow starts once
+// its dependencies have passed, so workflows that do not depend on each
+// other run concurrently, at most Workers at a time. When a workflow does not
+// pass, the workflows depending on it are cancelled.
+//
+// inputs maps workflow ids to their inputs. The error reports a schedule
+// that cannot run, such as a dependency cycle; the outcome of the workflows
+// is in the result.
+func (r *Runner) Run(ctx context.Context, inputs map[string]map[string]any, workflowIds ...string) (*Result, error) {
+	return r.run(ctx, inputs, workflowIds, nil)
+}
+
+// run runs workflows as Run does, resuming the checkpoint resume when not
+// nil.
+func (r *Runner) run(ctx context.Context, inputs map[string]map[string]any, workflowIds []string, resume *Checkpoint) (*Result, error) {
+	order, err := r.schedule(workflowIds)
+	if err != nil {
+		return nil, err
+	}
+	ckpt, err := newCheckpointer(r, inputs, workflowIds, resume)
+	if err != nil {
+		return nil, err
+	}
+	workers := r.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	position := make(map[string]int, len(order))
+	waiting := make(map[string]int, len(order))
+	dependents := make(map[string][]*arazzo1.Workflow)
+	var ready []*arazzo1.Workflow
+	for i, wf := range order {
+		position[wf.WorkflowId] = i
+		waiting[wf.WorkflowId] = len(wf.DependsOn)
+		for _, dep := range wf.DependsOn {
+			dependents[dep] = append(dependents[dep], wf)
+		}
+		if len(wf.DependsOn) == 0 {
+			ready = append(ready, wf)
+		}
+	}
+
+	e := &execution{r: r, ckpt: ckpt, workflows: make(map[string]*WorkflowResult)}
+	results := make(map[string]*WorkflowResult, len(order))
+	var finish func(res *WorkflowResult)
+	finish = func(res *WorkflowResult) {
+		results[res.WorkflowId] = res
+		for _, wf := range dependents[res.WorkflowId] {
+			id := wf.WorkflowId
+			if results[id] != nil {
+				continue
+			}
+			if res.Status != StatusPassed {
+				cancelled := &WorkflowResult{
+					WorkflowId: id,
+					Status:     StatusCancelled,
+					Inputs:     inputs[id],
+					Err:        &DependencyError{WorkflowId: id, Dependency: res.WorkflowId, Err: res.Err},
+				}
+				e.completed(cancelled)
+				finish(cancelled)
+				continue
+			}
+			if waiting[id]--; waiting[id] == 0 {
+				ready = append(ready, wf)
+			}
+		}
+	}
+
+	done := make(chan *WorkflowResult, len(order))
+	started := make(map[string]bool, len(order))
+	running := 0
+	// ctxDone is nil once ctx is done and stopped is set, when no more
+	// workflows start.
+	ctxDone, stopped := ctx.Done(), false
+	for len(results) < len(order) {
+		sort.Slice(ready, func(i, j int) bool {
+			return position[ready[i].WorkflowId] < position[ready[j].WorkflowId]
+		})
+		for !stopped && running < workers && len(ready) > 0 {
+			wf := ready[0]
+			ready = ready[1:]
+			running++
+			started[wf.WorkflowId] = true
+			go func() {
+				done <- e.runWorkflow(ctx, wf, inputs[wf.WorkflowId], nil)
+			}()
+		}
+		select {
+		case res := <-done:
+			running--
+			finish(res)
+		case <-ctxDone:
+			// The workflows that have not started are cancelled, and the
+			// running ones stop with ctx.
+			ctxDone, ready, stopped = nil, nil, true
+			for _, wf := range order {
+				id := wf.WorkflowId
+				if results[id] != nil || started[id] {
+					continue
+				}
+				cancelled := &WorkflowResult{
+					WorkflowId: id,
+					Status:     StatusCancelled,
+					Inputs:     inputs[id],
+					Err:        fmt.Errorf("workflow %s: %w", id, ctx.Err()),
+				}
+				e.completed(cancelled)
+				results[id] = cancelled
+			}
+		}
+	}
+
+	result := &Result{Workflows: make([]*WorkflowResult, len(order))}
+	for i, wf := range order {
+		result.Workflows[i] = results[wf.WorkflowId]
+	}
+	return result, nil
+}
+
+// schedule returns the workflows with the given ids and their dependencies,
+// transitively, in document order. It rejects duplicate and unknown
+// workflows and dependency cycles.
+func (r *Runner) schedule(workflowIds []string) ([]*arazzo1.Workflow, error) {
+	ids := make(map[string]bool, len(r.doc.Workflows))
+	for _, wf := range r.doc.Workflows {
+		if wf == nil {
+			continue
+		}
+		if ids[wf.WorkflowId] {
+			return nil, fmt.Errorf("duplicate workflow %q", wf.WorkflowId)
+		}
+		ids[wf.WorkflowId] = true
+	}
+	selected := make(map[string]bool)
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		for i, p := range path {
+			if p == id {
+				return fmt.Errorf("workflow dependency cycle: %s", strings.Join(slices.Concat(path[i:], []string{id}), " -> "))
+			}
+		}
+		wf := r.findWorkflow(id)
+		if wf == nil {
+			if len(path) == 0 {
+				return fmt.Errorf("unknown workflow %q", id)
+			}
+			return fmt.Errorf("workflow %s depends on unknown workflow %q", path[len(path)-1], id)
+		}
+		if selected[id] {
+			return nil
+		}
+		for _, dep := range wf.DependsOn {
+			if strings.HasPrefix(dep, "$") {
+				return fmt.Errorf("workflow %s depends on %s: workflows of other source descriptions cannot be run", id, dep)
+			}
+			if err := visit(dep, slices.Concat(path, []string{id})); err != nil {
+				return err
+			}
+		}
+		selected[id] = true
+		return nil
+	}
+
+	if len(workflowIds) == 0 {
+		for _, wf := range r.doc.Workflows {
+			if wf != nil {
+				workflowIds = append(workflowIds, wf.WorkflowId)
+			}
+		}
+	}
+	for _, id := range workflowIds {
+		if err := visit(id, nil); err != nil {
+			return nil, err
+		}
+	}
+	var order []*arazzo1.Workflow
+	for _, wf := range r.doc.Workflows {
+		if wf != nil && selected[wf.WorkflowId] {
+			order = append(order, wf)
+		}
+	}
+	return order, nil
+}
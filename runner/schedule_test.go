@@ -0,0 +1,274 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunDependsOn(t *testing.T) {
+	srv := newPetServer(t)
+	r := newRunner(t, srv, documentHeader+`
+workflows:
+  - workflowId: pets
+    dependsOn: [login]
+    steps:
+      - stepId: list
+        operationId: listPets
+        parameters:
+          - name: Authorization
+            in: header
+            value: Bearer {$workflows.login.outputs.token}
+        outputs:
+          name: $response.body#/0/name
+    outputs:
+      name: $steps.list.outputs.name
+  - workflowId: login
+    steps:
+      - stepId: login
+        operationId: login
+        requestBody:
+          payload:
+            user: $inputs.user
+        outputs:
+          token: $response.body#/token
+    outputs:
+      token: $steps.login.outputs.token
+  - workflowId: broken
+    steps:
+      - stepId: fail
+        operationId: fail
+  - workflowId: after-broken
+    dependsOn: [broken, login]
+    steps:
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - name: petId
+            in: path
+            value: 1
+  - workflowId: after-after
+    dependsOn: [after-broken]
+    steps:
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - name: petId
+            in: path
+            value: 2
+`)
+	result, err := r.Run(context.Background(), map[string]map[string]any{"login": {"user": "cy"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	var ids []string
+	for _, res := range result.Workflows {
+		ids = append(ids, res.WorkflowId)
+	}
+	if got := strings.Join(ids, ","); got != "pets,login,broken,after-broken,after-after" {
+		t.Errorf("workflows = %s, want document order", got)
+	}
+
+	pets := result.Workflow("pets")
+	if pets.Status != StatusPassed || pets.Outputs["name"] != "rex" {
+		t.Errorf("pets = %s %v (%v)", pets.Status, pets.Outputs, pets.Err)
+	}
+	if login := result.Workflow("login"); !pets.Start.After(login.Start.Add(login.Duration)) && !pets.Start.Equal(login.Start.Add(login.Duration)) {
+		t.Errorf("pets started before login completed")
+	}
+	if got := result.Workflow("broken").Status; got != StatusFailed {
+		t.Errorf("broken status = %s, want failed", got)
+	}
+
+	cancelled := result.Workflow("after-broken")
+	var depErr *DependencyError
+	if cancelled.Status != StatusCancelled || !errors.As(cancelled.Err, &depErr) || depErr.Dependency != "broken" {
+		t.Fatalf("after-broken = %s, %v", cancelled.Status, cancelled.Err)
+	}
+	if got := cancelled.Err.Error(); got != "workflow after-broken cancelled: dependency broken failed" {
+		t.Errorf("after-broken error = %q", got)
+	}
+	var stepErr *StepError
+	if !errors.As(cancelled.Err, &stepErr) || stepErr.Step != "fail" {
+		t.Errorf("after-broken error does not wrap the failed step: %v", cancelled.Err)
+	}
+	if got := result.Workflow("after-after").Err.Error(); got != "workflow after-after cancelled: dependency after-broken was cancelled" {
+		t.Errorf("after-after error = %q", got)
+	}
+	if len(cancelled.Steps) != 0 {
+		t.Errorf("cancelled workflow ran steps")
+	}
+	if result.Err() == nil {
+		t.Errorf("Err() = nil, want the failures")
+	}
+}
+
+func TestRunSelectsDependencies(t *testing.T) {
+	srv := newPetServer(t)
+	r := newRunner(t, srv, documentHeader+`
+workflows:
+  - workflowId: a
+    steps:
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - name: petId
+            in: path
+            value: 1
+  - workflowId: b
+    dependsOn: [a]
+    steps:
+      - stepId: get
+        operationId: getPet
+        parameters:
+          - name: petId
+            in: path
+            value: $workflows.a.inputs.id
+  - workflowId: c
+    steps:
+      - stepId: fail
+        operationId: fail
+`)
+	result, err := r.Run(context.Background(), map[string]map[string]any{"a": {"id": 9}}, "b")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(result.Workflows) != 2 || result.Workflow("c") != nil {
+		t.Fatalf("ran %d workflows, want a and b", len(result.Workflows))
+	}
+	if err := result.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if got := result.Workflow("b").Steps[0].Request.URL; got != srv.URL+"/pets/9" {
+		t.Errorf("b requested %s", got)
+	}
+}
+
+func TestRunWorkers(t *testing.T) {
+	srv := newPetServer(t)
+	srv.release = make(chan struct{})
+	var workflows strings.Builder
+	workflows.WriteString("workflows:\n")
+	for _, id := range []string{"w1", "w2", "w3", "w4", "w5"} {
+		workflows.WriteString(`
+  - workflowId: ` + id + `
+    steps:
+      - stepId: wait
+        operationId: slow
+`)
+	}
+	r := newRunner(t, srv, documentHeader+workflows.String())
+	r.Workers = 2
+
+	go func() {
+		for range 5 {
+			// Let the runner start as many workflows as it will
+			// before releasing one.
+			time.Sleep(20 * time.Millisecond)
+			srv.release <- struct{}{}
+		}
+	}()
+	result, err := r.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if err := result.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if srv.maxActive != 2 {
+		t.Errorf("ran %d workflows at once, want 2", srv.maxActive)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	srv := newPetServer(t)
+	srv.release = make(chan struct{})
+	r := newRunner(t, srv, documentHeader+`
+workflows:
+  - workflowId: w1
+    steps: [{stepId: wait, operationId: slow}]
+  - workflowId: w2
+    steps: [{stepId: wait, operationId: slow}]
+`)
+	r.Workers = 1
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := r.Run(ctx, nil)
+	close(srv.release)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, id := range []string{"w1", "w2"} {
+		res := result.Workflow(id)
+		if res.Status != StatusCancelled || !errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Errorf("%s = %s, %v", id, res.Status, res.Err)
+		}
+	}
+	if steps := result.Workflow("w2").Steps; len(steps) != 0 {
+		t.Errorf("w2 ran %d steps after the run was cancelled", len(steps))
+	}
+}
+
+func TestRunScheduleErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		workflows string
+		want      string
+	}{
+		{
+			name: "cycle",
+			workflows: `
+  - workflowId: a
+    dependsOn: [b]
+    steps: [{stepId: s, operationId: fail}]
+  - workflowId: b
+    dependsOn: [a]
+    steps: [{stepId: s, operationId: fail}]
+`,
+			want: "workflow dependency cycle: a -> b -> a",
+		},
+		{
+			name: "unknown dependency",
+			workflows: `
+  - workflowId: a
+    dependsOn: [missing]
+    steps: [{stepId: s, operationId: fail}]
+`,
+			want: `workflow a depends on unknown workflow "missing"`,
+		},
+		{
+			name: "external dependency",
+			workflows: `
+  - workflowId: a
+    dependsOn: [$sourceDescriptions.other.b]
+    steps: [{stepId: s, operationId: fail}]
+`,
+			want: "workflow a depends on $sourceDescriptions.other.b",
+		},
+		{
+			name: "duplicate workflow",
+			workflows: `
+  - workflowId: a
+    steps: [{stepId: s, operationId: fail}]
+  - workflowId: a
+    steps: [{stepId: s, operationId: listPets}]
+`,
+			want: `duplicate workflow "a"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRunner(t, newPetServer(t), documentHeader+"workflows:"+tt.workflows)
+			_, err := r.Run(context.Background(), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	r := newRunner(t, newPetServer(t), documentHeader+"workflows: [{workflowId: a, steps: [{stepId: s, operationId: fail}]}]")
+	if _, err := r.Run(context.Background(), nil, "nope"); err == nil || err.Error() != `unknown workflow "nope"` {
+		t.Errorf("err = %v", err)
+	}
+}
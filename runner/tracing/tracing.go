@@ -0,0 +1,226 @@
+// Package tracing instruments workflow runs with OpenTelemetry.
+//
+// A Hook added to runner.Runner.Hooks records a span for each workflow run,
+// a child span for each step, and a child span of the step for each attempt,
+// retries included. Workflows called by workflow steps are children of the
+// attempt that called them. The W3C trace context of the attempt is set on
+// its request, so backend traces join the trace of the run.
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/runner"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer.
+const ScopeName = "github.com/genelet/arazzo/runner/tracing"
+
+// Attribute keys set on the spans, in addition to the HTTP attributes of
+// the semantic conventions.
+const (
+	WorkflowIdKey     = attribute.Key("arazzo.workflow.id")
+	StepIdKey         = attribute.Key("arazzo.step.id")
+	OperationIdKey    = attribute.Key("arazzo.operation.id")
+	OperationPathKey  = attribute.Key("arazzo.operation.path")
+	StatusKey         = attribute.Key("arazzo.status")
+	AttemptKey        = attribute.Key("arazzo.attempt")
+	CriteriaPassedKey = attribute.Key("arazzo.criteria.passed")
+	CriteriaFailedKey = attribute.Key("arazzo.criteria.failed")
+)
+
+// Options configures a Hook.
+type Options struct {
+	// TracerProvider creates the tracer. The global provider is used when
+	// nil.
+	TracerProvider trace.TracerProvider
+
+	// Propagator sets the trace context on requests. W3C trace context is
+	// used when nil.
+	Propagator propagation.TextMapPropagator
+}
+
+// Hook records the spans of workflow runs. It is a runner.Hook; put it
+// first in runner.Runner.Hooks, so that its spans cover the other hooks.
+type Hook struct {
+	runner.NopHook
+
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	// workflows and steps hold the open spans by the result they record.
+	workflows sync.Map // *runner.WorkflowResult -> trace.Span
+	steps     sync.Map // *runner.StepResult -> *stepSpans
+}
+
+// stepSpans are the spans of a step and of its current attempt.
+type stepSpans struct {
+	ctx     context.Context
+	span    trace.Span
+	attempt trace.Span
+}
+
+// NewHook returns a Hook. opts may be nil.
+func NewHook(opts *Options) *Hook {
+	if opts == nil {
+		opts = &Options{}
+	}
+	tp := opts.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	h := &Hook{tracer: tp.Tracer(ScopeName), propagator: opts.Propagator}
+	if h.propagator == nil {
+		h.propagator = propagation.TraceContext{}
+	}
+	return h
+}
+
+// BeforeWorkflow starts the span of a workflow run.
+func (h *Hook) BeforeWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *runner.WorkflowResult) (context.Context, error) {
+	ctx, span := h.tracer.Start(ctx, "workflow "+wf.WorkflowId,
+		trace.WithTimestamp(res.Start),
+		trace.WithAttributes(WorkflowIdKey.String(wf.WorkflowId)))
+	h.workflows.Store(res, span)
+	return ctx, nil
+}
+
+// AfterWorkflow ends the span of a workflow run.
+func (h *Hook) AfterWorkflow(ctx context.Context, wf *arazzo1.Workflow, res *runner.WorkflowResult) {
+	v, ok := h.workflows.LoadAndDelete(res)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	span.SetAttributes(StatusKey.String(string(res.Status)))
+	setStatus(span, res.Status, res.Err)
+	span.End(trace.WithTimestamp(res.Start.Add(res.Duration)))
+}
+
+// BeforeStep starts the span of a step, on its first attempt, and the span
+// of the attempt, and sets the trace context of the attempt on its request.
+func (h *Hook) BeforeStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *runner.StepResult) (context.Context, error) {
+	v, _ := h.steps.Load(sr)
+	s, _ := v.(*stepSpans)
+	if s == nil {
+		s = &stepSpans{}
+		s.ctx, s.span = h.tracer.Start(ctx, "step "+step.StepId,
+			trace.WithTimestamp(sr.Start),
+			trace.WithAttributes(stepAttributes(wf, step, sr)...))
+		h.steps.Store(sr, s)
+	}
+
+	attrs := append(stepAttributes(wf, step, sr), AttemptKey.Int(sr.Attempts))
+	kind := trace.SpanKindInternal
+	if sr.Request != nil {
+		kind = trace.SpanKindClient
+		attrs = append(attrs,
+			semconv.HTTPRequestMethodKey.String(sr.Request.Method),
+			semconv.URLFull(sr.Request.URL))
+	}
+	ctx, s.attempt = h.tracer.Start(s.ctx, "attempt "+step.StepId, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+	if sr.Request != nil {
+		h.propagator.Inject(ctx, propagation.HeaderCarrier(sr.Request.Header))
+	}
+	return ctx, nil
+}
+
+// OnRetry ends the span of the attempt that failed.
+func (h *Hook) OnRetry(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *runner.StepResult, action *arazzo1.FailureAction) {
+	if v, ok := h.steps.Load(sr); ok {
+		s := v.(*stepSpans)
+		endAttempt(s, sr, runner.StatusFailed)
+	}
+}
+
+// AfterStep ends the spans of the last attempt and of the step.
+func (h *Hook) AfterStep(ctx context.Context, wf *arazzo1.Workflow, step *arazzo1.Step, sr *runner.StepResult) {
+	v, ok := h.steps.LoadAndDelete(sr)
+	if !ok {
+		// The step failed before its first attempt, such as when its
+		// operation could not be resolved.
+		_, span := h.tracer.Start(ctx, "step "+step.StepId,
+			trace.WithTimestamp(sr.Start),
+			trace.WithAttributes(stepAttributes(wf, step, sr)...))
+		v = &stepSpans{span: span}
+	}
+	s := v.(*stepSpans)
+	endAttempt(s, sr, sr.Status)
+	s.span.SetAttributes(StatusKey.String(string(sr.Status)), AttemptKey.Int(sr.Attempts))
+	if sr.OperationId != "" {
+		s.span.SetAttributes(OperationIdKey.String(sr.OperationId))
+	}
+	s.span.SetAttributes(resultAttributes(sr)...)
+	setStatus(s.span, sr.Status, sr.Err)
+	s.span.End(trace.WithTimestamp(sr.Start.Add(sr.Duration)))
+}
+
+// endAttempt ends the span of the current attempt, if any, with the
+// outcome of the attempt.
+func endAttempt(s *stepSpans, sr *runner.StepResult, status runner.Status) {
+	if s.attempt == nil {
+		return
+	}
+	s.attempt.SetAttributes(resultAttributes(sr)...)
+	setStatus(s.attempt, status, sr.Err)
+	s.attempt.End()
+	s.attempt = nil
+}
+
+func stepAttributes(wf *arazzo1.Workflow, step *arazzo1.Step, sr *runner.StepResult) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{WorkflowIdKey.String(wf.WorkflowId), StepIdKey.String(step.StepId)}
+	switch {
+	case sr.OperationId != "":
+		attrs = append(attrs, OperationIdKey.String(sr.OperationId))
+	case step.OperationId != "":
+		attrs = append(attrs, OperationIdKey.String(step.OperationId))
+	}
+	if step.OperationPath != "" {
+		attrs = append(attrs, OperationPathKey.String(step.OperationPath))
+	}
+	return attrs
+}
+
+// resultAttributes returns the status code and the criteria of the last
+// attempt of a step.
+func resultAttributes(sr *runner.StepResult) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if sr.Response != nil {
+		attrs = append(attrs, semconv.HTTPResponseStatusCode(sr.Response.StatusCode))
+	}
+	var passed, failed []string
+	for _, c := range sr.Criteria {
+		if c.Passed {
+			passed = append(passed, c.Criterion.Condition)
+		} else {
+			failed = append(failed, c.Criterion.Condition)
+		}
+	}
+	if len(passed) > 0 {
+		attrs = append(attrs, CriteriaPassedKey.StringSlice(passed))
+	}
+	if len(failed) > 0 {
+		attrs = append(attrs, CriteriaFailedKey.StringSlice(failed))
+	}
+	return attrs
+}
+
+func setStatus(span trace.Span, status runner.Status, err error) {
+	if status == runner.StatusPassed {
+		return
+	}
+	msg := string(status)
+	if err != nil {
+		msg = err.Error()
+		span.RecordError(err)
+	}
+	span.SetStatus(codes.Error, msg)
+}
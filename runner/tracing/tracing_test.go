@@ -0,0 +1,195 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/genelet/arazzo/arazzo1"
+	"github.com/genelet/arazzo/convert"
+	"github.com/genelet/arazzo/internal/oasutil"
+	"github.com/genelet/arazzo/runner"
+	"github.com/genelet/oas/openapi31"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testOpenAPI = `
+openapi: 3.1.0
+info: {title: Pets, version: 1.0.0}
+paths:
+  /flaky:
+    get:
+      operationId: flaky
+      responses: {"200": {description: ok}}
+  /pets:
+    get:
+      operationId: listPets
+      responses: {"200": {description: ok}}
+`
+
+const testArazzo = `
+arazzo: 1.0.0
+info: {title: Pets, version: 1.0.0}
+sourceDescriptions:
+  - {name: pets, url: pets.yaml, type: openapi}
+workflows:
+  - workflowId: list
+    steps:
+      - stepId: list
+        operationPath: '{$sourceDescriptions.pets.url}#/paths/~1pets/get'
+        successCriteria:
+          - condition: $statusCode == 200
+  - workflowId: pets
+    steps:
+      - stepId: flaky
+        operationId: flaky
+        successCriteria:
+          - condition: $statusCode == 200
+        onFailure:
+          - {name: again, type: retry, retryLimit: 1}
+      - stepId: call
+        workflowId: list
+`
+
+func TestHook(t *testing.T) {
+	var mu sync.Mutex
+	var flaky int
+	traceparents := map[string][]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		traceparents[r.URL.Path] = append(traceparents[r.URL.Path], r.Header.Get("traceparent"))
+		if r.URL.Path == "/flaky" {
+			if flaky++; flaky == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	var doc arazzo1.Arazzo
+	if err := convert.UnmarshalYAML([]byte(testArazzo), &doc); err != nil {
+		t.Fatal(err)
+	}
+	oa, err := oasutil.Parse([]byte(testOpenAPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	r := runner.New(&doc, map[string]*openapi31.OpenAPI{"pets": oa})
+	r.BaseURLs = map[string]string{"pets": srv.URL}
+	r.Hooks = []runner.Hook{NewHook(&Options{TracerProvider: tp})}
+
+	if _, err := r.RunWorkflow(context.Background(), "pets", nil); err != nil {
+		t.Fatalf("RunWorkflow failed: %v", err)
+	}
+
+	spans := make(map[string][]tracetest.SpanStub)
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = append(spans[s.Name], s)
+	}
+	one := func(name string) tracetest.SpanStub {
+		t.Helper()
+		if len(spans[name]) != 1 {
+			t.Fatalf("%d spans named %q, want 1", len(spans[name]), name)
+		}
+		return spans[name][0]
+	}
+	root := one("workflow pets")
+	flakyStep := one("step flaky")
+	call := one("step call")
+	sub := one("workflow list")
+	list := one("step list")
+	listAttempt := one("attempt list")
+	attempts := spans["attempt flaky"]
+	if len(attempts) != 2 {
+		t.Fatalf("%d attempts of flaky, want 2", len(attempts))
+	}
+
+	parent := func(child, parent tracetest.SpanStub) {
+		t.Helper()
+		if child.Parent.SpanID() != parent.SpanContext.SpanID() {
+			t.Errorf("parent of %s is not %s", child.Name, parent.Name)
+		}
+		if child.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("%s is not in the trace of the run", child.Name)
+		}
+	}
+	parent(flakyStep, root)
+	parent(attempts[0], flakyStep)
+	parent(attempts[1], flakyStep)
+	parent(call, root)
+	parent(sub, one("attempt call"))
+	parent(list, sub)
+	parent(listAttempt, list)
+
+	attrs := func(s tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range s.Attributes {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+	first := attrs(attempts[0])
+	if first[AttemptKey].AsInt64() != 1 || first["http.response.status_code"].AsInt64() != 503 ||
+		first[CriteriaFailedKey].AsStringSlice()[0] != "$statusCode == 200" || attempts[0].Status.Code != codes.Error {
+		t.Errorf("first attempt: %v %v", first, attempts[0].Status)
+	}
+	second := attrs(attempts[1])
+	if second[AttemptKey].AsInt64() != 2 || second[CriteriaPassedKey].AsStringSlice()[0] != "$statusCode == 200" {
+		t.Errorf("second attempt: %v", second)
+	}
+	step := attrs(flakyStep)
+	if step[WorkflowIdKey].AsString() != "pets" || step[StepIdKey].AsString() != "flaky" ||
+		step[OperationIdKey].AsString() != "flaky" || step[AttemptKey].AsInt64() != 2 || step[StatusKey].AsString() != "passed" {
+		t.Errorf("step: %v", step)
+	}
+	if got := attrs(list)[OperationIdKey].AsString(); got != "listPets" {
+		t.Errorf("operationId of an operationPath step = %q, want listPets", got)
+	}
+	if listAttempt.SpanKind != trace.SpanKindClient || attrs(listAttempt)["url.full"].AsString() != srv.URL+"/pets" {
+		t.Errorf("list attempt: %v %v", listAttempt.SpanKind, attrs(listAttempt))
+	}
+
+	// Each request carries the trace context of its attempt.
+	for i, tp := range traceparents["/flaky"] {
+		want := "00-" + root.SpanContext.TraceID().String() + "-" + attempts[i].SpanContext.SpanID().String() + "-01"
+		if tp != want {
+			t.Errorf("traceparent of attempt %d = %q, want %q", i+1, tp, want)
+		}
+	}
+	if got := traceparents["/pets"]; len(got) != 1 || got[0] == "" {
+		t.Errorf("traceparent of /pets = %v", got)
+	}
+}
+
+func TestHookFailedStep(t *testing.T) {
+	var doc arazzo1.Arazzo
+	if err := convert.UnmarshalYAML([]byte(testArazzo), &doc); err != nil {
+		t.Fatal(err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	// Without sources the operations cannot be resolved.
+	r := runner.New(&doc, nil)
+	r.Hooks = []runner.Hook{NewHook(&Options{TracerProvider: tp})}
+	if _, err := r.RunWorkflow(context.Background(), "pets", nil); err == nil {
+		t.Fatal("RunWorkflow succeeded without sources")
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want the workflow and the step", len(spans))
+	}
+	for _, s := range spans {
+		if s.Status.Code != codes.Error {
+			t.Errorf("%s status = %v, want an error", s.Name, s.Status)
+		}
+	}
+}